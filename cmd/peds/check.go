@@ -0,0 +1,461 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// checkFiles compares the generated files with the files on disk and writes a
+// unified diff to w for each file that differs. It returns true if any file differs.
+func checkFiles(files []generatedFile, w io.Writer) (bool, error) {
+	stale := false
+	for _, f := range files {
+		existing, err := os.ReadFile(f.path)
+		if err != nil && !os.IsNotExist(err) {
+			return false, err
+		}
+
+		if bytes.Equal(existing, f.content) {
+			continue
+		}
+
+		stale = true
+		oldName := f.path
+		if existing == nil {
+			oldName = os.DevNull
+		}
+
+		io.WriteString(w, unifiedDiff(oldName, f.path+" (generated)", string(existing), string(f.content)))
+	}
+
+	return stale, nil
+}
+
+// checkDirectives runs the peds go:generate directives found in the packages
+// matching patterns in check mode. A pattern is a directory, or a directory followed
+// by "/..." to include all directories below it. Returns true if any generated file
+// is stale.
+func checkDirectives(patterns []string, w io.Writer) (bool, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return false, err
+	}
+
+	stale := false
+	for _, pattern := range patterns {
+		dirs, err := patternDirs(pattern)
+		if err != nil {
+			return false, err
+		}
+
+		for _, dir := range dirs {
+			directives, err := generateDirectives(dir)
+			if err != nil {
+				return false, err
+			}
+
+			for _, d := range directives {
+				// Run the same way as go generate would, in the directory of the file
+				// holding the directive.
+				cmd := exec.Command(self, append([]string{"-check"}, d.args...)...)
+				cmd.Dir = dir
+				cmd.Env = append(os.Environ(), d.env...)
+				out := &bytes.Buffer{}
+				cmd.Stdout = out
+				cmd.Stderr = out
+				if err := cmd.Run(); err != nil {
+					if _, ok := err.(*exec.ExitError); !ok {
+						return false, err
+					}
+
+					stale = true
+					fmt.Fprintf(w, "%s:%d: peds -check failed\n", filepath.Join(dir, d.file), d.line)
+					w.Write(out.Bytes())
+				}
+			}
+		}
+	}
+
+	return stale, nil
+}
+
+func patternDirs(pattern string) ([]string, error) {
+	root, recursive := pattern, false
+	if pattern == "..." || strings.HasSuffix(pattern, "/...") {
+		root, recursive = strings.TrimSuffix(strings.TrimSuffix(pattern, "..."), "/"), true
+		if root == "" {
+			root = "."
+		}
+	}
+
+	if !recursive {
+		return []string{root}, nil
+	}
+
+	dirs := make([]string, 0)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		name := d.Name()
+		if p != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor") {
+			return filepath.SkipDir
+		}
+
+		dirs = append(dirs, p)
+		return nil
+	})
+
+	return dirs, err
+}
+
+type generateDirective struct {
+	file string
+	line int
+	args []string
+
+	// env holds the variables set by go generate when running the directive
+	env []string
+}
+
+// generateDirectives returns the go:generate directives running peds in the go files in dir.
+// The arguments are expanded the same way as go generate does.
+func generateDirectives(dir string) ([]generateDirective, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]generateDirective, 0)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".go") {
+			continue
+		}
+
+		f, err := os.Open(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		pkg := ""
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, 1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			text := strings.TrimSuffix(scanner.Text(), "\r")
+			if !strings.HasPrefix(text, "//go:generate ") && !strings.HasPrefix(text, "//go:generate\t") {
+				continue
+			}
+
+			words, err := splitGenerateArgs(text[len("//go:generate "):])
+			if err == nil && pkg == "" {
+				pkg, err = packageName(filepath.Join(dir, e.Name()))
+			}
+
+			if err != nil {
+				f.Close()
+				return nil, errors.Wrapf(err, "%s:%d", filepath.Join(dir, e.Name()), line)
+			}
+
+			env := generateEnv(e.Name(), line, pkg)
+			words = expandGenerateArgs(words, env)
+			if len(words) > 0 && words[0] == "peds" {
+				result = append(result, generateDirective{file: e.Name(), line: line, args: words[1:], env: env})
+			}
+		}
+
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func packageName(file string) (string, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.PackageClauseOnly)
+	if err != nil {
+		return "", err
+	}
+
+	return f.Name.Name, nil
+}
+
+// generateEnv returns the variables that go generate sets when running the directive
+// on line of file, a file in package pkg.
+func generateEnv(file string, line int, pkg string) []string {
+	return []string{
+		"GOROOT=" + build.Default.GOROOT,
+		"GOARCH=" + build.Default.GOARCH,
+		"GOOS=" + build.Default.GOOS,
+		"GOFILE=" + file,
+		"GOLINE=" + strconv.Itoa(line),
+		"GOPACKAGE=" + pkg,
+		"DOLLAR=$"}
+}
+
+// expandGenerateArgs expands $NAME and ${NAME} in words the same way as go generate
+// does. Variables in env take precedence over those in the environment.
+func expandGenerateArgs(words, env []string) []string {
+	lookup := func(name string) string {
+		for _, e := range env {
+			if strings.HasPrefix(e, name+"=") {
+				return e[len(name)+1:]
+			}
+		}
+
+		return os.Getenv(name)
+	}
+
+	result := make([]string, len(words))
+	for i, w := range words {
+		result[i] = os.Expand(w, lookup)
+	}
+
+	return result
+}
+
+// splitGenerateArgs splits the arguments of a go:generate directive into words the
+// same way as go generate does. Words are separated by spaces, double quoted words
+// are unquoted as Go strings.
+func splitGenerateArgs(line string) ([]string, error) {
+	words := make([]string, 0)
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" {
+			return words, nil
+		}
+
+		if line[0] == '"' {
+			end := 1
+			for ; end < len(line) && line[end] != '"'; end++ {
+				if line[end] == '\\' {
+					end++
+				}
+			}
+
+			if end >= len(line) {
+				return nil, errors.New("unterminated quoted string")
+			}
+
+			word, err := strconv.Unquote(line[:end+1])
+			if err != nil {
+				return nil, err
+			}
+
+			words = append(words, word)
+			line = line[end+1:]
+			continue
+		}
+
+		end := strings.IndexAny(line, " \t")
+		if end < 0 {
+			end = len(line)
+		}
+
+		words = append(words, line[:end])
+		line = line[end:]
+	}
+}
+
+////////////
+/// Diff ///
+////////////
+
+// unifiedDiff returns a unified diff, with three lines of context, between a and b.
+func unifiedDiff(aName, bName, a, b string) string {
+	aLines, bLines := splitLines(a), splitLines(b)
+	ops := diffLines(aLines, bLines)
+
+	out := &strings.Builder{}
+	fmt.Fprintf(out, "--- %s\n+++ %s\n", aName, bName)
+
+	const context = 3
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		// Extend the hunk until there are more than 2*context unchanged lines in a row
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+
+			if run == len(ops) || run-end > 2*context {
+				end += context
+				if end > len(ops) {
+					end = len(ops)
+				}
+
+				break
+			}
+
+			end = run
+		}
+
+		aStart, aCount, bStart, bCount := ops[start].aLine, 0, ops[start].bLine, 0
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				aCount++
+			}
+
+			if op.kind != '-' {
+				bCount++
+			}
+		}
+
+		fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+		for _, op := range ops[start:end] {
+			fmt.Fprintf(out, "%c%s\n", op.kind, op.text)
+		}
+
+		i = end
+	}
+
+	return out.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		// An empty range refers to the line before it
+		return fmt.Sprintf("%d,0", start)
+	}
+
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffOp is a line that is either kept, ' ', removed, '-', or added, '+'. aLine and
+// bLine are the number of lines in a and b before the line.
+type diffOp struct {
+	kind         byte
+	text         string
+	aLine, bLine int
+}
+
+// diffLines returns the edit script turning a into b. Common prefix and suffix are
+// kept as is. The remaining lines are diffed using a longest common subsequence unless
+// too large, in which case they are all replaced.
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	ai, bi := 0, 0
+	keep := func() {
+		ops = append(ops, diffOp{kind: ' ', text: a[ai], aLine: ai, bLine: bi})
+		ai, bi = ai+1, bi+1
+	}
+
+	remove := func() {
+		ops = append(ops, diffOp{kind: '-', text: a[ai], aLine: ai, bLine: bi})
+		ai++
+	}
+
+	add := func() {
+		ops = append(ops, diffOp{kind: '+', text: b[bi], aLine: ai, bLine: bi})
+		bi++
+	}
+
+	for ai < prefix {
+		keep()
+	}
+
+	aMid, bMid := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	n, m := len(aMid), len(bMid)
+	if n*m <= 4*1024*1024 {
+		// lcs[i][j] is the length of the longest common subsequence of aMid[i:] and bMid[j:]
+		lcs := make([][]int32, n+1)
+		for i := range lcs {
+			lcs[i] = make([]int32, m+1)
+		}
+
+		for i := n - 1; i >= 0; i-- {
+			for j := m - 1; j >= 0; j-- {
+				if aMid[i] == bMid[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else if lcs[i+1][j] >= lcs[i][j+1] {
+					lcs[i][j] = lcs[i+1][j]
+				} else {
+					lcs[i][j] = lcs[i][j+1]
+				}
+			}
+		}
+
+		i, j := 0, 0
+		for i < n && j < m {
+			switch {
+			case aMid[i] == bMid[j]:
+				keep()
+				i, j = i+1, j+1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				remove()
+				i++
+			default:
+				add()
+				j++
+			}
+		}
+	}
+
+	for ai < len(a)-suffix {
+		remove()
+	}
+
+	for bi < len(b)-suffix {
+		add()
+	}
+
+	for ai < len(a) {
+		keep()
+	}
+
+	return ops
+}
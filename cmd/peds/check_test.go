@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// runMainEnv makes the test binary run main instead of the tests. It is used when
+// checkDirectives runs the directives using the executable of the current process.
+const runMainEnv = "PEDS_TEST_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) != "" {
+		main()
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	lines := func(from, to int) string {
+		buf := &strings.Builder{}
+		for i := from; i < to; i++ {
+			buf.WriteString(string(rune('a'+i%26)) + "\n")
+		}
+
+		return buf.String()
+	}
+
+	for _, tc := range []struct {
+		name     string
+		a, b     string
+		expected string
+	}{
+		{
+			name:     "equal",
+			a:        "a\nb\n",
+			b:        "a\nb\n",
+			expected: "--- a\n+++ b\n",
+		},
+		{
+			name:     "new file",
+			a:        "",
+			b:        "a\nb\n",
+			expected: "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name:     "changed line with context",
+			a:        lines(0, 10),
+			b:        strings.Replace(lines(0, 10), "e\n", "x\n", 1),
+			expected: "--- a\n+++ b\n@@ -2,7 +2,7 @@\n b\n c\n d\n-e\n+x\n f\n g\n h\n",
+		},
+		{
+			name:     "removed last line",
+			a:        "a\nb\nc\n",
+			b:        "a\nb\n",
+			expected: "--- a\n+++ b\n@@ -1,3 +1,2 @@\n a\n b\n-c\n",
+		},
+		{
+			name: "separate hunks",
+			a:    lines(0, 20),
+			b:    "x\n" + lines(1, 19) + "y\n",
+			expected: "--- a\n+++ b\n@@ -1,4 +1,4 @@\n-a\n+x\n b\n c\n d\n" +
+				"@@ -17,4 +17,4 @@\n q\n r\n s\n-t\n+y\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := unifiedDiff("a", "b", tc.a, tc.b); diff != tc.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", tc.expected, diff)
+			}
+		})
+	}
+}
+
+func TestSplitGenerateArgs(t *testing.T) {
+	for _, tc := range []struct {
+		line     string
+		expected []string
+		err      string
+	}{
+		{line: "peds -vectors=V<int> -pkg=p", expected: []string{"peds", "-vectors=V<int>", "-pkg=p"}},
+		{line: " \tpeds\t-pkg=p  ", expected: []string{"peds", "-pkg=p"}},
+		{line: `peds "-maps=M<string, int>" "a\"b\\"`, expected: []string{"peds", "-maps=M<string, int>", `a"b\`}},
+		{line: "", expected: []string{}},
+		{line: `peds "-pkg=p`, err: "unterminated quoted string"},
+	} {
+		t.Run(tc.line, func(t *testing.T) {
+			words, err := splitGenerateArgs(tc.line)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Errorf("Expected error %q, got %v", tc.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(words, tc.expected) {
+				t.Errorf("Expected %q, got %q", tc.expected, words)
+			}
+		})
+	}
+}
+
+func TestGenerateDirectives(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "types.go"), "package collections\n\n"+
+		"//go:generate go run other\n"+
+		"//go:generate\tpeds -file=${GOFILE}_gen.go -pkg=$GOPACKAGE -imports=$DOLLAR$GOLINE\r\n")
+	writeFile(t, filepath.Join(dir, "README.md"), "//go:generate peds -pkg=readme\n")
+
+	directives, err := generateDirectives(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(directives) != 1 {
+		t.Fatalf("Expected one directive, got %#v", directives)
+	}
+
+	d := directives[0]
+	if d.file != "types.go" || d.line != 4 {
+		t.Errorf("Unexpected directive position %s:%d", d.file, d.line)
+	}
+
+	if expected := []string{"-file=types.go_gen.go", "-pkg=collections", "-imports=$4"}; !reflect.DeepEqual(d.args, expected) {
+		t.Errorf("Expected %q, got %q", expected, d.args)
+	}
+}
+
+func TestPatternDirs(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"a/b", "c", ".hidden/d", "_skipped", "testdata", "vendor/e"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		pattern  string
+		expected []string
+	}{
+		{pattern: "a", expected: []string{"a"}},
+		{pattern: "a/...", expected: []string{"a", "a/b"}},
+		{pattern: "...", expected: []string{".", "a", "a/b", "c"}},
+		{pattern: "a/b/...", expected: []string{"a/b"}},
+	} {
+		t.Run(tc.pattern, func(t *testing.T) {
+			dirs, err := patternDirs(filepath.Join(root, tc.pattern))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, d := range dirs {
+				rel, err := filepath.Rel(root, d)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				dirs[i] = filepath.ToSlash(rel)
+			}
+
+			if !reflect.DeepEqual(dirs, tc.expected) {
+				t.Errorf("Expected %q, got %q", tc.expected, dirs)
+			}
+		})
+	}
+}
+
+func TestCheckDirectives(t *testing.T) {
+	t.Setenv(runMainEnv, "1")
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "types.go"),
+		"package collections\n\n//go:generate peds -vectors=IntVector<int> -pkg=$GOPACKAGE -file=${GOFILE}_gen.go\n")
+	generated := filepath.Join(dir, "types.go_gen.go")
+
+	for _, tc := range []struct {
+		name     string
+		setup    func(t *testing.T)
+		stale    bool
+		expected string
+	}{
+		{
+			name:     "missing file",
+			setup:    func(t *testing.T) {},
+			stale:    true,
+			expected: "+++ types.go_gen.go (generated)",
+		},
+		{
+			name:  "up to date",
+			setup: func(t *testing.T) { runDirectives(t, dir) },
+		},
+		{
+			name: "edited file",
+			setup: func(t *testing.T) {
+				src, err := os.ReadFile(generated)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				writeFile(t, generated, string(src)+"// edited\n")
+			},
+			stale:    true,
+			expected: "\n-// edited\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(t)
+			out := &bytes.Buffer{}
+			stale, err := checkDirectives([]string{dir}, out)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if stale != tc.stale {
+				t.Errorf("Expected stale to be %t, output:\n%s", tc.stale, out)
+			}
+
+			if tc.stale && !strings.Contains(out.String(), "types.go:3: peds -check failed") {
+				t.Errorf("Expected the failing directive in the output:\n%s", out)
+			}
+
+			if !strings.Contains(out.String(), tc.expected) {
+				t.Errorf("Expected the output to contain %q:\n%s", tc.expected, out)
+			}
+		})
+	}
+}
+
+// runDirectives runs the peds directives in dir, the same way as go generate does.
+func runDirectives(t *testing.T, dir string) {
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	directives, err := generateDirectives(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, d := range directives {
+		cmd := exec.Command(self, d.args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), d.env...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,30 +1,34 @@
 package main
 
 import (
-	"bytes"
 	"flag"
 	"fmt"
-	"go/format"
-	"io"
 	"os"
-	"regexp"
+	"path/filepath"
 	"strings"
 	"text/tabwriter"
-	"text/template"
 
 	"github.com/pkg/errors"
-	"github.com/tobgu/peds/internal/templates"
+	"github.com/tobgu/peds/pkg/pedsgen"
 )
 
 func usage(fs *flag.FlagSet) func() {
 	return func() {
 		os.Stderr.WriteString("Generate statically type safe code for persistent data structures.\n\n")
 		os.Stderr.WriteString("USAGE\n")
-		os.Stderr.WriteString("peds\n\n")
+		os.Stderr.WriteString("peds [flags]\n")
+		os.Stderr.WriteString("peds -check [flags]\n")
+		os.Stderr.WriteString("peds -check dir/...\n\n")
 		os.Stderr.WriteString("FLAGS        EXAMPLE\n")
 		w := tabwriter.NewWriter(os.Stderr, 0, 2, 2, ' ', 0)
 		fs.VisitAll(func(f *flag.Flag) {
-			fmt.Fprintf(w, "\t-%s %s\t%s\n", f.Name, f.DefValue, f.Usage)
+			defValue := f.DefValue
+			if defValue == "false" {
+				// Boolean flag
+				defValue = ""
+			}
+
+			fmt.Fprintf(w, "\t-%s %s\t%s\n", f.Name, defValue, f.Usage)
 		})
 		w.Flush()
 		os.Stderr.WriteString("\n")
@@ -40,12 +44,23 @@ func logAndExit(err error, flagSet *flag.FlagSet) {
 func main() {
 	flagSet := flag.NewFlagSet("server", flag.ExitOnError)
 	var (
-		vectors = flagSet.String("vectors", "", "Vec1<int>")
-		maps    = flagSet.String("maps", "", "Map1<int,string>;Map2<float,int>")
-		sets    = flagSet.String("sets", "", "Set1<int>")
-		file    = flagSet.String("file", "", "path/to/file.go")
-		imports = flagSet.String("imports", "", "import1;import2")
-		pkg     = flagSet.String("pkg", "", "package_name")
+		vectors     = flagSet.String("vectors", "", "Vec1<int>;Vec2<int;methods=minimal,Slice>")
+		rrbVectors  = flagSet.String("rrbvectors", "", "RRBVec1<int>")
+		maps        = flagSet.String("maps", "", "Map1<int,string>;Map2<Key,int;hash=KeyHash;eq=KeyEq>")
+		sets        = flagSet.String("sets", "", "Set1<int>;Set2<Key;hash=KeyHash;eq=KeyEq>")
+		sortedMaps  = flagSet.String("sortedmaps", "", "SortedMap1<int,string>;SortedMap2<Key,int;less=KeyLess>")
+		sortedSets  = flagSet.String("sortedsets", "", "SortedSet1<int>;SortedSet2<Key;less=KeyLess>")
+		orderedMaps = flagSet.String("orderedmaps", "", "OrderedMap1<string,int>;OrderedMap2<Key,int;hash=KeyHash;eq=KeyEq>")
+		deques      = flagSet.String("deques", "", "Deque1<int>")
+		heaps       = flagSet.String("heaps", "", "Heap1<int>;Heap2<Job;less=JobLess>")
+		file        = flagSet.String("file", "", "path/to/file.go")
+		imports     = flagSet.String("imports", "", "import1;name=import2")
+		pkg         = flagSet.String("pkg", "", "package_name")
+		commonFile  = flagSet.String("commonfile", "", "path/to/common_gen.go")
+		goVersion   = flagSet.String("goversion", "", "1.23")
+		config      = flagSet.String("config", "", "path/to/peds.yaml")
+		scan        = flagSet.String("scan", "", "path/to/package")
+		check       = flagSet.Bool("check", false, "")
 	)
 
 	flagSet.Usage = usage(flagSet)
@@ -53,284 +68,166 @@ func main() {
 		logAndExit(err, flagSet)
 	}
 
-	buf := &bytes.Buffer{}
-
-	if err := renderCommon(buf, *pkg, *imports); err != nil {
-		logAndExit(err, flagSet)
-	}
-
-	if err := renderVectors(buf, *vectors); err != nil {
-		logAndExit(err, flagSet)
-	}
-
-	if err := renderMaps(buf, *maps); err != nil {
-		logAndExit(err, flagSet)
-	}
-
-	if err := renderSet(buf, *sets); err != nil {
-		logAndExit(err, flagSet)
-	}
-
-	if err := writeFile(buf, *file); err != nil {
-		logAndExit(err, flagSet)
-	}
-}
-
-///////////////
-/// Helpers ///
-///////////////
-
-func removeWhiteSpaces(s string) string {
-	return strings.Join(strings.Fields(s), "")
-}
-
-type templateSpec struct {
-	name     string
-	template string
-}
-
-func renderTemplates(specs []templateSpec, templateData interface{}, dst io.Writer) error {
-	for _, s := range specs {
-		t := template.New(s.name)
-		t, err := t.Parse(s.template)
+	if *check && flagSet.NArg() > 0 {
+		stale, err := checkDirectives(flagSet.Args(), os.Stdout)
 		if err != nil {
-			return err
+			logAndExit(err, flagSet)
 		}
 
-		err = t.Execute(dst, templateData)
-		if err != nil {
-			return err
+		if stale {
+			os.Exit(1)
 		}
-	}
 
-	return nil
-}
-
-//////////////
-/// Common ///
-//////////////
-
-func renderCommon(buf *bytes.Buffer, pkgName, importsString string) error {
-	pkgName = removeWhiteSpaces(pkgName)
-	if pkgName == "" {
-		return errors.New("pkg is required")
+		return
 	}
 
-	importTemplate := `{{if .Imports}}
-import (
-{{range $imp := .Imports}}
-	"{{$imp}}"
-{{end}}
-)
-{{end}}`
+	var cfgs []pedsgen.Config
+	if *config != "" {
+		var err error
+		if cfgs, err = pedsgen.LoadConfigFile(*config); err != nil {
+			logAndExit(err, flagSet)
+		}
+	} else {
+		cfg := pedsgen.Config{
+			File:       *file,
+			Imports:    strings.Split(strings.Trim(*imports, `"`), ";"),
+			Package:    *pkg,
+			CommonFile: *commonFile,
+			GoVersion:  *goVersion}
+
+		for _, f := range []struct {
+			kind  pedsgen.Kind
+			specs string
+		}{
+			{pedsgen.VectorKind, *vectors},
+			{pedsgen.RRBVectorKind, *rrbVectors},
+			{pedsgen.MapKind, *maps},
+			{pedsgen.SetKind, *sets},
+			{pedsgen.SortedMapKind, *sortedMaps},
+			{pedsgen.SortedSetKind, *sortedSets},
+			{pedsgen.OrderedMapKind, *orderedMaps},
+			{pedsgen.DequeKind, *deques},
+			{pedsgen.HeapKind, *heaps},
+		} {
+			if err := cfg.Parse(f.kind, f.specs); err != nil {
+				logAndExit(err, flagSet)
+			}
+		}
 
-	var imports []string = nil
-	importsString = removeWhiteSpaces(importsString)
-	if importsString != "" {
-		imports = strings.Split(importsString, ";")
-	}
+		if *scan != "" {
+			var err error
+			if cfg, err = withScannedPackage(cfg, *scan); err != nil {
+				logAndExit(err, flagSet)
+			}
+		}
 
-	return renderTemplates([]templateSpec{
-		{name: "pkg", template: "package {{index .PackageName 0}}\n"},
-		{name: "imports", template: importTemplate},
-		{name: "common", template: templates.CommonTemplate}},
-		map[string][]string{"PackageName": {pkgName}, "Imports": imports}, buf)
-}
+		cfgs = []pedsgen.Config{cfg}
+	}
 
-//////////////
-/// Vector ///
-//////////////
+	// Everything is generated before anything is written
+	files := make([]generatedFile, 0)
+	for _, cfg := range cfgs {
+		// go generate runs in the directory of the file holding the directive and
+		// passes its name in $GOFILE.
+		cfg.DirectiveFile = os.Getenv("GOFILE")
+		generated, err := generate(cfg)
+		if err != nil {
+			if *config != "" {
+				err = errors.Wrapf(err, "Failed to generate %s", cfg.File)
+			}
 
-func renderVectors(buf *bytes.Buffer, vectors string) error {
-	vectors = removeWhiteSpaces(vectors)
-	if vectors == "" {
-		return nil
-	}
+			logAndExit(err, flagSet)
+		}
 
-	vectorSpecs, err := parseVectorSpecs(vectors)
-	if err != nil {
-		return err
+		files = append(files, generated...)
 	}
 
-	for _, spec := range vectorSpecs {
-		err := renderTemplates([]templateSpec{
-			{name: "vector", template: templates.VectorTemplate},
-			{name: "slice", template: templates.SliceTemplate}},
-			spec, buf)
-
+	if *check {
+		stale, err := checkFiles(files, os.Stdout)
 		if err != nil {
-			return err
+			logAndExit(err, flagSet)
 		}
-	}
-
-	return nil
-}
-
-type vectorSpec struct {
-	VectorTypeName string
-	TypeName       string
-}
 
-func parseVectorSpecs(vectorDescriptor string) ([]vectorSpec, error) {
-	result := make([]vectorSpec, 0)
-	descriptors := strings.Split(vectorDescriptor, ";")
-	r := regexp.MustCompile(`([A-Za-z0-9]+)<(\*?[A-Za-z0-9.]+)>`)
-	for _, d := range descriptors {
-		m := r.FindStringSubmatch(strings.TrimSpace(d))
-		if len(m) != 3 {
-			return nil, fmt.Errorf("Invalid vector specification: %s", d)
+		if stale {
+			os.Exit(1)
 		}
 
-		result = append(result, vectorSpec{VectorTypeName: m[1], TypeName: m[2]})
+		return
 	}
 
-	return result, nil
+	if err := writeFiles(files); err != nil {
+		logAndExit(err, flagSet)
+	}
 }
 
-///////////
-/// Map ///
-///////////
-
-func renderMaps(buf *bytes.Buffer, maps string) error {
-	maps = removeWhiteSpaces(maps)
-	if maps == "" {
-		return nil
+// withScannedPackage adds the containers declared by directives in the package in dir
+// to cfg. Unless given, the package name is taken from the scanned package and
+// the output file is peds_gen.go in dir.
+func withScannedPackage(cfg pedsgen.Config, dir string) (pedsgen.Config, error) {
+	if cfg.File == "" {
+		cfg.File = filepath.Join(dir, "peds_gen.go")
 	}
 
-	mapSpecs, err := parseMapSpecs(maps)
+	scanned, err := pedsgen.ScanPackage(dir, cfg.File)
 	if err != nil {
-		return err
+		return pedsgen.Config{}, err
 	}
 
-	for _, spec := range mapSpecs {
-		err := renderTemplates([]templateSpec{
-			{name: "map_template", template: templates.PrivateMapTemplate},
-			{name: "public_map_template", template: templates.PublicMapTemplate}},
-			spec, buf)
-
-		if err != nil {
-			return err
-		}
+	if pkg := strings.TrimSpace(cfg.Package); pkg == "" {
+		cfg.Package = scanned.Package
+	} else if scanned.Package != "" && pkg != scanned.Package {
+		return pedsgen.Config{}, fmt.Errorf("pkg %s does not match the scanned package %s", pkg, scanned.Package)
 	}
 
-	return nil
+	cfg.Imports = append(cfg.Imports, scanned.Imports...)
+	cfg.Vectors = append(cfg.Vectors, scanned.Vectors...)
+	cfg.RRBVectors = append(cfg.RRBVectors, scanned.RRBVectors...)
+	cfg.Maps = append(cfg.Maps, scanned.Maps...)
+	cfg.Sets = append(cfg.Sets, scanned.Sets...)
+	cfg.SortedMaps = append(cfg.SortedMaps, scanned.SortedMaps...)
+	cfg.SortedSets = append(cfg.SortedSets, scanned.SortedSets...)
+	cfg.OrderedMaps = append(cfg.OrderedMaps, scanned.OrderedMaps...)
+	cfg.Deques = append(cfg.Deques, scanned.Deques...)
+	cfg.Heaps = append(cfg.Heaps, scanned.Heaps...)
+	return cfg, nil
 }
 
-type mapSpec struct {
-	MapTypeName      string
-	MapItemTypeName  string
-	MapKeyTypeName   string
-	MapValueTypeName string
-	MapKeyHashFunc   string
+// generatedFile is the formatted content of a file that is about to be written.
+type generatedFile struct {
+	path    string
+	content []byte
 }
 
-func hashFunc(typ string) string {
-	for _, hashTyp := range []string{"byte", "bool", "rune", "string",
-		"int8", "uint8", "int16", "uint16", "int32", "uint32", "int64", "uint64", "int", "uint", "float32", "float64"} {
-		if typ == hashTyp {
-			return typ + "Hash"
-		}
+// generate returns the file described by cfg and, if given, the common file.
+func generate(cfg pedsgen.Config) ([]generatedFile, error) {
+	if cfg.File == "" {
+		return nil, errors.New("Output file must be specified")
 	}
 
-	return "interfaceHash"
-}
-
-func parseMapSpecs(mapDescriptor string) ([]mapSpec, error) {
-	result := make([]mapSpec, 0)
-	descriptors := strings.Split(mapDescriptor, ";")
-	r := regexp.MustCompile(`([A-Za-z0-9]+)<([A-Za-z0-9.]+),(\*?[A-Za-z0-9.]+)>`)
-	for _, d := range descriptors {
-		m := r.FindStringSubmatch(strings.TrimSpace(d))
-		if len(m) != 4 {
-			return nil, fmt.Errorf("Invalid map specification: %s", d)
-		}
-
-		keyTypeName := m[2]
-		result = append(result,
-			mapSpec{MapTypeName: m[1], MapItemTypeName: m[1] + "Item", MapKeyTypeName: keyTypeName, MapValueTypeName: m[3], MapKeyHashFunc: hashFunc(keyTypeName)})
+	content, err := pedsgen.Generate(cfg)
+	if err != nil {
+		return nil, err
 	}
 
-	return result, nil
-}
-
-///////////
-/// Set ///
-///////////
-
-func renderSet(buf *bytes.Buffer, sets string) error {
-	sets = strings.Join(strings.Fields(sets), "")
-	if sets == "" {
-		return nil
+	files := []generatedFile{{path: cfg.File, content: content}}
+	if cfg.CommonFile == "" {
+		return files, nil
 	}
 
-	setSpecs, err := parseSetSpecs(sets)
+	common, err := pedsgen.GenerateCommon(cfg)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	for _, spec := range setSpecs {
-		err := renderTemplates([]templateSpec{
-			{name: "private_map_template", template: templates.PrivateMapTemplate},
-			{name: "set_template", template: templates.SetTemplate}},
-			spec, buf)
-
-		if err != nil {
-			return err
-		}
-	}
-
-	return err
+	return append(files, generatedFile{path: cfg.CommonFile, content: common}), nil
 }
 
-type setSpec struct {
-	mapSpec
-	SetTypeName string
-}
-
-func parseSetSpecs(setDescriptor string) ([]setSpec, error) {
-	result := make([]setSpec, 0)
-	descriptors := strings.Split(setDescriptor, ";")
-	r := regexp.MustCompile(`([A-Za-z0-9]+)<(\*?[A-Za-z0-9.]+)>`)
-	for _, d := range descriptors {
-		m := r.FindStringSubmatch(strings.TrimSpace(d))
-		if len(m) != 3 {
-			return nil, fmt.Errorf("Invalid set specification: %s", d)
+func writeFiles(files []generatedFile) error {
+	for _, f := range files {
+		if err := os.WriteFile(f.path, f.content, 0644); err != nil {
+			return err
 		}
-
-		keyTypeName := m[2]
-		mapName := "private" + m[1] + "Map"
-		result = append(result,
-			setSpec{
-				mapSpec:     mapSpec{MapTypeName: mapName, MapItemTypeName: mapName + "Item", MapKeyTypeName: keyTypeName, MapValueTypeName: "struct{}", MapKeyHashFunc: hashFunc(keyTypeName)},
-				SetTypeName: m[1]})
-	}
-
-	return result, nil
-}
-
-////////////
-/// File ///
-////////////
-
-func writeFile(buf *bytes.Buffer, file string) error {
-	if file == "" {
-		return errors.New("Output file must be specified")
 	}
 
-	f, err := os.Create(file)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	// The equivalent of "go fmt" before writing content
-	src := buf.Bytes()
-	fmtSrc, err := format.Source(src)
-	if err != nil {
-		os.Stdout.WriteString(string(src))
-		return errors.Wrap(err, "Format error")
-	}
-
-	_, err = f.Write(fmtSrc)
-	return err
+	return nil
 }
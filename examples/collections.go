@@ -1,10 +1,27 @@
+// Code generated by peds v0.5.0; DO NOT EDIT.
+//
+// Generated in the directory of this file by:
+// peds '-vectors=IntVector<int>' '-maps=PersonBySsn<string,Person>' '-sets=Persons<Person>' '-deques=PersonQueue<Person>' -pkg=examples -file=collections.go
+
 package examples
 
 import (
+	"bufio"
+	"bytes"
+	"encoding"
 	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
 	"fmt"
 	"hash/crc32"
+	"io"
 	"math"
+	"math/bits"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"unsafe"
 )
 
 const shiftSize = 5
@@ -45,9 +62,324 @@ func assertSliceOk(start, stop, len int) {
 	}
 }
 
-const upperMapLoadFactor float64 = 8.0
-const lowerMapLoadFactor float64 = 2.0
-const initialMapLoadFactor float64 = (upperMapLoadFactor + lowerMapLoadFactor) / 2
+func assertTransientEditable(editable bool) {
+	if !editable {
+		panic("Transient used after call to Persistent")
+	}
+}
+
+// transientOwner identifies the nodes that are owned, and may be edited in place,
+// by a transient.
+type transientOwner struct {
+	_ byte
+}
+
+// vectorNodeOwner is kept in a slot beyond the length of the vector trie nodes owned by
+// a transient. Leaves have no room for an owner of their own, the leaves of a node that
+// are owned by the transient are instead marked in leaves.
+type vectorNodeOwner struct {
+	transient *transientOwner
+	leaves    uint32
+}
+
+// Number of hash bits used to place items in a CHAMP tree. Items with identical
+// hashes are stored in collision nodes below the last level.
+const champHashBits = 32
+
+func champBitpos(hash uint32, shift uint) uint32 {
+	return 1 << ((hash >> shift) & shiftBitMask)
+}
+
+func champIndex(bitmap, bit uint32) int {
+	return bits.OnesCount32(bitmap & (bit - 1))
+}
+
+// Maximum and minimum number of items in a B-tree node. The root is allowed to
+// contain fewer than btreeMinItems items.
+const btreeMaxItems = 2*btreeMinItems + 1
+const btreeMinItems = 15
+
+// btreeCapacity returns the maximum number of items in a B-tree of the given height.
+func btreeCapacity(height int) int {
+	capacity := btreeMaxItems
+	for ; height > 0; height-- {
+		capacity = capacity*(btreeMaxItems+1) + btreeMaxItems
+	}
+
+	return capacity
+}
+
+// Maximum ratio between the lengths of the front and back lists of a deque.
+const dequeBalance = 3
+
+// jsonObjectKey returns key encoded as a JSON object key using the same rules as
+// encoding/json uses for map keys.
+func jsonObjectKey(key interface{}) ([]byte, error) {
+	v := reflect.ValueOf(key)
+	if v.Kind() == reflect.String {
+		return json.Marshal(v.String())
+	}
+
+	if tm, ok := key.(encoding.TextMarshaler); ok {
+		text, err := tm.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(string(text))
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return json.Marshal(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return json.Marshal(strconv.FormatUint(v.Uint(), 10))
+	}
+
+	return nil, fmt.Errorf("unsupported JSON object key type %T", key)
+}
+
+// jsonObjectKeys returns true if keys of the type pointed to by keyPtr are encoded as
+// JSON object keys, that is if encoding/json would accept them as map keys. Other keys
+// are encoded as arrays of [key, value] pairs.
+func jsonObjectKeys(keyPtr interface{}) bool {
+	t := reflect.TypeOf(keyPtr).Elem()
+	switch t.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+
+	return t.Implements(reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem())
+}
+
+// jsonDecodeObjectKey decodes s, a JSON object key, into the value pointed to by key
+// using the same rules as encoding/json uses for map keys.
+func jsonDecodeObjectKey(s string, key interface{}) error {
+	if tu, ok := key.(encoding.TextUnmarshaler); ok {
+		return tu.UnmarshalText([]byte(s))
+	}
+
+	v := reflect.ValueOf(key).Elem()
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || v.OverflowInt(n) {
+			return fmt.Errorf("invalid JSON object key %q for type %s", s, v.Type())
+		}
+
+		v.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil || v.OverflowUint(n) {
+			return fmt.Errorf("invalid JSON object key %q for type %s", s, v.Type())
+		}
+
+		v.SetUint(n)
+		return nil
+	}
+
+	return fmt.Errorf("unsupported JSON object key type %s", v.Type())
+}
+
+// jsonEncodeMap returns the JSON encoding of the entries passed by rangeEntries to its
+// argument. The entries are encoded as an object if objectKeys is true, otherwise as an
+// array of [key, value] arrays. Pointers to the keys and values are passed so that
+// MarshalJSON methods with pointer receivers, such as those of the containers, are used.
+func jsonEncodeMap(objectKeys bool, rangeEntries func(entry func(key, value interface{}) bool)) ([]byte, error) {
+	start, sep, end := byte('['), byte(','), byte(']')
+	if objectKeys {
+		start, sep, end = '{', ':', '}'
+	}
+
+	result := []byte{start}
+	var err error
+	rangeEntries(func(key, value interface{}) bool {
+		var k, v []byte
+		if objectKeys {
+			k, err = jsonObjectKey(reflect.ValueOf(key).Elem().Interface())
+		} else {
+			k, err = json.Marshal(key)
+		}
+
+		if err != nil {
+			return false
+		}
+
+		if v, err = json.Marshal(value); err != nil {
+			return false
+		}
+
+		if len(result) > 1 {
+			result = append(result, ',')
+		}
+
+		if !objectKeys {
+			result = append(result, '[')
+		}
+
+		result = append(append(append(result, k...), sep), v...)
+		if !objectKeys {
+			result = append(result, ']')
+		}
+
+		return true
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(result, end), nil
+}
+
+// jsonDecodeArray decodes data, a JSON array or null, calling decodeItem with a decoder
+// positioned at each element of the array.
+func jsonDecodeArray(data []byte, decodeItem func(dec *json.Decoder) error) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil || tok == nil {
+		return err
+	}
+
+	if tok != json.Delim('[') {
+		return fmt.Errorf("expected a JSON array, got %v", tok)
+	}
+
+	for dec.More() {
+		if err := decodeItem(dec); err != nil {
+			return err
+		}
+	}
+
+	_, err = dec.Token()
+	return err
+}
+
+// jsonDecodeMap decodes data, a JSON object, an array of [key, value] arrays or null.
+// decodeEntry is called for each entry with a function that decodes the key into the
+// value pointed to by its argument, and a decoder positioned at the value.
+func jsonDecodeMap(data []byte, decodeEntry func(decodeKey func(key interface{}) error, dec *json.Decoder) error) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil || tok == nil {
+		return err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+
+			decodeKey := func(key interface{}) error { return jsonDecodeObjectKey(keyTok.(string), key) }
+			if err := decodeEntry(decodeKey, dec); err != nil {
+				return err
+			}
+		}
+	case json.Delim('['):
+		for dec.More() {
+			if err := jsonExpectDelim(dec, '['); err != nil {
+				return err
+			}
+
+			if err := decodeEntry(dec.Decode, dec); err != nil {
+				return err
+			}
+
+			if err := jsonExpectDelim(dec, ']'); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("expected a JSON object or array, got %v", tok)
+	}
+
+	_, err = dec.Token()
+	return err
+}
+
+func jsonExpectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	if tok != delim {
+		return fmt.Errorf("expected %v in JSON key value pair, got %v", delim, tok)
+	}
+
+	return nil
+}
+
+// binaryFormatVersion is the first byte of the binary encoding of containers.
+const binaryFormatVersion = 1
+
+// binaryMarshal returns the binary encoding of a container with count items. The encoding
+// is a version byte, the number of items as a uvarint and the items in gob encoded chunks.
+// encodeChunks is called with a function gob encoding a chunk, a slice of items.
+func binaryMarshal(count int, encodeChunks func(encode func(chunk interface{}) error) error) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteByte(binaryFormatVersion)
+	var scratch [binary.MaxVarintLen64]byte
+	buf.Write(scratch[:binary.PutUvarint(scratch[:], uint64(count))])
+	if err := encodeChunks(gob.NewEncoder(buf).Encode); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// binaryUnmarshal decodes data written by binaryMarshal. decodeChunk is called with a function
+// gob decoding into its argument, a pointer to a slice of items, until all items have been
+// decoded. decodeChunk returns the number of items decoded.
+func binaryUnmarshal(data []byte, decodeChunk func(decode func(chunk interface{}) error) (int, error)) error {
+	r := bytes.NewReader(data)
+	version, err := r.ReadByte()
+	if err != nil {
+		return io.ErrUnexpectedEOF
+	}
+
+	if version != binaryFormatVersion {
+		return fmt.Errorf("unsupported binary format version %d", version)
+	}
+
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return io.ErrUnexpectedEOF
+	}
+
+	dec := gob.NewDecoder(r)
+	for decoded := uint64(0); decoded < count; {
+		n, err := decodeChunk(dec.Decode)
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if n == 0 || decoded+uint64(n) > count {
+			return fmt.Errorf("invalid binary encoding, chunk of %d items after %d of %d items", n, decoded, count)
+		}
+
+		decoded += uint64(n)
+	}
+
+	if r.Len() > 0 {
+		return fmt.Errorf("invalid binary encoding, %d bytes left after the last item", r.Len())
+	}
+
+	return nil
+}
 
 //////////////////////////
 //// Hash functions //////
@@ -57,8 +389,12 @@ func hash(x []byte) uint32 {
 	return crc32.ChecksumIEEE(x)
 }
 
+//go:noescape
+//go:linkname nilinterhash runtime.nilinterhash
+func nilinterhash(p unsafe.Pointer, h uintptr) uintptr
+
 func interfaceHash(x interface{}) uint32 {
-	return hash([]byte(fmt.Sprintf("%v", x)))
+	return uint32(nilinterhash(unsafe.Pointer(&x), 0))
 }
 
 func byteHash(x byte) uint32 {
@@ -135,6 +471,345 @@ func float32Hash(x float32) uint32 {
 	return uint32Hash(math.Float32bits(x))
 }
 
+// loadHash returns the container hash kept in cache, zero if it has not been computed yet.
+// Each version of a container has its own cache which is shared by all copies of it, zero
+// containers have no cache.
+func loadHash(cache *uint32) uint32 {
+	if cache == nil {
+		return 0
+	}
+
+	return atomic.LoadUint32(cache)
+}
+
+// cacheHash stores h, a computed container hash, in cache and returns it. Zero marks
+// hashes that have not been computed yet, computed hashes that are zero are stored as one.
+func cacheHash(cache *uint32, h uint32) uint32 {
+	if h == 0 {
+		h = 1
+	}
+
+	if cache != nil {
+		atomic.StoreUint32(cache, h)
+	}
+
+	return h
+}
+
+/////////////////
+/// Snapshots ///
+/////////////////
+
+// snapshotVersion is written at the start of snapshot streams to identify the format.
+const snapshotVersion = 1
+
+// Tags written before each container in a snapshot stream.
+const (
+	snapshotVectorTag = 'v'
+	snapshotMapTag    = 'm'
+	snapshotSetTag    = 's'
+)
+
+func snapshotKind(tag byte) string {
+	switch tag {
+	case snapshotVectorTag:
+		return "vector"
+	case snapshotMapTag:
+		return "map"
+	case snapshotSetTag:
+		return "set"
+	}
+
+	return fmt.Sprintf("unknown container %q", tag)
+}
+
+// A SnapshotElementEncoder encodes the items of the containers written to a snapshot. Encode
+// is called with slices of elements, and with slices of key value items for maps and sets.
+// *gob.Encoder is a SnapshotElementEncoder.
+type SnapshotElementEncoder interface {
+	Encode(elements interface{}) error
+}
+
+// A SnapshotElementDecoder decodes the items written by a SnapshotElementEncoder. Decode is
+// called with pointers to slices of the same types as were passed to Encode.
+// *gob.Decoder is a SnapshotElementDecoder.
+type SnapshotElementDecoder interface {
+	Decode(elements interface{}) error
+}
+
+// snapshotNodeKey identifies a node of a vector by the address of its first item and its length.
+type snapshotNodeKey struct {
+	first interface{}
+	len   int
+}
+
+// A SnapshotEncoder writes vectors, maps and sets to a stream. Every node of the containers
+// is written only once, nodes shared with previously written containers, eg. older versions
+// of the same vector, are written as references to the already written nodes. Decoding the
+// stream using a SnapshotDecoder restores the structure sharing between the containers.
+type SnapshotEncoder struct {
+	w        *bufio.Writer
+	elements SnapshotElementEncoder
+	buf      bytes.Buffer
+	ids      map[interface{}]uint64
+	nodes    uint64
+	started  bool
+	err      error
+	scratch  [binary.MaxVarintLen64]byte
+}
+
+// NewSnapshotEncoder returns a SnapshotEncoder writing to w. The items of the containers are
+// encoded by the SnapshotElementEncoder returned by newElementEncoder for the writer given to
+// it. If newElementEncoder is nil the items are gob encoded, gob uses the GobEncoder or
+// encoding.BinaryMarshaler implementations of types that have one.
+func NewSnapshotEncoder(w io.Writer, newElementEncoder func(w io.Writer) SnapshotElementEncoder) *SnapshotEncoder {
+	e := &SnapshotEncoder{w: bufio.NewWriter(w), ids: make(map[interface{}]uint64)}
+	if newElementEncoder == nil {
+		e.elements = gob.NewEncoder(&e.buf)
+	} else {
+		e.elements = newElementEncoder(&e.buf)
+	}
+
+	return e
+}
+
+// encode writes the tag of a container followed by the container, written by writeContainer,
+// and flushes the stream. Once an error has occurred it is returned by all following calls
+// since the stream can no longer be decoded.
+func (e *SnapshotEncoder) encode(tag byte, writeContainer func() error) error {
+	if e.err != nil {
+		return e.err
+	}
+
+	if !e.started {
+		e.writeUvarint(snapshotVersion)
+		e.started = true
+	}
+
+	e.w.WriteByte(tag)
+	e.err = writeContainer()
+	if e.err == nil {
+		// Write errors are kept by the buffered writer and returned here
+		e.err = e.w.Flush()
+	}
+
+	return e.err
+}
+
+func (e *SnapshotEncoder) writeUvarint(x uint64) {
+	n := binary.PutUvarint(e.scratch[:], x)
+	e.w.Write(e.scratch[:n])
+}
+
+// writeElements writes elements, a slice of items, using the element encoder.
+func (e *SnapshotEncoder) writeElements(elements interface{}) error {
+	e.buf.Reset()
+	if err := e.elements.Encode(elements); err != nil {
+		return err
+	}
+
+	e.writeUvarint(uint64(e.buf.Len()))
+	e.w.Write(e.buf.Bytes())
+	return nil
+}
+
+// writeNode writes a reference to the node identified by key. Nodes that have not been written
+// before are written by writeBody following a zero reference. Nodes with a nil key are always
+// written.
+func (e *SnapshotEncoder) writeNode(key interface{}, writeBody func() error) error {
+	if id, ok := e.ids[key]; ok && key != nil {
+		e.writeUvarint(id)
+		return nil
+	}
+
+	e.writeUvarint(0)
+	if err := writeBody(); err != nil {
+		return err
+	}
+
+	// Nodes are numbered in the order that they are completed, same as when decoding
+	e.nodes++
+	if key != nil {
+		e.ids[key] = e.nodes
+	}
+
+	return nil
+}
+
+// A SnapshotDecoder reads the vectors, maps and sets written by a SnapshotEncoder. The
+// containers must be read in the order that they were written, using the methods of the
+// same container types.
+type SnapshotDecoder struct {
+	r        *bufio.Reader
+	elements SnapshotElementDecoder
+	buf      bytes.Buffer
+	nodes    []interface{}
+	started  bool
+	err      error
+}
+
+// NewSnapshotDecoder returns a SnapshotDecoder reading from r. The items of the containers
+// are decoded by the SnapshotElementDecoder returned by newElementDecoder for the reader given
+// to it. If newElementDecoder is nil the items are gob decoded. The decoder may read beyond
+// the end of the snapshot stream in r.
+func NewSnapshotDecoder(r io.Reader, newElementDecoder func(r io.Reader) SnapshotElementDecoder) *SnapshotDecoder {
+	d := &SnapshotDecoder{r: bufio.NewReader(r)}
+	if newElementDecoder == nil {
+		d.elements = gob.NewDecoder(&d.buf)
+	} else {
+		d.elements = newElementDecoder(&d.buf)
+	}
+
+	return d
+}
+
+// decode reads the tag of the next container, verifies that it is tag, and reads the container
+// using readContainer. io.EOF is returned if there are no more containers in the stream. Once
+// any other error has occurred it is returned by all following calls.
+func (d *SnapshotDecoder) decode(tag byte, readContainer func() error) error {
+	if d.err != nil {
+		return d.err
+	}
+
+	d.err = d.doDecode(tag, readContainer)
+	return d.err
+}
+
+func (d *SnapshotDecoder) doDecode(tag byte, readContainer func() error) error {
+	if !d.started {
+		version, err := binary.ReadUvarint(d.r)
+		if err != nil {
+			return err
+		}
+
+		if version != snapshotVersion {
+			return fmt.Errorf("unsupported snapshot version %d", version)
+		}
+
+		d.started = true
+	}
+
+	actual, err := d.r.ReadByte()
+	if err != nil {
+		return err
+	}
+
+	if actual != tag {
+		return fmt.Errorf("expected a %s in snapshot, found a %s", snapshotKind(tag), snapshotKind(actual))
+	}
+
+	if err := readContainer(); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (d *SnapshotDecoder) readUvarint() (uint64, error) {
+	return binary.ReadUvarint(d.r)
+}
+
+// readLength reads a length that is at most max.
+func (d *SnapshotDecoder) readLength(max uint64) (int, error) {
+	x, err := d.readUvarint()
+	if err != nil {
+		return 0, err
+	}
+
+	if x > max {
+		return 0, fmt.Errorf("invalid snapshot, length %d is larger than %d", x, max)
+	}
+
+	return int(x), nil
+}
+
+func (d *SnapshotDecoder) readUint32() (uint32, error) {
+	x, err := d.readUvarint()
+	if err != nil {
+		return 0, err
+	}
+
+	if x > math.MaxUint32 {
+		return 0, fmt.Errorf("invalid snapshot, %d does not fit in 32 bits", x)
+	}
+
+	return uint32(x), nil
+}
+
+// readElements reads items written by writeElements into the slice pointed to by elements.
+func (d *SnapshotDecoder) readElements(elements interface{}) error {
+	n, err := d.readUvarint()
+	if err != nil {
+		return err
+	}
+
+	d.buf.Reset()
+	if _, err := io.CopyN(&d.buf, d.r, int64(n)); err != nil {
+		return err
+	}
+
+	if err := d.elements.Decode(elements); err != nil {
+		return err
+	}
+
+	if d.buf.Len() > 0 {
+		return fmt.Errorf("invalid snapshot, %d bytes left after decoding elements", d.buf.Len())
+	}
+
+	return nil
+}
+
+// readNode reads a node written by writeNode. New nodes are read by readBody, previously
+// read nodes are returned from the nodes read so far.
+func (d *SnapshotDecoder) readNode(readBody func() (interface{}, error)) (interface{}, error) {
+	id, err := d.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+
+	if id == 0 {
+		node, err := readBody()
+		if err != nil {
+			return nil, err
+		}
+
+		d.nodes = append(d.nodes, node)
+		return node, nil
+	}
+
+	if id > uint64(len(d.nodes)) {
+		return nil, fmt.Errorf("invalid snapshot, reference to unknown node %d", id)
+	}
+
+	return d.nodes[id-1], nil
+}
+
+func invalidSnapshotNode(node interface{}) error {
+	return fmt.Errorf("invalid snapshot, unexpected node of type %T", node)
+}
+
+// vectorSnapshotChildCount returns the number of children of a vector node at level
+// holding count elements.
+func vectorSnapshotChildCount(level uint, count uint64) uint64 {
+	if count == 0 {
+		return 0
+	}
+
+	return (count-1)>>level + 1
+}
+
+func checkVectorSnapshotNodeLen(length int, expected uint64) error {
+	if uint64(length) != expected {
+		return fmt.Errorf("invalid snapshot, vector node of length %d where %d was expected", length, expected)
+	}
+
+	return nil
+}
+
 //////////////
 /// Vector ///
 //////////////
@@ -146,10 +821,11 @@ type IntVector struct {
 	root  commonNode
 	len   uint
 	shift uint
+	hash  *uint32
 }
 
 var emptyIntVectorTail = make([]int, 0)
-var emptyIntVector *IntVector = &IntVector{root: emptyCommonNode, shift: shiftSize, tail: emptyIntVectorTail}
+var emptyIntVector *IntVector = &IntVector{root: emptyCommonNode, shift: shiftSize, tail: emptyIntVectorTail, hash: new(uint32)}
 
 // NewIntVector returns a new IntVector containing the items provided in items.
 func NewIntVector(items ...int) *IntVector {
@@ -196,22 +872,25 @@ func (v *IntVector) Set(i int, item int) *IntVector {
 		newTail := make([]int, len(v.tail))
 		copy(newTail, v.tail)
 		newTail[i&shiftBitMask] = item
-		return &IntVector{root: v.root, tail: newTail, len: v.len, shift: v.shift}
+		return &IntVector{root: v.root, tail: newTail, len: v.len, shift: v.shift, hash: new(uint32)}
 	}
 
-	return &IntVector{root: v.doAssoc(v.shift, v.root, uint(i), item), tail: v.tail, len: v.len, shift: v.shift}
+	return &IntVector{root: v.doAssoc(v.shift, v.root, uint(i), item), tail: v.tail, len: v.len, shift: v.shift, hash: new(uint32)}
 }
 
 func (v *IntVector) doAssoc(level uint, node commonNode, i uint, item int) commonNode {
 	if level == 0 {
-		ret := make([]int, nodeSize)
-		copy(ret, node.([]int))
+		leaf := node.([]int)
+		ret := make([]int, len(leaf))
+		copy(ret, leaf)
 		ret[i&shiftBitMask] = item
 		return ret
 	}
 
-	ret := make([]commonNode, nodeSize)
-	copy(ret, node.([]commonNode))
+	// Copies keep the length of the original, children beyond it are added by pushTail
+	children := node.([]commonNode)
+	ret := make([]commonNode, len(children))
+	copy(ret, children)
 	subidx := (i >> level) & shiftBitMask
 	ret[subidx] = v.doAssoc(level-shiftSize, ret[subidx], i, item)
 	return ret
@@ -254,7 +933,7 @@ func (v *IntVector) Append(item ...int) *IntVector {
 		newTail := make([]int, 0, tailLen+batchLen)
 		newTail = append(newTail, result.tail...)
 		newTail = append(newTail, item[insertOffset:insertOffset+batchLen]...)
-		result = &IntVector{root: result.root, tail: newTail, len: result.len + batchLen, shift: result.shift}
+		result = &IntVector{root: result.root, tail: newTail, len: result.len + batchLen, shift: result.shift, hash: new(uint32)}
 		insertOffset += batchLen
 	}
 
@@ -274,26 +953,105 @@ func (v *IntVector) pushLeafNode(node []int) *IntVector {
 		newRoot = v.pushTail(v.shift, v.root, node)
 	}
 
-	return &IntVector{root: newRoot, tail: v.tail, len: v.len, shift: newShift}
+	return &IntVector{root: newRoot, tail: v.tail, len: v.len, shift: newShift, hash: new(uint32)}
 }
 
-// Slice returns a IntVectorSlice that refers to all elements [start,stop) in v.
-func (v *IntVector) Slice(start, stop int) *IntVectorSlice {
-	assertSliceOk(start, stop, v.Len())
-	return &IntVectorSlice{vector: v, start: start, stop: stop}
-}
+// Pop returns a new vector with the last element removed, and the removed element.
+func (v *IntVector) Pop() (*IntVector, int) {
+	if v.len == 0 {
+		panic("Pop on empty vector")
+	}
 
-// Len returns the length of v.
-func (v *IntVector) Len() int {
-	return int(v.len)
+	item := v.tail[len(v.tail)-1]
+	return v.DropLast(1), item
 }
 
-// Range calls f repeatedly passing it each element in v in order as argument until either
-// all elements have been visited or f returns false.
-func (v *IntVector) Range(f func(int) bool) {
-	var currentNode []int
-	for i := uint(0); i < v.len; i++ {
-		if i&shiftBitMask == 0 {
+// DropLast returns a new vector with the last n elements removed. Nodes no longer
+// needed are released rather than kept alive as they would be by Slice.
+func (v *IntVector) DropLast(n int) *IntVector {
+	if n < 0 || uint(n) > v.len {
+		panic("Index out of bounds")
+	}
+
+	if n == 0 {
+		return v
+	}
+
+	newLen := v.len - uint(n)
+	if newLen == 0 {
+		return emptyIntVector
+	}
+
+	result := &IntVector{len: newLen, hash: new(uint32)}
+	newTailOffset := result.tailOffset()
+	newTail := make([]int, newLen-newTailOffset)
+	copy(newTail, v.sliceFor(newLen-1))
+	result.tail = newTail
+	if newTailOffset == 0 {
+		result.root, result.shift = emptyCommonNode, shiftSize
+		return result
+	}
+
+	result.root, result.shift = v.popTail(v.shift, v.root, newTailOffset-1), v.shift
+
+	// Root underflow?
+	for result.shift > shiftSize && len(result.root.([]commonNode)) == 1 {
+		result.root = result.root.([]commonNode)[0]
+		result.shift -= shiftSize
+	}
+
+	return result
+}
+
+// popTail returns a copy of the path from node down to the leaf holding element
+// last with all nodes after that path removed.
+func (v *IntVector) popTail(level uint, node commonNode, last uint) commonNode {
+	subIdx := (last >> level) & shiftBitMask
+	ret := make([]commonNode, subIdx+1)
+	copy(ret, node.([]commonNode))
+	if level > shiftSize {
+		ret[subIdx] = v.popTail(level-shiftSize, ret[subIdx], last)
+	}
+
+	return ret
+}
+
+// DropFirst returns a new vector with the first n elements removed. Unlike Slice the
+// result does not share any structure with v, it takes time proportional to its length.
+func (v *IntVector) DropFirst(n int) *IntVector {
+	if n < 0 || uint(n) > v.len {
+		panic("Index out of bounds")
+	}
+
+	if n == 0 {
+		return v
+	}
+
+	t := emptyIntVector.AsTransient()
+	for i := uint(n); i < v.len; i = (i | shiftBitMask) + 1 {
+		t.Append(v.sliceFor(i)[i&shiftBitMask:]...)
+	}
+
+	return t.Persistent()
+}
+
+// Slice returns a IntVectorSlice that refers to all elements [start,stop) in v.
+func (v *IntVector) Slice(start, stop int) *IntVectorSlice {
+	assertSliceOk(start, stop, v.Len())
+	return &IntVectorSlice{vector: v, start: start, stop: stop, hash: new(uint32)}
+}
+
+// Len returns the length of v.
+func (v *IntVector) Len() int {
+	return int(v.len)
+}
+
+// Range calls f repeatedly passing it each element in v in order as argument until either
+// all elements have been visited or f returns false.
+func (v *IntVector) Range(f func(int) bool) {
+	var currentNode []int
+	for i := uint(0); i < v.len; i++ {
+		if i&shiftBitMask == 0 {
 			currentNode = v.sliceFor(i)
 		}
 
@@ -313,6 +1071,436 @@ func (v *IntVector) ToNativeSlice() []int {
 	return result
 }
 
+// MarshalJSON encodes v as a JSON array.
+func (v *IntVector) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.ToNativeSlice())
+}
+
+// UnmarshalJSON sets v to the elements of data, a JSON array. v must not be used by
+// anyone else when this is called, it is meant to be used by encoding/json only.
+func (v *IntVector) UnmarshalJSON(data []byte) error {
+	t := emptyIntVector.AsTransient()
+	err := jsonDecodeArray(data, func(dec *json.Decoder) error {
+		var item int
+		if err := dec.Decode(&item); err != nil {
+			return err
+		}
+
+		t.Append(item)
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	*v = *t.Persistent()
+	return nil
+}
+
+// MarshalBinary returns the binary encoding of v. The items are gob encoded.
+func (v *IntVector) MarshalBinary() ([]byte, error) {
+	return binaryMarshal(v.Len(), func(encode func(interface{}) error) error {
+		for i := 0; i < v.Len(); i += nodeSize {
+			if err := encode(v.sliceFor(uint(i))); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// UnmarshalBinary sets v to the vector encoded in data by MarshalBinary. v must not
+// be used by anyone else when this is called, decode into a new zero IntVector.
+func (v *IntVector) UnmarshalBinary(data []byte) error {
+	t := emptyIntVector.AsTransient()
+	err := binaryUnmarshal(data, func(decode func(interface{}) error) (int, error) {
+		var chunk []int
+		err := decode(&chunk)
+		t.Append(chunk...)
+		return len(chunk), err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	*v = *t.Persistent()
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder using the same encoding as MarshalBinary.
+func (v *IntVector) GobEncode() ([]byte, error) {
+	return v.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder using the same encoding as UnmarshalBinary.
+func (v *IntVector) GobDecode(data []byte) error {
+	return v.UnmarshalBinary(data)
+}
+
+// EncodeSnapshot writes v to the snapshot stream of e. Nodes shared with containers
+// previously written to e are written as references to those.
+func (v *IntVector) EncodeSnapshot(e *SnapshotEncoder) error {
+	return e.encode(snapshotVectorTag, func() error {
+		e.writeUvarint(uint64(v.len))
+		e.writeUvarint(uint64(v.shift))
+		if err := v.encodeSnapshotNode(e, v.shift, v.root); err != nil {
+			return err
+		}
+
+		return v.encodeSnapshotNode(e, 0, v.tail)
+	})
+}
+
+func (v *IntVector) encodeSnapshotNode(e *SnapshotEncoder, level uint, node commonNode) error {
+	if level == 0 {
+		leaf := node.([]int)
+		var key interface{}
+		if len(leaf) > 0 {
+			key = snapshotNodeKey{first: &leaf[0], len: len(leaf)}
+		}
+
+		return e.writeNode(key, func() error {
+			return e.writeElements(leaf)
+		})
+	}
+
+	children := node.([]commonNode)
+	var key interface{}
+	if len(children) > 0 {
+		key = snapshotNodeKey{first: &children[0], len: len(children)}
+	}
+
+	return e.writeNode(key, func() error {
+		e.writeUvarint(uint64(len(children)))
+		for _, child := range children {
+			if err := v.encodeSnapshotNode(e, level-shiftSize, child); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// DecodeSnapshot sets v to the next container in the snapshot stream of d, which must
+// be a vector. io.EOF is returned if there are no more containers in the stream. v must
+// not be used by anyone else when this is called, decode into a new zero IntVector.
+func (v *IntVector) DecodeSnapshot(d *SnapshotDecoder) error {
+	return d.decode(snapshotVectorTag, func() error {
+		length, err := d.readUvarint()
+		if err != nil {
+			return err
+		}
+
+		shift, err := d.readUvarint()
+		if err != nil {
+			return err
+		}
+
+		if shift == 0 || shift%shiftSize != 0 || shift > 64 {
+			return fmt.Errorf("invalid snapshot, vector shift %d", shift)
+		}
+
+		// The root holds all but the elements in the tail, it has at least two children
+		// unless it is at the lowest level
+		result := IntVector{len: uint(length), shift: uint(shift), hash: new(uint32)}
+		rootLen := uint64(result.tailOffset())
+		if (shift > shiftSize && rootLen <= 1<<shift) || vectorSnapshotChildCount(uint(shift), rootLen) > nodeSize {
+			return fmt.Errorf("invalid snapshot, vector shift %d in vector of length %d", shift, length)
+		}
+
+		root, err := decodeIntVectorSnapshotNode(d, uint(shift), rootLen)
+		if err != nil {
+			return err
+		}
+
+		tail, err := decodeIntVectorSnapshotNode(d, 0, length-rootLen)
+		if err != nil {
+			return err
+		}
+
+		result.root, result.tail = root, tail.([]int)
+		*v = result
+		return nil
+	})
+}
+
+// decodeIntVectorSnapshotNode reads a node at level holding count elements. All
+// nodes but the rightmost ones on each level must be full.
+func decodeIntVectorSnapshotNode(d *SnapshotDecoder, level uint, count uint64) (commonNode, error) {
+	shared := true
+	node, err := d.readNode(func() (interface{}, error) {
+		shared = false
+		if level == 0 {
+			var leaf []int
+			if err := d.readElements(&leaf); err != nil {
+				return nil, err
+			}
+
+			return leaf, checkVectorSnapshotNodeLen(len(leaf), count)
+		}
+
+		n, err := d.readLength(nodeSize)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := checkVectorSnapshotNodeLen(n, vectorSnapshotChildCount(level, count)); err != nil {
+			return nil, err
+		}
+
+		children := make([]commonNode, n)
+		for i := range children {
+			childCount := uint64(1) << level
+			if i == n-1 {
+				childCount = count - uint64(i)<<level
+			}
+
+			if children[i], err = decodeIntVectorSnapshotNode(d, level-shiftSize, childCount); err != nil {
+				return nil, err
+			}
+		}
+
+		return children, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !shared {
+		return node, nil
+	}
+
+	// The nodes left of the rightmost path of a shared node are full, that was checked
+	// when it was first read. Only the rightmost path has to be checked against count.
+	for n := node; ; level -= shiftSize {
+		switch typed := n.(type) {
+		case []int:
+			if level == 0 {
+				if err := checkVectorSnapshotNodeLen(len(typed), count); err != nil {
+					return nil, err
+				}
+
+				return node, nil
+			}
+		case []commonNode:
+			if level > 0 {
+				if err := checkVectorSnapshotNodeLen(len(typed), vectorSnapshotChildCount(level, count)); err != nil {
+					return nil, err
+				}
+
+				if len(typed) == 0 {
+					return node, nil
+				}
+
+				count -= uint64(len(typed)-1) << level
+				n = typed[len(typed)-1]
+				continue
+			}
+		}
+
+		return nil, invalidSnapshotNode(n)
+	}
+}
+
+/////////////////
+/// Transient ///
+/////////////////
+
+// IntVectorTransient is a mutable builder for IntVector. Nodes created by
+// the transient are owned by it and edited in place rather than copied. Call Persistent
+// to turn it into a IntVector, the transient cannot be used after that.
+type IntVectorTransient struct {
+	tail  []int
+	root  commonNode
+	len   uint
+	shift uint
+	owner *transientOwner
+}
+
+// AsTransient returns a transient containing all elements of v. v is left untouched.
+func (v *IntVector) AsTransient() *IntVectorTransient {
+	tail := make([]int, len(v.tail), nodeSize)
+	copy(tail, v.tail)
+	return &IntVectorTransient{
+		tail:  tail,
+		root:  v.root,
+		len:   v.len,
+		shift: v.shift,
+		owner: &transientOwner{}}
+}
+
+// Persistent returns a IntVector containing all elements of t. t cannot be used
+// after this call.
+func (t *IntVectorTransient) Persistent() *IntVector {
+	assertTransientEditable(t.owner != nil)
+	t.owner = nil
+	return &IntVector{tail: t.tail, root: t.root, len: t.len, shift: t.shift, hash: new(uint32)}
+}
+
+// Len returns the length of t.
+func (t *IntVectorTransient) Len() int {
+	assertTransientEditable(t.owner != nil)
+	return int(t.len)
+}
+
+func (t *IntVectorTransient) tailOffset() uint {
+	if t.len < nodeSize {
+		return 0
+	}
+
+	return ((t.len - 1) >> shiftSize) << shiftSize
+}
+
+// Get returns the element at position i.
+func (t *IntVectorTransient) Get(i int) int {
+	assertTransientEditable(t.owner != nil)
+	if i < 0 || uint(i) >= t.len {
+		panic("Index out of bounds")
+	}
+
+	if uint(i) >= t.tailOffset() {
+		return t.tail[i&shiftBitMask]
+	}
+
+	node := t.root
+	for level := t.shift; level > 0; level -= shiftSize {
+		node = node.([]commonNode)[(uint(i)>>level)&shiftBitMask]
+	}
+
+	return node.([]int)[i&shiftBitMask]
+}
+
+// nodeOwner returns the owner of node if node is owned by t, otherwise nil.
+func (t *IntVectorTransient) nodeOwner(node []commonNode) *vectorNodeOwner {
+	if cap(node) > nodeSize {
+		if owner, ok := node[:nodeSize+1][nodeSize].(*vectorNodeOwner); ok && owner.transient == t.owner {
+			return owner
+		}
+	}
+
+	return nil
+}
+
+// editableNode returns node if it is owned by t, otherwise an owned copy of it.
+func (t *IntVectorTransient) editableNode(node []commonNode) []commonNode {
+	if t.nodeOwner(node) != nil {
+		return node
+	}
+
+	newNode := make([]commonNode, len(node), nodeSize+1)
+	copy(newNode, node)
+	newNode[:nodeSize+1][nodeSize] = &vectorNodeOwner{transient: t.owner}
+	return newNode
+}
+
+// editableLeaf returns the leaf at position ix of parent, a node owned by t, after
+// replacing it with an owned copy unless it is owned by t already.
+func (t *IntVectorTransient) editableLeaf(parent []commonNode, ix uint) []int {
+	owner := t.nodeOwner(parent)
+	leaf := parent[ix].([]int)
+	if owner.leaves&(1<<ix) != 0 {
+		return leaf
+	}
+
+	newLeaf := make([]int, len(leaf))
+	copy(newLeaf, leaf)
+	parent[ix] = newLeaf
+	owner.leaves |= 1 << ix
+	return newLeaf
+}
+
+// Set sets the element at position i to item.
+func (t *IntVectorTransient) Set(i int, item int) {
+	assertTransientEditable(t.owner != nil)
+	if i < 0 || uint(i) >= t.len {
+		panic("Index out of bounds")
+	}
+
+	if uint(i) >= t.tailOffset() {
+		t.tail[i&shiftBitMask] = item
+		return
+	}
+
+	t.root = t.doAssoc(t.shift, t.root, uint(i), item)
+}
+
+func (t *IntVectorTransient) doAssoc(level uint, node commonNode, i uint, item int) commonNode {
+	ret := t.editableNode(node.([]commonNode))
+	subidx := (i >> level) & shiftBitMask
+	if level == shiftSize {
+		t.editableLeaf(ret, subidx)[i&shiftBitMask] = item
+		return ret
+	}
+
+	ret[subidx] = t.doAssoc(level-shiftSize, ret[subidx], i, item)
+	return ret
+}
+
+// Append adds item(s) to the end of t.
+func (t *IntVectorTransient) Append(items ...int) {
+	assertTransientEditable(t.owner != nil)
+	for _, item := range items {
+		if uint(len(t.tail)) == nodeSize {
+			t.pushLeafNode(t.tail)
+			t.tail = make([]int, 0, nodeSize)
+		}
+
+		t.tail = append(t.tail, item)
+		t.len++
+	}
+}
+
+func (t *IntVectorTransient) newPath(shift uint, node commonNode) commonNode {
+	if shift == 0 {
+		return node
+	}
+
+	newNode := append(t.editableNode(nil), node)
+	if _, ok := node.([]int); ok {
+		t.nodeOwner(newNode).leaves = 1
+	}
+
+	return t.newPath(shift-shiftSize, commonNode(newNode))
+}
+
+func (t *IntVectorTransient) pushTail(level uint, parent commonNode, tailNode []int) commonNode {
+	subIdx := ((t.len - 1) >> level) & shiftBitMask
+	ret := t.editableNode(parent.([]commonNode))
+	var nodeToInsert commonNode
+
+	if level == shiftSize {
+		nodeToInsert = tailNode
+		t.nodeOwner(ret).leaves |= 1 << subIdx
+	} else if subIdx < uint(len(ret)) {
+		nodeToInsert = t.pushTail(level-shiftSize, ret[subIdx], tailNode)
+	} else {
+		nodeToInsert = t.newPath(level-shiftSize, tailNode)
+	}
+
+	if subIdx < uint(len(ret)) {
+		ret[subIdx] = nodeToInsert
+		return ret
+	}
+
+	return append(ret, nodeToInsert)
+}
+
+func (t *IntVectorTransient) pushLeafNode(node []int) {
+	// Root overflow?
+	if (t.len >> shiftSize) > (1 << t.shift) {
+		newRoot := t.editableNode(nil)
+		t.root = commonNode(append(newRoot, t.root, t.newPath(t.shift, node)))
+		t.shift += shiftSize
+	} else {
+		t.root = t.pushTail(t.shift, t.root, node)
+	}
+}
+
 ////////////////
 //// Slice /////
 ////////////////
@@ -321,11 +1509,12 @@ func (v *IntVector) ToNativeSlice() []int {
 type IntVectorSlice struct {
 	vector      *IntVector
 	start, stop int
+	hash        *uint32
 }
 
 // NewIntVectorSlice returns a new NewIntVectorSlice containing the items provided in items.
 func NewIntVectorSlice(items ...int) *IntVectorSlice {
-	return &IntVectorSlice{vector: emptyIntVector.Append(items...), start: 0, stop: len(items)}
+	return &IntVectorSlice{vector: emptyIntVector.Append(items...), start: 0, stop: len(items), hash: new(uint32)}
 }
 
 // Len returns the length of s.
@@ -353,7 +1542,7 @@ func (s *IntVectorSlice) Set(i int, item int) *IntVectorSlice {
 
 // Append returns a new slice with item(s) appended to it.
 func (s *IntVectorSlice) Append(items ...int) *IntVectorSlice {
-	newSlice := IntVectorSlice{vector: s.vector, start: s.start, stop: s.stop + len(items)}
+	newSlice := IntVectorSlice{vector: s.vector, start: s.start, stop: s.stop + len(items), hash: new(uint32)}
 
 	// If this is v slice that has an upper bound that is lower than the backing
 	// vector then set the values in the backing vector to achieve some structural
@@ -371,7 +1560,7 @@ func (s *IntVectorSlice) Append(items ...int) *IntVectorSlice {
 // Slice returns a IntVectorSlice that refers to all elements [start,stop) in s.
 func (s *IntVectorSlice) Slice(start, stop int) *IntVectorSlice {
 	assertSliceOk(start, stop, s.stop-s.start)
-	return &IntVectorSlice{vector: s.vector, start: s.start + start, stop: s.start + stop}
+	return &IntVectorSlice{vector: s.vector, start: s.start + start, stop: s.start + stop, hash: new(uint32)}
 }
 
 // Range calls f repeatedly passing it each element in s in order as argument until either
@@ -389,328 +1578,777 @@ func (s *IntVectorSlice) Range(f func(int) bool) {
 	}
 }
 
-///////////
-/// Map ///
-///////////
+// MarshalJSON encodes s as a JSON array.
+func (s *IntVectorSlice) MarshalJSON() ([]byte, error) {
+	items := make([]int, 0, s.Len())
+	s.Range(func(item int) bool {
+		items = append(items, item)
+		return true
+	})
 
-//////////////////////
-/// Backing vector ///
-//////////////////////
+	return json.Marshal(items)
+}
 
-type privatePersonBySsnItemBucketVector struct {
-	tail  []privatePersonBySsnItemBucket
-	root  commonNode
-	len   uint
-	shift uint
+// UnmarshalJSON sets s to the elements of data, a JSON array. s must not be used by
+// anyone else when this is called, it is meant to be used by encoding/json only.
+func (s *IntVectorSlice) UnmarshalJSON(data []byte) error {
+	v := &IntVector{}
+	if err := v.UnmarshalJSON(data); err != nil {
+		return err
+	}
+
+	*s = IntVectorSlice{vector: v, start: 0, stop: v.Len(), hash: new(uint32)}
+	return nil
+}
+
+// MarshalBinary returns the binary encoding of s, the same as that of a vector with
+// the items of s. The items are gob encoded.
+func (s *IntVectorSlice) MarshalBinary() ([]byte, error) {
+	return binaryMarshal(s.Len(), func(encode func(interface{}) error) error {
+		chunk := make([]int, 0, nodeSize)
+		var err error
+		s.Range(func(item int) bool {
+			chunk = append(chunk, item)
+			if len(chunk) == nodeSize {
+				err = encode(chunk)
+				chunk = chunk[:0]
+			}
+
+			return err == nil
+		})
+
+		if err == nil && len(chunk) > 0 {
+			err = encode(chunk)
+		}
+
+		return err
+	})
+}
+
+// UnmarshalBinary sets s to the items encoded in data by MarshalBinary. s must not
+// be used by anyone else when this is called, decode into a new zero IntVectorSlice.
+func (s *IntVectorSlice) UnmarshalBinary(data []byte) error {
+	v := &IntVector{}
+	if err := v.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	*s = IntVectorSlice{vector: v, start: 0, stop: v.Len(), hash: new(uint32)}
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder using the same encoding as MarshalBinary.
+func (s *IntVectorSlice) GobEncode() ([]byte, error) {
+	return s.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder using the same encoding as UnmarshalBinary.
+func (s *IntVectorSlice) GobDecode(data []byte) error {
+	return s.UnmarshalBinary(data)
+}
+
+// Equals returns true if v and other contain equal elements in the same order, false
+// otherwise. Parts of the trie shared between v and other are not compared.
+func (v *IntVector) Equals(other *IntVector) bool {
+	if v == other {
+		return true
+	}
+
+	if v.len != other.len {
+		return false
+	}
+
+	for i := uint(0); i < v.len; i += nodeSize {
+		leaf, otherLeaf := v.sliceFor(i), other.sliceFor(i)
+		if &leaf[0] == &otherLeaf[0] {
+			continue
+		}
+
+		for j := uint(0); j < uintMin(nodeSize, v.len-i); j++ {
+			if !privateIntVectorElementEqual(&leaf[j], &otherLeaf[j]) {
+				return false
+			}
+		}
+	}
+
+	return true
 }
 
+// Hash returns a hash of the elements in v. Vectors that are equal according to Equals
+// have the same hash. The hash is computed the first time it is needed and is then kept
+// for v and all copies of it, including copies stored in other containers.
+func (v *IntVector) Hash() uint32 {
+	if h := loadHash(v.hash); h != 0 {
+		return h
+	}
+
+	h := uint32(1)
+	for i := uint(0); i < v.len; i += nodeSize {
+		leaf := v.sliceFor(i)
+		for j := uint(0); j < uintMin(nodeSize, v.len-i); j++ {
+			h = 31*h + privateIntVectorElementHash(&leaf[j])
+		}
+	}
+
+	return cacheHash(v.hash, h)
+}
+
+// Equals returns true if s and other contain equal elements in the same order, false otherwise.
+func (s *IntVectorSlice) Equals(other *IntVectorSlice) bool {
+	if s.Len() != other.Len() {
+		return false
+	}
+
+	for i := 0; i < s.Len(); i++ {
+		a, b := s.Get(i), other.Get(i)
+		if !privateIntVectorElementEqual(&a, &b) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Hash returns a hash of the elements in s. Slices that are equal according to Equals
+// have the same hash, which is also the hash of a IntVector with the same elements.
+// The hash is computed the first time it is needed and is then kept for s and all copies of it.
+func (s *IntVectorSlice) Hash() uint32 {
+	if h := loadHash(s.hash); h != 0 {
+		return h
+	}
+
+	h := uint32(1)
+	s.Range(func(item int) bool {
+		h = 31*h + privateIntVectorElementHash(&item)
+		return true
+	})
+
+	return cacheHash(s.hash, h)
+}
+
+func privateIntVectorElementHash(x *int) uint32 {
+	return intHash(*x)
+}
+
+func privateIntVectorElementEqual(a, b *int) bool {
+	return *a == *b
+}
+
+///////////
+/// Map ///
+///////////
+
 type PersonBySsnItem struct {
 	Key   string
 	Value Person
 }
 
-type privatePersonBySsnItemBucket []PersonBySsnItem
+// privatePersonBySsnItemNode is a node in a compressed hash-array mapped prefix tree (CHAMP).
+// Items stored directly in the node are kept in items and their positions are marked in
+// dataMap. Sub nodes are kept in children and their positions are marked in nodeMap. Nodes
+// below the last level of the hash are collision nodes that only contain items.
+type privatePersonBySsnItemNode struct {
+	dataMap  uint32
+	nodeMap  uint32
+	items    []PersonBySsnItem
+	children []*privatePersonBySsnItemNode
+	owner    *transientOwner
+}
 
-var emptyPersonBySsnItemBucketVectorTail = make([]privatePersonBySsnItemBucket, 0)
-var emptyPersonBySsnItemBucketVector *privatePersonBySsnItemBucketVector = &privatePersonBySsnItemBucketVector{root: emptyCommonNode, shift: shiftSize, tail: emptyPersonBySsnItemBucketVectorTail}
+var emptyPersonBySsnItemNode = &privatePersonBySsnItemNode{}
 
-func (v *privatePersonBySsnItemBucketVector) Get(i int) privatePersonBySsnItemBucket {
-	if i < 0 || uint(i) >= v.len {
-		panic("Index out of bounds")
+// editable returns n if it is owned by owner, otherwise a copy of n owned by owner.
+// owner is nil for persistent updates in which case a copy is always returned.
+func (n *privatePersonBySsnItemNode) editable(owner *transientOwner) *privatePersonBySsnItemNode {
+	if owner != nil && n.owner == owner {
+		return n
 	}
 
-	return v.sliceFor(uint(i))[i&shiftBitMask]
+	items := make([]PersonBySsnItem, len(n.items), len(n.items)+1)
+	copy(items, n.items)
+	children := make([]*privatePersonBySsnItemNode, len(n.children), len(n.children)+1)
+	copy(children, n.children)
+	return &privatePersonBySsnItemNode{dataMap: n.dataMap, nodeMap: n.nodeMap, items: items, children: children, owner: owner}
 }
 
-func (v *privatePersonBySsnItemBucketVector) sliceFor(i uint) []privatePersonBySsnItemBucket {
-	if i >= v.tailOffset() {
-		return v.tail
+func (n *privatePersonBySsnItemNode) isSingleItem() bool {
+	return len(n.items) == 1 && len(n.children) == 0
+}
+
+func (n *privatePersonBySsnItemNode) insertItem(ix int, item PersonBySsnItem) {
+	var zeroItem PersonBySsnItem
+	n.items = append(n.items, zeroItem)
+	copy(n.items[ix+1:], n.items[ix:])
+	n.items[ix] = item
+}
+
+func (n *privatePersonBySsnItemNode) removeItem(ix int) {
+	var zeroItem PersonBySsnItem
+	copy(n.items[ix:], n.items[ix+1:])
+	n.items[len(n.items)-1] = zeroItem
+	n.items = n.items[:len(n.items)-1]
+}
+
+func (n *privatePersonBySsnItemNode) insertChild(ix int, child *privatePersonBySsnItemNode) {
+	n.children = append(n.children, nil)
+	copy(n.children[ix+1:], n.children[ix:])
+	n.children[ix] = child
+}
+
+func (n *privatePersonBySsnItemNode) removeChild(ix int) {
+	copy(n.children[ix:], n.children[ix+1:])
+	n.children[len(n.children)-1] = nil
+	n.children = n.children[:len(n.children)-1]
+}
+
+func (n *privatePersonBySsnItemNode) load(key string, hash uint32) (value Person, ok bool) {
+	for shift := uint(0); ; shift += shiftSize {
+		if shift >= champHashBits {
+			for _, item := range n.items {
+				if privatePersonBySsnKeyEqual(item.Key, key) {
+					return item.Value, true
+				}
+			}
+
+			break
+		}
+
+		bit := champBitpos(hash, shift)
+		if n.dataMap&bit != 0 {
+			item := n.items[champIndex(n.dataMap, bit)]
+			if privatePersonBySsnKeyEqual(item.Key, key) {
+				return item.Value, true
+			}
+
+			break
+		}
+
+		if n.nodeMap&bit == 0 {
+			break
+		}
+
+		n = n.children[champIndex(n.nodeMap, bit)]
 	}
 
-	node := v.root
-	for level := v.shift; level > 0; level -= shiftSize {
-		node = node.([]commonNode)[(i>>level)&shiftBitMask]
+	var zeroValue Person
+	return zeroValue, false
+}
+
+// store returns a node with item stored in it and true if the item was added rather
+// than replacing an existing item.
+func (n *privatePersonBySsnItemNode) store(item PersonBySsnItem, hash uint32, shift uint, owner *transientOwner) (*privatePersonBySsnItemNode, bool) {
+	if shift >= champHashBits {
+		// Collision node
+		for ix, existing := range n.items {
+			if privatePersonBySsnKeyEqual(existing.Key, item.Key) {
+				newNode := n.editable(owner)
+				newNode.items[ix] = item
+				return newNode, false
+			}
+		}
+
+		newNode := n.editable(owner)
+		newNode.items = append(newNode.items, item)
+		return newNode, true
+	}
+
+	bit := champBitpos(hash, shift)
+	if n.dataMap&bit != 0 {
+		ix := champIndex(n.dataMap, bit)
+		existing := n.items[ix]
+		newNode := n.editable(owner)
+		if privatePersonBySsnKeyEqual(existing.Key, item.Key) {
+			newNode.items[ix] = item
+			return newNode, false
+		}
+
+		// Push both items down into a new sub node
+		child := newPersonBySsnItemNode(existing, stringHash(existing.Key), item, hash, shift+shiftSize, owner)
+		newNode.removeItem(ix)
+		newNode.dataMap &^= bit
+		newNode.nodeMap |= bit
+		newNode.insertChild(champIndex(newNode.nodeMap, bit), child)
+		return newNode, true
+	}
+
+	if n.nodeMap&bit != 0 {
+		ix := champIndex(n.nodeMap, bit)
+		child, added := n.children[ix].store(item, hash, shift+shiftSize, owner)
+		newNode := n
+		if child != n.children[ix] {
+			newNode = n.editable(owner)
+			newNode.children[ix] = child
+		}
+
+		return newNode, added
 	}
 
-	return node.([]privatePersonBySsnItemBucket)
+	newNode := n.editable(owner)
+	newNode.dataMap |= bit
+	newNode.insertItem(champIndex(newNode.dataMap, bit), item)
+	return newNode, true
 }
 
-func (v *privatePersonBySsnItemBucketVector) tailOffset() uint {
-	if v.len < nodeSize {
-		return 0
+func newPersonBySsnItemNode(item1 PersonBySsnItem, hash1 uint32, item2 PersonBySsnItem, hash2 uint32, shift uint, owner *transientOwner) *privatePersonBySsnItemNode {
+	if shift >= champHashBits {
+		return &privatePersonBySsnItemNode{items: []PersonBySsnItem{item1, item2}, owner: owner}
 	}
 
-	return ((v.len - 1) >> shiftSize) << shiftSize
+	bit1, bit2 := champBitpos(hash1, shift), champBitpos(hash2, shift)
+	if bit1 == bit2 {
+		child := newPersonBySsnItemNode(item1, hash1, item2, hash2, shift+shiftSize, owner)
+		return &privatePersonBySsnItemNode{nodeMap: bit1, children: []*privatePersonBySsnItemNode{child}, owner: owner}
+	}
+
+	items := []PersonBySsnItem{item1, item2}
+	if bit2 < bit1 {
+		items[0], items[1] = item2, item1
+	}
+
+	return &privatePersonBySsnItemNode{dataMap: bit1 | bit2, items: items, owner: owner}
 }
 
-func (v *privatePersonBySsnItemBucketVector) Set(i int, item privatePersonBySsnItemBucket) *privatePersonBySsnItemBucketVector {
-	if i < 0 || uint(i) >= v.len {
-		panic("Index out of bounds")
+// delete returns a node without key and true if the key was found. A node that only
+// contains a single item is returned as is to let the parent inline the item.
+func (n *privatePersonBySsnItemNode) delete(key string, hash uint32, shift uint, owner *transientOwner) (*privatePersonBySsnItemNode, bool) {
+	if shift >= champHashBits {
+		// Collision node
+		for ix, existing := range n.items {
+			if privatePersonBySsnKeyEqual(existing.Key, key) {
+				newNode := n.editable(owner)
+				newNode.removeItem(ix)
+				return newNode, true
+			}
+		}
+
+		return n, false
 	}
 
-	if uint(i) >= v.tailOffset() {
-		newTail := make([]privatePersonBySsnItemBucket, len(v.tail))
-		copy(newTail, v.tail)
-		newTail[i&shiftBitMask] = item
-		return &privatePersonBySsnItemBucketVector{root: v.root, tail: newTail, len: v.len, shift: v.shift}
+	bit := champBitpos(hash, shift)
+	if n.dataMap&bit != 0 {
+		ix := champIndex(n.dataMap, bit)
+		if !privatePersonBySsnKeyEqual(n.items[ix].Key, key) {
+			return n, false
+		}
+
+		newNode := n.editable(owner)
+		newNode.removeItem(ix)
+		newNode.dataMap &^= bit
+		return newNode, true
 	}
 
-	return &privatePersonBySsnItemBucketVector{root: v.doAssoc(v.shift, v.root, uint(i), item), tail: v.tail, len: v.len, shift: v.shift}
+	if n.nodeMap&bit != 0 {
+		ix := champIndex(n.nodeMap, bit)
+		child, deleted := n.children[ix].delete(key, hash, shift+shiftSize, owner)
+		if !deleted {
+			return n, false
+		}
+
+		if child.isSingleItem() {
+			if shift > 0 && len(n.items) == 0 && len(n.children) == 1 {
+				// Let the parent inline the remaining item
+				return child, true
+			}
+
+			// Inline the remaining item of the child in this node
+			newNode := n.editable(owner)
+			newNode.removeChild(ix)
+			newNode.nodeMap &^= bit
+			newNode.dataMap |= bit
+			newNode.insertItem(champIndex(newNode.dataMap, bit), child.items[0])
+			return newNode, true
+		}
+
+		newNode := n.editable(owner)
+		newNode.children[ix] = child
+		return newNode, true
+	}
+
+	return n, false
 }
 
-func (v *privatePersonBySsnItemBucketVector) doAssoc(level uint, node commonNode, i uint, item privatePersonBySsnItemBucket) commonNode {
-	if level == 0 {
-		ret := make([]privatePersonBySsnItemBucket, nodeSize)
-		copy(ret, node.([]privatePersonBySsnItemBucket))
-		ret[i&shiftBitMask] = item
-		return ret
+func (n *privatePersonBySsnItemNode) rangeItems(f func(string, Person) bool) bool {
+	for _, item := range n.items {
+		if !f(item.Key, item.Value) {
+			return false
+		}
 	}
 
-	ret := make([]commonNode, nodeSize)
-	copy(ret, node.([]commonNode))
-	subidx := (i >> level) & shiftBitMask
-	ret[subidx] = v.doAssoc(level-shiftSize, ret[subidx], i, item)
-	return ret
+	for _, child := range n.children {
+		if !child.rangeItems(f) {
+			return false
+		}
+	}
+
+	return true
 }
 
-func (v *privatePersonBySsnItemBucketVector) pushTail(level uint, parent commonNode, tailNode []privatePersonBySsnItemBucket) commonNode {
-	subIdx := ((v.len - 1) >> level) & shiftBitMask
-	parentNode := parent.([]commonNode)
-	ret := make([]commonNode, subIdx+1)
-	copy(ret, parentNode)
-	var nodeToInsert commonNode
+// PersonBySsn is a persistent key - value map
+type PersonBySsn struct {
+	root *privatePersonBySsnItemNode
+	len  int
+	hash *uint32
+}
 
-	if level == shiftSize {
-		nodeToInsert = tailNode
-	} else if subIdx < uint(len(parentNode)) {
-		nodeToInsert = v.pushTail(level-shiftSize, parentNode[subIdx], tailNode)
-	} else {
-		nodeToInsert = newPath(level-shiftSize, tailNode)
+var emptyPersonBySsn = &PersonBySsn{root: emptyPersonBySsnItemNode, hash: new(uint32)}
+
+func newPersonBySsn(items []PersonBySsnItem) *PersonBySsn {
+	t := emptyPersonBySsn.AsTransient()
+	for _, item := range items {
+		t.Store(item.Key, item.Value)
 	}
 
-	ret[subIdx] = nodeToInsert
-	return ret
+	return t.Persistent()
 }
 
-func (v *privatePersonBySsnItemBucketVector) Append(item ...privatePersonBySsnItemBucket) *privatePersonBySsnItemBucketVector {
-	result := v
-	itemLen := uint(len(item))
-	for insertOffset := uint(0); insertOffset < itemLen; {
-		tailLen := result.len - result.tailOffset()
-		tailFree := nodeSize - tailLen
-		if tailFree == 0 {
-			result = result.pushLeafNode(result.tail)
-			result.tail = emptyPersonBySsnItemBucketVector.tail
-			tailFree = nodeSize
-			tailLen = 0
+// Len returns the number of items in m.
+func (m *PersonBySsn) Len() int {
+	return int(m.len)
+}
+
+// Load returns value identified by key. ok is set to true if key exists in the map, false otherwise.
+func (m *PersonBySsn) Load(key string) (value Person, ok bool) {
+	return m.root.load(key, stringHash(key))
+}
+
+// Store returns a new PersonBySsn containing value identified by key.
+func (m *PersonBySsn) Store(key string, value Person) *PersonBySsn {
+	root, added := m.root.store(PersonBySsnItem{Key: key, Value: value}, stringHash(key), 0, nil)
+	if added {
+		return &PersonBySsn{root: root, len: m.len + 1, hash: new(uint32)}
+	}
+
+	return &PersonBySsn{root: root, len: m.len, hash: new(uint32)}
+}
+
+// Delete returns a new PersonBySsn without the element identified by key.
+func (m *PersonBySsn) Delete(key string) *PersonBySsn {
+	root, deleted := m.root.delete(key, stringHash(key), 0, nil)
+	if !deleted {
+		return m
+	}
+
+	return &PersonBySsn{root: root, len: m.len - 1, hash: new(uint32)}
+}
+
+// Range calls f repeatedly passing it each key and value as argument until either
+// all elements have been visited or f returns false.
+func (m *PersonBySsn) Range(f func(string, Person) bool) {
+	m.root.rangeItems(f)
+}
+
+// MarshalJSON encodes m as a JSON object if the keys are strings, integers or implement
+// encoding.TextMarshaler, following the same rules as encoding/json uses for map keys.
+// Other keys are encoded as an array of [key, value] arrays.
+func (m *PersonBySsn) MarshalJSON() ([]byte, error) {
+	return jsonEncodeMap(jsonObjectKeys((*string)(nil)), func(entry func(key, value interface{}) bool) {
+		m.Range(func(key string, value Person) bool {
+			return entry(&key, &value)
+		})
+	})
+}
+
+// UnmarshalJSON sets m to the items of data, either a JSON object or an array of [key, value]
+// arrays. m must not be used by anyone else when this is called, it is meant to be used by
+// encoding/json only.
+func (m *PersonBySsn) UnmarshalJSON(data []byte) error {
+	t := emptyPersonBySsn.AsTransient()
+	err := jsonDecodeMap(data, func(decodeKey func(interface{}) error, dec *json.Decoder) error {
+		var item PersonBySsnItem
+		if err := decodeKey(&item.Key); err != nil {
+			return err
 		}
 
-		batchLen := uintMin(itemLen-insertOffset, tailFree)
-		newTail := make([]privatePersonBySsnItemBucket, 0, tailLen+batchLen)
-		newTail = append(newTail, result.tail...)
-		newTail = append(newTail, item[insertOffset:insertOffset+batchLen]...)
-		result = &privatePersonBySsnItemBucketVector{root: result.root, tail: newTail, len: result.len + batchLen, shift: result.shift}
-		insertOffset += batchLen
+		if err := dec.Decode(&item.Value); err != nil {
+			return err
+		}
+
+		t.Store(item.Key, item.Value)
+		return nil
+	})
+
+	if err != nil {
+		return err
 	}
 
-	return result
+	*m = *t.Persistent()
+	return nil
 }
 
-func (v *privatePersonBySsnItemBucketVector) pushLeafNode(node []privatePersonBySsnItemBucket) *privatePersonBySsnItemBucketVector {
-	var newRoot commonNode
-	newShift := v.shift
+// MarshalBinary returns the binary encoding of m. The keys and values are gob encoded.
+func (m *PersonBySsn) MarshalBinary() ([]byte, error) {
+	return binaryMarshal(m.Len(), func(encode func(interface{}) error) error {
+		chunk := make([]PersonBySsnItem, 0, nodeSize)
+		var err error
+		m.Range(func(key string, value Person) bool {
+			chunk = append(chunk, PersonBySsnItem{Key: key, Value: value})
+			if len(chunk) == nodeSize {
+				err = encode(chunk)
+				chunk = chunk[:0]
+			}
 
-	// Root overflow?
-	if (v.len >> shiftSize) > (1 << v.shift) {
-		newNode := newPath(v.shift, node)
-		newRoot = commonNode([]commonNode{v.root, newNode})
-		newShift = v.shift + shiftSize
-	} else {
-		newRoot = v.pushTail(v.shift, v.root, node)
+			return err == nil
+		})
+
+		if err == nil && len(chunk) > 0 {
+			err = encode(chunk)
+		}
+
+		return err
+	})
+}
+
+// UnmarshalBinary sets m to the map encoded in data by MarshalBinary. m must not
+// be used by anyone else when this is called, decode into a new zero PersonBySsn.
+func (m *PersonBySsn) UnmarshalBinary(data []byte) error {
+	t := emptyPersonBySsn.AsTransient()
+	err := binaryUnmarshal(data, func(decode func(interface{}) error) (int, error) {
+		var chunk []PersonBySsnItem
+		err := decode(&chunk)
+		for _, item := range chunk {
+			t.Store(item.Key, item.Value)
+		}
+
+		return len(chunk), err
+	})
+
+	if err != nil {
+		return err
 	}
 
-	return &privatePersonBySsnItemBucketVector{root: newRoot, tail: v.tail, len: v.len, shift: newShift}
+	*m = *t.Persistent()
+	return nil
 }
 
-func (v *privatePersonBySsnItemBucketVector) Len() int {
-	return int(v.len)
+// GobEncode implements gob.GobEncoder using the same encoding as MarshalBinary.
+func (m *PersonBySsn) GobEncode() ([]byte, error) {
+	return m.MarshalBinary()
 }
 
-func (v *privatePersonBySsnItemBucketVector) Range(f func(privatePersonBySsnItemBucket) bool) {
-	var currentNode []privatePersonBySsnItemBucket
-	for i := uint(0); i < v.len; i++ {
-		if i&shiftBitMask == 0 {
-			currentNode = v.sliceFor(uint(i))
-		}
-
-		if !f(currentNode[i&shiftBitMask]) {
-			return
-		}
-	}
+// GobDecode implements gob.GobDecoder using the same encoding as UnmarshalBinary.
+func (m *PersonBySsn) GobDecode(data []byte) error {
+	return m.UnmarshalBinary(data)
 }
 
-// PersonBySsn is a persistent key - value map
-type PersonBySsn struct {
-	backingVector *privatePersonBySsnItemBucketVector
-	len           int
+/////////////////
+/// Transient ///
+/////////////////
+
+// PersonBySsnTransient is a mutable builder for PersonBySsn. Nodes created by the
+// transient are owned by it and edited in place rather than copied. Call Persistent
+// to turn it into a PersonBySsn, the transient cannot be used after that.
+type PersonBySsnTransient struct {
+	root  *privatePersonBySsnItemNode
+	len   int
+	owner *transientOwner
 }
 
-func (m *PersonBySsn) pos(key string) int {
-	return int(uint64(stringHash(key)) % uint64(m.backingVector.Len()))
+// AsTransient returns a transient containing all items of m. m is left untouched.
+func (m *PersonBySsn) AsTransient() *PersonBySsnTransient {
+	return &PersonBySsnTransient{root: m.root, len: m.len, owner: &transientOwner{}}
 }
 
-// Helper type used during map creation and reallocation
-type privatePersonBySsnItemBuckets struct {
-	buckets []privatePersonBySsnItemBucket
-	length  int
+// Persistent returns a PersonBySsn containing all items of t. t cannot be used
+// after this call.
+func (t *PersonBySsnTransient) Persistent() *PersonBySsn {
+	assertTransientEditable(t.owner != nil)
+	t.owner = nil
+	return &PersonBySsn{root: t.root, len: t.len, hash: new(uint32)}
 }
 
-func newPrivatePersonBySsnItemBuckets(itemCount int) *privatePersonBySsnItemBuckets {
-	size := int(float64(itemCount)/initialMapLoadFactor) + 1
-	buckets := make([]privatePersonBySsnItemBucket, size)
-	return &privatePersonBySsnItemBuckets{buckets: buckets}
+// Len returns the number of items in t.
+func (t *PersonBySsnTransient) Len() int {
+	assertTransientEditable(t.owner != nil)
+	return t.len
 }
 
-func (b *privatePersonBySsnItemBuckets) AddItem(item PersonBySsnItem) {
-	ix := int(uint64(stringHash(item.Key)) % uint64(len(b.buckets)))
-	bucket := b.buckets[ix]
-	if bucket != nil {
-		// Hash collision, merge with existing bucket
-		for keyIx, bItem := range bucket {
-			if item.Key == bItem.Key {
-				bucket[keyIx] = item
-				return
-			}
-		}
+// Load returns value identified by key. ok is set to true if key exists in the map, false otherwise.
+func (t *PersonBySsnTransient) Load(key string) (value Person, ok bool) {
+	assertTransientEditable(t.owner != nil)
+	return t.root.load(key, stringHash(key))
+}
+
+// Store sets the value identified by key to value.
+func (t *PersonBySsnTransient) Store(key string, value Person) {
+	assertTransientEditable(t.owner != nil)
+	root, added := t.root.store(PersonBySsnItem{Key: key, Value: value}, stringHash(key), 0, t.owner)
+	t.root = root
+	if added {
+		t.len++
+	}
+}
 
-		b.buckets[ix] = append(bucket, PersonBySsnItem{Key: item.Key, Value: item.Value})
-		b.length++
-	} else {
-		bucket := make(privatePersonBySsnItemBucket, 0, int(math.Max(initialMapLoadFactor, 1.0)))
-		b.buckets[ix] = append(bucket, item)
-		b.length++
+// Delete removes the item identified by key.
+func (t *PersonBySsnTransient) Delete(key string) {
+	assertTransientEditable(t.owner != nil)
+	root, deleted := t.root.delete(key, stringHash(key), 0, t.owner)
+	t.root = root
+	if deleted {
+		t.len--
 	}
 }
 
-func (b *privatePersonBySsnItemBuckets) AddItemsFromMap(m *PersonBySsn) {
-	m.backingVector.Range(func(bucket privatePersonBySsnItemBucket) bool {
-		for _, item := range bucket {
-			b.AddItem(item)
-		}
+func privatePersonBySsnKeyEqual(a, b string) bool {
+	return a == b
+}
+
+// Equals returns true if m and other contain the same keys mapped to equal values, false otherwise.
+func (m *PersonBySsn) Equals(other *PersonBySsn) bool {
+	if m.root == other.root {
 		return true
+	}
+
+	if m.len != other.len {
+		return false
+	}
+
+	equal := true
+	m.Range(func(key string, value Person) bool {
+		otherValue, ok := other.Load(key)
+		equal = ok && privatePersonBySsnValueEqual(&value, &otherValue)
+		return equal
 	})
+
+	return equal
 }
 
-func newPersonBySsn(items []PersonBySsnItem) *PersonBySsn {
-	buckets := newPrivatePersonBySsnItemBuckets(len(items))
-	for _, item := range items {
-		buckets.AddItem(item)
+// Hash returns a hash of the items in m. Maps that are equal according to Equals have
+// the same hash. The hash is computed the first time it is needed and is then kept for m
+// and all copies of it, including copies stored in other containers.
+func (m *PersonBySsn) Hash() uint32 {
+	if h := loadHash(m.hash); h != 0 {
+		return h
 	}
 
-	return &PersonBySsn{backingVector: emptyPersonBySsnItemBucketVector.Append(buckets.buckets...), len: buckets.length}
+	// The items are summed since the order in which they are visited depends on the tree
+	h := uint32(0)
+	m.Range(func(key string, value Person) bool {
+		h += 31*stringHash(key) + privatePersonBySsnValueHash(&value)
+		return true
+	})
+
+	return cacheHash(m.hash, h)
 }
 
-// Len returns the number of items in m.
-func (m *PersonBySsn) Len() int {
-	return int(m.len)
+func privatePersonBySsnValueHash(x *Person) uint32 {
+	return interfaceHash(*x)
 }
 
-// Load returns value identified by key. ok is set to true if key exists in the map, false otherwise.
-func (m *PersonBySsn) Load(key string) (value Person, ok bool) {
-	bucket := m.backingVector.Get(m.pos(key))
-	if bucket != nil {
-		for _, item := range bucket {
-			if item.Key == key {
-				return item.Value, true
-			}
-		}
-	}
+func privatePersonBySsnValueEqual(a, b *Person) bool {
+	return *a == *b
+}
 
-	var zeroValue Person
-	return zeroValue, false
+// EncodeSnapshot writes m to the snapshot stream of e. Nodes shared with containers
+// previously written to e are written as references to those.
+func (m *PersonBySsn) EncodeSnapshot(e *SnapshotEncoder) error {
+	return e.encode(snapshotMapTag, func() error {
+		return m.encodeSnapshot(e)
+	})
 }
 
-// Store returns a new PersonBySsn containing value identified by key.
-func (m *PersonBySsn) Store(key string, value Person) *PersonBySsn {
-	// Grow backing vector if load factor is too high
-	if m.Len() >= m.backingVector.Len()*int(upperMapLoadFactor) {
-		buckets := newPrivatePersonBySsnItemBuckets(m.Len() + 1)
-		buckets.AddItemsFromMap(m)
-		buckets.AddItem(PersonBySsnItem{Key: key, Value: value})
-		return &PersonBySsn{backingVector: emptyPersonBySsnItemBucketVector.Append(buckets.buckets...), len: buckets.length}
-	}
-
-	pos := m.pos(key)
-	bucket := m.backingVector.Get(pos)
-	if bucket != nil {
-		for ix, item := range bucket {
-			if item.Key == key {
-				// Overwrite existing item
-				newBucket := make(privatePersonBySsnItemBucket, len(bucket))
-				copy(newBucket, bucket)
-				newBucket[ix] = PersonBySsnItem{Key: key, Value: value}
-				return &PersonBySsn{backingVector: m.backingVector.Set(pos, newBucket), len: m.len}
+func (m *PersonBySsn) encodeSnapshot(e *SnapshotEncoder) error {
+	e.writeUvarint(uint64(m.len))
+	return m.root.encodeSnapshot(e)
+}
+
+func (n *privatePersonBySsnItemNode) encodeSnapshot(e *SnapshotEncoder) error {
+	return e.writeNode(n, func() error {
+		e.writeUvarint(uint64(n.dataMap))
+		e.writeUvarint(uint64(n.nodeMap))
+		if err := e.writeElements(n.items); err != nil {
+			return err
+		}
+
+		e.writeUvarint(uint64(len(n.children)))
+		for _, child := range n.children {
+			if err := child.encodeSnapshot(e); err != nil {
+				return err
 			}
 		}
 
-		// Add new item to bucket
-		newBucket := make(privatePersonBySsnItemBucket, len(bucket), len(bucket)+1)
-		copy(newBucket, bucket)
-		newBucket = append(newBucket, PersonBySsnItem{Key: key, Value: value})
-		return &PersonBySsn{backingVector: m.backingVector.Set(pos, newBucket), len: m.len + 1}
+		return nil
+	})
+}
+
+// DecodeSnapshot sets m to the next container in the snapshot stream of d, which must
+// be a map. io.EOF is returned if there are no more containers in the stream. m must
+// not be used by anyone else when this is called, decode into a new zero PersonBySsn.
+func (m *PersonBySsn) DecodeSnapshot(d *SnapshotDecoder) error {
+	return d.decode(snapshotMapTag, func() error {
+		return m.decodeSnapshot(d)
+	})
+}
+
+func (m *PersonBySsn) decodeSnapshot(d *SnapshotDecoder) error {
+	length, err := d.readLength(math.MaxInt32)
+	if err != nil {
+		return err
+	}
+
+	root, err := decodePersonBySsnItemNodeSnapshot(d, 0)
+	if err != nil {
+		return err
 	}
 
-	item := PersonBySsnItem{Key: key, Value: value}
-	newBucket := privatePersonBySsnItemBucket{item}
-	return &PersonBySsn{backingVector: m.backingVector.Set(pos, newBucket), len: m.len + 1}
+	*m = PersonBySsn{root: root, len: length, hash: new(uint32)}
+	return nil
 }
 
-// Delete returns a new PersonBySsn without the element identified by key.
-func (m *PersonBySsn) Delete(key string) *PersonBySsn {
-	pos := m.pos(key)
-	bucket := m.backingVector.Get(pos)
-	if bucket != nil {
-		newBucket := make(privatePersonBySsnItemBucket, 0)
-		for _, item := range bucket {
-			if item.Key != key {
-				newBucket = append(newBucket, item)
-			}
+func decodePersonBySsnItemNodeSnapshot(d *SnapshotDecoder, shift uint) (*privatePersonBySsnItemNode, error) {
+	node, err := d.readNode(func() (interface{}, error) {
+		dataMap, err := d.readUint32()
+		if err != nil {
+			return nil, err
 		}
 
-		removedItemCount := len(bucket) - len(newBucket)
-		if removedItemCount == 0 {
-			return m
+		nodeMap, err := d.readUint32()
+		if err != nil {
+			return nil, err
 		}
 
-		if len(newBucket) == 0 {
-			newBucket = nil
+		n := &privatePersonBySsnItemNode{dataMap: dataMap, nodeMap: nodeMap}
+		if err := d.readElements(&n.items); err != nil {
+			return nil, err
 		}
 
-		newMap := &PersonBySsn{backingVector: m.backingVector.Set(pos, newBucket), len: m.len - removedItemCount}
-		if newMap.backingVector.Len() > 1 && newMap.Len() < newMap.backingVector.Len()*int(lowerMapLoadFactor) {
-			// Shrink backing vector if needed to avoid occupying excessive space
-			buckets := newPrivatePersonBySsnItemBuckets(newMap.Len())
-			buckets.AddItemsFromMap(newMap)
-			return &PersonBySsn{backingVector: emptyPersonBySsnItemBucketVector.Append(buckets.buckets...), len: buckets.length}
+		count, err := d.readLength(nodeSize)
+		if err != nil {
+			return nil, err
 		}
 
-		return newMap
+		n.children = make([]*privatePersonBySsnItemNode, count)
+		for i := range n.children {
+			if n.children[i], err = decodePersonBySsnItemNodeSnapshot(d, shift+shiftSize); err != nil {
+				return nil, err
+			}
+		}
+
+		collision := shift >= champHashBits && n.dataMap == 0 && n.nodeMap == 0 && count == 0
+		if !collision && (bits.OnesCount32(n.dataMap) != len(n.items) || bits.OnesCount32(n.nodeMap) != count) {
+			return nil, fmt.Errorf("invalid snapshot, map node with %d items and %d children does not match its bitmaps", len(n.items), count)
+		}
+
+		return n, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if n, ok := node.(*privatePersonBySsnItemNode); ok {
+		return n, nil
 	}
 
-	return m
+	return nil, invalidSnapshotNode(node)
 }
 
-// Range calls f repeatedly passing it each key and value as argument until either
-// all elements have been visited or f returns false.
-func (m *PersonBySsn) Range(f func(string, Person) bool) {
-	m.backingVector.Range(func(bucket privatePersonBySsnItemBucket) bool {
-		for _, item := range bucket {
-			if !f(item.Key, item.Value) {
-				return false
-			}
-		}
-		return true
-	})
+////////////////////
+/// Constructors ///
+////////////////////
+
+// NewPersonBySsn returns a new PersonBySsn containing all items in items.
+func NewPersonBySsn(items ...PersonBySsnItem) *PersonBySsn {
+	return newPersonBySsn(items)
 }
 
 // ToNativeMap returns a native Go map containing all elements of m.
@@ -724,358 +2362,622 @@ func (m *PersonBySsn) ToNativeMap() map[string]Person {
 	return result
 }
 
-////////////////////
-/// Constructors ///
-////////////////////
-
-// NewPersonBySsn returns a new PersonBySsn containing all items in items.
-func NewPersonBySsn(items ...PersonBySsnItem) *PersonBySsn {
-	return newPersonBySsn(items)
-}
-
 // NewPersonBySsnFromNativeMap returns a new PersonBySsn containing all items in m.
 func NewPersonBySsnFromNativeMap(m map[string]Person) *PersonBySsn {
-	buckets := newPrivatePersonBySsnItemBuckets(len(m))
+	t := emptyPersonBySsn.AsTransient()
 	for key, value := range m {
-		buckets.AddItem(PersonBySsnItem{Key: key, Value: value})
+		t.Store(key, value)
 	}
 
-	return &PersonBySsn{backingVector: emptyPersonBySsnItemBucketVector.Append(buckets.buckets...), len: buckets.length}
+	return t.Persistent()
 }
 
 ///////////
 /// Map ///
 ///////////
 
-//////////////////////
-/// Backing vector ///
-//////////////////////
-
-type privateprivatePersonsMapItemBucketVector struct {
-	tail  []privateprivatePersonsMapItemBucket
-	root  commonNode
-	len   uint
-	shift uint
-}
-
 type privatePersonsMapItem struct {
 	Key   Person
 	Value struct{}
 }
 
-type privateprivatePersonsMapItemBucket []privatePersonsMapItem
+// privateprivatePersonsMapItemNode is a node in a compressed hash-array mapped prefix tree (CHAMP).
+// Items stored directly in the node are kept in items and their positions are marked in
+// dataMap. Sub nodes are kept in children and their positions are marked in nodeMap. Nodes
+// below the last level of the hash are collision nodes that only contain items.
+type privateprivatePersonsMapItemNode struct {
+	dataMap  uint32
+	nodeMap  uint32
+	items    []privatePersonsMapItem
+	children []*privateprivatePersonsMapItemNode
+	owner    *transientOwner
+}
 
-var emptyprivatePersonsMapItemBucketVectorTail = make([]privateprivatePersonsMapItemBucket, 0)
-var emptyprivatePersonsMapItemBucketVector *privateprivatePersonsMapItemBucketVector = &privateprivatePersonsMapItemBucketVector{root: emptyCommonNode, shift: shiftSize, tail: emptyprivatePersonsMapItemBucketVectorTail}
+var emptyprivatePersonsMapItemNode = &privateprivatePersonsMapItemNode{}
 
-func (v *privateprivatePersonsMapItemBucketVector) Get(i int) privateprivatePersonsMapItemBucket {
-	if i < 0 || uint(i) >= v.len {
-		panic("Index out of bounds")
+// editable returns n if it is owned by owner, otherwise a copy of n owned by owner.
+// owner is nil for persistent updates in which case a copy is always returned.
+func (n *privateprivatePersonsMapItemNode) editable(owner *transientOwner) *privateprivatePersonsMapItemNode {
+	if owner != nil && n.owner == owner {
+		return n
 	}
 
-	return v.sliceFor(uint(i))[i&shiftBitMask]
+	items := make([]privatePersonsMapItem, len(n.items), len(n.items)+1)
+	copy(items, n.items)
+	children := make([]*privateprivatePersonsMapItemNode, len(n.children), len(n.children)+1)
+	copy(children, n.children)
+	return &privateprivatePersonsMapItemNode{dataMap: n.dataMap, nodeMap: n.nodeMap, items: items, children: children, owner: owner}
 }
 
-func (v *privateprivatePersonsMapItemBucketVector) sliceFor(i uint) []privateprivatePersonsMapItemBucket {
-	if i >= v.tailOffset() {
-		return v.tail
+func (n *privateprivatePersonsMapItemNode) isSingleItem() bool {
+	return len(n.items) == 1 && len(n.children) == 0
+}
+
+func (n *privateprivatePersonsMapItemNode) insertItem(ix int, item privatePersonsMapItem) {
+	var zeroItem privatePersonsMapItem
+	n.items = append(n.items, zeroItem)
+	copy(n.items[ix+1:], n.items[ix:])
+	n.items[ix] = item
+}
+
+func (n *privateprivatePersonsMapItemNode) removeItem(ix int) {
+	var zeroItem privatePersonsMapItem
+	copy(n.items[ix:], n.items[ix+1:])
+	n.items[len(n.items)-1] = zeroItem
+	n.items = n.items[:len(n.items)-1]
+}
+
+func (n *privateprivatePersonsMapItemNode) insertChild(ix int, child *privateprivatePersonsMapItemNode) {
+	n.children = append(n.children, nil)
+	copy(n.children[ix+1:], n.children[ix:])
+	n.children[ix] = child
+}
+
+func (n *privateprivatePersonsMapItemNode) removeChild(ix int) {
+	copy(n.children[ix:], n.children[ix+1:])
+	n.children[len(n.children)-1] = nil
+	n.children = n.children[:len(n.children)-1]
+}
+
+func (n *privateprivatePersonsMapItemNode) load(key Person, hash uint32) (value struct{}, ok bool) {
+	for shift := uint(0); ; shift += shiftSize {
+		if shift >= champHashBits {
+			for _, item := range n.items {
+				if privatePersonsMapKeyEqual(item.Key, key) {
+					return item.Value, true
+				}
+			}
+
+			break
+		}
+
+		bit := champBitpos(hash, shift)
+		if n.dataMap&bit != 0 {
+			item := n.items[champIndex(n.dataMap, bit)]
+			if privatePersonsMapKeyEqual(item.Key, key) {
+				return item.Value, true
+			}
+
+			break
+		}
+
+		if n.nodeMap&bit == 0 {
+			break
+		}
+
+		n = n.children[champIndex(n.nodeMap, bit)]
 	}
 
-	node := v.root
-	for level := v.shift; level > 0; level -= shiftSize {
-		node = node.([]commonNode)[(i>>level)&shiftBitMask]
+	var zeroValue struct{}
+	return zeroValue, false
+}
+
+// store returns a node with item stored in it and true if the item was added rather
+// than replacing an existing item.
+func (n *privateprivatePersonsMapItemNode) store(item privatePersonsMapItem, hash uint32, shift uint, owner *transientOwner) (*privateprivatePersonsMapItemNode, bool) {
+	if shift >= champHashBits {
+		// Collision node
+		for ix, existing := range n.items {
+			if privatePersonsMapKeyEqual(existing.Key, item.Key) {
+				newNode := n.editable(owner)
+				newNode.items[ix] = item
+				return newNode, false
+			}
+		}
+
+		newNode := n.editable(owner)
+		newNode.items = append(newNode.items, item)
+		return newNode, true
+	}
+
+	bit := champBitpos(hash, shift)
+	if n.dataMap&bit != 0 {
+		ix := champIndex(n.dataMap, bit)
+		existing := n.items[ix]
+		newNode := n.editable(owner)
+		if privatePersonsMapKeyEqual(existing.Key, item.Key) {
+			newNode.items[ix] = item
+			return newNode, false
+		}
+
+		// Push both items down into a new sub node
+		child := newprivatePersonsMapItemNode(existing, interfaceHash(existing.Key), item, hash, shift+shiftSize, owner)
+		newNode.removeItem(ix)
+		newNode.dataMap &^= bit
+		newNode.nodeMap |= bit
+		newNode.insertChild(champIndex(newNode.nodeMap, bit), child)
+		return newNode, true
 	}
 
-	return node.([]privateprivatePersonsMapItemBucket)
+	if n.nodeMap&bit != 0 {
+		ix := champIndex(n.nodeMap, bit)
+		child, added := n.children[ix].store(item, hash, shift+shiftSize, owner)
+		newNode := n
+		if child != n.children[ix] {
+			newNode = n.editable(owner)
+			newNode.children[ix] = child
+		}
+
+		return newNode, added
+	}
+
+	newNode := n.editable(owner)
+	newNode.dataMap |= bit
+	newNode.insertItem(champIndex(newNode.dataMap, bit), item)
+	return newNode, true
 }
 
-func (v *privateprivatePersonsMapItemBucketVector) tailOffset() uint {
-	if v.len < nodeSize {
-		return 0
+func newprivatePersonsMapItemNode(item1 privatePersonsMapItem, hash1 uint32, item2 privatePersonsMapItem, hash2 uint32, shift uint, owner *transientOwner) *privateprivatePersonsMapItemNode {
+	if shift >= champHashBits {
+		return &privateprivatePersonsMapItemNode{items: []privatePersonsMapItem{item1, item2}, owner: owner}
 	}
 
-	return ((v.len - 1) >> shiftSize) << shiftSize
+	bit1, bit2 := champBitpos(hash1, shift), champBitpos(hash2, shift)
+	if bit1 == bit2 {
+		child := newprivatePersonsMapItemNode(item1, hash1, item2, hash2, shift+shiftSize, owner)
+		return &privateprivatePersonsMapItemNode{nodeMap: bit1, children: []*privateprivatePersonsMapItemNode{child}, owner: owner}
+	}
+
+	items := []privatePersonsMapItem{item1, item2}
+	if bit2 < bit1 {
+		items[0], items[1] = item2, item1
+	}
+
+	return &privateprivatePersonsMapItemNode{dataMap: bit1 | bit2, items: items, owner: owner}
 }
 
-func (v *privateprivatePersonsMapItemBucketVector) Set(i int, item privateprivatePersonsMapItemBucket) *privateprivatePersonsMapItemBucketVector {
-	if i < 0 || uint(i) >= v.len {
-		panic("Index out of bounds")
+// delete returns a node without key and true if the key was found. A node that only
+// contains a single item is returned as is to let the parent inline the item.
+func (n *privateprivatePersonsMapItemNode) delete(key Person, hash uint32, shift uint, owner *transientOwner) (*privateprivatePersonsMapItemNode, bool) {
+	if shift >= champHashBits {
+		// Collision node
+		for ix, existing := range n.items {
+			if privatePersonsMapKeyEqual(existing.Key, key) {
+				newNode := n.editable(owner)
+				newNode.removeItem(ix)
+				return newNode, true
+			}
+		}
+
+		return n, false
 	}
 
-	if uint(i) >= v.tailOffset() {
-		newTail := make([]privateprivatePersonsMapItemBucket, len(v.tail))
-		copy(newTail, v.tail)
-		newTail[i&shiftBitMask] = item
-		return &privateprivatePersonsMapItemBucketVector{root: v.root, tail: newTail, len: v.len, shift: v.shift}
+	bit := champBitpos(hash, shift)
+	if n.dataMap&bit != 0 {
+		ix := champIndex(n.dataMap, bit)
+		if !privatePersonsMapKeyEqual(n.items[ix].Key, key) {
+			return n, false
+		}
+
+		newNode := n.editable(owner)
+		newNode.removeItem(ix)
+		newNode.dataMap &^= bit
+		return newNode, true
+	}
+
+	if n.nodeMap&bit != 0 {
+		ix := champIndex(n.nodeMap, bit)
+		child, deleted := n.children[ix].delete(key, hash, shift+shiftSize, owner)
+		if !deleted {
+			return n, false
+		}
+
+		if child.isSingleItem() {
+			if shift > 0 && len(n.items) == 0 && len(n.children) == 1 {
+				// Let the parent inline the remaining item
+				return child, true
+			}
+
+			// Inline the remaining item of the child in this node
+			newNode := n.editable(owner)
+			newNode.removeChild(ix)
+			newNode.nodeMap &^= bit
+			newNode.dataMap |= bit
+			newNode.insertItem(champIndex(newNode.dataMap, bit), child.items[0])
+			return newNode, true
+		}
+
+		newNode := n.editable(owner)
+		newNode.children[ix] = child
+		return newNode, true
 	}
 
-	return &privateprivatePersonsMapItemBucketVector{root: v.doAssoc(v.shift, v.root, uint(i), item), tail: v.tail, len: v.len, shift: v.shift}
+	return n, false
 }
 
-func (v *privateprivatePersonsMapItemBucketVector) doAssoc(level uint, node commonNode, i uint, item privateprivatePersonsMapItemBucket) commonNode {
-	if level == 0 {
-		ret := make([]privateprivatePersonsMapItemBucket, nodeSize)
-		copy(ret, node.([]privateprivatePersonsMapItemBucket))
-		ret[i&shiftBitMask] = item
-		return ret
+func (n *privateprivatePersonsMapItemNode) rangeItems(f func(Person, struct{}) bool) bool {
+	for _, item := range n.items {
+		if !f(item.Key, item.Value) {
+			return false
+		}
 	}
 
-	ret := make([]commonNode, nodeSize)
-	copy(ret, node.([]commonNode))
-	subidx := (i >> level) & shiftBitMask
-	ret[subidx] = v.doAssoc(level-shiftSize, ret[subidx], i, item)
-	return ret
+	for _, child := range n.children {
+		if !child.rangeItems(f) {
+			return false
+		}
+	}
+
+	return true
 }
 
-func (v *privateprivatePersonsMapItemBucketVector) pushTail(level uint, parent commonNode, tailNode []privateprivatePersonsMapItemBucket) commonNode {
-	subIdx := ((v.len - 1) >> level) & shiftBitMask
-	parentNode := parent.([]commonNode)
-	ret := make([]commonNode, subIdx+1)
-	copy(ret, parentNode)
-	var nodeToInsert commonNode
+// privatePersonsMap is a persistent key - value map
+type privatePersonsMap struct {
+	root *privateprivatePersonsMapItemNode
+	len  int
+	hash *uint32
+}
 
-	if level == shiftSize {
-		nodeToInsert = tailNode
-	} else if subIdx < uint(len(parentNode)) {
-		nodeToInsert = v.pushTail(level-shiftSize, parentNode[subIdx], tailNode)
-	} else {
-		nodeToInsert = newPath(level-shiftSize, tailNode)
+var emptyprivatePersonsMap = &privatePersonsMap{root: emptyprivatePersonsMapItemNode, hash: new(uint32)}
+
+func newprivatePersonsMap(items []privatePersonsMapItem) *privatePersonsMap {
+	t := emptyprivatePersonsMap.AsTransient()
+	for _, item := range items {
+		t.Store(item.Key, item.Value)
 	}
 
-	ret[subIdx] = nodeToInsert
-	return ret
+	return t.Persistent()
 }
 
-func (v *privateprivatePersonsMapItemBucketVector) Append(item ...privateprivatePersonsMapItemBucket) *privateprivatePersonsMapItemBucketVector {
-	result := v
-	itemLen := uint(len(item))
-	for insertOffset := uint(0); insertOffset < itemLen; {
-		tailLen := result.len - result.tailOffset()
-		tailFree := nodeSize - tailLen
-		if tailFree == 0 {
-			result = result.pushLeafNode(result.tail)
-			result.tail = emptyprivatePersonsMapItemBucketVector.tail
-			tailFree = nodeSize
-			tailLen = 0
-		}
+// Len returns the number of items in m.
+func (m *privatePersonsMap) Len() int {
+	return int(m.len)
+}
 
-		batchLen := uintMin(itemLen-insertOffset, tailFree)
-		newTail := make([]privateprivatePersonsMapItemBucket, 0, tailLen+batchLen)
-		newTail = append(newTail, result.tail...)
-		newTail = append(newTail, item[insertOffset:insertOffset+batchLen]...)
-		result = &privateprivatePersonsMapItemBucketVector{root: result.root, tail: newTail, len: result.len + batchLen, shift: result.shift}
-		insertOffset += batchLen
+// Load returns value identified by key. ok is set to true if key exists in the map, false otherwise.
+func (m *privatePersonsMap) Load(key Person) (value struct{}, ok bool) {
+	return m.root.load(key, interfaceHash(key))
+}
+
+// Store returns a new privatePersonsMap containing value identified by key.
+func (m *privatePersonsMap) Store(key Person, value struct{}) *privatePersonsMap {
+	root, added := m.root.store(privatePersonsMapItem{Key: key, Value: value}, interfaceHash(key), 0, nil)
+	if added {
+		return &privatePersonsMap{root: root, len: m.len + 1, hash: new(uint32)}
 	}
 
-	return result
+	return &privatePersonsMap{root: root, len: m.len, hash: new(uint32)}
 }
 
-func (v *privateprivatePersonsMapItemBucketVector) pushLeafNode(node []privateprivatePersonsMapItemBucket) *privateprivatePersonsMapItemBucketVector {
-	var newRoot commonNode
-	newShift := v.shift
+// Delete returns a new privatePersonsMap without the element identified by key.
+func (m *privatePersonsMap) Delete(key Person) *privatePersonsMap {
+	root, deleted := m.root.delete(key, interfaceHash(key), 0, nil)
+	if !deleted {
+		return m
+	}
 
-	// Root overflow?
-	if (v.len >> shiftSize) > (1 << v.shift) {
-		newNode := newPath(v.shift, node)
-		newRoot = commonNode([]commonNode{v.root, newNode})
-		newShift = v.shift + shiftSize
-	} else {
-		newRoot = v.pushTail(v.shift, v.root, node)
+	return &privatePersonsMap{root: root, len: m.len - 1, hash: new(uint32)}
+}
+
+// Range calls f repeatedly passing it each key and value as argument until either
+// all elements have been visited or f returns false.
+func (m *privatePersonsMap) Range(f func(Person, struct{}) bool) {
+	m.root.rangeItems(f)
+}
+
+// MarshalJSON encodes m as a JSON object if the keys are strings, integers or implement
+// encoding.TextMarshaler, following the same rules as encoding/json uses for map keys.
+// Other keys are encoded as an array of [key, value] arrays.
+func (m *privatePersonsMap) MarshalJSON() ([]byte, error) {
+	return jsonEncodeMap(jsonObjectKeys((*Person)(nil)), func(entry func(key, value interface{}) bool) {
+		m.Range(func(key Person, value struct{}) bool {
+			return entry(&key, &value)
+		})
+	})
+}
+
+// UnmarshalJSON sets m to the items of data, either a JSON object or an array of [key, value]
+// arrays. m must not be used by anyone else when this is called, it is meant to be used by
+// encoding/json only.
+func (m *privatePersonsMap) UnmarshalJSON(data []byte) error {
+	t := emptyprivatePersonsMap.AsTransient()
+	err := jsonDecodeMap(data, func(decodeKey func(interface{}) error, dec *json.Decoder) error {
+		var item privatePersonsMapItem
+		if err := decodeKey(&item.Key); err != nil {
+			return err
+		}
+
+		if err := dec.Decode(&item.Value); err != nil {
+			return err
+		}
+
+		t.Store(item.Key, item.Value)
+		return nil
+	})
+
+	if err != nil {
+		return err
 	}
 
-	return &privateprivatePersonsMapItemBucketVector{root: newRoot, tail: v.tail, len: v.len, shift: newShift}
+	*m = *t.Persistent()
+	return nil
 }
 
-func (v *privateprivatePersonsMapItemBucketVector) Len() int {
-	return int(v.len)
-}
+// MarshalBinary returns the binary encoding of m. The keys and values are gob encoded.
+func (m *privatePersonsMap) MarshalBinary() ([]byte, error) {
+	return binaryMarshal(m.Len(), func(encode func(interface{}) error) error {
+		chunk := make([]privatePersonsMapItem, 0, nodeSize)
+		var err error
+		m.Range(func(key Person, value struct{}) bool {
+			chunk = append(chunk, privatePersonsMapItem{Key: key, Value: value})
+			if len(chunk) == nodeSize {
+				err = encode(chunk)
+				chunk = chunk[:0]
+			}
 
-func (v *privateprivatePersonsMapItemBucketVector) Range(f func(privateprivatePersonsMapItemBucket) bool) {
-	var currentNode []privateprivatePersonsMapItemBucket
-	for i := uint(0); i < v.len; i++ {
-		if i&shiftBitMask == 0 {
-			currentNode = v.sliceFor(uint(i))
+			return err == nil
+		})
+
+		if err == nil && len(chunk) > 0 {
+			err = encode(chunk)
 		}
 
-		if !f(currentNode[i&shiftBitMask]) {
-			return
+		return err
+	})
+}
+
+// UnmarshalBinary sets m to the map encoded in data by MarshalBinary. m must not
+// be used by anyone else when this is called, decode into a new zero privatePersonsMap.
+func (m *privatePersonsMap) UnmarshalBinary(data []byte) error {
+	t := emptyprivatePersonsMap.AsTransient()
+	err := binaryUnmarshal(data, func(decode func(interface{}) error) (int, error) {
+		var chunk []privatePersonsMapItem
+		err := decode(&chunk)
+		for _, item := range chunk {
+			t.Store(item.Key, item.Value)
 		}
+
+		return len(chunk), err
+	})
+
+	if err != nil {
+		return err
 	}
+
+	*m = *t.Persistent()
+	return nil
 }
 
-// privatePersonsMap is a persistent key - value map
-type privatePersonsMap struct {
-	backingVector *privateprivatePersonsMapItemBucketVector
-	len           int
+// GobEncode implements gob.GobEncoder using the same encoding as MarshalBinary.
+func (m *privatePersonsMap) GobEncode() ([]byte, error) {
+	return m.MarshalBinary()
 }
 
-func (m *privatePersonsMap) pos(key Person) int {
-	return int(uint64(interfaceHash(key)) % uint64(m.backingVector.Len()))
+// GobDecode implements gob.GobDecoder using the same encoding as UnmarshalBinary.
+func (m *privatePersonsMap) GobDecode(data []byte) error {
+	return m.UnmarshalBinary(data)
 }
 
-// Helper type used during map creation and reallocation
-type privateprivatePersonsMapItemBuckets struct {
-	buckets []privateprivatePersonsMapItemBucket
-	length  int
+/////////////////
+/// Transient ///
+/////////////////
+
+// privatePersonsMapTransient is a mutable builder for privatePersonsMap. Nodes created by the
+// transient are owned by it and edited in place rather than copied. Call Persistent
+// to turn it into a privatePersonsMap, the transient cannot be used after that.
+type privatePersonsMapTransient struct {
+	root  *privateprivatePersonsMapItemNode
+	len   int
+	owner *transientOwner
 }
 
-func newPrivateprivatePersonsMapItemBuckets(itemCount int) *privateprivatePersonsMapItemBuckets {
-	size := int(float64(itemCount)/initialMapLoadFactor) + 1
-	buckets := make([]privateprivatePersonsMapItemBucket, size)
-	return &privateprivatePersonsMapItemBuckets{buckets: buckets}
+// AsTransient returns a transient containing all items of m. m is left untouched.
+func (m *privatePersonsMap) AsTransient() *privatePersonsMapTransient {
+	return &privatePersonsMapTransient{root: m.root, len: m.len, owner: &transientOwner{}}
 }
 
-func (b *privateprivatePersonsMapItemBuckets) AddItem(item privatePersonsMapItem) {
-	ix := int(uint64(interfaceHash(item.Key)) % uint64(len(b.buckets)))
-	bucket := b.buckets[ix]
-	if bucket != nil {
-		// Hash collision, merge with existing bucket
-		for keyIx, bItem := range bucket {
-			if item.Key == bItem.Key {
-				bucket[keyIx] = item
-				return
-			}
-		}
+// Persistent returns a privatePersonsMap containing all items of t. t cannot be used
+// after this call.
+func (t *privatePersonsMapTransient) Persistent() *privatePersonsMap {
+	assertTransientEditable(t.owner != nil)
+	t.owner = nil
+	return &privatePersonsMap{root: t.root, len: t.len, hash: new(uint32)}
+}
 
-		b.buckets[ix] = append(bucket, privatePersonsMapItem{Key: item.Key, Value: item.Value})
-		b.length++
-	} else {
-		bucket := make(privateprivatePersonsMapItemBucket, 0, int(math.Max(initialMapLoadFactor, 1.0)))
-		b.buckets[ix] = append(bucket, item)
-		b.length++
+// Len returns the number of items in t.
+func (t *privatePersonsMapTransient) Len() int {
+	assertTransientEditable(t.owner != nil)
+	return t.len
+}
+
+// Load returns value identified by key. ok is set to true if key exists in the map, false otherwise.
+func (t *privatePersonsMapTransient) Load(key Person) (value struct{}, ok bool) {
+	assertTransientEditable(t.owner != nil)
+	return t.root.load(key, interfaceHash(key))
+}
+
+// Store sets the value identified by key to value.
+func (t *privatePersonsMapTransient) Store(key Person, value struct{}) {
+	assertTransientEditable(t.owner != nil)
+	root, added := t.root.store(privatePersonsMapItem{Key: key, Value: value}, interfaceHash(key), 0, t.owner)
+	t.root = root
+	if added {
+		t.len++
 	}
 }
 
-func (b *privateprivatePersonsMapItemBuckets) AddItemsFromMap(m *privatePersonsMap) {
-	m.backingVector.Range(func(bucket privateprivatePersonsMapItemBucket) bool {
-		for _, item := range bucket {
-			b.AddItem(item)
-		}
+// Delete removes the item identified by key.
+func (t *privatePersonsMapTransient) Delete(key Person) {
+	assertTransientEditable(t.owner != nil)
+	root, deleted := t.root.delete(key, interfaceHash(key), 0, t.owner)
+	t.root = root
+	if deleted {
+		t.len--
+	}
+}
+
+func privatePersonsMapKeyEqual(a, b Person) bool {
+	return a == b
+}
+
+// Equals returns true if m and other contain the same keys mapped to equal values, false otherwise.
+func (m *privatePersonsMap) Equals(other *privatePersonsMap) bool {
+	if m.root == other.root {
 		return true
+	}
+
+	if m.len != other.len {
+		return false
+	}
+
+	equal := true
+	m.Range(func(key Person, value struct{}) bool {
+		otherValue, ok := other.Load(key)
+		equal = ok && privatePersonsMapValueEqual(&value, &otherValue)
+		return equal
 	})
+
+	return equal
 }
 
-func newprivatePersonsMap(items []privatePersonsMapItem) *privatePersonsMap {
-	buckets := newPrivateprivatePersonsMapItemBuckets(len(items))
-	for _, item := range items {
-		buckets.AddItem(item)
+// Hash returns a hash of the items in m. Maps that are equal according to Equals have
+// the same hash. The hash is computed the first time it is needed and is then kept for m
+// and all copies of it, including copies stored in other containers.
+func (m *privatePersonsMap) Hash() uint32 {
+	if h := loadHash(m.hash); h != 0 {
+		return h
 	}
 
-	return &privatePersonsMap{backingVector: emptyprivatePersonsMapItemBucketVector.Append(buckets.buckets...), len: buckets.length}
+	// The items are summed since the order in which they are visited depends on the tree
+	h := uint32(0)
+	m.Range(func(key Person, value struct{}) bool {
+		h += 31*interfaceHash(key) + privatePersonsMapValueHash(&value)
+		return true
+	})
+
+	return cacheHash(m.hash, h)
 }
 
-// Len returns the number of items in m.
-func (m *privatePersonsMap) Len() int {
-	return int(m.len)
+func privatePersonsMapValueHash(x *struct{}) uint32 {
+	return interfaceHash(*x)
 }
 
-// Load returns value identified by key. ok is set to true if key exists in the map, false otherwise.
-func (m *privatePersonsMap) Load(key Person) (value struct{}, ok bool) {
-	bucket := m.backingVector.Get(m.pos(key))
-	if bucket != nil {
-		for _, item := range bucket {
-			if item.Key == key {
-				return item.Value, true
-			}
-		}
-	}
+func privatePersonsMapValueEqual(a, b *struct{}) bool {
+	return *a == *b
+}
 
-	var zeroValue struct{}
-	return zeroValue, false
+// EncodeSnapshot writes m to the snapshot stream of e. Nodes shared with containers
+// previously written to e are written as references to those.
+func (m *privatePersonsMap) EncodeSnapshot(e *SnapshotEncoder) error {
+	return e.encode(snapshotMapTag, func() error {
+		return m.encodeSnapshot(e)
+	})
 }
 
-// Store returns a new privatePersonsMap containing value identified by key.
-func (m *privatePersonsMap) Store(key Person, value struct{}) *privatePersonsMap {
-	// Grow backing vector if load factor is too high
-	if m.Len() >= m.backingVector.Len()*int(upperMapLoadFactor) {
-		buckets := newPrivateprivatePersonsMapItemBuckets(m.Len() + 1)
-		buckets.AddItemsFromMap(m)
-		buckets.AddItem(privatePersonsMapItem{Key: key, Value: value})
-		return &privatePersonsMap{backingVector: emptyprivatePersonsMapItemBucketVector.Append(buckets.buckets...), len: buckets.length}
-	}
-
-	pos := m.pos(key)
-	bucket := m.backingVector.Get(pos)
-	if bucket != nil {
-		for ix, item := range bucket {
-			if item.Key == key {
-				// Overwrite existing item
-				newBucket := make(privateprivatePersonsMapItemBucket, len(bucket))
-				copy(newBucket, bucket)
-				newBucket[ix] = privatePersonsMapItem{Key: key, Value: value}
-				return &privatePersonsMap{backingVector: m.backingVector.Set(pos, newBucket), len: m.len}
+func (m *privatePersonsMap) encodeSnapshot(e *SnapshotEncoder) error {
+	e.writeUvarint(uint64(m.len))
+	return m.root.encodeSnapshot(e)
+}
+
+func (n *privateprivatePersonsMapItemNode) encodeSnapshot(e *SnapshotEncoder) error {
+	return e.writeNode(n, func() error {
+		e.writeUvarint(uint64(n.dataMap))
+		e.writeUvarint(uint64(n.nodeMap))
+		if err := e.writeElements(n.items); err != nil {
+			return err
+		}
+
+		e.writeUvarint(uint64(len(n.children)))
+		for _, child := range n.children {
+			if err := child.encodeSnapshot(e); err != nil {
+				return err
 			}
 		}
 
-		// Add new item to bucket
-		newBucket := make(privateprivatePersonsMapItemBucket, len(bucket), len(bucket)+1)
-		copy(newBucket, bucket)
-		newBucket = append(newBucket, privatePersonsMapItem{Key: key, Value: value})
-		return &privatePersonsMap{backingVector: m.backingVector.Set(pos, newBucket), len: m.len + 1}
+		return nil
+	})
+}
+
+// DecodeSnapshot sets m to the next container in the snapshot stream of d, which must
+// be a map. io.EOF is returned if there are no more containers in the stream. m must
+// not be used by anyone else when this is called, decode into a new zero privatePersonsMap.
+func (m *privatePersonsMap) DecodeSnapshot(d *SnapshotDecoder) error {
+	return d.decode(snapshotMapTag, func() error {
+		return m.decodeSnapshot(d)
+	})
+}
+
+func (m *privatePersonsMap) decodeSnapshot(d *SnapshotDecoder) error {
+	length, err := d.readLength(math.MaxInt32)
+	if err != nil {
+		return err
+	}
+
+	root, err := decodeprivatePersonsMapItemNodeSnapshot(d, 0)
+	if err != nil {
+		return err
 	}
 
-	item := privatePersonsMapItem{Key: key, Value: value}
-	newBucket := privateprivatePersonsMapItemBucket{item}
-	return &privatePersonsMap{backingVector: m.backingVector.Set(pos, newBucket), len: m.len + 1}
+	*m = privatePersonsMap{root: root, len: length, hash: new(uint32)}
+	return nil
 }
 
-// Delete returns a new privatePersonsMap without the element identified by key.
-func (m *privatePersonsMap) Delete(key Person) *privatePersonsMap {
-	pos := m.pos(key)
-	bucket := m.backingVector.Get(pos)
-	if bucket != nil {
-		newBucket := make(privateprivatePersonsMapItemBucket, 0)
-		for _, item := range bucket {
-			if item.Key != key {
-				newBucket = append(newBucket, item)
-			}
+func decodeprivatePersonsMapItemNodeSnapshot(d *SnapshotDecoder, shift uint) (*privateprivatePersonsMapItemNode, error) {
+	node, err := d.readNode(func() (interface{}, error) {
+		dataMap, err := d.readUint32()
+		if err != nil {
+			return nil, err
 		}
 
-		removedItemCount := len(bucket) - len(newBucket)
-		if removedItemCount == 0 {
-			return m
+		nodeMap, err := d.readUint32()
+		if err != nil {
+			return nil, err
 		}
 
-		if len(newBucket) == 0 {
-			newBucket = nil
+		n := &privateprivatePersonsMapItemNode{dataMap: dataMap, nodeMap: nodeMap}
+		if err := d.readElements(&n.items); err != nil {
+			return nil, err
 		}
 
-		newMap := &privatePersonsMap{backingVector: m.backingVector.Set(pos, newBucket), len: m.len - removedItemCount}
-		if newMap.backingVector.Len() > 1 && newMap.Len() < newMap.backingVector.Len()*int(lowerMapLoadFactor) {
-			// Shrink backing vector if needed to avoid occupying excessive space
-			buckets := newPrivateprivatePersonsMapItemBuckets(newMap.Len())
-			buckets.AddItemsFromMap(newMap)
-			return &privatePersonsMap{backingVector: emptyprivatePersonsMapItemBucketVector.Append(buckets.buckets...), len: buckets.length}
+		count, err := d.readLength(nodeSize)
+		if err != nil {
+			return nil, err
 		}
 
-		return newMap
-	}
-
-	return m
-}
-
-// Range calls f repeatedly passing it each key and value as argument until either
-// all elements have been visited or f returns false.
-func (m *privatePersonsMap) Range(f func(Person, struct{}) bool) {
-	m.backingVector.Range(func(bucket privateprivatePersonsMapItemBucket) bool {
-		for _, item := range bucket {
-			if !f(item.Key, item.Value) {
-				return false
+		n.children = make([]*privateprivatePersonsMapItemNode, count)
+		for i := range n.children {
+			if n.children[i], err = decodeprivatePersonsMapItemNodeSnapshot(d, shift+shiftSize); err != nil {
+				return nil, err
 			}
 		}
-		return true
-	})
-}
 
-// ToNativeMap returns a native Go map containing all elements of m.
-func (m *privatePersonsMap) ToNativeMap() map[Person]struct{} {
-	result := make(map[Person]struct{})
-	m.Range(func(key Person, value struct{}) bool {
-		result[key] = value
-		return true
+		collision := shift >= champHashBits && n.dataMap == 0 && n.nodeMap == 0 && count == 0
+		if !collision && (bits.OnesCount32(n.dataMap) != len(n.items) || bits.OnesCount32(n.nodeMap) != count) {
+			return nil, fmt.Errorf("invalid snapshot, map node with %d items and %d children does not match its bitmaps", len(n.items), count)
+		}
+
+		return n, nil
 	})
 
-	return result
+	if err != nil {
+		return nil, err
+	}
+
+	if n, ok := node.(*privateprivatePersonsMapItemNode); ok {
+		return n, nil
+	}
+
+	return nil, invalidSnapshotNode(node)
 }
 
 // Persons is a persistent set
@@ -1168,6 +3070,12 @@ func (s *Persons) Equals(other *Persons) bool {
 	return s.Len() == other.Len() && s.IsSubset(other)
 }
 
+// Hash returns a hash of the elements in s. Sets that are equal according to Equals have
+// the same hash.
+func (s *Persons) Hash() uint32 {
+	return s.backingMap.Hash()
+}
+
 func (s *Persons) difference(other *Persons) []Person {
 	items := make([]Person, 0)
 	s.Range(func(item Person) bool {
@@ -1225,3 +3133,421 @@ func (s *Persons) ToNativeSlice() []Person {
 
 	return items
 }
+
+// MarshalJSON encodes s as a JSON array.
+func (s *Persons) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.ToNativeSlice())
+}
+
+// UnmarshalJSON sets s to the elements of data, a JSON array. s must not be used by
+// anyone else when this is called, it is meant to be used by encoding/json only.
+func (s *Persons) UnmarshalJSON(data []byte) error {
+	t := NewPersons().AsTransient()
+	err := jsonDecodeArray(data, func(dec *json.Decoder) error {
+		var item Person
+		if err := dec.Decode(&item); err != nil {
+			return err
+		}
+
+		t.Add(item)
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	*s = *t.Persistent()
+	return nil
+}
+
+// MarshalBinary returns the binary encoding of s. The items are gob encoded.
+func (s *Persons) MarshalBinary() ([]byte, error) {
+	return s.backingMap.MarshalBinary()
+}
+
+// UnmarshalBinary sets s to the set encoded in data by MarshalBinary. s must not
+// be used by anyone else when this is called, decode into a new zero Persons.
+func (s *Persons) UnmarshalBinary(data []byte) error {
+	m := &privatePersonsMap{}
+	if err := m.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	*s = Persons{backingMap: m}
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder using the same encoding as MarshalBinary.
+func (s *Persons) GobEncode() ([]byte, error) {
+	return s.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder using the same encoding as UnmarshalBinary.
+func (s *Persons) GobDecode(data []byte) error {
+	return s.UnmarshalBinary(data)
+}
+
+// EncodeSnapshot writes s to the snapshot stream of e. Nodes shared with containers
+// previously written to e are written as references to those.
+func (s *Persons) EncodeSnapshot(e *SnapshotEncoder) error {
+	return e.encode(snapshotSetTag, func() error {
+		return s.backingMap.encodeSnapshot(e)
+	})
+}
+
+// DecodeSnapshot sets s to the next container in the snapshot stream of d, which must
+// be a set. io.EOF is returned if there are no more containers in the stream. s must
+// not be used by anyone else when this is called, decode into a new zero Persons.
+func (s *Persons) DecodeSnapshot(d *SnapshotDecoder) error {
+	return d.decode(snapshotSetTag, func() error {
+		m := &privatePersonsMap{}
+		if err := m.decodeSnapshot(d); err != nil {
+			return err
+		}
+
+		*s = Persons{backingMap: m}
+		return nil
+	})
+}
+
+// PersonsTransient is a mutable builder for Persons. Call Persistent
+// to turn it into a Persons, the transient cannot be used after that.
+type PersonsTransient struct {
+	backingMap *privatePersonsMapTransient
+}
+
+// AsTransient returns a transient containing all elements of s. s is left untouched.
+func (s *Persons) AsTransient() *PersonsTransient {
+	return &PersonsTransient{backingMap: s.backingMap.AsTransient()}
+}
+
+// Persistent returns a Persons containing all elements of t. t cannot be used
+// after this call.
+func (t *PersonsTransient) Persistent() *Persons {
+	return &Persons{backingMap: t.backingMap.Persistent()}
+}
+
+// Add adds item to t.
+func (t *PersonsTransient) Add(item Person) {
+	var mapValue struct{}
+	t.backingMap.Store(item, mapValue)
+}
+
+// Delete removes item from t.
+func (t *PersonsTransient) Delete(item Person) {
+	t.backingMap.Delete(item)
+}
+
+// Contains returns true if item is present in t, false otherwise.
+func (t *PersonsTransient) Contains(item Person) bool {
+	_, ok := t.backingMap.Load(item)
+	return ok
+}
+
+// Len returns the number of elements in t.
+func (t *PersonsTransient) Len() int {
+	return t.backingMap.Len()
+}
+
+/////////////
+/// Deque ///
+/////////////
+
+// privatePersonQueueList is a, possibly lazily evaluated, list of elements. A lazy
+// list is evaluated at most once, the first time it is forced, after which the result
+// is shared by all deques referring to it. A nil list is the empty list.
+type privatePersonQueueList struct {
+	item Person
+	next *privatePersonQueueList
+
+	// The fields above, and empty, are only valid once a lazy list has been forced
+	lazy    bool
+	once    sync.Once
+	compute func() (Person, *privatePersonQueueList, bool)
+	empty   bool
+}
+
+// force evaluates l, if needed, and returns false if l is empty.
+func (l *privatePersonQueueList) force() bool {
+	if l == nil {
+		return false
+	}
+
+	if l.lazy {
+		l.once.Do(l.evaluate)
+	}
+
+	return !l.empty
+}
+
+func (l *privatePersonQueueList) evaluate() {
+	var ok bool
+	l.item, l.next, ok = l.compute()
+	l.empty, l.compute = !ok, nil
+}
+
+func privatePersonQueueCons(item Person, l *privatePersonQueueList) *privatePersonQueueList {
+	return &privatePersonQueueList{item: item, next: l}
+}
+
+func privatePersonQueueLazy(compute func() (Person, *privatePersonQueueList, bool)) *privatePersonQueueList {
+	return &privatePersonQueueList{lazy: true, compute: compute}
+}
+
+// privatePersonQueueTake returns the first n elements of l, n must not be larger
+// than the length of l.
+func privatePersonQueueTake(n uint, l *privatePersonQueueList) *privatePersonQueueList {
+	if n == 0 {
+		return nil
+	}
+
+	return privatePersonQueueLazy(func() (Person, *privatePersonQueueList, bool) {
+		l.force()
+		return l.item, privatePersonQueueTake(n-1, l.next), true
+	})
+}
+
+// privatePersonQueueAppend returns the elements of l1 followed by those of l2.
+func privatePersonQueueAppend(l1, l2 *privatePersonQueueList) *privatePersonQueueList {
+	if l1 == nil {
+		return l2
+	}
+
+	return privatePersonQueueLazy(func() (Person, *privatePersonQueueList, bool) {
+		if !l1.force() {
+			if !l2.force() {
+				var item Person
+				return item, nil, false
+			}
+
+			return l2.item, l2.next, true
+		}
+
+		return l1.item, privatePersonQueueAppend(l1.next, l2), true
+	})
+}
+
+// privatePersonQueueDropReverse returns all but the first n elements of l in reverse
+// order. Unlike the other list functions the whole list is computed once forced.
+func privatePersonQueueDropReverse(n uint, l *privatePersonQueueList) *privatePersonQueueList {
+	return privatePersonQueueLazy(func() (Person, *privatePersonQueueList, bool) {
+		for ; n > 0; n-- {
+			l.force()
+			l = l.next
+		}
+
+		var result *privatePersonQueueList
+		for ; l.force(); l = l.next {
+			result = privatePersonQueueCons(l.item, result)
+		}
+
+		if result == nil {
+			var item Person
+			return item, nil, false
+		}
+
+		return result.item, result.next, true
+	})
+}
+
+// A PersonQueue is a persistent/immutable double ended queue. All operations, except
+// for Range and ToNativeSlice, run in amortized constant time, also when old versions of
+// a deque are reused.
+//
+// The deque is a banker's deque as described by Okasaki. The elements are kept in a front
+// list and a reversed back list. When one of them grows too large compared to the other,
+// half of the elements are moved to the other list. The move is done lazily, piece by
+// piece, as elements are popped, which spreads the cost of it over those operations.
+type PersonQueue struct {
+	front, back       *privatePersonQueueList
+	frontLen, backLen uint
+}
+
+var emptyPersonQueue = &PersonQueue{}
+
+// NewPersonQueue returns a new PersonQueue containing the items provided in items,
+// with the first item at the front.
+func NewPersonQueue(items ...Person) *PersonQueue {
+	half := len(items) / 2
+	result := &PersonQueue{frontLen: uint(half), backLen: uint(len(items) - half)}
+	for i := half - 1; i >= 0; i-- {
+		result.front = privatePersonQueueCons(items[i], result.front)
+	}
+
+	for _, item := range items[half:] {
+		result.back = privatePersonQueueCons(item, result.back)
+	}
+
+	return result
+}
+
+// newPersonQueue returns a deque of front and back with the elements balanced
+// between the two lists.
+func newPersonQueue(front *privatePersonQueueList, frontLen uint, back *privatePersonQueueList, backLen uint) *PersonQueue {
+	size := frontLen + backLen
+	if frontLen > dequeBalance*backLen+1 {
+		half := size / 2
+		return &PersonQueue{
+			front:    privatePersonQueueTake(half, front),
+			frontLen: half,
+			back:     privatePersonQueueAppend(back, privatePersonQueueDropReverse(half, front)),
+			backLen:  size - half}
+	}
+
+	if backLen > dequeBalance*frontLen+1 {
+		half := size / 2
+		return &PersonQueue{
+			front:    privatePersonQueueAppend(front, privatePersonQueueDropReverse(half, back)),
+			frontLen: size - half,
+			back:     privatePersonQueueTake(half, back),
+			backLen:  half}
+	}
+
+	return &PersonQueue{front: front, frontLen: frontLen, back: back, backLen: backLen}
+}
+
+// Len returns the number of elements in d.
+func (d *PersonQueue) Len() int {
+	return int(d.frontLen + d.backLen)
+}
+
+// PushFront returns a new deque with item added to the front.
+func (d *PersonQueue) PushFront(item Person) *PersonQueue {
+	return newPersonQueue(privatePersonQueueCons(item, d.front), d.frontLen+1, d.back, d.backLen)
+}
+
+// PushBack returns a new deque with item added to the back.
+func (d *PersonQueue) PushBack(item Person) *PersonQueue {
+	return newPersonQueue(d.front, d.frontLen, privatePersonQueueCons(item, d.back), d.backLen+1)
+}
+
+// PopFront returns a new deque with the front element removed, and the removed element.
+func (d *PersonQueue) PopFront() (*PersonQueue, Person) {
+	if d.frontLen == 0 {
+		if d.backLen == 0 {
+			panic("Pop on empty deque")
+		}
+
+		// The balance between the lists guarantees that this is the only element
+		d.back.force()
+		return emptyPersonQueue, d.back.item
+	}
+
+	d.front.force()
+	return newPersonQueue(d.front.next, d.frontLen-1, d.back, d.backLen), d.front.item
+}
+
+// PopBack returns a new deque with the back element removed, and the removed element.
+func (d *PersonQueue) PopBack() (*PersonQueue, Person) {
+	if d.backLen == 0 {
+		if d.frontLen == 0 {
+			panic("Pop on empty deque")
+		}
+
+		// The balance between the lists guarantees that this is the only element
+		d.front.force()
+		return emptyPersonQueue, d.front.item
+	}
+
+	d.back.force()
+	return newPersonQueue(d.front, d.frontLen, d.back.next, d.backLen-1), d.back.item
+}
+
+// PeekFront returns the front element of d. ok is false if d is empty.
+func (d *PersonQueue) PeekFront() (item Person, ok bool) {
+	l := d.front
+	if d.frontLen == 0 {
+		l = d.back
+	}
+
+	if !l.force() {
+		return item, false
+	}
+
+	return l.item, true
+}
+
+// PeekBack returns the back element of d. ok is false if d is empty.
+func (d *PersonQueue) PeekBack() (item Person, ok bool) {
+	l := d.back
+	if d.backLen == 0 {
+		l = d.front
+	}
+
+	if !l.force() {
+		return item, false
+	}
+
+	return l.item, true
+}
+
+// Range calls f repeatedly passing it each element in d, from front to back, as argument
+// until either all elements have been visited or f returns false.
+func (d *PersonQueue) Range(f func(Person) bool) {
+	for l := d.front; l.force(); l = l.next {
+		if !f(l.item) {
+			return
+		}
+	}
+
+	back := d.backSlice()
+	for i := len(back) - 1; i >= 0; i-- {
+		if !f(back[i]) {
+			return
+		}
+	}
+}
+
+// backSlice returns the elements of the back list, from back to front.
+func (d *PersonQueue) backSlice() []Person {
+	result := make([]Person, 0, d.backLen)
+	for l := d.back; l.force(); l = l.next {
+		result = append(result, l.item)
+	}
+
+	return result
+}
+
+// ToNativeSlice returns a Go slice containing all elements of d, from front to back.
+func (d *PersonQueue) ToNativeSlice() []Person {
+	result := make([]Person, d.Len())
+	i := 0
+	for l := d.front; l.force(); l = l.next {
+		result[i] = l.item
+		i++
+	}
+
+	for l, j := d.back, len(result)-1; l.force(); l, j = l.next, j-1 {
+		result[j] = l.item
+	}
+
+	return result
+}
+
+// MarshalJSON encodes d as a JSON array, from front to back.
+func (d *PersonQueue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.ToNativeSlice())
+}
+
+// UnmarshalJSON sets d to the elements of data, a JSON array. d must not be used by
+// anyone else when this is called, it is meant to be used by encoding/json only.
+func (d *PersonQueue) UnmarshalJSON(data []byte) error {
+	result := emptyPersonQueue
+	err := jsonDecodeArray(data, func(dec *json.Decoder) error {
+		var item Person
+		if err := dec.Decode(&item); err != nil {
+			return err
+		}
+
+		result = result.PushBack(item)
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	*d = *result
+	return nil
+}
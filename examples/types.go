@@ -1,15 +1,15 @@
 // Package examples contains a couple of examples of generated peds collections
 package examples
 
+//go:generate peds -scan=. -file=collections.go -vectors=IntVector<int>
+
 // Person is a custom example type that represents a person
+//
+//peds:map PersonBySsn key=string
+//peds:set Persons
+//peds:deque PersonQueue
 type Person struct {
 	name    string
 	ssn     string
 	address string
 }
-
-//go:generate peds -maps="PersonBySsn<string,Person>" -sets="Persons<Person>" -vectors="IntVector<int>" -file=collections.go -pkg=examples
-
-// Required for go generate it seems
-func f() {
-}
\ No newline at end of file
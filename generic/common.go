@@ -0,0 +1,205 @@
+// Package generic contains persistent data structures implemented using
+// Go type parameters. They behave the same as the code generated by peds.
+package generic
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"math/bits"
+	"unsafe"
+)
+
+const shiftSize = 5
+const nodeSize = 32
+const shiftBitMask = 0x1F
+
+type commonNode interface{}
+
+var emptyCommonNode commonNode = []commonNode{}
+
+func uintMin(a, b uint) uint {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+func newPath(shift uint, node commonNode) commonNode {
+	if shift == 0 {
+		return node
+	}
+
+	return newPath(shift-shiftSize, commonNode([]commonNode{node}))
+}
+
+func assertSliceOk(start, stop, len int) {
+	if start < 0 {
+		panic(fmt.Sprintf("Invalid slice index %d (index must be non-negative)", start))
+	}
+
+	if start > stop {
+		panic(fmt.Sprintf("Invalid slice index: %d > %d", start, stop))
+	}
+
+	if stop > len {
+		panic(fmt.Sprintf("Slice bounds out of range, start=%d, stop=%d, len=%d", start, stop, len))
+	}
+}
+
+func assertTransientEditable(editable bool) {
+	if !editable {
+		panic("Transient used after call to Persistent")
+	}
+}
+
+// transientOwner identifies the nodes that are owned, and may be edited in place,
+// by a transient.
+type transientOwner struct {
+	_ byte
+}
+
+// vectorNodeOwner is kept in a slot beyond the length of the vector trie nodes owned by
+// a transient. Leaves have no room for an owner of their own, the leaves of a node that
+// are owned by the transient are instead marked in leaves.
+type vectorNodeOwner struct {
+	transient *transientOwner
+	leaves    uint32
+}
+
+// Number of hash bits used to place items in a CHAMP tree. Items with identical
+// hashes are stored in collision nodes below the last level.
+const champHashBits = 32
+
+func champBitpos(hash uint32, shift uint) uint32 {
+	return 1 << ((hash >> shift) & shiftBitMask)
+}
+
+func champIndex(bitmap, bit uint32) int {
+	return bits.OnesCount32(bitmap & (bit - 1))
+}
+
+//////////////////////////
+//// Hash functions //////
+//////////////////////////
+
+func hash(x []byte) uint32 {
+	return crc32.ChecksumIEEE(x)
+}
+
+//go:noescape
+//go:linkname nilinterhash runtime.nilinterhash
+func nilinterhash(p unsafe.Pointer, h uintptr) uintptr
+
+func interfaceHash(x interface{}) uint32 {
+	return uint32(nilinterhash(unsafe.Pointer(&x), 0))
+}
+
+func byteHash(x byte) uint32 {
+	return hash([]byte{x})
+}
+
+func uint8Hash(x uint8) uint32 {
+	return byteHash(byte(x))
+}
+
+func int8Hash(x int8) uint32 {
+	return uint8Hash(uint8(x))
+}
+
+func uint16Hash(x uint16) uint32 {
+	bX := make([]byte, 2)
+	binary.LittleEndian.PutUint16(bX, x)
+	return hash(bX)
+}
+
+func int16Hash(x int16) uint32 {
+	return uint16Hash(uint16(x))
+}
+
+func uint32Hash(x uint32) uint32 {
+	bX := make([]byte, 4)
+	binary.LittleEndian.PutUint32(bX, x)
+	return hash(bX)
+}
+
+func int32Hash(x int32) uint32 {
+	return uint32Hash(uint32(x))
+}
+
+func uint64Hash(x uint64) uint32 {
+	bX := make([]byte, 8)
+	binary.LittleEndian.PutUint64(bX, x)
+	return hash(bX)
+}
+
+func int64Hash(x int64) uint32 {
+	return uint64Hash(uint64(x))
+}
+
+func intHash(x int) uint32 {
+	return int64Hash(int64(x))
+}
+
+func uintHash(x uint) uint32 {
+	return uint64Hash(uint64(x))
+}
+
+func boolHash(x bool) uint32 {
+	if x {
+		return 1
+	}
+
+	return 0
+}
+
+func stringHash(x string) uint32 {
+	return hash([]byte(x))
+}
+
+func float64Hash(x float64) uint32 {
+	return uint64Hash(math.Float64bits(x))
+}
+
+func float32Hash(x float32) uint32 {
+	return uint32Hash(math.Float32bits(x))
+}
+
+// hashKey returns the hash of key. Keys of the basic types are hashed the same
+// way as in the generated code, all other keys are hashed using the runtime.
+func hashKey[K comparable](key K) uint32 {
+	switch k := any(key).(type) {
+	case bool:
+		return boolHash(k)
+	case int8:
+		return int8Hash(k)
+	case uint8:
+		return uint8Hash(k)
+	case int16:
+		return int16Hash(k)
+	case uint16:
+		return uint16Hash(k)
+	case int32:
+		return int32Hash(k)
+	case uint32:
+		return uint32Hash(k)
+	case int64:
+		return int64Hash(k)
+	case uint64:
+		return uint64Hash(k)
+	case int:
+		return intHash(k)
+	case uint:
+		return uintHash(k)
+	case float32:
+		return float32Hash(k)
+	case float64:
+		return float64Hash(k)
+	case string:
+		return stringHash(k)
+	}
+
+	return interfaceHash(key)
+}
@@ -0,0 +1,96 @@
+//go:build go1.23
+
+package generic
+
+// The iterator methods are only available in Go 1.23 and later, where the iter
+// package and range over functions were introduced.
+
+import "iter"
+
+// All returns an iterator over the indexes and elements of v, in order.
+func (v *Vector[T]) All() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		i := 0
+		v.Range(func(item T) bool {
+			ok := yield(i, item)
+			i++
+			return ok
+		})
+	}
+}
+
+// Backward returns an iterator over the indexes and elements of v, in reverse order.
+func (v *Vector[T]) Backward() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		var currentNode []T
+		for i := int(v.len) - 1; i >= 0; i-- {
+			if i&shiftBitMask == shiftBitMask || i == int(v.len)-1 {
+				currentNode = v.sliceFor(uint(i))
+			}
+
+			if !yield(i, currentNode[i&shiftBitMask]) {
+				return
+			}
+		}
+	}
+}
+
+// All returns an iterator over the indexes and elements of s, in order.
+func (s *VectorSlice[T]) All() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		i := 0
+		s.Range(func(item T) bool {
+			ok := yield(i, item)
+			i++
+			return ok
+		})
+	}
+}
+
+// Backward returns an iterator over the indexes and elements of s, in reverse order.
+func (s *VectorSlice[T]) Backward() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		var currentNode []T
+		for i := s.stop - 1; i >= s.start; i-- {
+			if i&shiftBitMask == shiftBitMask || i == s.stop-1 {
+				currentNode = s.vector.sliceFor(uint(i))
+			}
+
+			if !yield(i-s.start, currentNode[i&shiftBitMask]) {
+				return
+			}
+		}
+	}
+}
+
+// All returns an iterator over the keys and values in m. The order is unspecified.
+func (m *Map[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		m.Range(yield)
+	}
+}
+
+// Keys returns an iterator over the keys in m. The order is unspecified.
+func (m *Map[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		m.Range(func(key K, _ V) bool {
+			return yield(key)
+		})
+	}
+}
+
+// Values returns an iterator over the values in m. The order is unspecified.
+func (m *Map[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		m.Range(func(_ K, value V) bool {
+			return yield(value)
+		})
+	}
+}
+
+// All returns an iterator over the elements in s. The order is unspecified.
+func (s *Set[K]) All() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		s.Range(yield)
+	}
+}
@@ -0,0 +1,89 @@
+//go:build go1.23
+
+package generic
+
+import (
+	"slices"
+	"sort"
+	"testing"
+)
+
+func TestVectorAllAndBackward(t *testing.T) {
+	for _, l := range []int{0, 1, 31, 32, 33, 1000, 1056} {
+		v := NewVector(inputSlice(0, l)...)
+		count := 0
+		for i, x := range v.All() {
+			assertEqual(t, count, i)
+			assertEqual(t, i, x)
+			count++
+		}
+
+		assertEqual(t, l, count)
+
+		expected := l - 1
+		for i, x := range v.Backward() {
+			assertEqual(t, expected, i)
+			assertEqual(t, i, x)
+			expected--
+		}
+
+		assertEqual(t, -1, expected)
+	}
+}
+
+func TestVectorAllStopsOnBreak(t *testing.T) {
+	v := NewVector(inputSlice(0, 100)...)
+	count := 0
+	for i := range v.Backward() {
+		if i == 90 {
+			break
+		}
+
+		count++
+	}
+
+	assertEqual(t, 9, count)
+}
+
+func TestVectorSliceAllAndBackward(t *testing.T) {
+	s := NewVector(inputSlice(0, 100)...).Slice(30, 70)
+	forward := make([]int, 0)
+	for i, x := range s.All() {
+		assertEqual(t, len(forward), i)
+		forward = append(forward, x)
+	}
+
+	assertEqualBool(t, true, slices.Equal(inputSlice(30, 40), forward))
+
+	backward := make([]int, 0)
+	for i, x := range s.Backward() {
+		assertEqual(t, x-30, i)
+		backward = append(backward, x)
+	}
+
+	slices.Reverse(backward)
+	assertEqualBool(t, true, slices.Equal(forward, backward))
+}
+
+func TestMapAllKeysAndValues(t *testing.T) {
+	m := NewMapFromNativeMap(map[string]int{"a": 1, "b": 2, "c": 3})
+	native := make(map[string]int)
+	for k, v := range m.All() {
+		native[k] = v
+	}
+
+	assertEqualBool(t, true, len(native) == 3 && native["b"] == 2)
+
+	keys := slices.Sorted(m.Keys())
+	assertEqualBool(t, true, slices.Equal([]string{"a", "b", "c"}, keys))
+
+	values := slices.Sorted(m.Values())
+	assertEqualBool(t, true, slices.Equal([]int{1, 2, 3}, values))
+}
+
+func TestSetAll(t *testing.T) {
+	s := NewSet[Foo](3, 1, 2)
+	items := slices.Collect(s.All())
+	sort.Slice(items, func(i, j int) bool { return items[i] < items[j] })
+	assertEqualBool(t, true, slices.Equal([]Foo{1, 2, 3}, items))
+}
@@ -0,0 +1,392 @@
+package generic
+
+///////////
+/// Map ///
+///////////
+
+// MapItem is a key - value pair stored in a Map.
+type MapItem[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// mapNode is a node in a compressed hash-array mapped prefix tree (CHAMP).
+// Items stored directly in the node are kept in items and their positions are marked in
+// dataMap. Sub nodes are kept in children and their positions are marked in nodeMap. Nodes
+// below the last level of the hash are collision nodes that only contain items.
+type mapNode[K comparable, V any] struct {
+	dataMap  uint32
+	nodeMap  uint32
+	items    []MapItem[K, V]
+	children []*mapNode[K, V]
+	owner    *transientOwner
+}
+
+// editable returns n if it is owned by owner, otherwise a copy of n owned by owner.
+// owner is nil for persistent updates in which case a copy is always returned.
+func (n *mapNode[K, V]) editable(owner *transientOwner) *mapNode[K, V] {
+	if owner != nil && n.owner == owner {
+		return n
+	}
+
+	items := make([]MapItem[K, V], len(n.items), len(n.items)+1)
+	copy(items, n.items)
+	children := make([]*mapNode[K, V], len(n.children), len(n.children)+1)
+	copy(children, n.children)
+	return &mapNode[K, V]{dataMap: n.dataMap, nodeMap: n.nodeMap, items: items, children: children, owner: owner}
+}
+
+func (n *mapNode[K, V]) isSingleItem() bool {
+	return len(n.items) == 1 && len(n.children) == 0
+}
+
+func (n *mapNode[K, V]) insertItem(ix int, item MapItem[K, V]) {
+	var zeroItem MapItem[K, V]
+	n.items = append(n.items, zeroItem)
+	copy(n.items[ix+1:], n.items[ix:])
+	n.items[ix] = item
+}
+
+func (n *mapNode[K, V]) removeItem(ix int) {
+	var zeroItem MapItem[K, V]
+	copy(n.items[ix:], n.items[ix+1:])
+	n.items[len(n.items)-1] = zeroItem
+	n.items = n.items[:len(n.items)-1]
+}
+
+func (n *mapNode[K, V]) insertChild(ix int, child *mapNode[K, V]) {
+	n.children = append(n.children, nil)
+	copy(n.children[ix+1:], n.children[ix:])
+	n.children[ix] = child
+}
+
+func (n *mapNode[K, V]) removeChild(ix int) {
+	copy(n.children[ix:], n.children[ix+1:])
+	n.children[len(n.children)-1] = nil
+	n.children = n.children[:len(n.children)-1]
+}
+
+func (n *mapNode[K, V]) load(key K, hash uint32) (value V, ok bool) {
+	for shift := uint(0); ; shift += shiftSize {
+		if shift >= champHashBits {
+			for _, item := range n.items {
+				if item.Key == key {
+					return item.Value, true
+				}
+			}
+
+			break
+		}
+
+		bit := champBitpos(hash, shift)
+		if n.dataMap&bit != 0 {
+			item := n.items[champIndex(n.dataMap, bit)]
+			if item.Key == key {
+				return item.Value, true
+			}
+
+			break
+		}
+
+		if n.nodeMap&bit == 0 {
+			break
+		}
+
+		n = n.children[champIndex(n.nodeMap, bit)]
+	}
+
+	var zeroValue V
+	return zeroValue, false
+}
+
+// store returns a node with item stored in it and true if the item was added rather
+// than replacing an existing item.
+func (n *mapNode[K, V]) store(item MapItem[K, V], hash uint32, shift uint, owner *transientOwner) (*mapNode[K, V], bool) {
+	if shift >= champHashBits {
+		// Collision node
+		for ix, existing := range n.items {
+			if existing.Key == item.Key {
+				newNode := n.editable(owner)
+				newNode.items[ix] = item
+				return newNode, false
+			}
+		}
+
+		newNode := n.editable(owner)
+		newNode.items = append(newNode.items, item)
+		return newNode, true
+	}
+
+	bit := champBitpos(hash, shift)
+	if n.dataMap&bit != 0 {
+		ix := champIndex(n.dataMap, bit)
+		existing := n.items[ix]
+		newNode := n.editable(owner)
+		if existing.Key == item.Key {
+			newNode.items[ix] = item
+			return newNode, false
+		}
+
+		// Push both items down into a new sub node
+		child := newMapNode(existing, hashKey(existing.Key), item, hash, shift+shiftSize, owner)
+		newNode.removeItem(ix)
+		newNode.dataMap &^= bit
+		newNode.nodeMap |= bit
+		newNode.insertChild(champIndex(newNode.nodeMap, bit), child)
+		return newNode, true
+	}
+
+	if n.nodeMap&bit != 0 {
+		ix := champIndex(n.nodeMap, bit)
+		child, added := n.children[ix].store(item, hash, shift+shiftSize, owner)
+		newNode := n
+		if child != n.children[ix] {
+			newNode = n.editable(owner)
+			newNode.children[ix] = child
+		}
+
+		return newNode, added
+	}
+
+	newNode := n.editable(owner)
+	newNode.dataMap |= bit
+	newNode.insertItem(champIndex(newNode.dataMap, bit), item)
+	return newNode, true
+}
+
+func newMapNode[K comparable, V any](item1 MapItem[K, V], hash1 uint32, item2 MapItem[K, V], hash2 uint32, shift uint, owner *transientOwner) *mapNode[K, V] {
+	if shift >= champHashBits {
+		return &mapNode[K, V]{items: []MapItem[K, V]{item1, item2}, owner: owner}
+	}
+
+	bit1, bit2 := champBitpos(hash1, shift), champBitpos(hash2, shift)
+	if bit1 == bit2 {
+		child := newMapNode(item1, hash1, item2, hash2, shift+shiftSize, owner)
+		return &mapNode[K, V]{nodeMap: bit1, children: []*mapNode[K, V]{child}, owner: owner}
+	}
+
+	items := []MapItem[K, V]{item1, item2}
+	if bit2 < bit1 {
+		items[0], items[1] = item2, item1
+	}
+
+	return &mapNode[K, V]{dataMap: bit1 | bit2, items: items, owner: owner}
+}
+
+// delete returns a node without key and true if the key was found. A node that only
+// contains a single item is returned as is to let the parent inline the item.
+func (n *mapNode[K, V]) delete(key K, hash uint32, shift uint, owner *transientOwner) (*mapNode[K, V], bool) {
+	if shift >= champHashBits {
+		// Collision node
+		for ix, existing := range n.items {
+			if existing.Key == key {
+				newNode := n.editable(owner)
+				newNode.removeItem(ix)
+				return newNode, true
+			}
+		}
+
+		return n, false
+	}
+
+	bit := champBitpos(hash, shift)
+	if n.dataMap&bit != 0 {
+		ix := champIndex(n.dataMap, bit)
+		if n.items[ix].Key != key {
+			return n, false
+		}
+
+		newNode := n.editable(owner)
+		newNode.removeItem(ix)
+		newNode.dataMap &^= bit
+		return newNode, true
+	}
+
+	if n.nodeMap&bit != 0 {
+		ix := champIndex(n.nodeMap, bit)
+		child, deleted := n.children[ix].delete(key, hash, shift+shiftSize, owner)
+		if !deleted {
+			return n, false
+		}
+
+		if child.isSingleItem() {
+			if shift > 0 && len(n.items) == 0 && len(n.children) == 1 {
+				// Let the parent inline the remaining item
+				return child, true
+			}
+
+			// Inline the remaining item of the child in this node
+			newNode := n.editable(owner)
+			newNode.removeChild(ix)
+			newNode.nodeMap &^= bit
+			newNode.dataMap |= bit
+			newNode.insertItem(champIndex(newNode.dataMap, bit), child.items[0])
+			return newNode, true
+		}
+
+		newNode := n.editable(owner)
+		newNode.children[ix] = child
+		return newNode, true
+	}
+
+	return n, false
+}
+
+func (n *mapNode[K, V]) rangeItems(f func(K, V) bool) bool {
+	for _, item := range n.items {
+		if !f(item.Key, item.Value) {
+			return false
+		}
+	}
+
+	for _, child := range n.children {
+		if !child.rangeItems(f) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Map is a persistent key - value map
+type Map[K comparable, V any] struct {
+	root *mapNode[K, V]
+	len  int
+}
+
+func emptyMap[K comparable, V any]() *Map[K, V] {
+	return &Map[K, V]{root: &mapNode[K, V]{}}
+}
+
+func newMap[K comparable, V any](items []MapItem[K, V]) *Map[K, V] {
+	t := emptyMap[K, V]().AsTransient()
+	for _, item := range items {
+		t.Store(item.Key, item.Value)
+	}
+
+	return t.Persistent()
+}
+
+// Len returns the number of items in m.
+func (m *Map[K, V]) Len() int {
+	return int(m.len)
+}
+
+// Load returns value identified by key. ok is set to true if key exists in the map, false otherwise.
+func (m *Map[K, V]) Load(key K) (value V, ok bool) {
+	return m.root.load(key, hashKey(key))
+}
+
+// Store returns a new Map containing value identified by key.
+func (m *Map[K, V]) Store(key K, value V) *Map[K, V] {
+	root, added := m.root.store(MapItem[K, V]{Key: key, Value: value}, hashKey(key), 0, nil)
+	if added {
+		return &Map[K, V]{root: root, len: m.len + 1}
+	}
+
+	return &Map[K, V]{root: root, len: m.len}
+}
+
+// Delete returns a new Map without the element identified by key.
+func (m *Map[K, V]) Delete(key K) *Map[K, V] {
+	root, deleted := m.root.delete(key, hashKey(key), 0, nil)
+	if !deleted {
+		return m
+	}
+
+	return &Map[K, V]{root: root, len: m.len - 1}
+}
+
+// Range calls f repeatedly passing it each key and value as argument until either
+// all elements have been visited or f returns false.
+func (m *Map[K, V]) Range(f func(K, V) bool) {
+	m.root.rangeItems(f)
+}
+
+// ToNativeMap returns a native Go map containing all elements of m.
+func (m *Map[K, V]) ToNativeMap() map[K]V {
+	result := make(map[K]V)
+	m.Range(func(key K, value V) bool {
+		result[key] = value
+		return true
+	})
+
+	return result
+}
+
+/////////////////
+/// Transient ///
+/////////////////
+
+// MapTransient is a mutable builder for Map. Nodes created by the
+// transient are owned by it and edited in place rather than copied. Call Persistent
+// to turn it into a Map, the transient cannot be used after that.
+type MapTransient[K comparable, V any] struct {
+	root  *mapNode[K, V]
+	len   int
+	owner *transientOwner
+}
+
+// AsTransient returns a transient containing all items of m. m is left untouched.
+func (m *Map[K, V]) AsTransient() *MapTransient[K, V] {
+	return &MapTransient[K, V]{root: m.root, len: m.len, owner: &transientOwner{}}
+}
+
+// Persistent returns a Map containing all items of t. t cannot be used
+// after this call.
+func (t *MapTransient[K, V]) Persistent() *Map[K, V] {
+	assertTransientEditable(t.owner != nil)
+	t.owner = nil
+	return &Map[K, V]{root: t.root, len: t.len}
+}
+
+// Len returns the number of items in t.
+func (t *MapTransient[K, V]) Len() int {
+	assertTransientEditable(t.owner != nil)
+	return t.len
+}
+
+// Load returns value identified by key. ok is set to true if key exists in the map, false otherwise.
+func (t *MapTransient[K, V]) Load(key K) (value V, ok bool) {
+	assertTransientEditable(t.owner != nil)
+	return t.root.load(key, hashKey(key))
+}
+
+// Store sets the value identified by key to value.
+func (t *MapTransient[K, V]) Store(key K, value V) {
+	assertTransientEditable(t.owner != nil)
+	root, added := t.root.store(MapItem[K, V]{Key: key, Value: value}, hashKey(key), 0, t.owner)
+	t.root = root
+	if added {
+		t.len++
+	}
+}
+
+// Delete removes the item identified by key.
+func (t *MapTransient[K, V]) Delete(key K) {
+	assertTransientEditable(t.owner != nil)
+	root, deleted := t.root.delete(key, hashKey(key), 0, t.owner)
+	t.root = root
+	if deleted {
+		t.len--
+	}
+}
+
+////////////////////
+/// Constructors ///
+////////////////////
+
+// NewMap returns a new Map containing all items in items.
+func NewMap[K comparable, V any](items ...MapItem[K, V]) *Map[K, V] {
+	return newMap(items)
+}
+
+// NewMapFromNativeMap returns a new Map containing all items in m.
+func NewMapFromNativeMap[K comparable, V any](m map[K]V) *Map[K, V] {
+	t := emptyMap[K, V]().AsTransient()
+	for key, value := range m {
+		t.Store(key, value)
+	}
+
+	return t.Persistent()
+}
@@ -0,0 +1,341 @@
+package generic
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math/rand"
+	"testing"
+)
+
+func TestLenOfNewMap(t *testing.T) {
+	m := NewMap[string, int]()
+	assertEqual(t, 0, m.Len())
+
+	m2 := NewMap(MapItem[string, int]{Key: "a", Value: 1})
+	assertEqual(t, 1, m2.Len())
+
+	m3 := NewMap(MapItem[string, int]{Key: "a", Value: 1}, MapItem[string, int]{Key: "b", Value: 2})
+	assertEqual(t, 2, m3.Len())
+}
+
+func TestLoadAndStore(t *testing.T) {
+	m := NewMap[string, int]()
+
+	m2 := m.Store("a", 1)
+	assertEqual(t, 0, m.Len())
+	assertEqual(t, 1, m2.Len())
+
+	v, ok := m.Load("a")
+	assertEqual(t, 0, v)
+	assertEqualBool(t, false, ok)
+
+	v, ok = m2.Load("a")
+	assertEqual(t, 1, v)
+	assertEqualBool(t, true, ok)
+}
+
+func TestLoadAndStoreIntKey(t *testing.T) {
+	m := NewMap[int, string]()
+
+	m2 := m.Store(1, "")
+	v, _ := m.Load(2)
+	assertEqualString(t, "", v)
+
+	v, _ = m2.Load(1)
+	assertEqualString(t, "", v)
+}
+
+func TestLoadAndDeleteExistingItem(t *testing.T) {
+	m := NewMap[string, int]()
+	m2 := m.Store("a", 1)
+	m3 := m.Delete("a")
+
+	assertEqual(t, 0, m3.Len())
+	assertEqual(t, 1, m2.Len())
+
+	v, ok := m2.Load("a")
+	assertEqualBool(t, true, ok)
+	assertEqual(t, 1, v)
+
+	v, ok = m3.Load("a")
+	assertEqualBool(t, false, ok)
+	assertEqual(t, 0, v)
+}
+
+func TestLoadAndDeleteNonExistingItem(t *testing.T) {
+	m := NewMap[string, int]()
+	m2 := m.Store("a", 1)
+	m3 := m2.Delete("b")
+
+	assertEqual(t, 1, m3.Len())
+	assertEqual(t, 1, m2.Len())
+
+	v, ok := m2.Load("a")
+	assertEqualBool(t, true, ok)
+	assertEqual(t, 1, v)
+
+	if m2 != m3 {
+		t.Errorf("m2 and m3 are not the same object: %p != %p", m2, m3)
+	}
+}
+
+func TestRangeAllItems(t *testing.T) {
+	m := NewMap(MapItem[string, int]{Key: "a", Value: 1}, MapItem[string, int]{Key: "b", Value: 2}, MapItem[string, int]{Key: "c", Value: 3})
+	sum := 0
+	m.Range(func(key string, value int) bool {
+		sum += value
+		return true
+	})
+	assertEqual(t, 6, sum)
+}
+
+func TestRangeStopOnKey(t *testing.T) {
+	m := NewMap(MapItem[string, int]{Key: "a", Value: 1}, MapItem[string, int]{Key: "b", Value: 2}, MapItem[string, int]{Key: "c", Value: 3})
+	count := 0
+	m.Range(func(key string, value int) bool {
+		if key == "c" || key == "b" {
+			return false
+		}
+
+		count++
+		return true
+	})
+
+	if count > 1 {
+		t.Errorf("Did not expect count to be more than 1")
+	}
+}
+
+func TestLargeInsertLookupDelete(t *testing.T) {
+	size := 50000
+	m := NewMap[string, int]()
+	for j := 0; j < size; j++ {
+		m = m.Store(fmt.Sprintf("%d", j), j)
+	}
+
+	for j := 0; j < size; j++ {
+		v, ok := m.Load(fmt.Sprintf("%d", j))
+		assertEqualBool(t, true, ok)
+		assertEqual(t, v, j)
+	}
+
+	for j := 0; j < size; j++ {
+		key := fmt.Sprintf("%d", j)
+		m = m.Delete(key)
+		assertEqual(t, size-j-1, m.Len())
+		_, ok := m.Load(key)
+		assertEqualBool(t, false, ok)
+	}
+}
+
+func TestFromToNativeMap(t *testing.T) {
+	input := map[string]int{
+		"a": 1,
+		"b": 2,
+		"c": 3}
+	m := NewMapFromNativeMap[string, int](input)
+	output := m.ToNativeMap()
+	assertEqual(t, len(input), len(output))
+	for key, value := range input {
+		assertEqual(t, value, output[key])
+	}
+}
+
+func TestRandomStoreAndDelete(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	expected := make(map[int]string)
+	m := NewMap[int, string]()
+	for i := 0; i < 20000; i++ {
+		key := r.Intn(5000)
+		if r.Intn(3) == 0 {
+			delete(expected, key)
+			m = m.Delete(key)
+		} else {
+			expected[key] = fmt.Sprintf("%d", i)
+			m = m.Store(key, fmt.Sprintf("%d", i))
+		}
+	}
+
+	assertEqual(t, len(expected), m.Len())
+	actual := m.ToNativeMap()
+	assertEqual(t, len(expected), len(actual))
+	for key, value := range expected {
+		assertEqualString(t, value, actual[key])
+		v, ok := m.Load(key)
+		assertEqualBool(t, true, ok)
+		assertEqualString(t, value, v)
+	}
+
+	// Delete everything and make sure the map is empty
+	for key := range expected {
+		m = m.Delete(key)
+	}
+
+	assertEqual(t, 0, m.Len())
+	assertEqual(t, 0, len(m.ToNativeMap()))
+}
+
+//////////////////
+/// Benchmarks ///
+//////////////////
+
+func BenchmarkInsertMap(b *testing.B) {
+	length := 0
+	for i := 0; i < b.N; i++ {
+		m := NewMap[int, string]()
+		for j := 0; j < 1000; j++ {
+			m = m.Store(j, "a")
+		}
+
+		length += m.Len()
+	}
+
+	fmt.Println("Total length", length)
+}
+
+func BenchmarkInsertNativeMap(b *testing.B) {
+	length := 0
+	for i := 0; i < b.N; i++ {
+		m := map[int]string{}
+		for j := 0; j < 1000; j++ {
+			m[j] = "a"
+		}
+
+		length += len(m)
+	}
+
+	fmt.Println("Total length", length)
+}
+
+func BenchmarkAccessMap(b *testing.B) {
+	m := NewMap[int, string]()
+	for j := 0; j < 1000; j++ {
+		m = m.Store(j, "a")
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < 1000; j++ {
+			_, _ = m.Load(j)
+		}
+	}
+}
+
+func BenchmarkAccessNativeMap(b *testing.B) {
+	m := map[int]string{}
+	for j := 0; j < 1000; j++ {
+		m[j] = "a"
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < 1000; j++ {
+			_, _ = m[j]
+		}
+	}
+}
+
+func BenchmarkInterfaceHash(b *testing.B) {
+	b.ReportAllocs()
+	result := uint32(0)
+	for i := 0; i < b.N; i++ {
+		result += interfaceHash(i)
+	}
+
+	fmt.Println(result)
+}
+
+func intHashFunc(x int) uint32 {
+	// Adler32 is the quickest by far of the hash functions provided in the stdlib but its distribution is bad.
+	// CRC32 has a fairly good distribution and is fairly quick.
+	bX := make([]byte, 8)
+	binary.LittleEndian.PutUint64(bX, uint64(x))
+	return crc32.ChecksumIEEE(bX)
+}
+
+func BenchmarkIntHash(b *testing.B) {
+	b.ReportAllocs()
+	result := uint32(0)
+	for i := 0; i < b.N; i++ {
+		result += intHashFunc(i)
+	}
+
+	fmt.Println(result)
+}
+
+func BenchmarkLargeInsertAndLookup(b *testing.B) {
+	b.ReportAllocs()
+	total := 0
+	for i := 0; i < b.N; i++ {
+
+		m := NewMap[string, int]()
+		total = 0
+		for j := 0; j < 100000; j++ {
+			m = m.Store(fmt.Sprintf("%d", j), j)
+		}
+
+		for j := 0; j < 100000; j++ {
+			v, _ := m.Load(fmt.Sprintf("%d", j))
+			total += v
+		}
+
+	}
+	fmt.Println(total)
+}
+
+func BenchmarkLargeCreateInsertAndLookup(b *testing.B) {
+	b.ReportAllocs()
+	total := 0
+	for i := 0; i < b.N; i++ {
+		input := make([]MapItem[string, int], 0, 100000)
+		for j := 0; j < 100000; j++ {
+			input = append(input, MapItem[string, int]{Key: fmt.Sprintf("%d", j), Value: j})
+		}
+
+		m := NewMap(input...)
+		total = 0
+
+		for j := 0; j < 100000; j++ {
+			v, _ := m.Load(fmt.Sprintf("%d", j))
+			total += v
+		}
+
+	}
+	fmt.Println(total)
+}
+
+/*
+Profiling commands:
+
+# Run specific benchmark
+go test -bench=BenchmarkInsertMap -benchmem -run=^$ -memprofile=insert.mprof -cpuprofile=insert.prof --memprofilerate 1
+
+# CPU
+go tool pprof tests.test insert.prof
+
+# Memory
+go tool pprof --alloc_objects tests.test insert.mprof
+
+*/
+
+/* TODO: - Improve parsing of specs to allow white spaces etc.
+         - More tests, invalid specs
+         - Custom imports?
+         - Non comparable types cannot be used as keys (should be detected during compilation)
+   	     - Test custom struct as key
+   	     - Make it possible to explicitly state which hash function to use and/or which the actual underlying type is
+*/
+
+func TestStructKey(t *testing.T) {
+	type key struct {
+		a int
+		b string
+	}
+
+	m := NewMap[key, int]().Store(key{a: 1, b: "a"}, 1).Store(key{a: 1, b: "b"}, 2)
+	v, ok := m.Load(key{a: 1, b: "b"})
+	assertEqualBool(t, true, ok)
+	assertEqual(t, 2, v)
+	assertEqual(t, 1, m.Delete(key{a: 1, b: "a"}).Len())
+}
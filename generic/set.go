@@ -0,0 +1,188 @@
+package generic
+
+// Set is a persistent set
+type Set[K comparable] struct {
+	backingMap *Map[K, struct{}]
+}
+
+// NewSet returns a new Set containing items.
+func NewSet[K comparable](items ...K) *Set[K] {
+	mapItems := make([]MapItem[K, struct{}], 0, len(items))
+	var mapValue struct{}
+	for _, x := range items {
+		mapItems = append(mapItems, MapItem[K, struct{}]{Key: x, Value: mapValue})
+	}
+
+	return &Set[K]{backingMap: newMap(mapItems)}
+}
+
+// Add returns a new Set containing item.
+func (s *Set[K]) Add(item K) *Set[K] {
+	var mapValue struct{}
+	return &Set[K]{backingMap: s.backingMap.Store(item, mapValue)}
+}
+
+// Delete returns a new Set without item.
+func (s *Set[K]) Delete(item K) *Set[K] {
+	newMap := s.backingMap.Delete(item)
+	if newMap == s.backingMap {
+		return s
+	}
+
+	return &Set[K]{backingMap: newMap}
+}
+
+// Contains returns true if item is present in s, false otherwise.
+func (s *Set[K]) Contains(item K) bool {
+	_, ok := s.backingMap.Load(item)
+	return ok
+}
+
+// Range calls f repeatedly passing it each element in s as argument until either
+// all elements have been visited or f returns false.
+func (s *Set[K]) Range(f func(K) bool) {
+	s.backingMap.Range(func(k K, _ struct{}) bool {
+		return f(k)
+	})
+}
+
+// IsSubset returns true if all elements in s are present in other, false otherwise.
+func (s *Set[K]) IsSubset(other *Set[K]) bool {
+	if other.Len() < s.Len() {
+		return false
+	}
+
+	isSubset := true
+	s.Range(func(item K) bool {
+		if !other.Contains(item) {
+			isSubset = false
+		}
+
+		return isSubset
+	})
+
+	return isSubset
+}
+
+// IsSuperset returns true if all elements in other are present in s, false otherwise.
+func (s *Set[K]) IsSuperset(other *Set[K]) bool {
+	return other.IsSubset(s)
+}
+
+// Union returns a new Set containing all elements present
+// in either s or other.
+func (s *Set[K]) Union(other *Set[K]) *Set[K] {
+	result := s
+
+	// Simplest possible solution right now. Would probable be more efficient
+	// to concatenate two slices of elements from the two sets and create a
+	// new set from that slice for many cases.
+	other.Range(func(item K) bool {
+		result = result.Add(item)
+		return true
+	})
+
+	return result
+}
+
+// Equals returns true if s and other contains the same elements, false otherwise.
+func (s *Set[K]) Equals(other *Set[K]) bool {
+	return s.Len() == other.Len() && s.IsSubset(other)
+}
+
+func (s *Set[K]) difference(other *Set[K]) []K {
+	items := make([]K, 0)
+	s.Range(func(item K) bool {
+		if !other.Contains(item) {
+			items = append(items, item)
+		}
+
+		return true
+	})
+
+	return items
+}
+
+// Difference returns a new Set containing all elements present
+// in s but not in other.
+func (s *Set[K]) Difference(other *Set[K]) *Set[K] {
+	return NewSet(s.difference(other)...)
+}
+
+// SymmetricDifference returns a new Set containing all elements present
+// in either s or other but not both.
+func (s *Set[K]) SymmetricDifference(other *Set[K]) *Set[K] {
+	items := s.difference(other)
+	items = append(items, other.difference(s)...)
+	return NewSet(items...)
+}
+
+// Intersection returns a new Set containing all elements present in both
+// s and other.
+func (s *Set[K]) Intersection(other *Set[K]) *Set[K] {
+	items := make([]K, 0)
+	s.Range(func(item K) bool {
+		if other.Contains(item) {
+			items = append(items, item)
+		}
+
+		return true
+	})
+
+	return NewSet(items...)
+}
+
+// Len returns the number of elements in s.
+func (s *Set[K]) Len() int {
+	return s.backingMap.Len()
+}
+
+// ToNativeSlice returns a native Go slice containing all elements of s.
+func (s *Set[K]) ToNativeSlice() []K {
+	items := make([]K, 0, s.Len())
+	s.Range(func(item K) bool {
+		items = append(items, item)
+		return true
+	})
+
+	return items
+}
+
+// SetTransient is a mutable builder for Set. Call Persistent
+// to turn it into a Set, the transient cannot be used after that.
+type SetTransient[K comparable] struct {
+	backingMap *MapTransient[K, struct{}]
+}
+
+// AsTransient returns a transient containing all elements of s. s is left untouched.
+func (s *Set[K]) AsTransient() *SetTransient[K] {
+	return &SetTransient[K]{backingMap: s.backingMap.AsTransient()}
+}
+
+// Persistent returns a Set containing all elements of t. t cannot be used
+// after this call.
+func (t *SetTransient[K]) Persistent() *Set[K] {
+	return &Set[K]{backingMap: t.backingMap.Persistent()}
+}
+
+// Add adds item to t.
+func (t *SetTransient[K]) Add(item K) {
+	var mapValue struct{}
+	t.backingMap.Store(item, mapValue)
+}
+
+// Delete removes item from t.
+func (t *SetTransient[K]) Delete(item K) {
+	t.backingMap.Delete(item)
+}
+
+// Contains returns true if item is present in t, false otherwise.
+func (t *SetTransient[K]) Contains(item K) bool {
+	_, ok := t.backingMap.Load(item)
+	return ok
+}
+
+// Len returns the number of elements in t.
+func (t *SetTransient[K]) Len() int {
+	return t.backingMap.Len()
+}
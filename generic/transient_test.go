@@ -0,0 +1,223 @@
+package generic
+
+import (
+	"fmt"
+	"testing"
+)
+
+/////////////////////////
+/// Vector transients ///
+/////////////////////////
+
+func TestTransientVectorAppend(t *testing.T) {
+	for _, l := range testSizes {
+		t.Run(fmt.Sprintf("Append %d", l), func(t *testing.T) {
+			vec := NewVector[int](inputSlice(0, l)...)
+			tr := vec.AsTransient()
+			for i := 0; i < 2000; i++ {
+				tr.Append(l + i)
+			}
+
+			newVec := tr.Persistent()
+			assertEqual(t, l+2000, newVec.Len())
+			for i := 0; i < l+2000; i++ {
+				assertEqual(t, i, newVec.Get(i))
+			}
+
+			// Original vector is unchanged
+			assertEqual(t, l, vec.Len())
+			for i := 0; i < l; i++ {
+				assertEqual(t, i, vec.Get(i))
+			}
+		})
+	}
+}
+
+func TestTransientVectorSet(t *testing.T) {
+	for _, l := range testSizes {
+		t.Run(fmt.Sprintf("Set %d", l), func(t *testing.T) {
+			vec := NewVector[int](inputSlice(0, l)...)
+			tr := vec.AsTransient()
+			for i := 0; i < l; i++ {
+				tr.Set(i, -i)
+				assertEqual(t, -i, tr.Get(i))
+			}
+
+			// Setting the same element twice edits the owned node in place
+			for i := 0; i < l; i++ {
+				tr.Set(i, -2*i)
+			}
+
+			newVec := tr.Persistent()
+			for i := 0; i < l; i++ {
+				assertEqual(t, -2*i, newVec.Get(i))
+				assertEqual(t, i, vec.Get(i))
+			}
+		})
+	}
+}
+
+func TestTransientVectorDoesNotAffectOtherVersions(t *testing.T) {
+	tr := NewVector[int]().AsTransient()
+	tr.Append(inputSlice(0, 5000)...)
+	vec := tr.Persistent()
+
+	vec2 := vec.Append(5000)
+	tr2 := vec.AsTransient()
+	tr2.Set(100, -1)
+	tr2.Append(-2)
+	vec3 := tr2.Persistent()
+
+	assertEqual(t, 100, vec.Get(100))
+	assertEqual(t, 5000, vec.Len())
+	assertEqual(t, 100, vec2.Get(100))
+	assertEqual(t, 5000, vec2.Get(5000))
+	assertEqual(t, -1, vec3.Get(100))
+	assertEqual(t, -2, vec3.Get(5000))
+}
+
+func TestTransientVectorsFromSameVersion(t *testing.T) {
+	tr := NewVector[int]().AsTransient()
+	tr.Append(inputSlice(0, 2000)...)
+	vec := tr.Persistent()
+
+	// Nodes owned by the first transient are not owned by transients made from its result
+	tr1, tr2 := vec.AsTransient(), vec.AsTransient()
+	tr1.Set(1000, -1)
+	tr2.Set(1000, -2)
+	tr1.Set(1001, -1)
+	assertEqual(t, -1, tr1.Get(1000))
+	assertEqual(t, -2, tr2.Get(1000))
+	assertEqual(t, 1001, tr2.Get(1001))
+	assertEqual(t, 1000, vec.Get(1000))
+	assertEqual(t, 1001, vec.Get(1001))
+}
+
+func TestTransientVectorUsedAfterPersistent(t *testing.T) {
+	tr := NewVector[int](1, 2, 3).AsTransient()
+	tr.Persistent()
+	defer assertPanic(t, "Transient used after call to Persistent")
+	tr.Append(4)
+}
+
+//////////////////////
+/// Map transients ///
+//////////////////////
+
+func TestTransientMapStoreAndDelete(t *testing.T) {
+	m := NewMap(MapItem[string, int]{Key: "a", Value: -1})
+	tr := m.AsTransient()
+	size := 10000
+	for i := 0; i < size; i++ {
+		tr.Store(fmt.Sprintf("%d", i), i)
+	}
+
+	tr.Store("a", 1)
+	assertEqual(t, size+1, tr.Len())
+	for i := 0; i < size; i += 2 {
+		tr.Delete(fmt.Sprintf("%d", i))
+	}
+
+	tr.Delete("does not exist")
+	m2 := tr.Persistent()
+	assertEqual(t, size/2+1, m2.Len())
+	for i := 0; i < size; i++ {
+		v, ok := m2.Load(fmt.Sprintf("%d", i))
+		assertEqualBool(t, i%2 == 1, ok)
+		if ok {
+			assertEqual(t, i, v)
+		}
+	}
+
+	v, _ := m2.Load("a")
+	assertEqual(t, 1, v)
+
+	// Original map is unchanged
+	assertEqual(t, 1, m.Len())
+	v, _ = m.Load("a")
+	assertEqual(t, -1, v)
+}
+
+func TestTransientMapDoesNotAffectOtherVersions(t *testing.T) {
+	m := NewMap(MapItem[int, string]{Key: 1, Value: "a"}, MapItem[int, string]{Key: 2, Value: "b"})
+	tr := m.AsTransient()
+	tr.Store(1, "c")
+	tr.Delete(2)
+	m2 := tr.Persistent()
+
+	m3 := m2.Store(1, "d")
+	v, _ := m.Load(1)
+	assertEqualString(t, "a", v)
+	v, _ = m2.Load(1)
+	assertEqualString(t, "c", v)
+	v, _ = m3.Load(1)
+	assertEqualString(t, "d", v)
+	_, ok := m.Load(2)
+	assertEqualBool(t, true, ok)
+	_, ok = m2.Load(2)
+	assertEqualBool(t, false, ok)
+}
+
+func TestTransientMapUsedAfterPersistent(t *testing.T) {
+	tr := NewMap[int, string]().AsTransient()
+	tr.Persistent()
+	defer assertPanic(t, "Transient used after call to Persistent")
+	tr.Store(1, "a")
+}
+
+//////////////////////
+/// Set transients ///
+//////////////////////
+
+func TestTransientSet(t *testing.T) {
+	s := NewSet[int](1, 2, 3)
+	tr := s.AsTransient()
+	for i := 0; i < 1000; i++ {
+		tr.Add(i)
+	}
+
+	tr.Delete(2)
+	assertEqualBool(t, true, tr.Contains(3))
+	assertEqualBool(t, false, tr.Contains(2))
+	s2 := tr.Persistent()
+
+	assertEqual(t, 999, s2.Len())
+	assertEqualBool(t, false, s2.Contains(2))
+	assertEqual(t, 3, s.Len())
+	assertEqualBool(t, true, s.Contains(2))
+}
+
+func TestTransientSetUsedAfterPersistent(t *testing.T) {
+	tr := NewSet[int]().AsTransient()
+	tr.Persistent()
+	defer assertPanic(t, "Transient used after call to Persistent")
+	tr.Add(1)
+}
+
+//////////////////
+/// Benchmarks ///
+//////////////////
+
+func BenchmarkTransientVectorAppend(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		tr := NewVector[int]().AsTransient()
+		for i := 0; i < 100000; i++ {
+			tr.Append(i)
+		}
+
+		result += tr.Persistent().Len()
+	}
+}
+
+func BenchmarkPersistentVectorAppend(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		v := NewVector[int]()
+		for i := 0; i < 100000; i++ {
+			v = v.Append(i)
+		}
+
+		result += v.Len()
+	}
+}
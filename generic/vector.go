@@ -0,0 +1,532 @@
+package generic
+
+//////////////
+/// Vector ///
+//////////////
+
+// A Vector is an ordered persistent/immutable collection of items corresponding roughly
+// to the use cases for a slice.
+type Vector[T any] struct {
+	tail  []T
+	root  commonNode
+	len   uint
+	shift uint
+}
+
+func emptyVector[T any]() *Vector[T] {
+	return &Vector[T]{root: emptyCommonNode, shift: shiftSize, tail: make([]T, 0)}
+}
+
+// NewVector returns a new Vector containing the items provided in items.
+func NewVector[T any](items ...T) *Vector[T] {
+	return emptyVector[T]().Append(items...)
+}
+
+// Get returns the element at position i.
+func (v *Vector[T]) Get(i int) T {
+	if i < 0 || uint(i) >= v.len {
+		panic("Index out of bounds")
+	}
+
+	return v.sliceFor(uint(i))[i&shiftBitMask]
+}
+
+func (v *Vector[T]) sliceFor(i uint) []T {
+	if i >= v.tailOffset() {
+		return v.tail
+	}
+
+	node := v.root
+	for level := v.shift; level > 0; level -= shiftSize {
+		node = node.([]commonNode)[(i>>level)&shiftBitMask]
+	}
+
+	return node.([]T)
+}
+
+func (v *Vector[T]) tailOffset() uint {
+	if v.len < nodeSize {
+		return 0
+	}
+
+	return ((v.len - 1) >> shiftSize) << shiftSize
+}
+
+// Set returns a new vector with the element at position i set to item.
+func (v *Vector[T]) Set(i int, item T) *Vector[T] {
+	if i < 0 || uint(i) >= v.len {
+		panic("Index out of bounds")
+	}
+
+	if uint(i) >= v.tailOffset() {
+		newTail := make([]T, len(v.tail))
+		copy(newTail, v.tail)
+		newTail[i&shiftBitMask] = item
+		return &Vector[T]{root: v.root, tail: newTail, len: v.len, shift: v.shift}
+	}
+
+	return &Vector[T]{root: v.doAssoc(v.shift, v.root, uint(i), item), tail: v.tail, len: v.len, shift: v.shift}
+}
+
+func (v *Vector[T]) doAssoc(level uint, node commonNode, i uint, item T) commonNode {
+	if level == 0 {
+		leaf := node.([]T)
+		ret := make([]T, len(leaf))
+		copy(ret, leaf)
+		ret[i&shiftBitMask] = item
+		return ret
+	}
+
+	// Copies keep the length of the original, children beyond it are added by pushTail
+	children := node.([]commonNode)
+	ret := make([]commonNode, len(children))
+	copy(ret, children)
+	subidx := (i >> level) & shiftBitMask
+	ret[subidx] = v.doAssoc(level-shiftSize, ret[subidx], i, item)
+	return ret
+}
+
+func (v *Vector[T]) pushTail(level uint, parent commonNode, tailNode []T) commonNode {
+	subIdx := ((v.len - 1) >> level) & shiftBitMask
+	parentNode := parent.([]commonNode)
+	ret := make([]commonNode, subIdx+1)
+	copy(ret, parentNode)
+	var nodeToInsert commonNode
+
+	if level == shiftSize {
+		nodeToInsert = tailNode
+	} else if subIdx < uint(len(parentNode)) {
+		nodeToInsert = v.pushTail(level-shiftSize, parentNode[subIdx], tailNode)
+	} else {
+		nodeToInsert = newPath(level-shiftSize, tailNode)
+	}
+
+	ret[subIdx] = nodeToInsert
+	return ret
+}
+
+// Append returns a new vector with item(s) appended to it.
+func (v *Vector[T]) Append(item ...T) *Vector[T] {
+	result := v
+	itemLen := uint(len(item))
+	for insertOffset := uint(0); insertOffset < itemLen; {
+		tailLen := result.len - result.tailOffset()
+		tailFree := nodeSize - tailLen
+		if tailFree == 0 {
+			result = result.pushLeafNode(result.tail)
+			result.tail = make([]T, 0)
+			tailFree = nodeSize
+			tailLen = 0
+		}
+
+		batchLen := uintMin(itemLen-insertOffset, tailFree)
+		newTail := make([]T, 0, tailLen+batchLen)
+		newTail = append(newTail, result.tail...)
+		newTail = append(newTail, item[insertOffset:insertOffset+batchLen]...)
+		result = &Vector[T]{root: result.root, tail: newTail, len: result.len + batchLen, shift: result.shift}
+		insertOffset += batchLen
+	}
+
+	return result
+}
+
+func (v *Vector[T]) pushLeafNode(node []T) *Vector[T] {
+	var newRoot commonNode
+	newShift := v.shift
+
+	// Root overflow?
+	if (v.len >> shiftSize) > (1 << v.shift) {
+		newNode := newPath(v.shift, node)
+		newRoot = commonNode([]commonNode{v.root, newNode})
+		newShift = v.shift + shiftSize
+	} else {
+		newRoot = v.pushTail(v.shift, v.root, node)
+	}
+
+	return &Vector[T]{root: newRoot, tail: v.tail, len: v.len, shift: newShift}
+}
+
+// Pop returns a new vector with the last element removed, and the removed element.
+func (v *Vector[T]) Pop() (*Vector[T], T) {
+	if v.len == 0 {
+		panic("Pop on empty vector")
+	}
+
+	item := v.tail[len(v.tail)-1]
+	return v.DropLast(1), item
+}
+
+// DropLast returns a new vector with the last n elements removed. Nodes no longer
+// needed are released rather than kept alive as they would be by Slice.
+func (v *Vector[T]) DropLast(n int) *Vector[T] {
+	if n < 0 || uint(n) > v.len {
+		panic("Index out of bounds")
+	}
+
+	if n == 0 {
+		return v
+	}
+
+	newLen := v.len - uint(n)
+	if newLen == 0 {
+		return emptyVector[T]()
+	}
+
+	result := &Vector[T]{len: newLen}
+	newTailOffset := result.tailOffset()
+	newTail := make([]T, newLen-newTailOffset)
+	copy(newTail, v.sliceFor(newLen-1))
+	result.tail = newTail
+	if newTailOffset == 0 {
+		result.root, result.shift = emptyCommonNode, shiftSize
+		return result
+	}
+
+	result.root, result.shift = v.popTail(v.shift, v.root, newTailOffset-1), v.shift
+
+	// Root underflow?
+	for result.shift > shiftSize && len(result.root.([]commonNode)) == 1 {
+		result.root = result.root.([]commonNode)[0]
+		result.shift -= shiftSize
+	}
+
+	return result
+}
+
+// popTail returns a copy of the path from node down to the leaf holding element
+// last with all nodes after that path removed.
+func (v *Vector[T]) popTail(level uint, node commonNode, last uint) commonNode {
+	subIdx := (last >> level) & shiftBitMask
+	ret := make([]commonNode, subIdx+1)
+	copy(ret, node.([]commonNode))
+	if level > shiftSize {
+		ret[subIdx] = v.popTail(level-shiftSize, ret[subIdx], last)
+	}
+
+	return ret
+}
+
+// DropFirst returns a new vector with the first n elements removed. Unlike Slice the
+// result does not share any structure with v, it takes time proportional to its length.
+func (v *Vector[T]) DropFirst(n int) *Vector[T] {
+	if n < 0 || uint(n) > v.len {
+		panic("Index out of bounds")
+	}
+
+	if n == 0 {
+		return v
+	}
+
+	t := emptyVector[T]().AsTransient()
+	for i := uint(n); i < v.len; i = (i | shiftBitMask) + 1 {
+		t.Append(v.sliceFor(i)[i&shiftBitMask:]...)
+	}
+
+	return t.Persistent()
+}
+
+// Slice returns a VectorSlice that refers to all elements [start,stop) in v.
+func (v *Vector[T]) Slice(start, stop int) *VectorSlice[T] {
+	assertSliceOk(start, stop, v.Len())
+	return &VectorSlice[T]{vector: v, start: start, stop: stop}
+}
+
+// Len returns the length of v.
+func (v *Vector[T]) Len() int {
+	return int(v.len)
+}
+
+// Range calls f repeatedly passing it each element in v in order as argument until either
+// all elements have been visited or f returns false.
+func (v *Vector[T]) Range(f func(T) bool) {
+	var currentNode []T
+	for i := uint(0); i < v.len; i++ {
+		if i&shiftBitMask == 0 {
+			currentNode = v.sliceFor(i)
+		}
+
+		if !f(currentNode[i&shiftBitMask]) {
+			return
+		}
+	}
+}
+
+// ToNativeSlice returns a Go slice containing all elements of v
+func (v *Vector[T]) ToNativeSlice() []T {
+	result := make([]T, 0, v.len)
+	for i := uint(0); i < v.len; i += nodeSize {
+		result = append(result, v.sliceFor(i)...)
+	}
+
+	return result
+}
+
+/////////////////
+/// Transient ///
+/////////////////
+
+// VectorTransient is a mutable builder for Vector. Nodes created by
+// the transient are owned by it and edited in place rather than copied. Call Persistent
+// to turn it into a Vector, the transient cannot be used after that.
+type VectorTransient[T any] struct {
+	tail  []T
+	root  commonNode
+	len   uint
+	shift uint
+	owner *transientOwner
+}
+
+// AsTransient returns a transient containing all elements of v. v is left untouched.
+func (v *Vector[T]) AsTransient() *VectorTransient[T] {
+	tail := make([]T, len(v.tail), nodeSize)
+	copy(tail, v.tail)
+	return &VectorTransient[T]{
+		tail:  tail,
+		root:  v.root,
+		len:   v.len,
+		shift: v.shift,
+		owner: &transientOwner{}}
+}
+
+// Persistent returns a Vector containing all elements of t. t cannot be used
+// after this call.
+func (t *VectorTransient[T]) Persistent() *Vector[T] {
+	assertTransientEditable(t.owner != nil)
+	t.owner = nil
+	return &Vector[T]{tail: t.tail, root: t.root, len: t.len, shift: t.shift}
+}
+
+// Len returns the length of t.
+func (t *VectorTransient[T]) Len() int {
+	assertTransientEditable(t.owner != nil)
+	return int(t.len)
+}
+
+func (t *VectorTransient[T]) tailOffset() uint {
+	if t.len < nodeSize {
+		return 0
+	}
+
+	return ((t.len - 1) >> shiftSize) << shiftSize
+}
+
+// Get returns the element at position i.
+func (t *VectorTransient[T]) Get(i int) T {
+	assertTransientEditable(t.owner != nil)
+	if i < 0 || uint(i) >= t.len {
+		panic("Index out of bounds")
+	}
+
+	if uint(i) >= t.tailOffset() {
+		return t.tail[i&shiftBitMask]
+	}
+
+	node := t.root
+	for level := t.shift; level > 0; level -= shiftSize {
+		node = node.([]commonNode)[(uint(i)>>level)&shiftBitMask]
+	}
+
+	return node.([]T)[i&shiftBitMask]
+}
+
+// nodeOwner returns the owner of node if node is owned by t, otherwise nil.
+func (t *VectorTransient[T]) nodeOwner(node []commonNode) *vectorNodeOwner {
+	if cap(node) > nodeSize {
+		if owner, ok := node[:nodeSize+1][nodeSize].(*vectorNodeOwner); ok && owner.transient == t.owner {
+			return owner
+		}
+	}
+
+	return nil
+}
+
+// editableNode returns node if it is owned by t, otherwise an owned copy of it.
+func (t *VectorTransient[T]) editableNode(node []commonNode) []commonNode {
+	if t.nodeOwner(node) != nil {
+		return node
+	}
+
+	newNode := make([]commonNode, len(node), nodeSize+1)
+	copy(newNode, node)
+	newNode[:nodeSize+1][nodeSize] = &vectorNodeOwner{transient: t.owner}
+	return newNode
+}
+
+// editableLeaf returns the leaf at position ix of parent, a node owned by t, after
+// replacing it with an owned copy unless it is owned by t already.
+func (t *VectorTransient[T]) editableLeaf(parent []commonNode, ix uint) []T {
+	owner := t.nodeOwner(parent)
+	leaf := parent[ix].([]T)
+	if owner.leaves&(1<<ix) != 0 {
+		return leaf
+	}
+
+	newLeaf := make([]T, len(leaf))
+	copy(newLeaf, leaf)
+	parent[ix] = newLeaf
+	owner.leaves |= 1 << ix
+	return newLeaf
+}
+
+// Set sets the element at position i to item.
+func (t *VectorTransient[T]) Set(i int, item T) {
+	assertTransientEditable(t.owner != nil)
+	if i < 0 || uint(i) >= t.len {
+		panic("Index out of bounds")
+	}
+
+	if uint(i) >= t.tailOffset() {
+		t.tail[i&shiftBitMask] = item
+		return
+	}
+
+	t.root = t.doAssoc(t.shift, t.root, uint(i), item)
+}
+
+func (t *VectorTransient[T]) doAssoc(level uint, node commonNode, i uint, item T) commonNode {
+	ret := t.editableNode(node.([]commonNode))
+	subidx := (i >> level) & shiftBitMask
+	if level == shiftSize {
+		t.editableLeaf(ret, subidx)[i&shiftBitMask] = item
+		return ret
+	}
+
+	ret[subidx] = t.doAssoc(level-shiftSize, ret[subidx], i, item)
+	return ret
+}
+
+// Append adds item(s) to the end of t.
+func (t *VectorTransient[T]) Append(items ...T) {
+	assertTransientEditable(t.owner != nil)
+	for _, item := range items {
+		if uint(len(t.tail)) == nodeSize {
+			t.pushLeafNode(t.tail)
+			t.tail = make([]T, 0, nodeSize)
+		}
+
+		t.tail = append(t.tail, item)
+		t.len++
+	}
+}
+
+func (t *VectorTransient[T]) newPath(shift uint, node commonNode) commonNode {
+	if shift == 0 {
+		return node
+	}
+
+	newNode := append(t.editableNode(nil), node)
+	if _, ok := node.([]T); ok {
+		t.nodeOwner(newNode).leaves = 1
+	}
+
+	return t.newPath(shift-shiftSize, commonNode(newNode))
+}
+
+func (t *VectorTransient[T]) pushTail(level uint, parent commonNode, tailNode []T) commonNode {
+	subIdx := ((t.len - 1) >> level) & shiftBitMask
+	ret := t.editableNode(parent.([]commonNode))
+	var nodeToInsert commonNode
+
+	if level == shiftSize {
+		nodeToInsert = tailNode
+		t.nodeOwner(ret).leaves |= 1 << subIdx
+	} else if subIdx < uint(len(ret)) {
+		nodeToInsert = t.pushTail(level-shiftSize, ret[subIdx], tailNode)
+	} else {
+		nodeToInsert = t.newPath(level-shiftSize, tailNode)
+	}
+
+	if subIdx < uint(len(ret)) {
+		ret[subIdx] = nodeToInsert
+		return ret
+	}
+
+	return append(ret, nodeToInsert)
+}
+
+func (t *VectorTransient[T]) pushLeafNode(node []T) {
+	// Root overflow?
+	if (t.len >> shiftSize) > (1 << t.shift) {
+		newRoot := t.editableNode(nil)
+		t.root = commonNode(append(newRoot, t.root, t.newPath(t.shift, node)))
+		t.shift += shiftSize
+	} else {
+		t.root = t.pushTail(t.shift, t.root, node)
+	}
+}
+
+////////////////
+//// Slice /////
+////////////////
+
+// VectorSlice is a slice type backed by a Vector.
+type VectorSlice[T any] struct {
+	vector      *Vector[T]
+	start, stop int
+}
+
+// NewVectorSlice returns a new NewVectorSlice containing the items provided in items.
+func NewVectorSlice[T any](items ...T) *VectorSlice[T] {
+	return &VectorSlice[T]{vector: emptyVector[T]().Append(items...), start: 0, stop: len(items)}
+}
+
+// Len returns the length of s.
+func (s *VectorSlice[T]) Len() int {
+	return s.stop - s.start
+}
+
+// Get returns the element at position i.
+func (s *VectorSlice[T]) Get(i int) T {
+	if i < 0 || s.start+i >= s.stop {
+		panic("Index out of bounds")
+	}
+
+	return s.vector.Get(s.start + i)
+}
+
+// Set returns a new slice with the element at position i set to item.
+func (s *VectorSlice[T]) Set(i int, item T) *VectorSlice[T] {
+	if i < 0 || s.start+i >= s.stop {
+		panic("Index out of bounds")
+	}
+
+	return s.vector.Set(s.start+i, item).Slice(s.start, s.stop)
+}
+
+// Append returns a new slice with item(s) appended to it.
+func (s *VectorSlice[T]) Append(items ...T) *VectorSlice[T] {
+	newSlice := VectorSlice[T]{vector: s.vector, start: s.start, stop: s.stop + len(items)}
+
+	// If this is v slice that has an upper bound that is lower than the backing
+	// vector then set the values in the backing vector to achieve some structural
+	// sharing.
+	itemPos := 0
+	for ; s.stop+itemPos < s.vector.Len() && itemPos < len(items); itemPos++ {
+		newSlice.vector = newSlice.vector.Set(s.stop+itemPos, items[itemPos])
+	}
+
+	// For the rest just append it to the underlying vector
+	newSlice.vector = newSlice.vector.Append(items[itemPos:]...)
+	return &newSlice
+}
+
+// Slice returns a VectorSlice that refers to all elements [start,stop) in s.
+func (s *VectorSlice[T]) Slice(start, stop int) *VectorSlice[T] {
+	assertSliceOk(start, stop, s.stop-s.start)
+	return &VectorSlice[T]{vector: s.vector, start: s.start + start, stop: s.start + stop}
+}
+
+// Range calls f repeatedly passing it each element in s in order as argument until either
+// all elements have been visited or f returns false.
+func (s *VectorSlice[T]) Range(f func(T) bool) {
+	var currentNode []T
+	for i := uint(s.start); i < uint(s.stop); i++ {
+		if i&shiftBitMask == 0 || i == uint(s.start) {
+			currentNode = s.vector.sliceFor(uint(i))
+		}
+
+		if !f(currentNode[i&shiftBitMask]) {
+			return
+		}
+	}
+}
@@ -1,30 +1,12 @@
-package peds
+package generic
 
 import (
-    "fmt"
-    "runtime"
-    "strings"
-    "testing"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
 )
 
-// TODO: Should not be needed but code generation seems a bit broken
-// type commonNode interface{}
-
-func TestCanBeInstantiated(t *testing.T) {
-    v := NewVector(int)(1, 2, 3)
-    v2 := v.Append(4)
-    if v2.Len() != 4 {
-        t.Errorf("Expected %d != Expected 4", v2.Len())
-    }
-
-    for i := 0; i < 4; i++ {
-        actual, expected := v2.Get(i), i+1
-        if actual != expected {
-            t.Errorf("Actual %d != Expected %d", actual, expected)
-        }
-    }
-}
-
 ///////////////
 /// Helpers ///
 ///////////////
@@ -80,7 +62,7 @@ var testSizes = []int{0, 1, 20, 32, 33, 50, 500, 32 * 32, 32*32 + 1, 10000, 32 *
 func TestPropertiesOfNewVector(t *testing.T) {
 	for _, l := range testSizes {
 		t.Run(fmt.Sprintf("NewVector %d", l), func(t *testing.T) {
-			vec := NewVector(inputSlice(0, l)...)
+			vec := NewVector[int](inputSlice(0, l)...)
 			assertEqual(t, vec.Len(), l)
 			for i := 0; i < l; i++ {
 				assertEqual(t, i, vec.Get(i))
@@ -92,7 +74,7 @@ func TestPropertiesOfNewVector(t *testing.T) {
 func TestSetItem(t *testing.T) {
 	for _, l := range testSizes {
 		t.Run(fmt.Sprintf("Set %d", l), func(t *testing.T) {
-			vec := NewVector(inputSlice(0, l)...)
+			vec := NewVector[int](inputSlice(0, l)...)
 			for i := 0; i < l; i++ {
 				newArr := vec.Set(i, -i)
 				assertEqual(t, -i, newArr.Get(i))
@@ -102,9 +84,22 @@ func TestSetItem(t *testing.T) {
 	}
 }
 
+func TestAppendAfterSet(t *testing.T) {
+	// Set on the last, partially filled, branch of the trie must not leave
+	// holes that appends descend into later
+	vec := NewVector(inputSlice(0, 1100)...).Set(1060, -1)
+	appended := vec.Append(inputSlice(1100, 2000)...)
+	assertEqual(t, -1, appended.Get(1060))
+	assertEqual(t, 3099, appended.Get(3099))
+
+	tr := vec.AsTransient()
+	tr.Append(inputSlice(1100, 2000)...)
+	assertEqual(t, 3099, tr.Persistent().Get(3099))
+}
+
 func TestAppend(t *testing.T) {
 	for _, l := range testSizes {
-		vec := NewVector(inputSlice(0, l)...)
+		vec := NewVector[int](inputSlice(0, l)...)
 		t.Run(fmt.Sprintf("Append %d", l), func(t *testing.T) {
 			for i := 0; i < 70; i++ {
 				newVec := vec.Append(inputSlice(l, i)...)
@@ -120,24 +115,125 @@ func TestAppend(t *testing.T) {
 	}
 }
 
+func TestPop(t *testing.T) {
+	for _, l := range testSizes[1:] {
+		t.Run(fmt.Sprintf("Pop %d", l), func(t *testing.T) {
+			vec := NewVector[int](inputSlice(0, l)...)
+			for i := l - 1; i >= 0; i-- {
+				newVec, item := vec.Pop()
+				assertEqual(t, i, item)
+				assertEqual(t, i, newVec.Len())
+				if i > 0 {
+					assertEqual(t, i-1, newVec.Get(i-1))
+				}
+
+				// Original vector is unchanged
+				assertEqual(t, i+1, vec.Len())
+				assertEqual(t, i, vec.Get(i))
+				vec = newVec
+			}
+		})
+	}
+}
+
+func TestPopEmpty(t *testing.T) {
+	defer assertPanic(t, "Pop on empty vector")
+	NewVector[int]().Pop()
+}
+
+func TestDropLast(t *testing.T) {
+	for _, l := range testSizes {
+		vec := NewVector[int](inputSlice(0, l)...)
+		t.Run(fmt.Sprintf("DropLast %d", l), func(t *testing.T) {
+			for _, n := range []int{0, 1, 31, 32, 33, l / 2, l - 33, l - 32, l - 1, l} {
+				if n < 0 || n > l {
+					continue
+				}
+
+				newVec := vec.DropLast(n)
+				assertEqual(t, l-n, newVec.Len())
+				for j := 0; j < l-n; j++ {
+					assertEqual(t, j, newVec.Get(j))
+				}
+
+				// The result can be grown again
+				newVec = newVec.Append(inputSlice(l-n, n+40)...)
+				assertEqual(t, l+40, newVec.Len())
+				for j := 0; j < l+40; j++ {
+					assertEqual(t, j, newVec.Get(j))
+				}
+
+				assertEqual(t, l, vec.Len())
+			}
+		})
+	}
+}
+
+func TestDropLastShrinksTrie(t *testing.T) {
+	vec := NewVector[int](inputSlice(0, 32*32*32+1)...).DropLast(32*32*32 - 40)
+	assertEqual(t, shiftSize, int(vec.shift))
+	assertEqual(t, 1, len(vec.root.([]commonNode)))
+	assertEqual(t, 9, len(vec.tail))
+}
+
+func TestDropFirst(t *testing.T) {
+	for _, l := range testSizes {
+		vec := NewVector[int](inputSlice(0, l)...)
+		t.Run(fmt.Sprintf("DropFirst %d", l), func(t *testing.T) {
+			for _, n := range []int{0, 1, 31, 32, 33, l / 2, l - 1, l} {
+				if n < 0 || n > l {
+					continue
+				}
+
+				newVec := vec.DropFirst(n)
+				assertEqual(t, l-n, newVec.Len())
+				for j := 0; j < l-n; j++ {
+					assertEqual(t, n+j, newVec.Get(j))
+				}
+
+				assertEqual(t, l, vec.Len())
+			}
+		})
+	}
+}
+
+func TestVectorDropOutOfBounds(t *testing.T) {
+	tests := []struct {
+		name string
+		drop func(v *Vector[int]) *Vector[int]
+	}{
+		{"DropLast negative", func(v *Vector[int]) *Vector[int] { return v.DropLast(-1) }},
+		{"DropLast beyond end", func(v *Vector[int]) *Vector[int] { return v.DropLast(11) }},
+		{"DropFirst negative", func(v *Vector[int]) *Vector[int] { return v.DropFirst(-1) }},
+		{"DropFirst beyond end", func(v *Vector[int]) *Vector[int] { return v.DropFirst(11) }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer assertPanic(t, "Index out of bounds")
+			test.drop(NewVector[int](inputSlice(0, 10)...))
+		})
+	}
+}
+
 func TestVectorSetOutOfBoundsNegative(t *testing.T) {
 	defer assertPanic(t, "Index out of bounds")
-	NewVector(inputSlice(0, 10)...).Set(-1, 0)
+	NewVector[int](inputSlice(0, 10)...).Set(-1, 0)
 }
 
 func TestVectorSetOutOfBoundsBeyondEnd(t *testing.T) {
 	defer assertPanic(t, "Index out of bounds")
-	NewVector(inputSlice(0, 10)...).Set(10, 0)
+	NewVector[int](inputSlice(0, 10)...).Set(10, 0)
 }
 
 func TestVectorGetOutOfBoundsNegative(t *testing.T) {
 	defer assertPanic(t, "Index out of bounds")
-	NewVector(inputSlice(0, 10)...).Get(-1)
+	NewVector[int](inputSlice(0, 10)...).Get(-1)
 }
 
 func TestVectorGetOutOfBoundsBeyondEnd(t *testing.T) {
 	defer assertPanic(t, "Index out of bounds")
-	NewVector(inputSlice(0, 10)...).Get(10)
+	NewVector[int](inputSlice(0, 10)...).Get(10)
 }
 
 func TestVectorSliceOutOfBounds(t *testing.T) {
@@ -153,7 +249,7 @@ func TestVectorSliceOutOfBounds(t *testing.T) {
 	for _, s := range tests {
 		t.Run(fmt.Sprintf("start=%d, stop=%d", s.start, s.stop), func(t *testing.T) {
 			defer assertPanic(t, s.msg)
-			NewVector(inputSlice(0, 10)...).Slice(s.start, s.stop)
+			NewVector[int](inputSlice(0, 10)...).Slice(s.start, s.stop)
 		})
 	}
 }
@@ -161,7 +257,7 @@ func TestVectorSliceOutOfBounds(t *testing.T) {
 func TestCompleteIteration(t *testing.T) {
 	input := inputSlice(0, 10000)
 	dst := make([]int, 0, 10000)
-	NewVector(input...).Range(func(elem int) bool {
+	NewVector[int](input...).Range(func(elem int) bool {
 		dst = append(dst, elem)
 		return true
 	})
@@ -175,7 +271,7 @@ func TestCompleteIteration(t *testing.T) {
 func TestCanceledIteration(t *testing.T) {
 	input := inputSlice(0, 10000)
 	count := 0
-	NewVector(input...).Range(func(elem int) bool {
+	NewVector[int](input...).Range(func(elem int) bool {
 		count++
 		if count == 5 {
 			return false
@@ -191,7 +287,7 @@ func TestCanceledIteration(t *testing.T) {
 /////////////
 
 func TestSliceIndexes(t *testing.T) {
-	vec := NewVector(inputSlice(0, 1000)...)
+	vec := NewVector[int](inputSlice(0, 1000)...)
 	slice := vec.Slice(0, 10)
 	assertEqual(t, 1000, vec.Len())
 	assertEqual(t, 10, slice.Len())
@@ -208,7 +304,7 @@ func TestSliceIndexes(t *testing.T) {
 
 func TestSliceCreation(t *testing.T) {
 	sliceLen := 10000
-	slice := NewVectorSlice(inputSlice(0, sliceLen)...)
+	slice := NewVectorSlice[int](inputSlice(0, sliceLen)...)
 	assertEqual(t, slice.Len(), sliceLen)
 	for i := 0; i < sliceLen; i++ {
 		assertEqual(t, i, slice.Get(i))
@@ -216,7 +312,7 @@ func TestSliceCreation(t *testing.T) {
 }
 
 func TestSliceSet(t *testing.T) {
-	vector := NewVector(inputSlice(0, 1000)...)
+	vector := NewVector[int](inputSlice(0, 1000)...)
 	slice := vector.Slice(10, 100)
 	slice2 := slice.Set(5, 123)
 
@@ -228,7 +324,7 @@ func TestSliceSet(t *testing.T) {
 }
 
 func TestSliceAppendInTheMiddleOfBackingVector(t *testing.T) {
-	vector := NewVector(inputSlice(0, 100)...)
+	vector := NewVector[int](inputSlice(0, 100)...)
 	slice := vector.Slice(0, 50)
 	slice2 := slice.Append(inputSlice(0, 10)...)
 
@@ -247,7 +343,7 @@ func TestSliceAppendInTheMiddleOfBackingVector(t *testing.T) {
 }
 
 func TestSliceAppendAtTheEndOfBackingVector(t *testing.T) {
-	vector := NewVector(inputSlice(0, 100)...)
+	vector := NewVector[int](inputSlice(0, 100)...)
 	slice := vector.Slice(0, 100)
 	slice2 := slice.Append(inputSlice(0, 10)...)
 
@@ -261,7 +357,7 @@ func TestSliceAppendAtTheEndOfBackingVector(t *testing.T) {
 }
 
 func TestSliceAppendAtMiddleToEndOfBackingVector(t *testing.T) {
-	vector := NewVector(inputSlice(0, 100)...)
+	vector := NewVector[int](inputSlice(0, 100)...)
 	slice := vector.Slice(0, 50)
 	slice2 := slice.Append(inputSlice(0, 100)...)
 
@@ -275,7 +371,7 @@ func TestSliceAppendAtMiddleToEndOfBackingVector(t *testing.T) {
 }
 
 func TestSliceCompleteIteration(t *testing.T) {
-	vec := NewVector(inputSlice(0, 1000)...)
+	vec := NewVector[int](inputSlice(0, 1000)...)
 	dst := make([]int, 0)
 
 	vec.Slice(5, 200).Range(func(elem int) bool {
@@ -290,7 +386,7 @@ func TestSliceCompleteIteration(t *testing.T) {
 }
 
 func TestSliceCanceledIteration(t *testing.T) {
-	vec := NewVector(inputSlice(0, 1000)...)
+	vec := NewVector[int](inputSlice(0, 1000)...)
 	count := 0
 
 	vec.Slice(5, 200).Range(func(elem int) bool {
@@ -307,22 +403,22 @@ func TestSliceCanceledIteration(t *testing.T) {
 
 func TestSliceSetOutOfBoundsNegative(t *testing.T) {
 	defer assertPanic(t, "Index out of bounds")
-	NewVector(inputSlice(0, 10)...).Slice(2, 5).Set(-1, 0)
+	NewVector[int](inputSlice(0, 10)...).Slice(2, 5).Set(-1, 0)
 }
 
 func TestSliceSetOutOfBoundsBeyondEnd(t *testing.T) {
 	defer assertPanic(t, "Index out of bounds")
-	NewVector(inputSlice(0, 10)...).Slice(2, 5).Set(4, 0)
+	NewVector[int](inputSlice(0, 10)...).Slice(2, 5).Set(4, 0)
 }
 
 func TestSliceGetOutOfBoundsNegative(t *testing.T) {
 	defer assertPanic(t, "Index out of bounds")
-	NewVector(inputSlice(0, 10)...).Slice(2, 5).Get(-1)
+	NewVector[int](inputSlice(0, 10)...).Slice(2, 5).Get(-1)
 }
 
 func TestSliceGetOutOfBoundsBeyondEnd(t *testing.T) {
 	defer assertPanic(t, "Index out of bounds")
-	NewVector(inputSlice(0, 10)...).Slice(2, 5).Get(4)
+	NewVector[int](inputSlice(0, 10)...).Slice(2, 5).Get(4)
 }
 
 func TestSliceSliceOutOfBounds(t *testing.T) {
@@ -338,7 +434,7 @@ func TestSliceSliceOutOfBounds(t *testing.T) {
 	for _, s := range tests {
 		t.Run(fmt.Sprintf("start=%d, stop=%d", s.start, s.stop), func(t *testing.T) {
 			defer assertPanic(t, s.msg)
-			NewVector(inputSlice(0, 10)...).Slice(2, 5).Slice(s.start, s.stop)
+			NewVector[int](inputSlice(0, 10)...).Slice(2, 5).Slice(s.start, s.stop)
 		})
 	}
 }
@@ -348,7 +444,7 @@ func TestToNativeVector(t *testing.T) {
 	for _, length := range lengths {
 		t.Run(fmt.Sprintf("length=%d", length), func(t *testing.T) {
 			inputS := inputSlice(0, length)
-			v := NewVector(inputS...)
+			v := NewVector[int](inputS...)
 
 			outputS := v.ToNativeSlice()
 
@@ -359,3 +455,33 @@ func TestToNativeVector(t *testing.T) {
 		})
 	}
 }
+
+// TODO:
+// - Document public types and methods
+// - Expand README with examples
+
+//////////////////////
+///// Benchmarks /////
+//////////////////////
+
+// Used to avoid that the compiler optimizes the code away
+var result int
+
+func runIteration(b *testing.B, size int) {
+	vec := NewVector[int](inputSlice(0, size)...)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		vec.Range(func(x int) bool {
+			result += x
+			return true
+		})
+	}
+}
+
+func BenchmarkLargeIteration(b *testing.B) {
+	runIteration(b, 100000)
+}
+
+func BenchmarkSmallIteration(b *testing.B) {
+	runIteration(b, 10)
+}
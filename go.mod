@@ -1,5 +1,12 @@
 module github.com/tobgu/peds
 
-go 1.13
+go 1.22.0
 
-require github.com/pkg/errors v0.8.1
+require (
+	github.com/pkg/errors v0.8.1
+	golang.org/x/mod v0.21.0
+	golang.org/x/tools v0.26.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sync v0.8.0 // indirect
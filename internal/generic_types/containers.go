@@ -2,16 +2,30 @@
 // will be used in text templates to generate specific implementations.
 package generic_types
 
-//template:CommonTemplate
+//template:CommonImportsTemplate
 
 import (
+	"bufio"
+	"bytes"
+	"encoding"
 	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
 	"fmt"
 	"hash/crc32"
+	"io"
 	"math"
+	"math/bits"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"unsafe"
 )
 
+//template:CommonTemplate
+
 const shiftSize = 5
 const nodeSize = 32
 const shiftBitMask = 0x1F
@@ -50,9 +64,324 @@ func assertSliceOk(start, stop, len int) {
 	}
 }
 
-const upperMapLoadFactor float64 = 8.0
-const lowerMapLoadFactor float64 = 2.0
-const initialMapLoadFactor float64 = (upperMapLoadFactor + lowerMapLoadFactor) / 2
+func assertTransientEditable(editable bool) {
+	if !editable {
+		panic("Transient used after call to Persistent")
+	}
+}
+
+// transientOwner identifies the nodes that are owned, and may be edited in place,
+// by a transient.
+type transientOwner struct {
+	_ byte
+}
+
+// vectorNodeOwner is kept in a slot beyond the length of the vector trie nodes owned by
+// a transient. Leaves have no room for an owner of their own, the leaves of a node that
+// are owned by the transient are instead marked in leaves.
+type vectorNodeOwner struct {
+	transient *transientOwner
+	leaves    uint32
+}
+
+// Number of hash bits used to place items in a CHAMP tree. Items with identical
+// hashes are stored in collision nodes below the last level.
+const champHashBits = 32
+
+func champBitpos(hash uint32, shift uint) uint32 {
+	return 1 << ((hash >> shift) & shiftBitMask)
+}
+
+func champIndex(bitmap, bit uint32) int {
+	return bits.OnesCount32(bitmap & (bit - 1))
+}
+
+// Maximum and minimum number of items in a B-tree node. The root is allowed to
+// contain fewer than btreeMinItems items.
+const btreeMaxItems = 2*btreeMinItems + 1
+const btreeMinItems = 15
+
+// btreeCapacity returns the maximum number of items in a B-tree of the given height.
+func btreeCapacity(height int) int {
+	capacity := btreeMaxItems
+	for ; height > 0; height-- {
+		capacity = capacity*(btreeMaxItems+1) + btreeMaxItems
+	}
+
+	return capacity
+}
+
+// Maximum ratio between the lengths of the front and back lists of a deque.
+const dequeBalance = 3
+
+// jsonObjectKey returns key encoded as a JSON object key using the same rules as
+// encoding/json uses for map keys.
+func jsonObjectKey(key interface{}) ([]byte, error) {
+	v := reflect.ValueOf(key)
+	if v.Kind() == reflect.String {
+		return json.Marshal(v.String())
+	}
+
+	if tm, ok := key.(encoding.TextMarshaler); ok {
+		text, err := tm.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(string(text))
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return json.Marshal(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return json.Marshal(strconv.FormatUint(v.Uint(), 10))
+	}
+
+	return nil, fmt.Errorf("unsupported JSON object key type %T", key)
+}
+
+// jsonObjectKeys returns true if keys of the type pointed to by keyPtr are encoded as
+// JSON object keys, that is if encoding/json would accept them as map keys. Other keys
+// are encoded as arrays of [key, value] pairs.
+func jsonObjectKeys(keyPtr interface{}) bool {
+	t := reflect.TypeOf(keyPtr).Elem()
+	switch t.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+
+	return t.Implements(reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem())
+}
+
+// jsonDecodeObjectKey decodes s, a JSON object key, into the value pointed to by key
+// using the same rules as encoding/json uses for map keys.
+func jsonDecodeObjectKey(s string, key interface{}) error {
+	if tu, ok := key.(encoding.TextUnmarshaler); ok {
+		return tu.UnmarshalText([]byte(s))
+	}
+
+	v := reflect.ValueOf(key).Elem()
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || v.OverflowInt(n) {
+			return fmt.Errorf("invalid JSON object key %q for type %s", s, v.Type())
+		}
+
+		v.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil || v.OverflowUint(n) {
+			return fmt.Errorf("invalid JSON object key %q for type %s", s, v.Type())
+		}
+
+		v.SetUint(n)
+		return nil
+	}
+
+	return fmt.Errorf("unsupported JSON object key type %s", v.Type())
+}
+
+// jsonEncodeMap returns the JSON encoding of the entries passed by rangeEntries to its
+// argument. The entries are encoded as an object if objectKeys is true, otherwise as an
+// array of [key, value] arrays. Pointers to the keys and values are passed so that
+// MarshalJSON methods with pointer receivers, such as those of the containers, are used.
+func jsonEncodeMap(objectKeys bool, rangeEntries func(entry func(key, value interface{}) bool)) ([]byte, error) {
+	start, sep, end := byte('['), byte(','), byte(']')
+	if objectKeys {
+		start, sep, end = '{', ':', '}'
+	}
+
+	result := []byte{start}
+	var err error
+	rangeEntries(func(key, value interface{}) bool {
+		var k, v []byte
+		if objectKeys {
+			k, err = jsonObjectKey(reflect.ValueOf(key).Elem().Interface())
+		} else {
+			k, err = json.Marshal(key)
+		}
+
+		if err != nil {
+			return false
+		}
+
+		if v, err = json.Marshal(value); err != nil {
+			return false
+		}
+
+		if len(result) > 1 {
+			result = append(result, ',')
+		}
+
+		if !objectKeys {
+			result = append(result, '[')
+		}
+
+		result = append(append(append(result, k...), sep), v...)
+		if !objectKeys {
+			result = append(result, ']')
+		}
+
+		return true
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(result, end), nil
+}
+
+// jsonDecodeArray decodes data, a JSON array or null, calling decodeItem with a decoder
+// positioned at each element of the array.
+func jsonDecodeArray(data []byte, decodeItem func(dec *json.Decoder) error) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil || tok == nil {
+		return err
+	}
+
+	if tok != json.Delim('[') {
+		return fmt.Errorf("expected a JSON array, got %v", tok)
+	}
+
+	for dec.More() {
+		if err := decodeItem(dec); err != nil {
+			return err
+		}
+	}
+
+	_, err = dec.Token()
+	return err
+}
+
+// jsonDecodeMap decodes data, a JSON object, an array of [key, value] arrays or null.
+// decodeEntry is called for each entry with a function that decodes the key into the
+// value pointed to by its argument, and a decoder positioned at the value.
+func jsonDecodeMap(data []byte, decodeEntry func(decodeKey func(key interface{}) error, dec *json.Decoder) error) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil || tok == nil {
+		return err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+
+			decodeKey := func(key interface{}) error { return jsonDecodeObjectKey(keyTok.(string), key) }
+			if err := decodeEntry(decodeKey, dec); err != nil {
+				return err
+			}
+		}
+	case json.Delim('['):
+		for dec.More() {
+			if err := jsonExpectDelim(dec, '['); err != nil {
+				return err
+			}
+
+			if err := decodeEntry(dec.Decode, dec); err != nil {
+				return err
+			}
+
+			if err := jsonExpectDelim(dec, ']'); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("expected a JSON object or array, got %v", tok)
+	}
+
+	_, err = dec.Token()
+	return err
+}
+
+func jsonExpectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	if tok != delim {
+		return fmt.Errorf("expected %v in JSON key value pair, got %v", delim, tok)
+	}
+
+	return nil
+}
+
+// binaryFormatVersion is the first byte of the binary encoding of containers.
+const binaryFormatVersion = 1
+
+// binaryMarshal returns the binary encoding of a container with count items. The encoding
+// is a version byte, the number of items as a uvarint and the items in gob encoded chunks.
+// encodeChunks is called with a function gob encoding a chunk, a slice of items.
+func binaryMarshal(count int, encodeChunks func(encode func(chunk interface{}) error) error) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteByte(binaryFormatVersion)
+	var scratch [binary.MaxVarintLen64]byte
+	buf.Write(scratch[:binary.PutUvarint(scratch[:], uint64(count))])
+	if err := encodeChunks(gob.NewEncoder(buf).Encode); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// binaryUnmarshal decodes data written by binaryMarshal. decodeChunk is called with a function
+// gob decoding into its argument, a pointer to a slice of items, until all items have been
+// decoded. decodeChunk returns the number of items decoded.
+func binaryUnmarshal(data []byte, decodeChunk func(decode func(chunk interface{}) error) (int, error)) error {
+	r := bytes.NewReader(data)
+	version, err := r.ReadByte()
+	if err != nil {
+		return io.ErrUnexpectedEOF
+	}
+
+	if version != binaryFormatVersion {
+		return fmt.Errorf("unsupported binary format version %d", version)
+	}
+
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return io.ErrUnexpectedEOF
+	}
+
+	dec := gob.NewDecoder(r)
+	for decoded := uint64(0); decoded < count; {
+		n, err := decodeChunk(dec.Decode)
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if n == 0 || decoded+uint64(n) > count {
+			return fmt.Errorf("invalid binary encoding, chunk of %d items after %d of %d items", n, decoded, count)
+		}
+
+		decoded += uint64(n)
+	}
+
+	if r.Len() > 0 {
+		return fmt.Errorf("invalid binary encoding, %d bytes left after the last item", r.Len())
+	}
+
+	return nil
+}
 
 //////////////////////////
 //// Hash functions //////
@@ -144,6 +473,31 @@ func float32Hash(x float32) uint32 {
 	return uint32Hash(math.Float32bits(x))
 }
 
+// loadHash returns the container hash kept in cache, zero if it has not been computed yet.
+// Each version of a container has its own cache which is shared by all copies of it, zero
+// containers have no cache.
+func loadHash(cache *uint32) uint32 {
+	if cache == nil {
+		return 0
+	}
+
+	return atomic.LoadUint32(cache)
+}
+
+// cacheHash stores h, a computed container hash, in cache and returns it. Zero marks
+// hashes that have not been computed yet, computed hashes that are zero are stored as one.
+func cacheHash(cache *uint32, h uint32) uint32 {
+	if h == 0 {
+		h = 1
+	}
+
+	if cache != nil {
+		atomic.StoreUint32(cache, h)
+	}
+
+	return h
+}
+
 //template:VectorTemplate
 
 //////////////
@@ -157,10 +511,11 @@ type GenericVectorType struct {
 	root  commonNode
 	len   uint
 	shift uint
+	hash  *uint32
 }
 
 var emptyGenericVectorTypeTail = make([]GenericType, 0)
-var emptyGenericVectorType *GenericVectorType = &GenericVectorType{root: emptyCommonNode, shift: shiftSize, tail: emptyGenericVectorTypeTail}
+var emptyGenericVectorType *GenericVectorType = &GenericVectorType{root: emptyCommonNode, shift: shiftSize, tail: emptyGenericVectorTypeTail, hash: new(uint32)}
 
 // NewGenericVectorType returns a new GenericVectorType containing the items provided in items.
 func NewGenericVectorType(items ...GenericType) *GenericVectorType {
@@ -207,22 +562,25 @@ func (v *GenericVectorType) Set(i int, item GenericType) *GenericVectorType {
 		newTail := make([]GenericType, len(v.tail))
 		copy(newTail, v.tail)
 		newTail[i&shiftBitMask] = item
-		return &GenericVectorType{root: v.root, tail: newTail, len: v.len, shift: v.shift}
+		return &GenericVectorType{root: v.root, tail: newTail, len: v.len, shift: v.shift, hash: new(uint32)}
 	}
 
-	return &GenericVectorType{root: v.doAssoc(v.shift, v.root, uint(i), item), tail: v.tail, len: v.len, shift: v.shift}
+	return &GenericVectorType{root: v.doAssoc(v.shift, v.root, uint(i), item), tail: v.tail, len: v.len, shift: v.shift, hash: new(uint32)}
 }
 
 func (v *GenericVectorType) doAssoc(level uint, node commonNode, i uint, item GenericType) commonNode {
 	if level == 0 {
-		ret := make([]GenericType, nodeSize)
-		copy(ret, node.([]GenericType))
+		leaf := node.([]GenericType)
+		ret := make([]GenericType, len(leaf))
+		copy(ret, leaf)
 		ret[i&shiftBitMask] = item
 		return ret
 	}
 
-	ret := make([]commonNode, nodeSize)
-	copy(ret, node.([]commonNode))
+	// Copies keep the length of the original, children beyond it are added by pushTail
+	children := node.([]commonNode)
+	ret := make([]commonNode, len(children))
+	copy(ret, children)
 	subidx := (i >> level) & shiftBitMask
 	ret[subidx] = v.doAssoc(level-shiftSize, ret[subidx], i, item)
 	return ret
@@ -265,7 +623,7 @@ func (v *GenericVectorType) Append(item ...GenericType) *GenericVectorType {
 		newTail := make([]GenericType, 0, tailLen+batchLen)
 		newTail = append(newTail, result.tail...)
 		newTail = append(newTail, item[insertOffset:insertOffset+batchLen]...)
-		result = &GenericVectorType{root: result.root, tail: newTail, len: result.len + batchLen, shift: result.shift}
+		result = &GenericVectorType{root: result.root, tail: newTail, len: result.len + batchLen, shift: result.shift, hash: new(uint32)}
 		insertOffset += batchLen
 	}
 
@@ -285,13 +643,92 @@ func (v *GenericVectorType) pushLeafNode(node []GenericType) *GenericVectorType
 		newRoot = v.pushTail(v.shift, v.root, node)
 	}
 
-	return &GenericVectorType{root: newRoot, tail: v.tail, len: v.len, shift: newShift}
+	return &GenericVectorType{root: newRoot, tail: v.tail, len: v.len, shift: newShift, hash: new(uint32)}
+}
+
+// Pop returns a new vector with the last element removed, and the removed element.
+func (v *GenericVectorType) Pop() (*GenericVectorType, GenericType) {
+	if v.len == 0 {
+		panic("Pop on empty vector")
+	}
+
+	item := v.tail[len(v.tail)-1]
+	return v.DropLast(1), item
+}
+
+// DropLast returns a new vector with the last n elements removed. Nodes no longer
+// needed are released rather than kept alive as they would be by Slice.
+func (v *GenericVectorType) DropLast(n int) *GenericVectorType {
+	if n < 0 || uint(n) > v.len {
+		panic("Index out of bounds")
+	}
+
+	if n == 0 {
+		return v
+	}
+
+	newLen := v.len - uint(n)
+	if newLen == 0 {
+		return emptyGenericVectorType
+	}
+
+	result := &GenericVectorType{len: newLen, hash: new(uint32)}
+	newTailOffset := result.tailOffset()
+	newTail := make([]GenericType, newLen-newTailOffset)
+	copy(newTail, v.sliceFor(newLen-1))
+	result.tail = newTail
+	if newTailOffset == 0 {
+		result.root, result.shift = emptyCommonNode, shiftSize
+		return result
+	}
+
+	result.root, result.shift = v.popTail(v.shift, v.root, newTailOffset-1), v.shift
+
+	// Root underflow?
+	for result.shift > shiftSize && len(result.root.([]commonNode)) == 1 {
+		result.root = result.root.([]commonNode)[0]
+		result.shift -= shiftSize
+	}
+
+	return result
+}
+
+// popTail returns a copy of the path from node down to the leaf holding element
+// last with all nodes after that path removed.
+func (v *GenericVectorType) popTail(level uint, node commonNode, last uint) commonNode {
+	subIdx := (last >> level) & shiftBitMask
+	ret := make([]commonNode, subIdx+1)
+	copy(ret, node.([]commonNode))
+	if level > shiftSize {
+		ret[subIdx] = v.popTail(level-shiftSize, ret[subIdx], last)
+	}
+
+	return ret
+}
+
+// DropFirst returns a new vector with the first n elements removed. Unlike Slice the
+// result does not share any structure with v, it takes time proportional to its length.
+func (v *GenericVectorType) DropFirst(n int) *GenericVectorType {
+	if n < 0 || uint(n) > v.len {
+		panic("Index out of bounds")
+	}
+
+	if n == 0 {
+		return v
+	}
+
+	t := emptyGenericVectorType.AsTransient()
+	for i := uint(n); i < v.len; i = (i | shiftBitMask) + 1 {
+		t.Append(v.sliceFor(i)[i&shiftBitMask:]...)
+	}
+
+	return t.Persistent()
 }
 
 // Slice returns a GenericVectorTypeSlice that refers to all elements [start,stop) in v.
 func (v *GenericVectorType) Slice(start, stop int) *GenericVectorTypeSlice {
 	assertSliceOk(start, stop, v.Len())
-	return &GenericVectorTypeSlice{vector: v, start: start, stop: stop}
+	return &GenericVectorTypeSlice{vector: v, start: start, stop: stop, hash: new(uint32)}
 }
 
 // Len returns the length of v.
@@ -324,419 +761,1575 @@ func (v *GenericVectorType) ToNativeSlice() []GenericType {
 	return result
 }
 
-//template:SliceTemplate
-
-////////////////
-//// Slice /////
-////////////////
-
-// GenericVectorTypeSlice is a slice type backed by a GenericVectorType.
-type GenericVectorTypeSlice struct {
-	vector      *GenericVectorType
-	start, stop int
+// MarshalJSON encodes v as a JSON array.
+func (v *GenericVectorType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.ToNativeSlice())
 }
 
-// NewGenericVectorTypeSlice returns a new NewGenericVectorTypeSlice containing the items provided in items.
-func NewGenericVectorTypeSlice(items ...GenericType) *GenericVectorTypeSlice {
-	return &GenericVectorTypeSlice{vector: emptyGenericVectorType.Append(items...), start: 0, stop: len(items)}
-}
+// UnmarshalJSON sets v to the elements of data, a JSON array. v must not be used by
+// anyone else when this is called, it is meant to be used by encoding/json only.
+func (v *GenericVectorType) UnmarshalJSON(data []byte) error {
+	t := emptyGenericVectorType.AsTransient()
+	err := jsonDecodeArray(data, func(dec *json.Decoder) error {
+		var item GenericType
+		if err := dec.Decode(&item); err != nil {
+			return err
+		}
 
-// Len returns the length of s.
-func (s *GenericVectorTypeSlice) Len() int {
-	return s.stop - s.start
-}
+		t.Append(item)
+		return nil
+	})
 
-// Get returns the element at position i.
-func (s *GenericVectorTypeSlice) Get(i int) GenericType {
-	if i < 0 || s.start+i >= s.stop {
-		panic("Index out of bounds")
+	if err != nil {
+		return err
 	}
 
-	return s.vector.Get(s.start + i)
+	*v = *t.Persistent()
+	return nil
 }
 
-// Set returns a new slice with the element at position i set to item.
-func (s *GenericVectorTypeSlice) Set(i int, item GenericType) *GenericVectorTypeSlice {
-	if i < 0 || s.start+i >= s.stop {
-		panic("Index out of bounds")
-	}
+// MarshalBinary returns the binary encoding of v. The items are gob encoded.
+func (v *GenericVectorType) MarshalBinary() ([]byte, error) {
+	return binaryMarshal(v.Len(), func(encode func(interface{}) error) error {
+		for i := 0; i < v.Len(); i += nodeSize {
+			if err := encode(v.sliceFor(uint(i))); err != nil {
+				return err
+			}
+		}
 
-	return s.vector.Set(s.start+i, item).Slice(s.start, s.stop)
+		return nil
+	})
 }
 
-// Append returns a new slice with item(s) appended to it.
-func (s *GenericVectorTypeSlice) Append(items ...GenericType) *GenericVectorTypeSlice {
-	newSlice := GenericVectorTypeSlice{vector: s.vector, start: s.start, stop: s.stop + len(items)}
+// UnmarshalBinary sets v to the vector encoded in data by MarshalBinary. v must not
+// be used by anyone else when this is called, decode into a new zero GenericVectorType.
+func (v *GenericVectorType) UnmarshalBinary(data []byte) error {
+	t := emptyGenericVectorType.AsTransient()
+	err := binaryUnmarshal(data, func(decode func(interface{}) error) (int, error) {
+		var chunk []GenericType
+		err := decode(&chunk)
+		t.Append(chunk...)
+		return len(chunk), err
+	})
 
-	// If this is v slice that has an upper bound that is lower than the backing
-	// vector then set the values in the backing vector to achieve some structural
-	// sharing.
-	itemPos := 0
-	for ; s.stop+itemPos < s.vector.Len() && itemPos < len(items); itemPos++ {
-		newSlice.vector = newSlice.vector.Set(s.stop+itemPos, items[itemPos])
+	if err != nil {
+		return err
 	}
 
-	// For the rest just append it to the underlying vector
-	newSlice.vector = newSlice.vector.Append(items[itemPos:]...)
-	return &newSlice
+	*v = *t.Persistent()
+	return nil
 }
 
-// Slice returns a GenericVectorTypeSlice that refers to all elements [start,stop) in s.
-func (s *GenericVectorTypeSlice) Slice(start, stop int) *GenericVectorTypeSlice {
-	assertSliceOk(start, stop, s.stop-s.start)
-	return &GenericVectorTypeSlice{vector: s.vector, start: s.start + start, stop: s.start + stop}
+// GobEncode implements gob.GobEncoder using the same encoding as MarshalBinary.
+func (v *GenericVectorType) GobEncode() ([]byte, error) {
+	return v.MarshalBinary()
 }
 
-// Range calls f repeatedly passing it each element in s in order as argument until either
-// all elements have been visited or f returns false.
-func (s *GenericVectorTypeSlice) Range(f func(GenericType) bool) {
-	var currentNode []GenericType
-	for i := uint(s.start); i < uint(s.stop); i++ {
-		if i&shiftBitMask == 0 || i == uint(s.start) {
-			currentNode = s.vector.sliceFor(uint(i))
-		}
+// GobDecode implements gob.GobDecoder using the same encoding as UnmarshalBinary.
+func (v *GenericVectorType) GobDecode(data []byte) error {
+	return v.UnmarshalBinary(data)
+}
 
-		if !f(currentNode[i&shiftBitMask]) {
-			return
+// EncodeSnapshot writes v to the snapshot stream of e. Nodes shared with containers
+// previously written to e are written as references to those.
+func (v *GenericVectorType) EncodeSnapshot(e *SnapshotEncoder) error {
+	return e.encode(snapshotVectorTag, func() error {
+		e.writeUvarint(uint64(v.len))
+		e.writeUvarint(uint64(v.shift))
+		if err := v.encodeSnapshotNode(e, v.shift, v.root); err != nil {
+			return err
 		}
-	}
+
+		return v.encodeSnapshotNode(e, 0, v.tail)
+	})
 }
 
-//template:PrivateMapTemplate
+func (v *GenericVectorType) encodeSnapshotNode(e *SnapshotEncoder, level uint, node commonNode) error {
+	if level == 0 {
+		leaf := node.([]GenericType)
+		var key interface{}
+		if len(leaf) > 0 {
+			key = snapshotNodeKey{first: &leaf[0], len: len(leaf)}
+		}
 
-///////////
-/// Map ///
-///////////
+		return e.writeNode(key, func() error {
+			return e.writeElements(leaf)
+		})
+	}
 
-//////////////////////
-/// Backing vector ///
-//////////////////////
+	children := node.([]commonNode)
+	var key interface{}
+	if len(children) > 0 {
+		key = snapshotNodeKey{first: &children[0], len: len(children)}
+	}
 
-type privateGenericMapItemBucketVector struct {
-	tail  []privateGenericMapItemBucket
-	root  commonNode
-	len   uint
-	shift uint
-}
+	return e.writeNode(key, func() error {
+		e.writeUvarint(uint64(len(children)))
+		for _, child := range children {
+			if err := v.encodeSnapshotNode(e, level-shiftSize, child); err != nil {
+				return err
+			}
+		}
 
-type GenericMapItem struct {
-	Key   GenericMapKeyType
-	Value GenericMapValueType
+		return nil
+	})
 }
 
-type privateGenericMapItemBucket []GenericMapItem
+// DecodeSnapshot sets v to the next container in the snapshot stream of d, which must
+// be a vector. io.EOF is returned if there are no more containers in the stream. v must
+// not be used by anyone else when this is called, decode into a new zero GenericVectorType.
+func (v *GenericVectorType) DecodeSnapshot(d *SnapshotDecoder) error {
+	return d.decode(snapshotVectorTag, func() error {
+		length, err := d.readUvarint()
+		if err != nil {
+			return err
+		}
 
-var emptyGenericMapItemBucketVectorTail = make([]privateGenericMapItemBucket, 0)
-var emptyGenericMapItemBucketVector *privateGenericMapItemBucketVector = &privateGenericMapItemBucketVector{root: emptyCommonNode, shift: shiftSize, tail: emptyGenericMapItemBucketVectorTail}
+		shift, err := d.readUvarint()
+		if err != nil {
+			return err
+		}
 
-func (v *privateGenericMapItemBucketVector) Get(i int) privateGenericMapItemBucket {
-	if i < 0 || uint(i) >= v.len {
-		panic("Index out of bounds")
-	}
+		if shift == 0 || shift%shiftSize != 0 || shift > 64 {
+			return fmt.Errorf("invalid snapshot, vector shift %d", shift)
+		}
 
-	return v.sliceFor(uint(i))[i&shiftBitMask]
-}
+		// The root holds all but the elements in the tail, it has at least two children
+		// unless it is at the lowest level
+		result := GenericVectorType{len: uint(length), shift: uint(shift), hash: new(uint32)}
+		rootLen := uint64(result.tailOffset())
+		if (shift > shiftSize && rootLen <= 1<<shift) || vectorSnapshotChildCount(uint(shift), rootLen) > nodeSize {
+			return fmt.Errorf("invalid snapshot, vector shift %d in vector of length %d", shift, length)
+		}
 
-func (v *privateGenericMapItemBucketVector) sliceFor(i uint) []privateGenericMapItemBucket {
-	if i >= v.tailOffset() {
-		return v.tail
+		root, err := decodeGenericVectorTypeSnapshotNode(d, uint(shift), rootLen)
+		if err != nil {
+			return err
+		}
+
+		tail, err := decodeGenericVectorTypeSnapshotNode(d, 0, length-rootLen)
+		if err != nil {
+			return err
+		}
+
+		result.root, result.tail = root, tail.([]GenericType)
+		*v = result
+		return nil
+	})
+}
+
+// decodeGenericVectorTypeSnapshotNode reads a node at level holding count elements. All
+// nodes but the rightmost ones on each level must be full.
+func decodeGenericVectorTypeSnapshotNode(d *SnapshotDecoder, level uint, count uint64) (commonNode, error) {
+	shared := true
+	node, err := d.readNode(func() (interface{}, error) {
+		shared = false
+		if level == 0 {
+			var leaf []GenericType
+			if err := d.readElements(&leaf); err != nil {
+				return nil, err
+			}
+
+			return leaf, checkVectorSnapshotNodeLen(len(leaf), count)
+		}
+
+		n, err := d.readLength(nodeSize)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := checkVectorSnapshotNodeLen(n, vectorSnapshotChildCount(level, count)); err != nil {
+			return nil, err
+		}
+
+		children := make([]commonNode, n)
+		for i := range children {
+			childCount := uint64(1) << level
+			if i == n-1 {
+				childCount = count - uint64(i)<<level
+			}
+
+			if children[i], err = decodeGenericVectorTypeSnapshotNode(d, level-shiftSize, childCount); err != nil {
+				return nil, err
+			}
+		}
+
+		return children, nil
+	})
+
+	if err != nil {
+		return nil, err
 	}
 
-	node := v.root
-	for level := v.shift; level > 0; level -= shiftSize {
-		node = node.([]commonNode)[(i>>level)&shiftBitMask]
+	if !shared {
+		return node, nil
+	}
+
+	// The nodes left of the rightmost path of a shared node are full, that was checked
+	// when it was first read. Only the rightmost path has to be checked against count.
+	for n := node; ; level -= shiftSize {
+		switch typed := n.(type) {
+		case []GenericType:
+			if level == 0 {
+				if err := checkVectorSnapshotNodeLen(len(typed), count); err != nil {
+					return nil, err
+				}
+
+				return node, nil
+			}
+		case []commonNode:
+			if level > 0 {
+				if err := checkVectorSnapshotNodeLen(len(typed), vectorSnapshotChildCount(level, count)); err != nil {
+					return nil, err
+				}
+
+				if len(typed) == 0 {
+					return node, nil
+				}
+
+				count -= uint64(len(typed)-1) << level
+				n = typed[len(typed)-1]
+				continue
+			}
+		}
+
+		return nil, invalidSnapshotNode(n)
+	}
+}
+
+/////////////////
+/// Transient ///
+/////////////////
+
+// GenericVectorTypeTransient is a mutable builder for GenericVectorType. Nodes created by
+// the transient are owned by it and edited in place rather than copied. Call Persistent
+// to turn it into a GenericVectorType, the transient cannot be used after that.
+type GenericVectorTypeTransient struct {
+	tail  []GenericType
+	root  commonNode
+	len   uint
+	shift uint
+	owner *transientOwner
+}
+
+// AsTransient returns a transient containing all elements of v. v is left untouched.
+func (v *GenericVectorType) AsTransient() *GenericVectorTypeTransient {
+	tail := make([]GenericType, len(v.tail), nodeSize)
+	copy(tail, v.tail)
+	return &GenericVectorTypeTransient{
+		tail:  tail,
+		root:  v.root,
+		len:   v.len,
+		shift: v.shift,
+		owner: &transientOwner{}}
+}
+
+// Persistent returns a GenericVectorType containing all elements of t. t cannot be used
+// after this call.
+func (t *GenericVectorTypeTransient) Persistent() *GenericVectorType {
+	assertTransientEditable(t.owner != nil)
+	t.owner = nil
+	return &GenericVectorType{tail: t.tail, root: t.root, len: t.len, shift: t.shift, hash: new(uint32)}
+}
+
+// Len returns the length of t.
+func (t *GenericVectorTypeTransient) Len() int {
+	assertTransientEditable(t.owner != nil)
+	return int(t.len)
+}
+
+func (t *GenericVectorTypeTransient) tailOffset() uint {
+	if t.len < nodeSize {
+		return 0
+	}
+
+	return ((t.len - 1) >> shiftSize) << shiftSize
+}
+
+// Get returns the element at position i.
+func (t *GenericVectorTypeTransient) Get(i int) GenericType {
+	assertTransientEditable(t.owner != nil)
+	if i < 0 || uint(i) >= t.len {
+		panic("Index out of bounds")
+	}
+
+	if uint(i) >= t.tailOffset() {
+		return t.tail[i&shiftBitMask]
+	}
+
+	node := t.root
+	for level := t.shift; level > 0; level -= shiftSize {
+		node = node.([]commonNode)[(uint(i)>>level)&shiftBitMask]
+	}
+
+	return node.([]GenericType)[i&shiftBitMask]
+}
+
+// nodeOwner returns the owner of node if node is owned by t, otherwise nil.
+func (t *GenericVectorTypeTransient) nodeOwner(node []commonNode) *vectorNodeOwner {
+	if cap(node) > nodeSize {
+		if owner, ok := node[:nodeSize+1][nodeSize].(*vectorNodeOwner); ok && owner.transient == t.owner {
+			return owner
+		}
+	}
+
+	return nil
+}
+
+// editableNode returns node if it is owned by t, otherwise an owned copy of it.
+func (t *GenericVectorTypeTransient) editableNode(node []commonNode) []commonNode {
+	if t.nodeOwner(node) != nil {
+		return node
+	}
+
+	newNode := make([]commonNode, len(node), nodeSize+1)
+	copy(newNode, node)
+	newNode[:nodeSize+1][nodeSize] = &vectorNodeOwner{transient: t.owner}
+	return newNode
+}
+
+// editableLeaf returns the leaf at position ix of parent, a node owned by t, after
+// replacing it with an owned copy unless it is owned by t already.
+func (t *GenericVectorTypeTransient) editableLeaf(parent []commonNode, ix uint) []GenericType {
+	owner := t.nodeOwner(parent)
+	leaf := parent[ix].([]GenericType)
+	if owner.leaves&(1<<ix) != 0 {
+		return leaf
+	}
+
+	newLeaf := make([]GenericType, len(leaf))
+	copy(newLeaf, leaf)
+	parent[ix] = newLeaf
+	owner.leaves |= 1 << ix
+	return newLeaf
+}
+
+// Set sets the element at position i to item.
+func (t *GenericVectorTypeTransient) Set(i int, item GenericType) {
+	assertTransientEditable(t.owner != nil)
+	if i < 0 || uint(i) >= t.len {
+		panic("Index out of bounds")
+	}
+
+	if uint(i) >= t.tailOffset() {
+		t.tail[i&shiftBitMask] = item
+		return
+	}
+
+	t.root = t.doAssoc(t.shift, t.root, uint(i), item)
+}
+
+func (t *GenericVectorTypeTransient) doAssoc(level uint, node commonNode, i uint, item GenericType) commonNode {
+	ret := t.editableNode(node.([]commonNode))
+	subidx := (i >> level) & shiftBitMask
+	if level == shiftSize {
+		t.editableLeaf(ret, subidx)[i&shiftBitMask] = item
+		return ret
+	}
+
+	ret[subidx] = t.doAssoc(level-shiftSize, ret[subidx], i, item)
+	return ret
+}
+
+// Append adds item(s) to the end of t.
+func (t *GenericVectorTypeTransient) Append(items ...GenericType) {
+	assertTransientEditable(t.owner != nil)
+	for _, item := range items {
+		if uint(len(t.tail)) == nodeSize {
+			t.pushLeafNode(t.tail)
+			t.tail = make([]GenericType, 0, nodeSize)
+		}
+
+		t.tail = append(t.tail, item)
+		t.len++
+	}
+}
+
+func (t *GenericVectorTypeTransient) newPath(shift uint, node commonNode) commonNode {
+	if shift == 0 {
+		return node
+	}
+
+	newNode := append(t.editableNode(nil), node)
+	if _, ok := node.([]GenericType); ok {
+		t.nodeOwner(newNode).leaves = 1
+	}
+
+	return t.newPath(shift-shiftSize, commonNode(newNode))
+}
+
+func (t *GenericVectorTypeTransient) pushTail(level uint, parent commonNode, tailNode []GenericType) commonNode {
+	subIdx := ((t.len - 1) >> level) & shiftBitMask
+	ret := t.editableNode(parent.([]commonNode))
+	var nodeToInsert commonNode
+
+	if level == shiftSize {
+		nodeToInsert = tailNode
+		t.nodeOwner(ret).leaves |= 1 << subIdx
+	} else if subIdx < uint(len(ret)) {
+		nodeToInsert = t.pushTail(level-shiftSize, ret[subIdx], tailNode)
+	} else {
+		nodeToInsert = t.newPath(level-shiftSize, tailNode)
+	}
+
+	if subIdx < uint(len(ret)) {
+		ret[subIdx] = nodeToInsert
+		return ret
+	}
+
+	return append(ret, nodeToInsert)
+}
+
+func (t *GenericVectorTypeTransient) pushLeafNode(node []GenericType) {
+	// Root overflow?
+	if (t.len >> shiftSize) > (1 << t.shift) {
+		newRoot := t.editableNode(nil)
+		t.root = commonNode(append(newRoot, t.root, t.newPath(t.shift, node)))
+		t.shift += shiftSize
+	} else {
+		t.root = t.pushTail(t.shift, t.root, node)
+	}
+}
+
+//template:SliceTemplate
+
+////////////////
+//// Slice /////
+////////////////
+
+// GenericVectorTypeSlice is a slice type backed by a GenericVectorType.
+type GenericVectorTypeSlice struct {
+	vector      *GenericVectorType
+	start, stop int
+	hash        *uint32
+}
+
+// NewGenericVectorTypeSlice returns a new NewGenericVectorTypeSlice containing the items provided in items.
+func NewGenericVectorTypeSlice(items ...GenericType) *GenericVectorTypeSlice {
+	return &GenericVectorTypeSlice{vector: emptyGenericVectorType.Append(items...), start: 0, stop: len(items), hash: new(uint32)}
+}
+
+// Len returns the length of s.
+func (s *GenericVectorTypeSlice) Len() int {
+	return s.stop - s.start
+}
+
+// Get returns the element at position i.
+func (s *GenericVectorTypeSlice) Get(i int) GenericType {
+	if i < 0 || s.start+i >= s.stop {
+		panic("Index out of bounds")
+	}
+
+	return s.vector.Get(s.start + i)
+}
+
+// Set returns a new slice with the element at position i set to item.
+func (s *GenericVectorTypeSlice) Set(i int, item GenericType) *GenericVectorTypeSlice {
+	if i < 0 || s.start+i >= s.stop {
+		panic("Index out of bounds")
+	}
+
+	return s.vector.Set(s.start+i, item).Slice(s.start, s.stop)
+}
+
+// Append returns a new slice with item(s) appended to it.
+func (s *GenericVectorTypeSlice) Append(items ...GenericType) *GenericVectorTypeSlice {
+	newSlice := GenericVectorTypeSlice{vector: s.vector, start: s.start, stop: s.stop + len(items), hash: new(uint32)}
+
+	// If this is v slice that has an upper bound that is lower than the backing
+	// vector then set the values in the backing vector to achieve some structural
+	// sharing.
+	itemPos := 0
+	for ; s.stop+itemPos < s.vector.Len() && itemPos < len(items); itemPos++ {
+		newSlice.vector = newSlice.vector.Set(s.stop+itemPos, items[itemPos])
+	}
+
+	// For the rest just append it to the underlying vector
+	newSlice.vector = newSlice.vector.Append(items[itemPos:]...)
+	return &newSlice
+}
+
+// Slice returns a GenericVectorTypeSlice that refers to all elements [start,stop) in s.
+func (s *GenericVectorTypeSlice) Slice(start, stop int) *GenericVectorTypeSlice {
+	assertSliceOk(start, stop, s.stop-s.start)
+	return &GenericVectorTypeSlice{vector: s.vector, start: s.start + start, stop: s.start + stop, hash: new(uint32)}
+}
+
+// Range calls f repeatedly passing it each element in s in order as argument until either
+// all elements have been visited or f returns false.
+func (s *GenericVectorTypeSlice) Range(f func(GenericType) bool) {
+	var currentNode []GenericType
+	for i := uint(s.start); i < uint(s.stop); i++ {
+		if i&shiftBitMask == 0 || i == uint(s.start) {
+			currentNode = s.vector.sliceFor(uint(i))
+		}
+
+		if !f(currentNode[i&shiftBitMask]) {
+			return
+		}
+	}
+}
+
+// MarshalJSON encodes s as a JSON array.
+func (s *GenericVectorTypeSlice) MarshalJSON() ([]byte, error) {
+	items := make([]GenericType, 0, s.Len())
+	s.Range(func(item GenericType) bool {
+		items = append(items, item)
+		return true
+	})
+
+	return json.Marshal(items)
+}
+
+// UnmarshalJSON sets s to the elements of data, a JSON array. s must not be used by
+// anyone else when this is called, it is meant to be used by encoding/json only.
+func (s *GenericVectorTypeSlice) UnmarshalJSON(data []byte) error {
+	v := &GenericVectorType{}
+	if err := v.UnmarshalJSON(data); err != nil {
+		return err
+	}
+
+	*s = GenericVectorTypeSlice{vector: v, start: 0, stop: v.Len(), hash: new(uint32)}
+	return nil
+}
+
+// MarshalBinary returns the binary encoding of s, the same as that of a vector with
+// the items of s. The items are gob encoded.
+func (s *GenericVectorTypeSlice) MarshalBinary() ([]byte, error) {
+	return binaryMarshal(s.Len(), func(encode func(interface{}) error) error {
+		chunk := make([]GenericType, 0, nodeSize)
+		var err error
+		s.Range(func(item GenericType) bool {
+			chunk = append(chunk, item)
+			if len(chunk) == nodeSize {
+				err = encode(chunk)
+				chunk = chunk[:0]
+			}
+
+			return err == nil
+		})
+
+		if err == nil && len(chunk) > 0 {
+			err = encode(chunk)
+		}
+
+		return err
+	})
+}
+
+// UnmarshalBinary sets s to the items encoded in data by MarshalBinary. s must not
+// be used by anyone else when this is called, decode into a new zero GenericVectorTypeSlice.
+func (s *GenericVectorTypeSlice) UnmarshalBinary(data []byte) error {
+	v := &GenericVectorType{}
+	if err := v.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	*s = GenericVectorTypeSlice{vector: v, start: 0, stop: v.Len(), hash: new(uint32)}
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder using the same encoding as MarshalBinary.
+func (s *GenericVectorTypeSlice) GobEncode() ([]byte, error) {
+	return s.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder using the same encoding as UnmarshalBinary.
+func (s *GenericVectorTypeSlice) GobDecode(data []byte) error {
+	return s.UnmarshalBinary(data)
+}
+
+//template:VectorHashTemplate
+
+// Equals returns true if v and other contain equal elements in the same order, false
+// otherwise. Parts of the trie shared between v and other are not compared.
+func (v *GenericVectorType) Equals(other *GenericVectorType) bool {
+	if v == other {
+		return true
+	}
+
+	if v.len != other.len {
+		return false
+	}
+
+	for i := uint(0); i < v.len; i += nodeSize {
+		leaf, otherLeaf := v.sliceFor(i), other.sliceFor(i)
+		if &leaf[0] == &otherLeaf[0] {
+			continue
+		}
+
+		for j := uint(0); j < uintMin(nodeSize, v.len-i); j++ {
+			if !genericElementEqual(&leaf[j], &otherLeaf[j]) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Hash returns a hash of the elements in v. Vectors that are equal according to Equals
+// have the same hash. The hash is computed the first time it is needed and is then kept
+// for v and all copies of it, including copies stored in other containers.
+func (v *GenericVectorType) Hash() uint32 {
+	if h := loadHash(v.hash); h != 0 {
+		return h
+	}
+
+	h := uint32(1)
+	for i := uint(0); i < v.len; i += nodeSize {
+		leaf := v.sliceFor(i)
+		for j := uint(0); j < uintMin(nodeSize, v.len-i); j++ {
+			h = 31*h + genericElementHash(&leaf[j])
+		}
+	}
+
+	return cacheHash(v.hash, h)
+}
+
+// Equals returns true if s and other contain equal elements in the same order, false otherwise.
+func (s *GenericVectorTypeSlice) Equals(other *GenericVectorTypeSlice) bool {
+	if s.Len() != other.Len() {
+		return false
+	}
+
+	for i := 0; i < s.Len(); i++ {
+		a, b := s.Get(i), other.Get(i)
+		if !genericElementEqual(&a, &b) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Hash returns a hash of the elements in s. Slices that are equal according to Equals
+// have the same hash, which is also the hash of a GenericVectorType with the same elements.
+// The hash is computed the first time it is needed and is then kept for s and all copies of it.
+func (s *GenericVectorTypeSlice) Hash() uint32 {
+	if h := loadHash(s.hash); h != 0 {
+		return h
+	}
+
+	h := uint32(1)
+	s.Range(func(item GenericType) bool {
+		h = 31*h + genericElementHash(&item)
+		return true
+	})
+
+	return cacheHash(s.hash, h)
+}
+
+//template:DequeTemplate
+
+/////////////
+/// Deque ///
+/////////////
+
+// privateGenericDequeTypeList is a, possibly lazily evaluated, list of elements. A lazy
+// list is evaluated at most once, the first time it is forced, after which the result
+// is shared by all deques referring to it. A nil list is the empty list.
+type privateGenericDequeTypeList struct {
+	item GenericType
+	next *privateGenericDequeTypeList
+
+	// The fields above, and empty, are only valid once a lazy list has been forced
+	lazy    bool
+	once    sync.Once
+	compute func() (GenericType, *privateGenericDequeTypeList, bool)
+	empty   bool
+}
+
+// force evaluates l, if needed, and returns false if l is empty.
+func (l *privateGenericDequeTypeList) force() bool {
+	if l == nil {
+		return false
+	}
+
+	if l.lazy {
+		l.once.Do(l.evaluate)
+	}
+
+	return !l.empty
+}
+
+func (l *privateGenericDequeTypeList) evaluate() {
+	var ok bool
+	l.item, l.next, ok = l.compute()
+	l.empty, l.compute = !ok, nil
+}
+
+func privateGenericDequeTypeCons(item GenericType, l *privateGenericDequeTypeList) *privateGenericDequeTypeList {
+	return &privateGenericDequeTypeList{item: item, next: l}
+}
+
+func privateGenericDequeTypeLazy(compute func() (GenericType, *privateGenericDequeTypeList, bool)) *privateGenericDequeTypeList {
+	return &privateGenericDequeTypeList{lazy: true, compute: compute}
+}
+
+// privateGenericDequeTypeTake returns the first n elements of l, n must not be larger
+// than the length of l.
+func privateGenericDequeTypeTake(n uint, l *privateGenericDequeTypeList) *privateGenericDequeTypeList {
+	if n == 0 {
+		return nil
+	}
+
+	return privateGenericDequeTypeLazy(func() (GenericType, *privateGenericDequeTypeList, bool) {
+		l.force()
+		return l.item, privateGenericDequeTypeTake(n-1, l.next), true
+	})
+}
+
+// privateGenericDequeTypeAppend returns the elements of l1 followed by those of l2.
+func privateGenericDequeTypeAppend(l1, l2 *privateGenericDequeTypeList) *privateGenericDequeTypeList {
+	if l1 == nil {
+		return l2
+	}
+
+	return privateGenericDequeTypeLazy(func() (GenericType, *privateGenericDequeTypeList, bool) {
+		if !l1.force() {
+			if !l2.force() {
+				var item GenericType
+				return item, nil, false
+			}
+
+			return l2.item, l2.next, true
+		}
+
+		return l1.item, privateGenericDequeTypeAppend(l1.next, l2), true
+	})
+}
+
+// privateGenericDequeTypeDropReverse returns all but the first n elements of l in reverse
+// order. Unlike the other list functions the whole list is computed once forced.
+func privateGenericDequeTypeDropReverse(n uint, l *privateGenericDequeTypeList) *privateGenericDequeTypeList {
+	return privateGenericDequeTypeLazy(func() (GenericType, *privateGenericDequeTypeList, bool) {
+		for ; n > 0; n-- {
+			l.force()
+			l = l.next
+		}
+
+		var result *privateGenericDequeTypeList
+		for ; l.force(); l = l.next {
+			result = privateGenericDequeTypeCons(l.item, result)
+		}
+
+		if result == nil {
+			var item GenericType
+			return item, nil, false
+		}
+
+		return result.item, result.next, true
+	})
+}
+
+// A GenericDequeType is a persistent/immutable double ended queue. All operations, except
+// for Range and ToNativeSlice, run in amortized constant time, also when old versions of
+// a deque are reused.
+//
+// The deque is a banker's deque as described by Okasaki. The elements are kept in a front
+// list and a reversed back list. When one of them grows too large compared to the other,
+// half of the elements are moved to the other list. The move is done lazily, piece by
+// piece, as elements are popped, which spreads the cost of it over those operations.
+type GenericDequeType struct {
+	front, back       *privateGenericDequeTypeList
+	frontLen, backLen uint
+}
+
+var emptyGenericDequeType = &GenericDequeType{}
+
+// NewGenericDequeType returns a new GenericDequeType containing the items provided in items,
+// with the first item at the front.
+func NewGenericDequeType(items ...GenericType) *GenericDequeType {
+	half := len(items) / 2
+	result := &GenericDequeType{frontLen: uint(half), backLen: uint(len(items) - half)}
+	for i := half - 1; i >= 0; i-- {
+		result.front = privateGenericDequeTypeCons(items[i], result.front)
+	}
+
+	for _, item := range items[half:] {
+		result.back = privateGenericDequeTypeCons(item, result.back)
+	}
+
+	return result
+}
+
+// newGenericDequeType returns a deque of front and back with the elements balanced
+// between the two lists.
+func newGenericDequeType(front *privateGenericDequeTypeList, frontLen uint, back *privateGenericDequeTypeList, backLen uint) *GenericDequeType {
+	size := frontLen + backLen
+	if frontLen > dequeBalance*backLen+1 {
+		half := size / 2
+		return &GenericDequeType{
+			front:    privateGenericDequeTypeTake(half, front),
+			frontLen: half,
+			back:     privateGenericDequeTypeAppend(back, privateGenericDequeTypeDropReverse(half, front)),
+			backLen:  size - half}
+	}
+
+	if backLen > dequeBalance*frontLen+1 {
+		half := size / 2
+		return &GenericDequeType{
+			front:    privateGenericDequeTypeAppend(front, privateGenericDequeTypeDropReverse(half, back)),
+			frontLen: size - half,
+			back:     privateGenericDequeTypeTake(half, back),
+			backLen:  half}
+	}
+
+	return &GenericDequeType{front: front, frontLen: frontLen, back: back, backLen: backLen}
+}
+
+// Len returns the number of elements in d.
+func (d *GenericDequeType) Len() int {
+	return int(d.frontLen + d.backLen)
+}
+
+// PushFront returns a new deque with item added to the front.
+func (d *GenericDequeType) PushFront(item GenericType) *GenericDequeType {
+	return newGenericDequeType(privateGenericDequeTypeCons(item, d.front), d.frontLen+1, d.back, d.backLen)
+}
+
+// PushBack returns a new deque with item added to the back.
+func (d *GenericDequeType) PushBack(item GenericType) *GenericDequeType {
+	return newGenericDequeType(d.front, d.frontLen, privateGenericDequeTypeCons(item, d.back), d.backLen+1)
+}
+
+// PopFront returns a new deque with the front element removed, and the removed element.
+func (d *GenericDequeType) PopFront() (*GenericDequeType, GenericType) {
+	if d.frontLen == 0 {
+		if d.backLen == 0 {
+			panic("Pop on empty deque")
+		}
+
+		// The balance between the lists guarantees that this is the only element
+		d.back.force()
+		return emptyGenericDequeType, d.back.item
+	}
+
+	d.front.force()
+	return newGenericDequeType(d.front.next, d.frontLen-1, d.back, d.backLen), d.front.item
+}
+
+// PopBack returns a new deque with the back element removed, and the removed element.
+func (d *GenericDequeType) PopBack() (*GenericDequeType, GenericType) {
+	if d.backLen == 0 {
+		if d.frontLen == 0 {
+			panic("Pop on empty deque")
+		}
+
+		// The balance between the lists guarantees that this is the only element
+		d.front.force()
+		return emptyGenericDequeType, d.front.item
+	}
+
+	d.back.force()
+	return newGenericDequeType(d.front, d.frontLen, d.back.next, d.backLen-1), d.back.item
+}
+
+// PeekFront returns the front element of d. ok is false if d is empty.
+func (d *GenericDequeType) PeekFront() (item GenericType, ok bool) {
+	l := d.front
+	if d.frontLen == 0 {
+		l = d.back
+	}
+
+	if !l.force() {
+		return item, false
+	}
+
+	return l.item, true
+}
+
+// PeekBack returns the back element of d. ok is false if d is empty.
+func (d *GenericDequeType) PeekBack() (item GenericType, ok bool) {
+	l := d.back
+	if d.backLen == 0 {
+		l = d.front
+	}
+
+	if !l.force() {
+		return item, false
+	}
+
+	return l.item, true
+}
+
+// Range calls f repeatedly passing it each element in d, from front to back, as argument
+// until either all elements have been visited or f returns false.
+func (d *GenericDequeType) Range(f func(GenericType) bool) {
+	for l := d.front; l.force(); l = l.next {
+		if !f(l.item) {
+			return
+		}
+	}
+
+	back := d.backSlice()
+	for i := len(back) - 1; i >= 0; i-- {
+		if !f(back[i]) {
+			return
+		}
+	}
+}
+
+// backSlice returns the elements of the back list, from back to front.
+func (d *GenericDequeType) backSlice() []GenericType {
+	result := make([]GenericType, 0, d.backLen)
+	for l := d.back; l.force(); l = l.next {
+		result = append(result, l.item)
+	}
+
+	return result
+}
+
+// ToNativeSlice returns a Go slice containing all elements of d, from front to back.
+func (d *GenericDequeType) ToNativeSlice() []GenericType {
+	result := make([]GenericType, d.Len())
+	i := 0
+	for l := d.front; l.force(); l = l.next {
+		result[i] = l.item
+		i++
+	}
+
+	for l, j := d.back, len(result)-1; l.force(); l, j = l.next, j-1 {
+		result[j] = l.item
+	}
+
+	return result
+}
+
+// MarshalJSON encodes d as a JSON array, from front to back.
+func (d *GenericDequeType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.ToNativeSlice())
+}
+
+// UnmarshalJSON sets d to the elements of data, a JSON array. d must not be used by
+// anyone else when this is called, it is meant to be used by encoding/json only.
+func (d *GenericDequeType) UnmarshalJSON(data []byte) error {
+	result := emptyGenericDequeType
+	err := jsonDecodeArray(data, func(dec *json.Decoder) error {
+		var item GenericType
+		if err := dec.Decode(&item); err != nil {
+			return err
+		}
+
+		result = result.PushBack(item)
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	*d = *result
+	return nil
+}
+
+//template:PrivateMapTemplate
+
+///////////
+/// Map ///
+///////////
+
+type GenericMapItem struct {
+	Key   GenericMapKeyType
+	Value GenericMapValueType
+}
+
+// privateGenericMapItemNode is a node in a compressed hash-array mapped prefix tree (CHAMP).
+// Items stored directly in the node are kept in items and their positions are marked in
+// dataMap. Sub nodes are kept in children and their positions are marked in nodeMap. Nodes
+// below the last level of the hash are collision nodes that only contain items.
+type privateGenericMapItemNode struct {
+	dataMap  uint32
+	nodeMap  uint32
+	items    []GenericMapItem
+	children []*privateGenericMapItemNode
+	owner    *transientOwner
+}
+
+var emptyGenericMapItemNode = &privateGenericMapItemNode{}
+
+// editable returns n if it is owned by owner, otherwise a copy of n owned by owner.
+// owner is nil for persistent updates in which case a copy is always returned.
+func (n *privateGenericMapItemNode) editable(owner *transientOwner) *privateGenericMapItemNode {
+	if owner != nil && n.owner == owner {
+		return n
+	}
+
+	items := make([]GenericMapItem, len(n.items), len(n.items)+1)
+	copy(items, n.items)
+	children := make([]*privateGenericMapItemNode, len(n.children), len(n.children)+1)
+	copy(children, n.children)
+	return &privateGenericMapItemNode{dataMap: n.dataMap, nodeMap: n.nodeMap, items: items, children: children, owner: owner}
+}
+
+func (n *privateGenericMapItemNode) isSingleItem() bool {
+	return len(n.items) == 1 && len(n.children) == 0
+}
+
+func (n *privateGenericMapItemNode) insertItem(ix int, item GenericMapItem) {
+	var zeroItem GenericMapItem
+	n.items = append(n.items, zeroItem)
+	copy(n.items[ix+1:], n.items[ix:])
+	n.items[ix] = item
+}
+
+func (n *privateGenericMapItemNode) removeItem(ix int) {
+	var zeroItem GenericMapItem
+	copy(n.items[ix:], n.items[ix+1:])
+	n.items[len(n.items)-1] = zeroItem
+	n.items = n.items[:len(n.items)-1]
+}
+
+func (n *privateGenericMapItemNode) insertChild(ix int, child *privateGenericMapItemNode) {
+	n.children = append(n.children, nil)
+	copy(n.children[ix+1:], n.children[ix:])
+	n.children[ix] = child
+}
+
+func (n *privateGenericMapItemNode) removeChild(ix int) {
+	copy(n.children[ix:], n.children[ix+1:])
+	n.children[len(n.children)-1] = nil
+	n.children = n.children[:len(n.children)-1]
+}
+
+func (n *privateGenericMapItemNode) load(key GenericMapKeyType, hash uint32) (value GenericMapValueType, ok bool) {
+	for shift := uint(0); ; shift += shiftSize {
+		if shift >= champHashBits {
+			for _, item := range n.items {
+				if genericEqual(item.Key, key) {
+					return item.Value, true
+				}
+			}
+
+			break
+		}
+
+		bit := champBitpos(hash, shift)
+		if n.dataMap&bit != 0 {
+			item := n.items[champIndex(n.dataMap, bit)]
+			if genericEqual(item.Key, key) {
+				return item.Value, true
+			}
+
+			break
+		}
+
+		if n.nodeMap&bit == 0 {
+			break
+		}
+
+		n = n.children[champIndex(n.nodeMap, bit)]
+	}
+
+	var zeroValue GenericMapValueType
+	return zeroValue, false
+}
+
+// store returns a node with item stored in it and true if the item was added rather
+// than replacing an existing item.
+func (n *privateGenericMapItemNode) store(item GenericMapItem, hash uint32, shift uint, owner *transientOwner) (*privateGenericMapItemNode, bool) {
+	if shift >= champHashBits {
+		// Collision node
+		for ix, existing := range n.items {
+			if genericEqual(existing.Key, item.Key) {
+				newNode := n.editable(owner)
+				newNode.items[ix] = item
+				return newNode, false
+			}
+		}
+
+		newNode := n.editable(owner)
+		newNode.items = append(newNode.items, item)
+		return newNode, true
+	}
+
+	bit := champBitpos(hash, shift)
+	if n.dataMap&bit != 0 {
+		ix := champIndex(n.dataMap, bit)
+		existing := n.items[ix]
+		newNode := n.editable(owner)
+		if genericEqual(existing.Key, item.Key) {
+			newNode.items[ix] = item
+			return newNode, false
+		}
+
+		// Push both items down into a new sub node
+		child := newGenericMapItemNode(existing, genericHash(existing.Key), item, hash, shift+shiftSize, owner)
+		newNode.removeItem(ix)
+		newNode.dataMap &^= bit
+		newNode.nodeMap |= bit
+		newNode.insertChild(champIndex(newNode.nodeMap, bit), child)
+		return newNode, true
 	}
 
-	return node.([]privateGenericMapItemBucket)
-}
+	if n.nodeMap&bit != 0 {
+		ix := champIndex(n.nodeMap, bit)
+		child, added := n.children[ix].store(item, hash, shift+shiftSize, owner)
+		newNode := n
+		if child != n.children[ix] {
+			newNode = n.editable(owner)
+			newNode.children[ix] = child
+		}
 
-func (v *privateGenericMapItemBucketVector) tailOffset() uint {
-	if v.len < nodeSize {
-		return 0
+		return newNode, added
 	}
 
-	return ((v.len - 1) >> shiftSize) << shiftSize
+	newNode := n.editable(owner)
+	newNode.dataMap |= bit
+	newNode.insertItem(champIndex(newNode.dataMap, bit), item)
+	return newNode, true
 }
 
-func (v *privateGenericMapItemBucketVector) Set(i int, item privateGenericMapItemBucket) *privateGenericMapItemBucketVector {
-	if i < 0 || uint(i) >= v.len {
-		panic("Index out of bounds")
+func newGenericMapItemNode(item1 GenericMapItem, hash1 uint32, item2 GenericMapItem, hash2 uint32, shift uint, owner *transientOwner) *privateGenericMapItemNode {
+	if shift >= champHashBits {
+		return &privateGenericMapItemNode{items: []GenericMapItem{item1, item2}, owner: owner}
 	}
 
-	if uint(i) >= v.tailOffset() {
-		newTail := make([]privateGenericMapItemBucket, len(v.tail))
-		copy(newTail, v.tail)
-		newTail[i&shiftBitMask] = item
-		return &privateGenericMapItemBucketVector{root: v.root, tail: newTail, len: v.len, shift: v.shift}
+	bit1, bit2 := champBitpos(hash1, shift), champBitpos(hash2, shift)
+	if bit1 == bit2 {
+		child := newGenericMapItemNode(item1, hash1, item2, hash2, shift+shiftSize, owner)
+		return &privateGenericMapItemNode{nodeMap: bit1, children: []*privateGenericMapItemNode{child}, owner: owner}
 	}
 
-	return &privateGenericMapItemBucketVector{root: v.doAssoc(v.shift, v.root, uint(i), item), tail: v.tail, len: v.len, shift: v.shift}
+	items := []GenericMapItem{item1, item2}
+	if bit2 < bit1 {
+		items[0], items[1] = item2, item1
+	}
+
+	return &privateGenericMapItemNode{dataMap: bit1 | bit2, items: items, owner: owner}
 }
 
-func (v *privateGenericMapItemBucketVector) doAssoc(level uint, node commonNode, i uint, item privateGenericMapItemBucket) commonNode {
-	if level == 0 {
-		ret := make([]privateGenericMapItemBucket, nodeSize)
-		copy(ret, node.([]privateGenericMapItemBucket))
-		ret[i&shiftBitMask] = item
-		return ret
+// delete returns a node without key and true if the key was found. A node that only
+// contains a single item is returned as is to let the parent inline the item.
+func (n *privateGenericMapItemNode) delete(key GenericMapKeyType, hash uint32, shift uint, owner *transientOwner) (*privateGenericMapItemNode, bool) {
+	if shift >= champHashBits {
+		// Collision node
+		for ix, existing := range n.items {
+			if genericEqual(existing.Key, key) {
+				newNode := n.editable(owner)
+				newNode.removeItem(ix)
+				return newNode, true
+			}
+		}
+
+		return n, false
 	}
 
-	ret := make([]commonNode, nodeSize)
-	copy(ret, node.([]commonNode))
-	subidx := (i >> level) & shiftBitMask
-	ret[subidx] = v.doAssoc(level-shiftSize, ret[subidx], i, item)
-	return ret
-}
+	bit := champBitpos(hash, shift)
+	if n.dataMap&bit != 0 {
+		ix := champIndex(n.dataMap, bit)
+		if !genericEqual(n.items[ix].Key, key) {
+			return n, false
+		}
 
-func (v *privateGenericMapItemBucketVector) pushTail(level uint, parent commonNode, tailNode []privateGenericMapItemBucket) commonNode {
-	subIdx := ((v.len - 1) >> level) & shiftBitMask
-	parentNode := parent.([]commonNode)
-	ret := make([]commonNode, subIdx+1)
-	copy(ret, parentNode)
-	var nodeToInsert commonNode
+		newNode := n.editable(owner)
+		newNode.removeItem(ix)
+		newNode.dataMap &^= bit
+		return newNode, true
+	}
 
-	if level == shiftSize {
-		nodeToInsert = tailNode
-	} else if subIdx < uint(len(parentNode)) {
-		nodeToInsert = v.pushTail(level-shiftSize, parentNode[subIdx], tailNode)
-	} else {
-		nodeToInsert = newPath(level-shiftSize, tailNode)
+	if n.nodeMap&bit != 0 {
+		ix := champIndex(n.nodeMap, bit)
+		child, deleted := n.children[ix].delete(key, hash, shift+shiftSize, owner)
+		if !deleted {
+			return n, false
+		}
+
+		if child.isSingleItem() {
+			if shift > 0 && len(n.items) == 0 && len(n.children) == 1 {
+				// Let the parent inline the remaining item
+				return child, true
+			}
+
+			// Inline the remaining item of the child in this node
+			newNode := n.editable(owner)
+			newNode.removeChild(ix)
+			newNode.nodeMap &^= bit
+			newNode.dataMap |= bit
+			newNode.insertItem(champIndex(newNode.dataMap, bit), child.items[0])
+			return newNode, true
+		}
+
+		newNode := n.editable(owner)
+		newNode.children[ix] = child
+		return newNode, true
 	}
 
-	ret[subIdx] = nodeToInsert
-	return ret
+	return n, false
 }
 
-func (v *privateGenericMapItemBucketVector) Append(item ...privateGenericMapItemBucket) *privateGenericMapItemBucketVector {
-	result := v
-	itemLen := uint(len(item))
-	for insertOffset := uint(0); insertOffset < itemLen; {
-		tailLen := result.len - result.tailOffset()
-		tailFree := nodeSize - tailLen
-		if tailFree == 0 {
-			result = result.pushLeafNode(result.tail)
-			result.tail = emptyGenericMapItemBucketVector.tail
-			tailFree = nodeSize
-			tailLen = 0
+func (n *privateGenericMapItemNode) rangeItems(f func(GenericMapKeyType, GenericMapValueType) bool) bool {
+	for _, item := range n.items {
+		if !f(item.Key, item.Value) {
+			return false
 		}
+	}
 
-		batchLen := uintMin(itemLen-insertOffset, tailFree)
-		newTail := make([]privateGenericMapItemBucket, 0, tailLen+batchLen)
-		newTail = append(newTail, result.tail...)
-		newTail = append(newTail, item[insertOffset:insertOffset+batchLen]...)
-		result = &privateGenericMapItemBucketVector{root: result.root, tail: newTail, len: result.len + batchLen, shift: result.shift}
-		insertOffset += batchLen
+	for _, child := range n.children {
+		if !child.rangeItems(f) {
+			return false
+		}
 	}
 
-	return result
+	return true
 }
 
-func (v *privateGenericMapItemBucketVector) pushLeafNode(node []privateGenericMapItemBucket) *privateGenericMapItemBucketVector {
-	var newRoot commonNode
-	newShift := v.shift
+// GenericMapType is a persistent key - value map
+type GenericMapType struct {
+	root *privateGenericMapItemNode
+	len  int
+	hash *uint32
+}
 
-	// Root overflow?
-	if (v.len >> shiftSize) > (1 << v.shift) {
-		newNode := newPath(v.shift, node)
-		newRoot = commonNode([]commonNode{v.root, newNode})
-		newShift = v.shift + shiftSize
-	} else {
-		newRoot = v.pushTail(v.shift, v.root, node)
+var emptyGenericMapType = &GenericMapType{root: emptyGenericMapItemNode, hash: new(uint32)}
+
+func newGenericMapType(items []GenericMapItem) *GenericMapType {
+	t := emptyGenericMapType.AsTransient()
+	for _, item := range items {
+		t.Store(item.Key, item.Value)
 	}
 
-	return &privateGenericMapItemBucketVector{root: newRoot, tail: v.tail, len: v.len, shift: newShift}
+	return t.Persistent()
 }
 
-func (v *privateGenericMapItemBucketVector) Len() int {
-	return int(v.len)
+// Len returns the number of items in m.
+func (m *GenericMapType) Len() int {
+	return int(m.len)
 }
 
-func (v *privateGenericMapItemBucketVector) Range(f func(privateGenericMapItemBucket) bool) {
-	var currentNode []privateGenericMapItemBucket
-	for i := uint(0); i < v.len; i++ {
-		if i&shiftBitMask == 0 {
-			currentNode = v.sliceFor(uint(i))
-		}
+// Load returns value identified by key. ok is set to true if key exists in the map, false otherwise.
+func (m *GenericMapType) Load(key GenericMapKeyType) (value GenericMapValueType, ok bool) {
+	return m.root.load(key, genericHash(key))
+}
 
-		if !f(currentNode[i&shiftBitMask]) {
-			return
-		}
+// Store returns a new GenericMapType containing value identified by key.
+func (m *GenericMapType) Store(key GenericMapKeyType, value GenericMapValueType) *GenericMapType {
+	root, added := m.root.store(GenericMapItem{Key: key, Value: value}, genericHash(key), 0, nil)
+	if added {
+		return &GenericMapType{root: root, len: m.len + 1, hash: new(uint32)}
 	}
-}
 
-// GenericMapType is a persistent key - value map
-type GenericMapType struct {
-	backingVector *privateGenericMapItemBucketVector
-	len           int
+	return &GenericMapType{root: root, len: m.len, hash: new(uint32)}
 }
 
-func (m *GenericMapType) pos(key GenericMapKeyType) int {
-	return int(uint64(genericHash(key)) % uint64(m.backingVector.Len()))
+// Delete returns a new GenericMapType without the element identified by key.
+func (m *GenericMapType) Delete(key GenericMapKeyType) *GenericMapType {
+	root, deleted := m.root.delete(key, genericHash(key), 0, nil)
+	if !deleted {
+		return m
+	}
+
+	return &GenericMapType{root: root, len: m.len - 1, hash: new(uint32)}
 }
 
-// Helper type used during map creation and reallocation
-type privateGenericMapItemBuckets struct {
-	buckets []privateGenericMapItemBucket
-	length  int
+// Range calls f repeatedly passing it each key and value as argument until either
+// all elements have been visited or f returns false.
+func (m *GenericMapType) Range(f func(GenericMapKeyType, GenericMapValueType) bool) {
+	m.root.rangeItems(f)
 }
 
-func newPrivateGenericMapItemBuckets(itemCount int) *privateGenericMapItemBuckets {
-	size := int(float64(itemCount)/initialMapLoadFactor) + 1
-	buckets := make([]privateGenericMapItemBucket, size)
-	return &privateGenericMapItemBuckets{buckets: buckets}
+// MarshalJSON encodes m as a JSON object if the keys are strings, integers or implement
+// encoding.TextMarshaler, following the same rules as encoding/json uses for map keys.
+// Other keys are encoded as an array of [key, value] arrays.
+func (m *GenericMapType) MarshalJSON() ([]byte, error) {
+	return jsonEncodeMap(jsonObjectKeys((*GenericMapKeyType)(nil)), func(entry func(key, value interface{}) bool) {
+		m.Range(func(key GenericMapKeyType, value GenericMapValueType) bool {
+			return entry(&key, &value)
+		})
+	})
 }
 
-func (b *privateGenericMapItemBuckets) AddItem(item GenericMapItem) {
-	ix := int(uint64(genericHash(item.Key)) % uint64(len(b.buckets)))
-	bucket := b.buckets[ix]
-	if bucket != nil {
-		// Hash collision, merge with existing bucket
-		for keyIx, bItem := range bucket {
-			if item.Key == bItem.Key {
-				bucket[keyIx] = item
-				return
-			}
+// UnmarshalJSON sets m to the items of data, either a JSON object or an array of [key, value]
+// arrays. m must not be used by anyone else when this is called, it is meant to be used by
+// encoding/json only.
+func (m *GenericMapType) UnmarshalJSON(data []byte) error {
+	t := emptyGenericMapType.AsTransient()
+	err := jsonDecodeMap(data, func(decodeKey func(interface{}) error, dec *json.Decoder) error {
+		var item GenericMapItem
+		if err := decodeKey(&item.Key); err != nil {
+			return err
 		}
 
-		b.buckets[ix] = append(bucket, GenericMapItem{Key: item.Key, Value: item.Value})
-		b.length++
-	} else {
-		bucket := make(privateGenericMapItemBucket, 0, int(math.Max(initialMapLoadFactor, 1.0)))
-		b.buckets[ix] = append(bucket, item)
-		b.length++
+		if err := dec.Decode(&item.Value); err != nil {
+			return err
+		}
+
+		t.Store(item.Key, item.Value)
+		return nil
+	})
+
+	if err != nil {
+		return err
 	}
+
+	*m = *t.Persistent()
+	return nil
 }
 
-func (b *privateGenericMapItemBuckets) AddItemsFromMap(m *GenericMapType) {
-	m.backingVector.Range(func(bucket privateGenericMapItemBucket) bool {
-		for _, item := range bucket {
-			b.AddItem(item)
+// MarshalBinary returns the binary encoding of m. The keys and values are gob encoded.
+func (m *GenericMapType) MarshalBinary() ([]byte, error) {
+	return binaryMarshal(m.Len(), func(encode func(interface{}) error) error {
+		chunk := make([]GenericMapItem, 0, nodeSize)
+		var err error
+		m.Range(func(key GenericMapKeyType, value GenericMapValueType) bool {
+			chunk = append(chunk, GenericMapItem{Key: key, Value: value})
+			if len(chunk) == nodeSize {
+				err = encode(chunk)
+				chunk = chunk[:0]
+			}
+
+			return err == nil
+		})
+
+		if err == nil && len(chunk) > 0 {
+			err = encode(chunk)
 		}
-		return true
+
+		return err
 	})
 }
 
-func newGenericMapType(items []GenericMapItem) *GenericMapType {
-	buckets := newPrivateGenericMapItemBuckets(len(items))
-	for _, item := range items {
-		buckets.AddItem(item)
+// UnmarshalBinary sets m to the map encoded in data by MarshalBinary. m must not
+// be used by anyone else when this is called, decode into a new zero GenericMapType.
+func (m *GenericMapType) UnmarshalBinary(data []byte) error {
+	t := emptyGenericMapType.AsTransient()
+	err := binaryUnmarshal(data, func(decode func(interface{}) error) (int, error) {
+		var chunk []GenericMapItem
+		err := decode(&chunk)
+		for _, item := range chunk {
+			t.Store(item.Key, item.Value)
+		}
+
+		return len(chunk), err
+	})
+
+	if err != nil {
+		return err
 	}
 
-	return &GenericMapType{backingVector: emptyGenericMapItemBucketVector.Append(buckets.buckets...), len: buckets.length}
+	*m = *t.Persistent()
+	return nil
 }
 
-// Len returns the number of items in m.
-func (m *GenericMapType) Len() int {
-	return int(m.len)
+// GobEncode implements gob.GobEncoder using the same encoding as MarshalBinary.
+func (m *GenericMapType) GobEncode() ([]byte, error) {
+	return m.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder using the same encoding as UnmarshalBinary.
+func (m *GenericMapType) GobDecode(data []byte) error {
+	return m.UnmarshalBinary(data)
+}
+
+/////////////////
+/// Transient ///
+/////////////////
+
+// GenericMapTypeTransient is a mutable builder for GenericMapType. Nodes created by the
+// transient are owned by it and edited in place rather than copied. Call Persistent
+// to turn it into a GenericMapType, the transient cannot be used after that.
+type GenericMapTypeTransient struct {
+	root  *privateGenericMapItemNode
+	len   int
+	owner *transientOwner
+}
+
+// AsTransient returns a transient containing all items of m. m is left untouched.
+func (m *GenericMapType) AsTransient() *GenericMapTypeTransient {
+	return &GenericMapTypeTransient{root: m.root, len: m.len, owner: &transientOwner{}}
+}
+
+// Persistent returns a GenericMapType containing all items of t. t cannot be used
+// after this call.
+func (t *GenericMapTypeTransient) Persistent() *GenericMapType {
+	assertTransientEditable(t.owner != nil)
+	t.owner = nil
+	return &GenericMapType{root: t.root, len: t.len, hash: new(uint32)}
+}
+
+// Len returns the number of items in t.
+func (t *GenericMapTypeTransient) Len() int {
+	assertTransientEditable(t.owner != nil)
+	return t.len
 }
 
 // Load returns value identified by key. ok is set to true if key exists in the map, false otherwise.
-func (m *GenericMapType) Load(key GenericMapKeyType) (value GenericMapValueType, ok bool) {
-	bucket := m.backingVector.Get(m.pos(key))
-	if bucket != nil {
-		for _, item := range bucket {
-			if item.Key == key {
-				return item.Value, true
-			}
-		}
+func (t *GenericMapTypeTransient) Load(key GenericMapKeyType) (value GenericMapValueType, ok bool) {
+	assertTransientEditable(t.owner != nil)
+	return t.root.load(key, genericHash(key))
+}
+
+// Store sets the value identified by key to value.
+func (t *GenericMapTypeTransient) Store(key GenericMapKeyType, value GenericMapValueType) {
+	assertTransientEditable(t.owner != nil)
+	root, added := t.root.store(GenericMapItem{Key: key, Value: value}, genericHash(key), 0, t.owner)
+	t.root = root
+	if added {
+		t.len++
 	}
+}
 
-	var zeroValue GenericMapValueType
-	return zeroValue, false
+// Delete removes the item identified by key.
+func (t *GenericMapTypeTransient) Delete(key GenericMapKeyType) {
+	assertTransientEditable(t.owner != nil)
+	root, deleted := t.root.delete(key, genericHash(key), 0, t.owner)
+	t.root = root
+	if deleted {
+		t.len--
+	}
 }
 
-// Store returns a new GenericMapType containing value identified by key.
-func (m *GenericMapType) Store(key GenericMapKeyType, value GenericMapValueType) *GenericMapType {
-	// Grow backing vector if load factor is too high
-	if m.Len() >= m.backingVector.Len()*int(upperMapLoadFactor) {
-		buckets := newPrivateGenericMapItemBuckets(m.Len() + 1)
-		buckets.AddItemsFromMap(m)
-		buckets.AddItem(GenericMapItem{Key: key, Value: value})
-		return &GenericMapType{backingVector: emptyGenericMapItemBucketVector.Append(buckets.buckets...), len: buckets.length}
-	}
-
-	pos := m.pos(key)
-	bucket := m.backingVector.Get(pos)
-	if bucket != nil {
-		for ix, item := range bucket {
-			if item.Key == key {
-				// Overwrite existing item
-				newBucket := make(privateGenericMapItemBucket, len(bucket))
-				copy(newBucket, bucket)
-				newBucket[ix] = GenericMapItem{Key: key, Value: value}
-				return &GenericMapType{backingVector: m.backingVector.Set(pos, newBucket), len: m.len}
+//template:MapSnapshotTemplate
+
+// EncodeSnapshot writes m to the snapshot stream of e. Nodes shared with containers
+// previously written to e are written as references to those.
+func (m *GenericMapType) EncodeSnapshot(e *SnapshotEncoder) error {
+	return e.encode(snapshotMapTag, func() error {
+		return m.encodeSnapshot(e)
+	})
+}
+
+func (m *GenericMapType) encodeSnapshot(e *SnapshotEncoder) error {
+	e.writeUvarint(uint64(m.len))
+	return m.root.encodeSnapshot(e)
+}
+
+func (n *privateGenericMapItemNode) encodeSnapshot(e *SnapshotEncoder) error {
+	return e.writeNode(n, func() error {
+		e.writeUvarint(uint64(n.dataMap))
+		e.writeUvarint(uint64(n.nodeMap))
+		if err := e.writeElements(n.items); err != nil {
+			return err
+		}
+
+		e.writeUvarint(uint64(len(n.children)))
+		for _, child := range n.children {
+			if err := child.encodeSnapshot(e); err != nil {
+				return err
 			}
 		}
 
-		// Add new item to bucket
-		newBucket := make(privateGenericMapItemBucket, len(bucket), len(bucket)+1)
-		copy(newBucket, bucket)
-		newBucket = append(newBucket, GenericMapItem{Key: key, Value: value})
-		return &GenericMapType{backingVector: m.backingVector.Set(pos, newBucket), len: m.len + 1}
+		return nil
+	})
+}
+
+// DecodeSnapshot sets m to the next container in the snapshot stream of d, which must
+// be a map. io.EOF is returned if there are no more containers in the stream. m must
+// not be used by anyone else when this is called, decode into a new zero GenericMapType.
+func (m *GenericMapType) DecodeSnapshot(d *SnapshotDecoder) error {
+	return d.decode(snapshotMapTag, func() error {
+		return m.decodeSnapshot(d)
+	})
+}
+
+func (m *GenericMapType) decodeSnapshot(d *SnapshotDecoder) error {
+	length, err := d.readLength(math.MaxInt32)
+	if err != nil {
+		return err
+	}
+
+	root, err := decodeGenericMapItemNodeSnapshot(d, 0)
+	if err != nil {
+		return err
 	}
 
-	item := GenericMapItem{Key: key, Value: value}
-	newBucket := privateGenericMapItemBucket{item}
-	return &GenericMapType{backingVector: m.backingVector.Set(pos, newBucket), len: m.len + 1}
+	*m = GenericMapType{root: root, len: length, hash: new(uint32)}
+	return nil
 }
 
-// Delete returns a new GenericMapType without the element identified by key.
-func (m *GenericMapType) Delete(key GenericMapKeyType) *GenericMapType {
-	pos := m.pos(key)
-	bucket := m.backingVector.Get(pos)
-	if bucket != nil {
-		newBucket := make(privateGenericMapItemBucket, 0)
-		for _, item := range bucket {
-			if item.Key != key {
-				newBucket = append(newBucket, item)
-			}
+func decodeGenericMapItemNodeSnapshot(d *SnapshotDecoder, shift uint) (*privateGenericMapItemNode, error) {
+	node, err := d.readNode(func() (interface{}, error) {
+		dataMap, err := d.readUint32()
+		if err != nil {
+			return nil, err
+		}
+
+		nodeMap, err := d.readUint32()
+		if err != nil {
+			return nil, err
+		}
+
+		n := &privateGenericMapItemNode{dataMap: dataMap, nodeMap: nodeMap}
+		if err := d.readElements(&n.items); err != nil {
+			return nil, err
 		}
 
-		removedItemCount := len(bucket) - len(newBucket)
-		if removedItemCount == 0 {
-			return m
+		count, err := d.readLength(nodeSize)
+		if err != nil {
+			return nil, err
 		}
 
-		if len(newBucket) == 0 {
-			newBucket = nil
+		n.children = make([]*privateGenericMapItemNode, count)
+		for i := range n.children {
+			if n.children[i], err = decodeGenericMapItemNodeSnapshot(d, shift+shiftSize); err != nil {
+				return nil, err
+			}
 		}
 
-		newMap := &GenericMapType{backingVector: m.backingVector.Set(pos, newBucket), len: m.len - removedItemCount}
-		if newMap.backingVector.Len() > 1 && newMap.Len() < newMap.backingVector.Len()*int(lowerMapLoadFactor) {
-			// Shrink backing vector if needed to avoid occupying excessive space
-			buckets := newPrivateGenericMapItemBuckets(newMap.Len())
-			buckets.AddItemsFromMap(newMap)
-			return &GenericMapType{backingVector: emptyGenericMapItemBucketVector.Append(buckets.buckets...), len: buckets.length}
+		collision := shift >= champHashBits && n.dataMap == 0 && n.nodeMap == 0 && count == 0
+		if !collision && (bits.OnesCount32(n.dataMap) != len(n.items) || bits.OnesCount32(n.nodeMap) != count) {
+			return nil, fmt.Errorf("invalid snapshot, map node with %d items and %d children does not match its bitmaps", len(n.items), count)
 		}
 
-		return newMap
+		return n, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if n, ok := node.(*privateGenericMapItemNode); ok {
+		return n, nil
 	}
 
-	return m
+	return nil, invalidSnapshotNode(node)
 }
 
-// Range calls f repeatedly passing it each key and value as argument until either
-// all elements have been visited or f returns false.
-func (m *GenericMapType) Range(f func(GenericMapKeyType, GenericMapValueType) bool) {
-	m.backingVector.Range(func(bucket privateGenericMapItemBucket) bool {
-		for _, item := range bucket {
-			if !f(item.Key, item.Value) {
-				return false
-			}
-		}
+//template:MapHashTemplate
+
+// Equals returns true if m and other contain the same keys mapped to equal values, false otherwise.
+func (m *GenericMapType) Equals(other *GenericMapType) bool {
+	if m.root == other.root {
 		return true
+	}
+
+	if m.len != other.len {
+		return false
+	}
+
+	equal := true
+	m.Range(func(key GenericMapKeyType, value GenericMapValueType) bool {
+		otherValue, ok := other.Load(key)
+		equal = ok && genericValueEqual(&value, &otherValue)
+		return equal
 	})
+
+	return equal
 }
 
-// ToNativeMap returns a native Go map containing all elements of m.
-func (m *GenericMapType) ToNativeMap() map[GenericMapKeyType]GenericMapValueType {
-	result := make(map[GenericMapKeyType]GenericMapValueType)
+// Hash returns a hash of the items in m. Maps that are equal according to Equals have
+// the same hash. The hash is computed the first time it is needed and is then kept for m
+// and all copies of it, including copies stored in other containers.
+func (m *GenericMapType) Hash() uint32 {
+	if h := loadHash(m.hash); h != 0 {
+		return h
+	}
+
+	// The items are summed since the order in which they are visited depends on the tree
+	h := uint32(0)
 	m.Range(func(key GenericMapKeyType, value GenericMapValueType) bool {
-		result[key] = value
+		h += 31*genericHash(key) + genericValueHash(&value)
 		return true
 	})
 
-	return result
+	return cacheHash(m.hash, h)
+}
+
+//template:DefaultKeyEqualTemplate
+
+func genericEqual(a, b GenericMapKeyType) bool {
+	return a == b
 }
 
 //template:PublicMapTemplate
@@ -750,14 +2343,27 @@ func NewGenericMapType(items ...GenericMapItem) *GenericMapType {
 	return newGenericMapType(items)
 }
 
+//template:NativeMapTemplate
+
+// ToNativeMap returns a native Go map containing all elements of m.
+func (m *GenericMapType) ToNativeMap() map[GenericMapKeyType]GenericMapValueType {
+	result := make(map[GenericMapKeyType]GenericMapValueType)
+	m.Range(func(key GenericMapKeyType, value GenericMapValueType) bool {
+		result[key] = value
+		return true
+	})
+
+	return result
+}
+
 // NewGenericMapTypeFromNativeMap returns a new GenericMapType containing all items in m.
 func NewGenericMapTypeFromNativeMap(m map[GenericMapKeyType]GenericMapValueType) *GenericMapType {
-	buckets := newPrivateGenericMapItemBuckets(len(m))
+	t := emptyGenericMapType.AsTransient()
 	for key, value := range m {
-		buckets.AddItem(GenericMapItem{Key: key, Value: value})
+		t.Store(key, value)
 	}
 
-	return &GenericMapType{backingVector: emptyGenericMapItemBucketVector.Append(buckets.buckets...), len: buckets.length}
+	return t.Persistent()
 }
 
 //template:SetTemplate
@@ -852,6 +2458,12 @@ func (s *GenericSetType) Equals(other *GenericSetType) bool {
 	return s.Len() == other.Len() && s.IsSubset(other)
 }
 
+// Hash returns a hash of the elements in s. Sets that are equal according to Equals have
+// the same hash.
+func (s *GenericSetType) Hash() uint32 {
+	return s.backingMap.Hash()
+}
+
 func (s *GenericSetType) difference(other *GenericSetType) []GenericMapKeyType {
 	items := make([]GenericMapKeyType, 0)
 	s.Range(func(item GenericMapKeyType) bool {
@@ -910,8 +2522,128 @@ func (s *GenericSetType) ToNativeSlice() []GenericMapKeyType {
 	return items
 }
 
+// MarshalJSON encodes s as a JSON array.
+func (s *GenericSetType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.ToNativeSlice())
+}
+
+// UnmarshalJSON sets s to the elements of data, a JSON array. s must not be used by
+// anyone else when this is called, it is meant to be used by encoding/json only.
+func (s *GenericSetType) UnmarshalJSON(data []byte) error {
+	t := NewGenericSetType().AsTransient()
+	err := jsonDecodeArray(data, func(dec *json.Decoder) error {
+		var item GenericMapKeyType
+		if err := dec.Decode(&item); err != nil {
+			return err
+		}
+
+		t.Add(item)
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	*s = *t.Persistent()
+	return nil
+}
+
+// MarshalBinary returns the binary encoding of s. The items are gob encoded.
+func (s *GenericSetType) MarshalBinary() ([]byte, error) {
+	return s.backingMap.MarshalBinary()
+}
+
+// UnmarshalBinary sets s to the set encoded in data by MarshalBinary. s must not
+// be used by anyone else when this is called, decode into a new zero GenericSetType.
+func (s *GenericSetType) UnmarshalBinary(data []byte) error {
+	m := &GenericMapType{}
+	if err := m.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	*s = GenericSetType{backingMap: m}
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder using the same encoding as MarshalBinary.
+func (s *GenericSetType) GobEncode() ([]byte, error) {
+	return s.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder using the same encoding as UnmarshalBinary.
+func (s *GenericSetType) GobDecode(data []byte) error {
+	return s.UnmarshalBinary(data)
+}
+
+// EncodeSnapshot writes s to the snapshot stream of e. Nodes shared with containers
+// previously written to e are written as references to those.
+func (s *GenericSetType) EncodeSnapshot(e *SnapshotEncoder) error {
+	return e.encode(snapshotSetTag, func() error {
+		return s.backingMap.encodeSnapshot(e)
+	})
+}
+
+// DecodeSnapshot sets s to the next container in the snapshot stream of d, which must
+// be a set. io.EOF is returned if there are no more containers in the stream. s must
+// not be used by anyone else when this is called, decode into a new zero GenericSetType.
+func (s *GenericSetType) DecodeSnapshot(d *SnapshotDecoder) error {
+	return d.decode(snapshotSetTag, func() error {
+		m := &GenericMapType{}
+		if err := m.decodeSnapshot(d); err != nil {
+			return err
+		}
+
+		*s = GenericSetType{backingMap: m}
+		return nil
+	})
+}
+
+// GenericSetTypeTransient is a mutable builder for GenericSetType. Call Persistent
+// to turn it into a GenericSetType, the transient cannot be used after that.
+type GenericSetTypeTransient struct {
+	backingMap *GenericMapTypeTransient
+}
+
+// AsTransient returns a transient containing all elements of s. s is left untouched.
+func (s *GenericSetType) AsTransient() *GenericSetTypeTransient {
+	return &GenericSetTypeTransient{backingMap: s.backingMap.AsTransient()}
+}
+
+// Persistent returns a GenericSetType containing all elements of t. t cannot be used
+// after this call.
+func (t *GenericSetTypeTransient) Persistent() *GenericSetType {
+	return &GenericSetType{backingMap: t.backingMap.Persistent()}
+}
+
+// Add adds item to t.
+func (t *GenericSetTypeTransient) Add(item GenericMapKeyType) {
+	var mapValue GenericMapValueType
+	t.backingMap.Store(item, mapValue)
+}
+
+// Delete removes item from t.
+func (t *GenericSetTypeTransient) Delete(item GenericMapKeyType) {
+	t.backingMap.Delete(item)
+}
+
+// Contains returns true if item is present in t, false otherwise.
+func (t *GenericSetTypeTransient) Contains(item GenericMapKeyType) bool {
+	_, ok := t.backingMap.Load(item)
+	return ok
+}
+
+// Len returns the number of elements in t.
+func (t *GenericSetTypeTransient) Len() int {
+	return t.backingMap.Len()
+}
+
 //template:commentsNotWantedInGeneratedCode
 
+// Common imports that are only used by templates in other files of this package
+var _ = sort.SliceStable
+var _ = bufio.NewReader
+
 // peds -maps "FooMap<int, string>;BarMap<int16, int32>"
 //      -sets "FooSet<mypackage.MyType>"
 //      -vectors "FooVec<io.Bar>"
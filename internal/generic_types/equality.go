@@ -0,0 +1,40 @@
+package generic_types
+
+// The templates in this file define the functions used to hash and compare the elements
+// of vectors and the keys and values of maps. Which template is used for a type depends
+// on what is known about it when the code is generated. Types that are not known to be
+// comparable are compared using ==, leaving it to the compiler to reject those that are
+// not comparable. The values are passed as pointers to where they are stored so that
+// containers are neither copied nor hashed again once their hashes have been computed.
+// Map keys are passed by value, like the keys given to the maps. Copies of containers
+// share the hashes kept for them.
+
+//template:ComparableValueOpsTemplate
+
+func genericComparableHash(x *GenericComparableType) uint32 {
+	return genericBasicHash(*x)
+}
+
+func genericComparableEqual(a, b *GenericComparableType) bool {
+	return *a == *b
+}
+
+//template:ContainerValueOpsTemplate
+
+func genericContainerHash(x *GenericContainerType) uint32 {
+	return x.Hash()
+}
+
+func genericContainerEqual(a, b *GenericContainerType) bool {
+	return a.Equals(b)
+}
+
+//template:ContainerKeyOpsTemplate
+
+func genericContainerKeyHash(x GenericContainerType) uint32 {
+	return x.Hash()
+}
+
+func genericContainerKeyEqual(a, b GenericContainerType) bool {
+	return a.Equals(&b)
+}
@@ -6,3 +6,23 @@ package generic_types
 func genericHash(x interface{}) uint32 {
 	return interfaceHash(x)
 }
+
+func genericBasicHash(x GenericComparableType) uint32 {
+	return interfaceHash(x)
+}
+
+func genericElementHash(x *GenericType) uint32 {
+	return interfaceHash(*x)
+}
+
+func genericElementEqual(a, b *GenericType) bool {
+	return *a == *b
+}
+
+func genericValueHash(x *GenericMapValueType) uint32 {
+	return interfaceHash(*x)
+}
+
+func genericValueEqual(a, b *GenericMapValueType) bool {
+	return *a == *b
+}
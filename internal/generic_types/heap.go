@@ -0,0 +1,190 @@
+package generic_types
+
+import "encoding/json"
+
+//template:DefaultHeapLessTemplate
+
+func genericHeapLess(a, b GenericType) bool {
+	return a < b
+}
+
+//template:HeapTemplate
+
+////////////
+/// Heap ///
+////////////
+
+// privateGenericHeapTypeNode is a node in a leftist heap. The rank of a node is the
+// length of its right spine, the rank of the left child of a node is never smaller
+// than that of the right child. The right spine is therefore at most logarithmic in
+// the size of the heap.
+type privateGenericHeapTypeNode struct {
+	item        GenericType
+	left, right *privateGenericHeapTypeNode
+	rank        int
+}
+
+func (n *privateGenericHeapTypeNode) rankOf() int {
+	if n == nil {
+		return 0
+	}
+
+	return n.rank
+}
+
+// privateGenericHeapTypeMerge returns a heap containing the items of both n1 and n2.
+// Only nodes on the right spines of the heaps are copied.
+func privateGenericHeapTypeMerge(n1, n2 *privateGenericHeapTypeNode) *privateGenericHeapTypeNode {
+	if n1 == nil {
+		return n2
+	}
+
+	if n2 == nil {
+		return n1
+	}
+
+	if genericHeapLess(n2.item, n1.item) {
+		n1, n2 = n2, n1
+	}
+
+	left, right := n1.left, privateGenericHeapTypeMerge(n1.right, n2)
+	if left.rankOf() < right.rankOf() {
+		left, right = right, left
+	}
+
+	return &privateGenericHeapTypeNode{item: n1.item, left: left, right: right, rank: right.rankOf() + 1}
+}
+
+// A GenericHeapType is a persistent/immutable priority queue implemented as a leftist heap.
+// Push, Pop and Merge run in logarithmic time, Peek and Len in constant time.
+type GenericHeapType struct {
+	root *privateGenericHeapTypeNode
+	len  int
+}
+
+var emptyGenericHeapType = &GenericHeapType{}
+
+// NewGenericHeapType returns a new GenericHeapType containing the items provided in items.
+func NewGenericHeapType(items ...GenericType) *GenericHeapType {
+	if len(items) == 0 {
+		return emptyGenericHeapType
+	}
+
+	// Merging the items pairwise builds the heap in linear time
+	nodes := make([]*privateGenericHeapTypeNode, len(items))
+	for i, item := range items {
+		nodes[i] = &privateGenericHeapTypeNode{item: item, rank: 1}
+	}
+
+	for len(nodes) > 1 {
+		merged := nodes[:0]
+		for i := 0; i < len(nodes); i += 2 {
+			if i+1 < len(nodes) {
+				merged = append(merged, privateGenericHeapTypeMerge(nodes[i], nodes[i+1]))
+			} else {
+				merged = append(merged, nodes[i])
+			}
+		}
+
+		nodes = merged
+	}
+
+	return &GenericHeapType{root: nodes[0], len: len(items)}
+}
+
+// Len returns the number of items in h.
+func (h *GenericHeapType) Len() int {
+	return h.len
+}
+
+// Push returns a new heap with item added to it.
+func (h *GenericHeapType) Push(item GenericType) *GenericHeapType {
+	node := &privateGenericHeapTypeNode{item: item, rank: 1}
+	return &GenericHeapType{root: privateGenericHeapTypeMerge(h.root, node), len: h.len + 1}
+}
+
+// Peek returns the smallest item in h. ok is false if h is empty.
+func (h *GenericHeapType) Peek() (item GenericType, ok bool) {
+	if h.root == nil {
+		return item, false
+	}
+
+	return h.root.item, true
+}
+
+// Pop returns a new heap with the smallest item removed, and the removed item.
+func (h *GenericHeapType) Pop() (*GenericHeapType, GenericType) {
+	if h.root == nil {
+		panic("Pop on empty heap")
+	}
+
+	return &GenericHeapType{root: privateGenericHeapTypeMerge(h.root.left, h.root.right), len: h.len - 1}, h.root.item
+}
+
+// Merge returns a new heap containing the items of both h and other.
+func (h *GenericHeapType) Merge(other *GenericHeapType) *GenericHeapType {
+	return &GenericHeapType{root: privateGenericHeapTypeMerge(h.root, other.root), len: h.len + other.len}
+}
+
+// Range calls f repeatedly passing it each item in h as argument until either all items
+// have been visited or f returns false. The items are visited in no particular order,
+// use ToNativeSlice to get them in ascending order.
+func (h *GenericHeapType) Range(f func(GenericType) bool) {
+	if h.root == nil {
+		return
+	}
+
+	stack := []*privateGenericHeapTypeNode{h.root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !f(node.item) {
+			return
+		}
+
+		if node.right != nil {
+			stack = append(stack, node.right)
+		}
+
+		if node.left != nil {
+			stack = append(stack, node.left)
+		}
+	}
+}
+
+// ToNativeSlice returns a Go slice containing all items of h in ascending order.
+func (h *GenericHeapType) ToNativeSlice() []GenericType {
+	result := make([]GenericType, 0, h.len)
+	for root := h.root; root != nil; root = privateGenericHeapTypeMerge(root.left, root.right) {
+		result = append(result, root.item)
+	}
+
+	return result
+}
+
+// MarshalJSON encodes h as a JSON array in ascending order.
+func (h *GenericHeapType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.ToNativeSlice())
+}
+
+// UnmarshalJSON sets h to the elements of data, a JSON array. h must not be used by
+// anyone else when this is called, it is meant to be used by encoding/json only.
+func (h *GenericHeapType) UnmarshalJSON(data []byte) error {
+	items := make([]GenericType, 0)
+	err := jsonDecodeArray(data, func(dec *json.Decoder) error {
+		var item GenericType
+		if err := dec.Decode(&item); err != nil {
+			return err
+		}
+
+		items = append(items, item)
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	*h = *NewGenericHeapType(items...)
+	return nil
+}
@@ -0,0 +1,104 @@
+//go:build go1.23
+
+package generic_types
+
+// The iterator methods are only generated for Go 1.23 and later, where the iter
+// package and range over functions are available.
+
+//template:IterImportsTemplate
+
+import "iter"
+
+//template:VectorIterTemplate
+
+// All returns an iterator over the indexes and elements of v, in order.
+func (v *GenericVectorType) All() iter.Seq2[int, GenericType] {
+	return func(yield func(int, GenericType) bool) {
+		i := 0
+		v.Range(func(item GenericType) bool {
+			ok := yield(i, item)
+			i++
+			return ok
+		})
+	}
+}
+
+// Backward returns an iterator over the indexes and elements of v, in reverse order.
+func (v *GenericVectorType) Backward() iter.Seq2[int, GenericType] {
+	return func(yield func(int, GenericType) bool) {
+		var currentNode []GenericType
+		for i := int(v.len) - 1; i >= 0; i-- {
+			if i&shiftBitMask == shiftBitMask || i == int(v.len)-1 {
+				currentNode = v.sliceFor(uint(i))
+			}
+
+			if !yield(i, currentNode[i&shiftBitMask]) {
+				return
+			}
+		}
+	}
+}
+
+// All returns an iterator over the indexes and elements of s, in order.
+func (s *GenericVectorTypeSlice) All() iter.Seq2[int, GenericType] {
+	return func(yield func(int, GenericType) bool) {
+		i := 0
+		s.Range(func(item GenericType) bool {
+			ok := yield(i, item)
+			i++
+			return ok
+		})
+	}
+}
+
+// Backward returns an iterator over the indexes and elements of s, in reverse order.
+func (s *GenericVectorTypeSlice) Backward() iter.Seq2[int, GenericType] {
+	return func(yield func(int, GenericType) bool) {
+		var currentNode []GenericType
+		for i := s.stop - 1; i >= s.start; i-- {
+			if i&shiftBitMask == shiftBitMask || i == s.stop-1 {
+				currentNode = s.vector.sliceFor(uint(i))
+			}
+
+			if !yield(i-s.start, currentNode[i&shiftBitMask]) {
+				return
+			}
+		}
+	}
+}
+
+//template:MapIterTemplate
+
+// All returns an iterator over the keys and values in m. The order is unspecified.
+func (m *GenericMapType) All() iter.Seq2[GenericMapKeyType, GenericMapValueType] {
+	return func(yield func(GenericMapKeyType, GenericMapValueType) bool) {
+		m.Range(yield)
+	}
+}
+
+// Keys returns an iterator over the keys in m. The order is unspecified.
+func (m *GenericMapType) Keys() iter.Seq[GenericMapKeyType] {
+	return func(yield func(GenericMapKeyType) bool) {
+		m.Range(func(key GenericMapKeyType, _ GenericMapValueType) bool {
+			return yield(key)
+		})
+	}
+}
+
+// Values returns an iterator over the values in m. The order is unspecified.
+func (m *GenericMapType) Values() iter.Seq[GenericMapValueType] {
+	return func(yield func(GenericMapValueType) bool) {
+		m.Range(func(_ GenericMapKeyType, value GenericMapValueType) bool {
+			return yield(value)
+		})
+	}
+}
+
+//template:SetIterTemplate
+
+// All returns an iterator over the elements in s. The order is unspecified.
+func (s *GenericSetType) All() iter.Seq[GenericMapKeyType] {
+	return func(yield func(GenericMapKeyType) bool) {
+		s.Range(yield)
+	}
+}
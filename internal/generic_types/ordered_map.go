@@ -0,0 +1,231 @@
+package generic_types
+
+import "encoding/json"
+
+// The ordered map is built from a private hash map and a private sorted map that are
+// generated separately. The declarations below are placeholders for those maps, needed
+// for this package to be compilable. They are not part of any template.
+
+type privateGenericOrderedMapTypeEntries struct{}
+
+var emptyprivateGenericOrderedMapTypeEntries = &privateGenericOrderedMapTypeEntries{}
+
+func (m *privateGenericOrderedMapTypeEntries) Len() int { return 0 }
+
+func (m *privateGenericOrderedMapTypeEntries) Load(key GenericMapKeyType) (value privateGenericOrderedMapTypeEntry, ok bool) {
+	return value, false
+}
+
+func (m *privateGenericOrderedMapTypeEntries) Store(key GenericMapKeyType, value privateGenericOrderedMapTypeEntry) *privateGenericOrderedMapTypeEntries {
+	return m
+}
+
+func (m *privateGenericOrderedMapTypeEntries) Delete(key GenericMapKeyType) *privateGenericOrderedMapTypeEntries {
+	return m
+}
+
+func (m *privateGenericOrderedMapTypeEntries) AsTransient() *privateGenericOrderedMapTypeEntriesTransient {
+	return &privateGenericOrderedMapTypeEntriesTransient{}
+}
+
+type privateGenericOrderedMapTypeEntriesTransient struct{}
+
+func (t *privateGenericOrderedMapTypeEntriesTransient) Load(key GenericMapKeyType) (value privateGenericOrderedMapTypeEntry, ok bool) {
+	return value, false
+}
+
+func (t *privateGenericOrderedMapTypeEntriesTransient) Store(key GenericMapKeyType, value privateGenericOrderedMapTypeEntry) {
+}
+
+func (t *privateGenericOrderedMapTypeEntriesTransient) Persistent() *privateGenericOrderedMapTypeEntries {
+	return emptyprivateGenericOrderedMapTypeEntries
+}
+
+type privateGenericOrderedMapTypeOrder struct{}
+
+var emptyprivateGenericOrderedMapTypeOrder = &privateGenericOrderedMapTypeOrder{}
+
+func (m *privateGenericOrderedMapTypeOrder) Store(key int, value GenericMapKeyType) *privateGenericOrderedMapTypeOrder {
+	return m
+}
+
+func (m *privateGenericOrderedMapTypeOrder) Delete(key int) *privateGenericOrderedMapTypeOrder {
+	return m
+}
+
+func (m *privateGenericOrderedMapTypeOrder) Range(f func(int, GenericMapKeyType) bool) {}
+
+type privateGenericOrderedMapTypeOrderItem struct {
+	Key   int
+	Value GenericMapKeyType
+}
+
+func newprivateGenericOrderedMapTypeOrder(items []privateGenericOrderedMapTypeOrderItem) *privateGenericOrderedMapTypeOrder {
+	return emptyprivateGenericOrderedMapTypeOrder
+}
+
+//template:OrderedMapTemplate
+
+///////////////////
+/// Ordered map ///
+///////////////////
+
+type GenericOrderedMapItem struct {
+	Key   GenericMapKeyType
+	Value GenericMapValueType
+}
+
+// privateGenericOrderedMapTypeEntry is the value stored for each key in GenericOrderedMapType.
+// seq is the position of the key in the insertion order.
+type privateGenericOrderedMapTypeEntry struct {
+	value GenericMapValueType
+	seq   int
+}
+
+// GenericOrderedMapType is a persistent key - value map that remembers the order in which
+// keys were first inserted. Keys and values are kept in a hash map, the insertion order
+// in a sorted map from insertion sequence number to key.
+type GenericOrderedMapType struct {
+	entries *privateGenericOrderedMapTypeEntries
+	order   *privateGenericOrderedMapTypeOrder
+	nextSeq int
+}
+
+var emptyGenericOrderedMapType = &GenericOrderedMapType{entries: emptyprivateGenericOrderedMapTypeEntries, order: emptyprivateGenericOrderedMapTypeOrder}
+
+// NewGenericOrderedMapType returns a new GenericOrderedMapType containing all items in items,
+// in the order given.
+func NewGenericOrderedMapType(items ...GenericOrderedMapItem) *GenericOrderedMapType {
+	return newGenericOrderedMapType(items)
+}
+
+// newGenericOrderedMapType returns a map containing items, in the order given. The hash map
+// is built using a transient and the insertion order from the sequence numbers in order.
+func newGenericOrderedMapType(items []GenericOrderedMapItem) *GenericOrderedMapType {
+	if len(items) == 0 {
+		return emptyGenericOrderedMapType
+	}
+
+	entries := emptyprivateGenericOrderedMapTypeEntries.AsTransient()
+	order := make([]privateGenericOrderedMapTypeOrderItem, 0, len(items))
+	for _, item := range items {
+		seq := len(order)
+		if entry, ok := entries.Load(item.Key); ok {
+			seq = entry.seq
+		} else {
+			order = append(order, privateGenericOrderedMapTypeOrderItem{Key: seq, Value: item.Key})
+		}
+
+		entries.Store(item.Key, privateGenericOrderedMapTypeEntry{value: item.Value, seq: seq})
+	}
+
+	return &GenericOrderedMapType{
+		entries: entries.Persistent(),
+		order:   newprivateGenericOrderedMapTypeOrder(order),
+		nextSeq: len(order)}
+}
+
+// Len returns the number of items in m.
+func (m *GenericOrderedMapType) Len() int {
+	return m.entries.Len()
+}
+
+// Load returns value identified by key. ok is set to true if key exists in the map, false otherwise.
+func (m *GenericOrderedMapType) Load(key GenericMapKeyType) (value GenericMapValueType, ok bool) {
+	entry, ok := m.entries.Load(key)
+	return entry.value, ok
+}
+
+// Store returns a new GenericOrderedMapType containing value identified by key. A key that
+// already exists in m keeps its position in the insertion order, a new key is placed last.
+func (m *GenericOrderedMapType) Store(key GenericMapKeyType, value GenericMapValueType) *GenericOrderedMapType {
+	if entry, ok := m.entries.Load(key); ok {
+		entries := m.entries.Store(key, privateGenericOrderedMapTypeEntry{value: value, seq: entry.seq})
+		return &GenericOrderedMapType{entries: entries, order: m.order, nextSeq: m.nextSeq}
+	}
+
+	return &GenericOrderedMapType{
+		entries: m.entries.Store(key, privateGenericOrderedMapTypeEntry{value: value, seq: m.nextSeq}),
+		order:   m.order.Store(m.nextSeq, key),
+		nextSeq: m.nextSeq + 1}
+}
+
+// Delete returns a new GenericOrderedMapType without the element identified by key.
+func (m *GenericOrderedMapType) Delete(key GenericMapKeyType) *GenericOrderedMapType {
+	entry, ok := m.entries.Load(key)
+	if !ok {
+		return m
+	}
+
+	return &GenericOrderedMapType{entries: m.entries.Delete(key), order: m.order.Delete(entry.seq), nextSeq: m.nextSeq}
+}
+
+// Range calls f repeatedly passing it each key and value as argument, in insertion order,
+// until either all elements have been visited or f returns false.
+func (m *GenericOrderedMapType) Range(f func(GenericMapKeyType, GenericMapValueType) bool) {
+	m.order.Range(func(_ int, key GenericMapKeyType) bool {
+		entry, _ := m.entries.Load(key)
+		return f(key, entry.value)
+	})
+}
+
+// ToNativeSlice returns a native Go slice containing all items of m in insertion order.
+func (m *GenericOrderedMapType) ToNativeSlice() []GenericOrderedMapItem {
+	result := make([]GenericOrderedMapItem, 0, m.Len())
+	m.Range(func(key GenericMapKeyType, value GenericMapValueType) bool {
+		result = append(result, GenericOrderedMapItem{Key: key, Value: value})
+		return true
+	})
+
+	return result
+}
+
+// ToNativeMap returns a native Go map containing all elements of m.
+func (m *GenericOrderedMapType) ToNativeMap() map[GenericMapKeyType]GenericMapValueType {
+	result := make(map[GenericMapKeyType]GenericMapValueType)
+	m.Range(func(key GenericMapKeyType, value GenericMapValueType) bool {
+		result[key] = value
+		return true
+	})
+
+	return result
+}
+
+// MarshalJSON encodes m with the keys in insertion order. m is encoded as a JSON object
+// if the keys are strings, integers or implement encoding.TextMarshaler, following the
+// same rules as encoding/json uses for map keys. Other keys are encoded as an array of
+// [key, value] arrays.
+func (m *GenericOrderedMapType) MarshalJSON() ([]byte, error) {
+	return jsonEncodeMap(jsonObjectKeys((*GenericMapKeyType)(nil)), func(entry func(key, value interface{}) bool) {
+		m.Range(func(key GenericMapKeyType, value GenericMapValueType) bool {
+			return entry(&key, &value)
+		})
+	})
+}
+
+// UnmarshalJSON sets m to the items of data, either a JSON object or an array of [key, value]
+// arrays, keeping the order of the keys in data. m must not be used by anyone else when this
+// is called, it is meant to be used by encoding/json only.
+func (m *GenericOrderedMapType) UnmarshalJSON(data []byte) error {
+	items := make([]GenericOrderedMapItem, 0)
+	err := jsonDecodeMap(data, func(decodeKey func(interface{}) error, dec *json.Decoder) error {
+		var item GenericOrderedMapItem
+		if err := decodeKey(&item.Key); err != nil {
+			return err
+		}
+
+		if err := dec.Decode(&item.Value); err != nil {
+			return err
+		}
+
+		items = append(items, item)
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	*m = *newGenericOrderedMapType(items)
+	return nil
+}
@@ -0,0 +1,537 @@
+package generic_types
+
+import "encoding/json"
+
+//template:RRBCommonTemplate
+
+////////////////
+/// RRB tree ///
+////////////////
+
+// Number of slots that a node may be short of a full node and still be
+// considered to satisfy the search step invariant.
+const rrbInvariant = 1
+
+// Number of extra slots allowed, compared to the optimal number of slots,
+// before nodes are redistributed during concatenation.
+const rrbExtras = 2
+
+// rrbNode is an internal node in a relaxed radix balanced tree. sizes contains
+// the cumulative number of elements in the children of the node. It is nil if all
+// children but the last one are full in which case radix indexing can be used.
+type rrbNode struct {
+	children []commonNode
+	sizes    []uint
+	len      uint
+}
+
+func (n *rrbNode) childIndex(i, shift uint) (int, uint) {
+	idx := i >> shift
+	if n.sizes == nil {
+		return int(idx), i - (idx << shift)
+	}
+
+	for n.sizes[idx] <= i {
+		idx++
+	}
+
+	if idx > 0 {
+		i -= n.sizes[idx-1]
+	}
+
+	return int(idx), i
+}
+
+func uintMax(a, b uint) uint {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+// rrbConcatPlan returns the slot counts that nodes with the slot counts in counts
+// should be redistributed into to satisfy the search step invariant. counts is
+// modified in the process.
+func rrbConcatPlan(counts []int) []int {
+	total := 0
+	for _, c := range counts {
+		total += c
+	}
+
+	optimal := (total-1)/nodeSize + 1
+	n := len(counts)
+	i := 0
+	for optimal+rrbExtras < n {
+		// Skip all nodes that already satisfy the invariant
+		for counts[i] > nodeSize-rrbInvariant {
+			i++
+		}
+
+		// Found a short node, spread its content over the following nodes
+		remaining := counts[i]
+		for remaining > 0 {
+			size := remaining + counts[i+1]
+			if size > nodeSize {
+				size = nodeSize
+			}
+
+			counts[i] = size
+			remaining = remaining + counts[i+1] - size
+			i++
+		}
+
+		// Node i is now empty, remove it
+		copy(counts[i:n-1], counts[i+1:n])
+		n--
+		i--
+	}
+
+	return counts[:n]
+}
+
+//template:RRBVectorTemplate
+
+//////////////////
+/// RRB Vector ///
+//////////////////
+
+// A GenericRRBVectorType is an ordered persistent/immutable collection of items backed by a
+// relaxed radix balanced tree. Apart from the operations of a regular vector it supports
+// concatenation, insertion and removal at arbitrary positions in logarithmic time.
+type GenericRRBVectorType struct {
+	root  commonNode
+	len   uint
+	shift uint
+}
+
+var emptyGenericRRBVectorType *GenericRRBVectorType = &GenericRRBVectorType{root: []GenericType{}}
+
+// NewGenericRRBVectorType returns a new GenericRRBVectorType containing the items provided in items.
+func NewGenericRRBVectorType(items ...GenericType) *GenericRRBVectorType {
+	return newGenericRRBVectorTypeTree(items)
+}
+
+// newGenericRRBVectorTypeTree builds a balanced tree bottom up from items.
+func newGenericRRBVectorTypeTree(items []GenericType) *GenericRRBVectorType {
+	if len(items) == 0 {
+		return emptyGenericRRBVectorType
+	}
+
+	itemLen := uint(len(items))
+	level := make([]commonNode, 0, (itemLen+nodeSize-1)/nodeSize)
+	for start := uint(0); start < itemLen; start += nodeSize {
+		leaf := make([]GenericType, uintMin(itemLen-start, nodeSize))
+		copy(leaf, items[start:])
+		level = append(level, leaf)
+	}
+
+	shift := uint(0)
+	for len(level) > 1 {
+		shift += shiftSize
+		levelLen := uint(len(level))
+		parents := make([]commonNode, 0, (levelLen+nodeSize-1)/nodeSize)
+		for start := uint(0); start < levelLen; start += nodeSize {
+			children := make([]commonNode, uintMin(levelLen-start, nodeSize))
+			copy(children, level[start:])
+			parents = append(parents, newGenericRRBVectorTypeNode(children, shift))
+		}
+
+		level = parents
+	}
+
+	return &GenericRRBVectorType{root: level[0], len: itemLen, shift: shift}
+}
+
+// newGenericRRBVectorTypeNode creates a new node at level shift with children. A size table is
+// only created if the children cannot be indexed using plain radix indexing.
+func newGenericRRBVectorTypeNode(children []commonNode, shift uint) *rrbNode {
+	childShift := shift - shiftSize
+	var sizes []uint
+	total := uint(0)
+	for i, child := range children {
+		childLen := lenGenericRRBVectorTypeNode(child, childShift)
+		if sizes == nil && i < len(children)-1 && childLen != 1<<shift {
+			sizes = make([]uint, len(children))
+			for j := 0; j < i; j++ {
+				sizes[j] = uint(j+1) << shift
+			}
+		}
+
+		total += childLen
+		if sizes != nil {
+			sizes[i] = total
+		}
+	}
+
+	return &rrbNode{children: children, sizes: sizes, len: total}
+}
+
+func lenGenericRRBVectorTypeNode(node commonNode, shift uint) uint {
+	if shift == 0 {
+		return uint(len(node.([]GenericType)))
+	}
+
+	return node.(*rrbNode).len
+}
+
+func slotsGenericRRBVectorTypeNode(node commonNode, shift uint) int {
+	if shift == 0 {
+		return len(node.([]GenericType))
+	}
+
+	return len(node.(*rrbNode).children)
+}
+
+// Get returns the element at position i.
+func (v *GenericRRBVectorType) Get(i int) GenericType {
+	if i < 0 || uint(i) >= v.len {
+		panic("Index out of bounds")
+	}
+
+	leaf, leafIx := v.leafFor(uint(i))
+	return leaf[leafIx]
+}
+
+func (v *GenericRRBVectorType) leafFor(i uint) ([]GenericType, uint) {
+	node := v.root
+	for shift := v.shift; shift > 0; shift -= shiftSize {
+		n := node.(*rrbNode)
+		var idx int
+		idx, i = n.childIndex(i, shift)
+		node = n.children[idx]
+	}
+
+	return node.([]GenericType), i
+}
+
+// Set returns a new vector with the element at position i set to item.
+func (v *GenericRRBVectorType) Set(i int, item GenericType) *GenericRRBVectorType {
+	if i < 0 || uint(i) >= v.len {
+		panic("Index out of bounds")
+	}
+
+	return &GenericRRBVectorType{root: v.doAssoc(v.shift, v.root, uint(i), item), len: v.len, shift: v.shift}
+}
+
+func (v *GenericRRBVectorType) doAssoc(shift uint, node commonNode, i uint, item GenericType) commonNode {
+	if shift == 0 {
+		leaf := node.([]GenericType)
+		ret := make([]GenericType, len(leaf))
+		copy(ret, leaf)
+		ret[i] = item
+		return ret
+	}
+
+	n := node.(*rrbNode)
+	idx, subI := n.childIndex(i, shift)
+	children := make([]commonNode, len(n.children))
+	copy(children, n.children)
+	children[idx] = v.doAssoc(shift-shiftSize, children[idx], subI, item)
+	return &rrbNode{children: children, sizes: n.sizes, len: n.len}
+}
+
+// Append returns a new vector with item(s) appended to it.
+func (v *GenericRRBVectorType) Append(items ...GenericType) *GenericRRBVectorType {
+	return v.Concat(newGenericRRBVectorTypeTree(items))
+}
+
+// Concat returns a new vector containing all elements in v followed by all elements in other.
+func (v *GenericRRBVectorType) Concat(other *GenericRRBVectorType) *GenericRRBVectorType {
+	if other.len == 0 {
+		return v
+	}
+
+	if v.len == 0 {
+		return other
+	}
+
+	nodes := concatGenericRRBVectorTypeNodes(v.root, v.shift, other.root, other.shift)
+	shift := uintMax(v.shift, other.shift)
+	if len(nodes) == 1 {
+		return &GenericRRBVectorType{root: nodes[0], len: v.len + other.len, shift: shift}
+	}
+
+	shift += shiftSize
+	return &GenericRRBVectorType{root: newGenericRRBVectorTypeNode(nodes, shift), len: v.len + other.len, shift: shift}
+}
+
+// concatGenericRRBVectorTypeNodes merges the right edge of left with the left edge of right.
+// The result is one or two nodes at the level of the highest of left and right.
+func concatGenericRRBVectorTypeNodes(left commonNode, leftShift uint, right commonNode, rightShift uint) []commonNode {
+	if leftShift > rightShift {
+		l := left.(*rrbNode)
+		last := len(l.children) - 1
+		middle := concatGenericRRBVectorTypeNodes(l.children[last], leftShift-shiftSize, right, rightShift)
+		return rebalanceGenericRRBVectorTypeNodes(l.children[:last], middle, nil, leftShift)
+	}
+
+	if leftShift < rightShift {
+		r := right.(*rrbNode)
+		middle := concatGenericRRBVectorTypeNodes(left, leftShift, r.children[0], rightShift-shiftSize)
+		return rebalanceGenericRRBVectorTypeNodes(nil, middle, r.children[1:], rightShift)
+	}
+
+	if leftShift == 0 {
+		l, r := left.([]GenericType), right.([]GenericType)
+		if len(l)+len(r) > nodeSize {
+			return []commonNode{left, right}
+		}
+
+		leaf := make([]GenericType, 0, len(l)+len(r))
+		leaf = append(leaf, l...)
+		leaf = append(leaf, r...)
+		return []commonNode{leaf}
+	}
+
+	l, r := left.(*rrbNode), right.(*rrbNode)
+	last := len(l.children) - 1
+	middle := concatGenericRRBVectorTypeNodes(l.children[last], leftShift-shiftSize, r.children[0], rightShift-shiftSize)
+	return rebalanceGenericRRBVectorTypeNodes(l.children[:last], middle, r.children[1:], leftShift)
+}
+
+// rebalanceGenericRRBVectorTypeNodes redistributes the children in left, middle and right
+// into one or two new nodes at level shift.
+func rebalanceGenericRRBVectorTypeNodes(left, middle, right []commonNode, shift uint) []commonNode {
+	all := make([]commonNode, 0, len(left)+len(middle)+len(right))
+	all = append(all, left...)
+	all = append(all, middle...)
+	all = append(all, right...)
+	all = redistributeGenericRRBVectorTypeNodes(all, shift-shiftSize)
+	if len(all) <= nodeSize {
+		return []commonNode{newGenericRRBVectorTypeNode(all, shift)}
+	}
+
+	return []commonNode{
+		newGenericRRBVectorTypeNode(all[:nodeSize:nodeSize], shift),
+		newGenericRRBVectorTypeNode(all[nodeSize:], shift)}
+}
+
+func redistributeGenericRRBVectorTypeNodes(nodes []commonNode, shift uint) []commonNode {
+	counts := make([]int, len(nodes))
+	for i, node := range nodes {
+		counts[i] = slotsGenericRRBVectorTypeNode(node, shift)
+	}
+
+	plan := rrbConcatPlan(counts)
+	if len(plan) == len(nodes) {
+		return nodes
+	}
+
+	result := make([]commonNode, len(plan))
+	nodeIx, offset := 0, 0
+	for i, size := range plan {
+		if offset == 0 && size == slotsGenericRRBVectorTypeNode(nodes[nodeIx], shift) {
+			result[i] = nodes[nodeIx]
+			nodeIx++
+			continue
+		}
+
+		if shift == 0 {
+			leaf := make([]GenericType, 0, size)
+			for len(leaf) < size {
+				old := nodes[nodeIx].([]GenericType)
+				end := offset + size - len(leaf)
+				if end > len(old) {
+					end = len(old)
+				}
+
+				leaf = append(leaf, old[offset:end]...)
+				offset = end
+				if offset == len(old) {
+					nodeIx++
+					offset = 0
+				}
+			}
+
+			result[i] = leaf
+		} else {
+			children := make([]commonNode, 0, size)
+			for len(children) < size {
+				old := nodes[nodeIx].(*rrbNode).children
+				end := offset + size - len(children)
+				if end > len(old) {
+					end = len(old)
+				}
+
+				children = append(children, old[offset:end]...)
+				offset = end
+				if offset == len(old) {
+					nodeIx++
+					offset = 0
+				}
+			}
+
+			result[i] = newGenericRRBVectorTypeNode(children, shift)
+		}
+	}
+
+	return result
+}
+
+// takeGenericRRBVectorTypeNode returns a node containing the first n elements of node.
+func takeGenericRRBVectorTypeNode(node commonNode, shift, n uint) commonNode {
+	if shift == 0 {
+		leaf := make([]GenericType, n)
+		copy(leaf, node.([]GenericType))
+		return leaf
+	}
+
+	nd := node.(*rrbNode)
+	idx, subI := nd.childIndex(n-1, shift)
+	children := make([]commonNode, idx+1)
+	copy(children, nd.children)
+	children[idx] = takeGenericRRBVectorTypeNode(children[idx], shift-shiftSize, subI+1)
+	return newGenericRRBVectorTypeNode(children, shift)
+}
+
+// dropGenericRRBVectorTypeNode returns a node containing all but the first n elements of node.
+func dropGenericRRBVectorTypeNode(node commonNode, shift, n uint) commonNode {
+	if shift == 0 {
+		leaf := node.([]GenericType)
+		ret := make([]GenericType, uint(len(leaf))-n)
+		copy(ret, leaf[n:])
+		return ret
+	}
+
+	nd := node.(*rrbNode)
+	idx, subI := nd.childIndex(n, shift)
+	children := make([]commonNode, len(nd.children)-idx)
+	copy(children, nd.children[idx:])
+	if subI > 0 {
+		children[0] = dropGenericRRBVectorTypeNode(children[0], shift-shiftSize, subI)
+	}
+
+	return newGenericRRBVectorTypeNode(children, shift)
+}
+
+// Slice returns a new GenericRRBVectorType containing the elements [start,stop) in v.
+// Contrary to the slice of a regular vector no reference to elements outside of
+// the slice is kept.
+func (v *GenericRRBVectorType) Slice(start, stop int) *GenericRRBVectorType {
+	assertSliceOk(start, stop, v.Len())
+	if start == stop {
+		return emptyGenericRRBVectorType
+	}
+
+	if start == 0 && uint(stop) == v.len {
+		return v
+	}
+
+	root, shift := v.root, v.shift
+	if uint(stop) < v.len {
+		root = takeGenericRRBVectorTypeNode(root, shift, uint(stop))
+	}
+
+	if start > 0 {
+		root = dropGenericRRBVectorTypeNode(root, shift, uint(start))
+	}
+
+	// Remove levels that have become redundant
+	for shift > 0 && len(root.(*rrbNode).children) == 1 {
+		root = root.(*rrbNode).children[0]
+		shift -= shiftSize
+	}
+
+	return &GenericRRBVectorType{root: root, len: uint(stop - start), shift: shift}
+}
+
+// InsertAt returns a new vector with item(s) inserted before the element at position i.
+// If i equals the length of v the items are appended to the vector.
+func (v *GenericRRBVectorType) InsertAt(i int, items ...GenericType) *GenericRRBVectorType {
+	if i < 0 || uint(i) > v.len {
+		panic("Index out of bounds")
+	}
+
+	if len(items) == 0 {
+		return v
+	}
+
+	return v.Slice(0, i).Concat(newGenericRRBVectorTypeTree(items)).Concat(v.Slice(i, v.Len()))
+}
+
+// RemoveAt returns a new vector with the n elements starting at position i removed.
+func (v *GenericRRBVectorType) RemoveAt(i, n int) *GenericRRBVectorType {
+	assertSliceOk(i, i+n, v.Len())
+	if n == 0 {
+		return v
+	}
+
+	return v.Slice(0, i).Concat(v.Slice(i+n, v.Len()))
+}
+
+// Len returns the length of v.
+func (v *GenericRRBVectorType) Len() int {
+	return int(v.len)
+}
+
+// Range calls f repeatedly passing it each element in v in order as argument until either
+// all elements have been visited or f returns false.
+func (v *GenericRRBVectorType) Range(f func(GenericType) bool) {
+	rangeGenericRRBVectorTypeNode(v.root, v.shift, f)
+}
+
+func rangeGenericRRBVectorTypeNode(node commonNode, shift uint, f func(GenericType) bool) bool {
+	if shift == 0 {
+		for _, item := range node.([]GenericType) {
+			if !f(item) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	for _, child := range node.(*rrbNode).children {
+		if !rangeGenericRRBVectorTypeNode(child, shift-shiftSize, f) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ToNativeSlice returns a Go slice containing all elements of v
+func (v *GenericRRBVectorType) ToNativeSlice() []GenericType {
+	result := make([]GenericType, 0, v.len)
+	return appendGenericRRBVectorTypeNode(result, v.root, v.shift)
+}
+
+// MarshalJSON encodes v as a JSON array.
+func (v *GenericRRBVectorType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.ToNativeSlice())
+}
+
+// UnmarshalJSON sets v to the elements of data, a JSON array. v must not be used by
+// anyone else when this is called, it is meant to be used by encoding/json only.
+func (v *GenericRRBVectorType) UnmarshalJSON(data []byte) error {
+	items := make([]GenericType, 0)
+	err := jsonDecodeArray(data, func(dec *json.Decoder) error {
+		var item GenericType
+		if err := dec.Decode(&item); err != nil {
+			return err
+		}
+
+		items = append(items, item)
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	*v = *NewGenericRRBVectorType(items...)
+	return nil
+}
+
+func appendGenericRRBVectorTypeNode(dst []GenericType, node commonNode, shift uint) []GenericType {
+	if shift == 0 {
+		return append(dst, node.([]GenericType)...)
+	}
+
+	for _, child := range node.(*rrbNode).children {
+		dst = appendGenericRRBVectorTypeNode(dst, child, shift-shiftSize)
+	}
+
+	return dst
+}
@@ -0,0 +1,327 @@
+package generic_types
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"math"
+)
+
+//template:SnapshotTemplate
+
+/////////////////
+/// Snapshots ///
+/////////////////
+
+// snapshotVersion is written at the start of snapshot streams to identify the format.
+const snapshotVersion = 1
+
+// Tags written before each container in a snapshot stream.
+const (
+	snapshotVectorTag = 'v'
+	snapshotMapTag    = 'm'
+	snapshotSetTag    = 's'
+)
+
+func snapshotKind(tag byte) string {
+	switch tag {
+	case snapshotVectorTag:
+		return "vector"
+	case snapshotMapTag:
+		return "map"
+	case snapshotSetTag:
+		return "set"
+	}
+
+	return fmt.Sprintf("unknown container %q", tag)
+}
+
+// A SnapshotElementEncoder encodes the items of the containers written to a snapshot. Encode
+// is called with slices of elements, and with slices of key value items for maps and sets.
+// *gob.Encoder is a SnapshotElementEncoder.
+type SnapshotElementEncoder interface {
+	Encode(elements interface{}) error
+}
+
+// A SnapshotElementDecoder decodes the items written by a SnapshotElementEncoder. Decode is
+// called with pointers to slices of the same types as were passed to Encode.
+// *gob.Decoder is a SnapshotElementDecoder.
+type SnapshotElementDecoder interface {
+	Decode(elements interface{}) error
+}
+
+// snapshotNodeKey identifies a node of a vector by the address of its first item and its length.
+type snapshotNodeKey struct {
+	first interface{}
+	len   int
+}
+
+// A SnapshotEncoder writes vectors, maps and sets to a stream. Every node of the containers
+// is written only once, nodes shared with previously written containers, eg. older versions
+// of the same vector, are written as references to the already written nodes. Decoding the
+// stream using a SnapshotDecoder restores the structure sharing between the containers.
+type SnapshotEncoder struct {
+	w        *bufio.Writer
+	elements SnapshotElementEncoder
+	buf      bytes.Buffer
+	ids      map[interface{}]uint64
+	nodes    uint64
+	started  bool
+	err      error
+	scratch  [binary.MaxVarintLen64]byte
+}
+
+// NewSnapshotEncoder returns a SnapshotEncoder writing to w. The items of the containers are
+// encoded by the SnapshotElementEncoder returned by newElementEncoder for the writer given to
+// it. If newElementEncoder is nil the items are gob encoded, gob uses the GobEncoder or
+// encoding.BinaryMarshaler implementations of types that have one.
+func NewSnapshotEncoder(w io.Writer, newElementEncoder func(w io.Writer) SnapshotElementEncoder) *SnapshotEncoder {
+	e := &SnapshotEncoder{w: bufio.NewWriter(w), ids: make(map[interface{}]uint64)}
+	if newElementEncoder == nil {
+		e.elements = gob.NewEncoder(&e.buf)
+	} else {
+		e.elements = newElementEncoder(&e.buf)
+	}
+
+	return e
+}
+
+// encode writes the tag of a container followed by the container, written by writeContainer,
+// and flushes the stream. Once an error has occurred it is returned by all following calls
+// since the stream can no longer be decoded.
+func (e *SnapshotEncoder) encode(tag byte, writeContainer func() error) error {
+	if e.err != nil {
+		return e.err
+	}
+
+	if !e.started {
+		e.writeUvarint(snapshotVersion)
+		e.started = true
+	}
+
+	e.w.WriteByte(tag)
+	e.err = writeContainer()
+	if e.err == nil {
+		// Write errors are kept by the buffered writer and returned here
+		e.err = e.w.Flush()
+	}
+
+	return e.err
+}
+
+func (e *SnapshotEncoder) writeUvarint(x uint64) {
+	n := binary.PutUvarint(e.scratch[:], x)
+	e.w.Write(e.scratch[:n])
+}
+
+// writeElements writes elements, a slice of items, using the element encoder.
+func (e *SnapshotEncoder) writeElements(elements interface{}) error {
+	e.buf.Reset()
+	if err := e.elements.Encode(elements); err != nil {
+		return err
+	}
+
+	e.writeUvarint(uint64(e.buf.Len()))
+	e.w.Write(e.buf.Bytes())
+	return nil
+}
+
+// writeNode writes a reference to the node identified by key. Nodes that have not been written
+// before are written by writeBody following a zero reference. Nodes with a nil key are always
+// written.
+func (e *SnapshotEncoder) writeNode(key interface{}, writeBody func() error) error {
+	if id, ok := e.ids[key]; ok && key != nil {
+		e.writeUvarint(id)
+		return nil
+	}
+
+	e.writeUvarint(0)
+	if err := writeBody(); err != nil {
+		return err
+	}
+
+	// Nodes are numbered in the order that they are completed, same as when decoding
+	e.nodes++
+	if key != nil {
+		e.ids[key] = e.nodes
+	}
+
+	return nil
+}
+
+// A SnapshotDecoder reads the vectors, maps and sets written by a SnapshotEncoder. The
+// containers must be read in the order that they were written, using the methods of the
+// same container types.
+type SnapshotDecoder struct {
+	r        *bufio.Reader
+	elements SnapshotElementDecoder
+	buf      bytes.Buffer
+	nodes    []interface{}
+	started  bool
+	err      error
+}
+
+// NewSnapshotDecoder returns a SnapshotDecoder reading from r. The items of the containers
+// are decoded by the SnapshotElementDecoder returned by newElementDecoder for the reader given
+// to it. If newElementDecoder is nil the items are gob decoded. The decoder may read beyond
+// the end of the snapshot stream in r.
+func NewSnapshotDecoder(r io.Reader, newElementDecoder func(r io.Reader) SnapshotElementDecoder) *SnapshotDecoder {
+	d := &SnapshotDecoder{r: bufio.NewReader(r)}
+	if newElementDecoder == nil {
+		d.elements = gob.NewDecoder(&d.buf)
+	} else {
+		d.elements = newElementDecoder(&d.buf)
+	}
+
+	return d
+}
+
+// decode reads the tag of the next container, verifies that it is tag, and reads the container
+// using readContainer. io.EOF is returned if there are no more containers in the stream. Once
+// any other error has occurred it is returned by all following calls.
+func (d *SnapshotDecoder) decode(tag byte, readContainer func() error) error {
+	if d.err != nil {
+		return d.err
+	}
+
+	d.err = d.doDecode(tag, readContainer)
+	return d.err
+}
+
+func (d *SnapshotDecoder) doDecode(tag byte, readContainer func() error) error {
+	if !d.started {
+		version, err := binary.ReadUvarint(d.r)
+		if err != nil {
+			return err
+		}
+
+		if version != snapshotVersion {
+			return fmt.Errorf("unsupported snapshot version %d", version)
+		}
+
+		d.started = true
+	}
+
+	actual, err := d.r.ReadByte()
+	if err != nil {
+		return err
+	}
+
+	if actual != tag {
+		return fmt.Errorf("expected a %s in snapshot, found a %s", snapshotKind(tag), snapshotKind(actual))
+	}
+
+	if err := readContainer(); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (d *SnapshotDecoder) readUvarint() (uint64, error) {
+	return binary.ReadUvarint(d.r)
+}
+
+// readLength reads a length that is at most max.
+func (d *SnapshotDecoder) readLength(max uint64) (int, error) {
+	x, err := d.readUvarint()
+	if err != nil {
+		return 0, err
+	}
+
+	if x > max {
+		return 0, fmt.Errorf("invalid snapshot, length %d is larger than %d", x, max)
+	}
+
+	return int(x), nil
+}
+
+func (d *SnapshotDecoder) readUint32() (uint32, error) {
+	x, err := d.readUvarint()
+	if err != nil {
+		return 0, err
+	}
+
+	if x > math.MaxUint32 {
+		return 0, fmt.Errorf("invalid snapshot, %d does not fit in 32 bits", x)
+	}
+
+	return uint32(x), nil
+}
+
+// readElements reads items written by writeElements into the slice pointed to by elements.
+func (d *SnapshotDecoder) readElements(elements interface{}) error {
+	n, err := d.readUvarint()
+	if err != nil {
+		return err
+	}
+
+	d.buf.Reset()
+	if _, err := io.CopyN(&d.buf, d.r, int64(n)); err != nil {
+		return err
+	}
+
+	if err := d.elements.Decode(elements); err != nil {
+		return err
+	}
+
+	if d.buf.Len() > 0 {
+		return fmt.Errorf("invalid snapshot, %d bytes left after decoding elements", d.buf.Len())
+	}
+
+	return nil
+}
+
+// readNode reads a node written by writeNode. New nodes are read by readBody, previously
+// read nodes are returned from the nodes read so far.
+func (d *SnapshotDecoder) readNode(readBody func() (interface{}, error)) (interface{}, error) {
+	id, err := d.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+
+	if id == 0 {
+		node, err := readBody()
+		if err != nil {
+			return nil, err
+		}
+
+		d.nodes = append(d.nodes, node)
+		return node, nil
+	}
+
+	if id > uint64(len(d.nodes)) {
+		return nil, fmt.Errorf("invalid snapshot, reference to unknown node %d", id)
+	}
+
+	return d.nodes[id-1], nil
+}
+
+func invalidSnapshotNode(node interface{}) error {
+	return fmt.Errorf("invalid snapshot, unexpected node of type %T", node)
+}
+
+// vectorSnapshotChildCount returns the number of children of a vector node at level
+// holding count elements.
+func vectorSnapshotChildCount(level uint, count uint64) uint64 {
+	if count == 0 {
+		return 0
+	}
+
+	return (count-1)>>level + 1
+}
+
+func checkVectorSnapshotNodeLen(length int, expected uint64) error {
+	if uint64(length) != expected {
+		return fmt.Errorf("invalid snapshot, vector node of length %d where %d was expected", length, expected)
+	}
+
+	return nil
+}
@@ -0,0 +1,722 @@
+package generic_types
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+//template:PrivateSortedMapTemplate
+
+//////////////////
+/// Sorted map ///
+//////////////////
+
+type GenericSortedMapItem struct {
+	Key   GenericMapKeyType
+	Value GenericMapValueType
+}
+
+// privateGenericSortedMapItemNode is a node in a persistent B-tree. Items are kept in
+// key order. children is nil for leaf nodes, internal nodes have one child more than
+// they have items. size is the total number of items in the subtree rooted at the node.
+// Nodes are never modified once created, all updates copy the path from the root.
+type privateGenericSortedMapItemNode struct {
+	items    []GenericSortedMapItem
+	children []*privateGenericSortedMapItemNode
+	size     int
+}
+
+var emptyGenericSortedMapItemNode = &privateGenericSortedMapItemNode{}
+
+func newGenericSortedMapItemNode(items []GenericSortedMapItem, children []*privateGenericSortedMapItemNode) *privateGenericSortedMapItemNode {
+	size := len(items)
+	for _, child := range children {
+		size += child.size
+	}
+
+	return &privateGenericSortedMapItemNode{items: items, children: children, size: size}
+}
+
+func (n *privateGenericSortedMapItemNode) isLeaf() bool {
+	return n.children == nil
+}
+
+// search returns the index of the first item in n with a key that is not less than
+// key and whether the key of that item is equal to key.
+func (n *privateGenericSortedMapItemNode) search(key GenericMapKeyType) (int, bool) {
+	lo, hi := 0, len(n.items)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if genericLess(n.items[mid].Key, key) {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+
+	return lo, lo < len(n.items) && !genericLess(key, n.items[lo].Key)
+}
+
+func (n *privateGenericSortedMapItemNode) load(key GenericMapKeyType) (value GenericMapValueType, ok bool) {
+	for {
+		i, found := n.search(key)
+		if found {
+			return n.items[i].Value, true
+		}
+
+		if n.isLeaf() {
+			return value, false
+		}
+
+		n = n.children[i]
+	}
+}
+
+// store returns a copy of n containing item. The returned node may contain one item more
+// than btreeMaxItems in which case it has to be split by the caller.
+func (n *privateGenericSortedMapItemNode) store(item GenericSortedMapItem) (*privateGenericSortedMapItemNode, bool) {
+	i, found := n.search(item.Key)
+	if found {
+		items := append([]GenericSortedMapItem(nil), n.items...)
+		items[i] = item
+		return &privateGenericSortedMapItemNode{items: items, children: n.children, size: n.size}, false
+	}
+
+	if n.isLeaf() {
+		return &privateGenericSortedMapItemNode{items: insertGenericSortedMapItem(n.items, i, item), size: n.size + 1}, true
+	}
+
+	child, added := n.children[i].store(item)
+	children := append([]*privateGenericSortedMapItemNode(nil), n.children...)
+	children[i] = child
+	if len(child.items) <= btreeMaxItems {
+		size := n.size
+		if added {
+			size++
+		}
+
+		return &privateGenericSortedMapItemNode{items: n.items, children: children, size: size}, added
+	}
+
+	left, median, right := child.split()
+	children[i] = left
+	children = append(children, nil)
+	copy(children[i+2:], children[i+1:])
+	children[i+1] = right
+	return newGenericSortedMapItemNode(insertGenericSortedMapItem(n.items, i, median), children), added
+}
+
+// split splits an overfull node into two nodes and the item separating them.
+func (n *privateGenericSortedMapItemNode) split() (*privateGenericSortedMapItemNode, GenericSortedMapItem, *privateGenericSortedMapItemNode) {
+	m := len(n.items) / 2
+	var leftChildren, rightChildren []*privateGenericSortedMapItemNode
+	if !n.isLeaf() {
+		leftChildren, rightChildren = n.children[:m+1:m+1], n.children[m+1:]
+	}
+
+	return newGenericSortedMapItemNode(n.items[:m:m], leftChildren), n.items[m], newGenericSortedMapItemNode(n.items[m+1:], rightChildren)
+}
+
+func insertGenericSortedMapItem(items []GenericSortedMapItem, i int, item GenericSortedMapItem) []GenericSortedMapItem {
+	result := make([]GenericSortedMapItem, len(items)+1)
+	copy(result, items[:i])
+	result[i] = item
+	copy(result[i+1:], items[i:])
+	return result
+}
+
+func removeGenericSortedMapItem(items []GenericSortedMapItem, i int) []GenericSortedMapItem {
+	result := make([]GenericSortedMapItem, len(items)-1)
+	copy(result, items[:i])
+	copy(result[i:], items[i+1:])
+	return result
+}
+
+// delete returns a copy of n without the item identified by key. The returned node may
+// contain fewer than btreeMinItems items in which case it has to be rebalanced by the caller.
+func (n *privateGenericSortedMapItemNode) delete(key GenericMapKeyType) (*privateGenericSortedMapItemNode, bool) {
+	i, found := n.search(key)
+	if n.isLeaf() {
+		if !found {
+			return n, false
+		}
+
+		return &privateGenericSortedMapItemNode{items: removeGenericSortedMapItem(n.items, i), size: n.size - 1}, true
+	}
+
+	items := n.items
+	var child *privateGenericSortedMapItemNode
+	if found {
+		// Replace the deleted item with its predecessor
+		var predecessor GenericSortedMapItem
+		child, predecessor = n.children[i].deleteMax()
+		items = append([]GenericSortedMapItem(nil), n.items...)
+		items[i] = predecessor
+	} else {
+		var deleted bool
+		child, deleted = n.children[i].delete(key)
+		if !deleted {
+			return n, false
+		}
+	}
+
+	children := append([]*privateGenericSortedMapItemNode(nil), n.children...)
+	children[i] = child
+	result := &privateGenericSortedMapItemNode{items: items, children: children, size: n.size - 1}
+	if len(child.items) < btreeMinItems {
+		result = result.rebalance(i)
+	}
+
+	return result, true
+}
+
+// deleteMax returns a copy of n without its largest item together with that item.
+func (n *privateGenericSortedMapItemNode) deleteMax() (*privateGenericSortedMapItemNode, GenericSortedMapItem) {
+	last := len(n.items) - 1
+	if n.isLeaf() {
+		return &privateGenericSortedMapItemNode{items: n.items[:last:last], size: n.size - 1}, n.items[last]
+	}
+
+	child, item := n.children[last+1].deleteMax()
+	children := append([]*privateGenericSortedMapItemNode(nil), n.children...)
+	children[last+1] = child
+	result := &privateGenericSortedMapItemNode{items: n.items, children: children, size: n.size - 1}
+	if len(child.items) < btreeMinItems {
+		result = result.rebalance(last + 1)
+	}
+
+	return result, item
+}
+
+// rebalance restores the minimum number of items in child i of n by moving an item
+// from one of its siblings or by merging it with a sibling. The children of n must
+// not be shared with any other node, they are updated in place.
+func (n *privateGenericSortedMapItemNode) rebalance(i int) *privateGenericSortedMapItemNode {
+	child := n.children[i]
+	items := append([]GenericSortedMapItem(nil), n.items...)
+	if i > 0 && len(n.children[i-1].items) > btreeMinItems {
+		// Rotate the last item of the left sibling through n into child
+		left := n.children[i-1]
+		last := len(left.items) - 1
+		var leftChildren, childChildren []*privateGenericSortedMapItemNode
+		if !child.isLeaf() {
+			leftChildren = left.children[: last+1 : last+1]
+			childChildren = append([]*privateGenericSortedMapItemNode{left.children[last+1]}, child.children...)
+		}
+
+		n.children[i-1] = newGenericSortedMapItemNode(left.items[:last:last], leftChildren)
+		n.children[i] = newGenericSortedMapItemNode(insertGenericSortedMapItem(child.items, 0, items[i-1]), childChildren)
+		items[i-1] = left.items[last]
+		return &privateGenericSortedMapItemNode{items: items, children: n.children, size: n.size}
+	}
+
+	if i < len(n.children)-1 && len(n.children[i+1].items) > btreeMinItems {
+		// Rotate the first item of the right sibling through n into child
+		right := n.children[i+1]
+		var rightChildren, childChildren []*privateGenericSortedMapItemNode
+		if !child.isLeaf() {
+			rightChildren = right.children[1:]
+			childChildren = append(append([]*privateGenericSortedMapItemNode(nil), child.children...), right.children[0])
+		}
+
+		n.children[i] = newGenericSortedMapItemNode(insertGenericSortedMapItem(child.items, len(child.items), items[i]), childChildren)
+		n.children[i+1] = newGenericSortedMapItemNode(right.items[1:], rightChildren)
+		items[i] = right.items[0]
+		return &privateGenericSortedMapItemNode{items: items, children: n.children, size: n.size}
+	}
+
+	// Merge child with one of its siblings and the item separating them
+	if i == len(n.children)-1 {
+		i--
+	}
+
+	left, right := n.children[i], n.children[i+1]
+	mergedItems := make([]GenericSortedMapItem, 0, len(left.items)+len(right.items)+1)
+	mergedItems = append(append(append(mergedItems, left.items...), items[i]), right.items...)
+	var mergedChildren []*privateGenericSortedMapItemNode
+	if !left.isLeaf() {
+		mergedChildren = make([]*privateGenericSortedMapItemNode, 0, len(left.children)+len(right.children))
+		mergedChildren = append(append(mergedChildren, left.children...), right.children...)
+	}
+
+	children := append(n.children[:i+1], n.children[i+2:]...)
+	children[i] = &privateGenericSortedMapItemNode{items: mergedItems, children: mergedChildren, size: left.size + right.size + 1}
+	return &privateGenericSortedMapItemNode{items: removeGenericSortedMapItem(items, i), children: children, size: n.size}
+}
+
+// get returns the item with rank i in the subtree rooted at n.
+func (n *privateGenericSortedMapItemNode) get(i int) GenericSortedMapItem {
+	for !n.isLeaf() {
+		j := 0
+		for ; i >= n.children[j].size; j++ {
+			i -= n.children[j].size
+			if i == 0 {
+				return n.items[j]
+			}
+
+			i--
+		}
+
+		n = n.children[j]
+	}
+
+	return n.items[i]
+}
+
+func (n *privateGenericSortedMapItemNode) rangeItems(f func(GenericMapKeyType, GenericMapValueType) bool) bool {
+	for i, item := range n.items {
+		if !n.isLeaf() && !n.children[i].rangeItems(f) {
+			return false
+		}
+
+		if !f(item.Key, item.Value) {
+			return false
+		}
+	}
+
+	return n.isLeaf() || n.children[len(n.items)].rangeItems(f)
+}
+
+// rangeFrom calls f for all items in the subtree rooted at n, in order, starting at
+// the first item with a key that is not less than key.
+func (n *privateGenericSortedMapItemNode) rangeFrom(key GenericMapKeyType, f func(GenericMapKeyType, GenericMapValueType) bool) bool {
+	i, found := n.search(key)
+	if !n.isLeaf() && !found && !n.children[i].rangeFrom(key, f) {
+		return false
+	}
+
+	for ; i < len(n.items); i++ {
+		if !f(n.items[i].Key, n.items[i].Value) {
+			return false
+		}
+
+		if !n.isLeaf() && !n.children[i+1].rangeItems(f) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// GenericSortedMapType is a persistent key - value map ordered by key
+type GenericSortedMapType struct {
+	root *privateGenericSortedMapItemNode
+}
+
+var emptyGenericSortedMapType = &GenericSortedMapType{root: emptyGenericSortedMapItemNode}
+
+// newGenericSortedMapType returns a map containing items. For items with equal keys the
+// last one is kept. The tree is built bottom up from the sorted items rather than by
+// storing the items one at a time.
+func newGenericSortedMapType(items []GenericSortedMapItem) *GenericSortedMapType {
+	if len(items) == 0 {
+		return emptyGenericSortedMapType
+	}
+
+	sorted := make([]GenericSortedMapItem, len(items))
+	copy(sorted, items)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return genericLess(sorted[i].Key, sorted[j].Key)
+	})
+
+	unique := sorted[:0]
+	for i, item := range sorted {
+		if i+1 < len(sorted) && !genericLess(item.Key, sorted[i+1].Key) {
+			continue
+		}
+
+		unique = append(unique, item)
+	}
+
+	height := 0
+	for btreeCapacity(height) < len(unique) {
+		height++
+	}
+
+	return &GenericSortedMapType{root: buildGenericSortedMapItemNode(unique, height)}
+}
+
+// buildGenericSortedMapItemNode returns a node of the given height holding the sorted
+// items. The items are spread evenly over as few children as possible, which keeps all
+// nodes within the B-tree limits.
+func buildGenericSortedMapItemNode(items []GenericSortedMapItem, height int) *privateGenericSortedMapItemNode {
+	if height == 0 {
+		return newGenericSortedMapItemNode(items[:len(items):len(items)], nil)
+	}
+
+	childCapacity := btreeCapacity(height - 1)
+	count := (len(items) + childCapacity + 1) / (childCapacity + 1)
+	if count < 2 {
+		count = 2
+	}
+
+	// The items not in the children separate them
+	childItems := len(items) - (count - 1)
+	nodeItems := make([]GenericSortedMapItem, 0, count-1)
+	children := make([]*privateGenericSortedMapItemNode, 0, count)
+	start := 0
+	for i := 0; i < count; i++ {
+		size := childItems / count
+		if i < childItems%count {
+			size++
+		}
+
+		children = append(children, buildGenericSortedMapItemNode(items[start:start+size], height-1))
+		start += size
+		if i < count-1 {
+			nodeItems = append(nodeItems, items[start])
+			start++
+		}
+	}
+
+	return newGenericSortedMapItemNode(nodeItems, children)
+}
+
+// Len returns the number of items in m.
+func (m *GenericSortedMapType) Len() int {
+	return m.root.size
+}
+
+// Load returns value identified by key. ok is set to true if key exists in the map, false otherwise.
+func (m *GenericSortedMapType) Load(key GenericMapKeyType) (value GenericMapValueType, ok bool) {
+	return m.root.load(key)
+}
+
+// Store returns a new GenericSortedMapType containing value identified by key.
+func (m *GenericSortedMapType) Store(key GenericMapKeyType, value GenericMapValueType) *GenericSortedMapType {
+	root, _ := m.root.store(GenericSortedMapItem{Key: key, Value: value})
+	if len(root.items) > btreeMaxItems {
+		left, median, right := root.split()
+		root = newGenericSortedMapItemNode([]GenericSortedMapItem{median}, []*privateGenericSortedMapItemNode{left, right})
+	}
+
+	return &GenericSortedMapType{root: root}
+}
+
+// Delete returns a new GenericSortedMapType without the element identified by key.
+func (m *GenericSortedMapType) Delete(key GenericMapKeyType) *GenericSortedMapType {
+	root, deleted := m.root.delete(key)
+	if !deleted {
+		return m
+	}
+
+	if len(root.items) == 0 && !root.isLeaf() {
+		root = root.children[0]
+	}
+
+	return &GenericSortedMapType{root: root}
+}
+
+// Get returns the key and value of the item at position i in key order.
+func (m *GenericSortedMapType) Get(i int) (GenericMapKeyType, GenericMapValueType) {
+	if i < 0 || i >= m.root.size {
+		panic("Index out of bounds")
+	}
+
+	item := m.root.get(i)
+	return item.Key, item.Value
+}
+
+// Min returns the item with the smallest key. ok is set to false if m is empty.
+func (m *GenericSortedMapType) Min() (key GenericMapKeyType, value GenericMapValueType, ok bool) {
+	if m.root.size == 0 {
+		return key, value, false
+	}
+
+	n := m.root
+	for !n.isLeaf() {
+		n = n.children[0]
+	}
+
+	return n.items[0].Key, n.items[0].Value, true
+}
+
+// Max returns the item with the largest key. ok is set to false if m is empty.
+func (m *GenericSortedMapType) Max() (key GenericMapKeyType, value GenericMapValueType, ok bool) {
+	if m.root.size == 0 {
+		return key, value, false
+	}
+
+	n := m.root
+	for !n.isLeaf() {
+		n = n.children[len(n.children)-1]
+	}
+
+	item := n.items[len(n.items)-1]
+	return item.Key, item.Value, true
+}
+
+// Floor returns the item with the largest key that is less than or equal to key.
+// ok is set to false if there is no such item.
+func (m *GenericSortedMapType) Floor(key GenericMapKeyType) (floorKey GenericMapKeyType, value GenericMapValueType, ok bool) {
+	n := m.root
+	for {
+		i, found := n.search(key)
+		if found {
+			return n.items[i].Key, n.items[i].Value, true
+		}
+
+		if i > 0 {
+			floorKey, value, ok = n.items[i-1].Key, n.items[i-1].Value, true
+		}
+
+		if n.isLeaf() {
+			return floorKey, value, ok
+		}
+
+		n = n.children[i]
+	}
+}
+
+// Ceiling returns the item with the smallest key that is greater than or equal to key.
+// ok is set to false if there is no such item.
+func (m *GenericSortedMapType) Ceiling(key GenericMapKeyType) (ceilingKey GenericMapKeyType, value GenericMapValueType, ok bool) {
+	n := m.root
+	for {
+		i, found := n.search(key)
+		if i < len(n.items) {
+			ceilingKey, value, ok = n.items[i].Key, n.items[i].Value, true
+		}
+
+		if found || n.isLeaf() {
+			return ceilingKey, value, ok
+		}
+
+		n = n.children[i]
+	}
+}
+
+// Range calls f repeatedly passing it each key and value as argument, in key order,
+// until either all elements have been visited or f returns false.
+func (m *GenericSortedMapType) Range(f func(GenericMapKeyType, GenericMapValueType) bool) {
+	m.root.rangeItems(f)
+}
+
+// RangeFrom works like Range but starts at the first item with a key that is greater
+// than or equal to key.
+func (m *GenericSortedMapType) RangeFrom(key GenericMapKeyType, f func(GenericMapKeyType, GenericMapValueType) bool) {
+	m.root.rangeFrom(key, f)
+}
+
+// RangeBetween works like Range but only visits the items with a key that is greater
+// than or equal to lo and less than hi.
+func (m *GenericSortedMapType) RangeBetween(lo, hi GenericMapKeyType, f func(GenericMapKeyType, GenericMapValueType) bool) {
+	m.root.rangeFrom(lo, func(key GenericMapKeyType, value GenericMapValueType) bool {
+		return genericLess(key, hi) && f(key, value)
+	})
+}
+
+// ToNativeMap returns a native Go map containing all elements of m.
+func (m *GenericSortedMapType) ToNativeMap() map[GenericMapKeyType]GenericMapValueType {
+	result := make(map[GenericMapKeyType]GenericMapValueType)
+	m.Range(func(key GenericMapKeyType, value GenericMapValueType) bool {
+		result[key] = value
+		return true
+	})
+
+	return result
+}
+
+// MarshalJSON encodes m in key order as a JSON object if the keys are strings, integers or
+// implement encoding.TextMarshaler, following the same rules as encoding/json uses for map
+// keys. Other keys are encoded as an array of [key, value] arrays.
+func (m *GenericSortedMapType) MarshalJSON() ([]byte, error) {
+	return jsonEncodeMap(jsonObjectKeys((*GenericMapKeyType)(nil)), func(entry func(key, value interface{}) bool) {
+		m.Range(func(key GenericMapKeyType, value GenericMapValueType) bool {
+			return entry(&key, &value)
+		})
+	})
+}
+
+// UnmarshalJSON sets m to the items of data, either a JSON object or an array of [key, value]
+// arrays. m must not be used by anyone else when this is called, it is meant to be used by
+// encoding/json only.
+func (m *GenericSortedMapType) UnmarshalJSON(data []byte) error {
+	items := make([]GenericSortedMapItem, 0)
+	err := jsonDecodeMap(data, func(decodeKey func(interface{}) error, dec *json.Decoder) error {
+		var item GenericSortedMapItem
+		if err := decodeKey(&item.Key); err != nil {
+			return err
+		}
+
+		if err := dec.Decode(&item.Value); err != nil {
+			return err
+		}
+
+		items = append(items, item)
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	*m = *newGenericSortedMapType(items)
+	return nil
+}
+
+//template:DefaultKeyLessTemplate
+
+func genericLess(a, b GenericMapKeyType) bool {
+	return a < b
+}
+
+//template:PublicSortedMapTemplate
+
+////////////////////
+/// Constructors ///
+////////////////////
+
+// NewGenericSortedMapType returns a new GenericSortedMapType containing all items in items.
+func NewGenericSortedMapType(items ...GenericSortedMapItem) *GenericSortedMapType {
+	return newGenericSortedMapType(items)
+}
+
+// NewGenericSortedMapTypeFromNativeMap returns a new GenericSortedMapType containing all items in m.
+func NewGenericSortedMapTypeFromNativeMap(m map[GenericMapKeyType]GenericMapValueType) *GenericSortedMapType {
+	items := make([]GenericSortedMapItem, 0, len(m))
+	for key, value := range m {
+		items = append(items, GenericSortedMapItem{Key: key, Value: value})
+	}
+
+	return newGenericSortedMapType(items)
+}
+
+//template:SortedSetTemplate
+
+// GenericSortedSetType is a persistent set ordered by element
+type GenericSortedSetType struct {
+	backingMap *GenericSortedMapType
+}
+
+// NewGenericSortedSetType returns a new GenericSortedSetType containing items.
+func NewGenericSortedSetType(items ...GenericMapKeyType) *GenericSortedSetType {
+	mapItems := make([]GenericSortedMapItem, 0, len(items))
+	var mapValue GenericMapValueType
+	for _, x := range items {
+		mapItems = append(mapItems, GenericSortedMapItem{Key: x, Value: mapValue})
+	}
+
+	return &GenericSortedSetType{backingMap: newGenericSortedMapType(mapItems)}
+}
+
+// Add returns a new GenericSortedSetType containing item.
+func (s *GenericSortedSetType) Add(item GenericMapKeyType) *GenericSortedSetType {
+	var mapValue GenericMapValueType
+	return &GenericSortedSetType{backingMap: s.backingMap.Store(item, mapValue)}
+}
+
+// Delete returns a new GenericSortedSetType without item.
+func (s *GenericSortedSetType) Delete(item GenericMapKeyType) *GenericSortedSetType {
+	newMap := s.backingMap.Delete(item)
+	if newMap == s.backingMap {
+		return s
+	}
+
+	return &GenericSortedSetType{backingMap: newMap}
+}
+
+// Contains returns true if item is present in s, false otherwise.
+func (s *GenericSortedSetType) Contains(item GenericMapKeyType) bool {
+	_, ok := s.backingMap.Load(item)
+	return ok
+}
+
+// Len returns the number of elements in s.
+func (s *GenericSortedSetType) Len() int {
+	return s.backingMap.Len()
+}
+
+// Get returns the element at position i in sort order.
+func (s *GenericSortedSetType) Get(i int) GenericMapKeyType {
+	item, _ := s.backingMap.Get(i)
+	return item
+}
+
+// Min returns the smallest element in s. ok is set to false if s is empty.
+func (s *GenericSortedSetType) Min() (item GenericMapKeyType, ok bool) {
+	item, _, ok = s.backingMap.Min()
+	return item, ok
+}
+
+// Max returns the largest element in s. ok is set to false if s is empty.
+func (s *GenericSortedSetType) Max() (item GenericMapKeyType, ok bool) {
+	item, _, ok = s.backingMap.Max()
+	return item, ok
+}
+
+// Floor returns the largest element in s that is less than or equal to item.
+// ok is set to false if there is no such element.
+func (s *GenericSortedSetType) Floor(item GenericMapKeyType) (floorItem GenericMapKeyType, ok bool) {
+	floorItem, _, ok = s.backingMap.Floor(item)
+	return floorItem, ok
+}
+
+// Ceiling returns the smallest element in s that is greater than or equal to item.
+// ok is set to false if there is no such element.
+func (s *GenericSortedSetType) Ceiling(item GenericMapKeyType) (ceilingItem GenericMapKeyType, ok bool) {
+	ceilingItem, _, ok = s.backingMap.Ceiling(item)
+	return ceilingItem, ok
+}
+
+// Range calls f repeatedly passing it each element in s as argument, in order,
+// until either all elements have been visited or f returns false.
+func (s *GenericSortedSetType) Range(f func(GenericMapKeyType) bool) {
+	s.backingMap.Range(func(k GenericMapKeyType, _ GenericMapValueType) bool {
+		return f(k)
+	})
+}
+
+// RangeFrom works like Range but starts at the first element that is greater than
+// or equal to item.
+func (s *GenericSortedSetType) RangeFrom(item GenericMapKeyType, f func(GenericMapKeyType) bool) {
+	s.backingMap.RangeFrom(item, func(k GenericMapKeyType, _ GenericMapValueType) bool {
+		return f(k)
+	})
+}
+
+// RangeBetween works like Range but only visits the elements that are greater than
+// or equal to lo and less than hi.
+func (s *GenericSortedSetType) RangeBetween(lo, hi GenericMapKeyType, f func(GenericMapKeyType) bool) {
+	s.backingMap.RangeBetween(lo, hi, func(k GenericMapKeyType, _ GenericMapValueType) bool {
+		return f(k)
+	})
+}
+
+// ToNativeSlice returns a native Go slice containing all elements of s in order.
+func (s *GenericSortedSetType) ToNativeSlice() []GenericMapKeyType {
+	items := make([]GenericMapKeyType, 0, s.Len())
+	s.Range(func(item GenericMapKeyType) bool {
+		items = append(items, item)
+		return true
+	})
+
+	return items
+}
+
+// MarshalJSON encodes s as a JSON array in element order.
+func (s *GenericSortedSetType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.ToNativeSlice())
+}
+
+// UnmarshalJSON sets s to the elements of data, a JSON array. s must not be used by
+// anyone else when this is called, it is meant to be used by encoding/json only.
+func (s *GenericSortedSetType) UnmarshalJSON(data []byte) error {
+	items := make([]GenericMapKeyType, 0)
+	err := jsonDecodeArray(data, func(dec *json.Decoder) error {
+		var item GenericMapKeyType
+		if err := dec.Decode(&item); err != nil {
+			return err
+		}
+
+		items = append(items, item)
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	*s = *NewGenericSortedSetType(items...)
+	return nil
+}
@@ -9,3 +9,11 @@ type GenericMapKeyType int
 
 // GenericMapValueType is a placeholder for types used as values in maps.
 type GenericMapValueType int
+
+// GenericComparableType is a placeholder for comparable types that are hashed and
+// compared by the generated code.
+type GenericComparableType int
+
+// GenericContainerType is a placeholder for containers that are hashed and compared
+// by the generated code.
+type GenericContainerType = GenericVectorType
@@ -1,15 +1,29 @@
 package templates
 
 // NOTE: This file is auto generated, don't edit manually!
-const CommonTemplate string = `
+const CommonImportsTemplate string = `
 import (
+	"bufio"
+	"bytes"
+	"encoding"
 	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
 	"fmt"
 	"hash/crc32"
+	"io"
 	"math"
+	"math/bits"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"unsafe"
 )
 
+`
+const CommonTemplate string = `
 const shiftSize = 5
 const nodeSize = 32
 const shiftBitMask = 0x1F
@@ -48,9 +62,324 @@ func assertSliceOk(start, stop, len int) {
 	}
 }
 
-const upperMapLoadFactor float64 = 8.0
-const lowerMapLoadFactor float64 = 2.0
-const initialMapLoadFactor float64 = (upperMapLoadFactor + lowerMapLoadFactor) / 2
+func assertTransientEditable(editable bool) {
+	if !editable {
+		panic("Transient used after call to Persistent")
+	}
+}
+
+// transientOwner identifies the nodes that are owned, and may be edited in place,
+// by a transient.
+type transientOwner struct {
+	_ byte
+}
+
+// vectorNodeOwner is kept in a slot beyond the length of the vector trie nodes owned by
+// a transient. Leaves have no room for an owner of their own, the leaves of a node that
+// are owned by the transient are instead marked in leaves.
+type vectorNodeOwner struct {
+	transient *transientOwner
+	leaves    uint32
+}
+
+// Number of hash bits used to place items in a CHAMP tree. Items with identical
+// hashes are stored in collision nodes below the last level.
+const champHashBits = 32
+
+func champBitpos(hash uint32, shift uint) uint32 {
+	return 1 << ((hash >> shift) & shiftBitMask)
+}
+
+func champIndex(bitmap, bit uint32) int {
+	return bits.OnesCount32(bitmap & (bit - 1))
+}
+
+// Maximum and minimum number of items in a B-tree node. The root is allowed to
+// contain fewer than btreeMinItems items.
+const btreeMaxItems = 2*btreeMinItems + 1
+const btreeMinItems = 15
+
+// btreeCapacity returns the maximum number of items in a B-tree of the given height.
+func btreeCapacity(height int) int {
+	capacity := btreeMaxItems
+	for ; height > 0; height-- {
+		capacity = capacity*(btreeMaxItems+1) + btreeMaxItems
+	}
+
+	return capacity
+}
+
+// Maximum ratio between the lengths of the front and back lists of a deque.
+const dequeBalance = 3
+
+// jsonObjectKey returns key encoded as a JSON object key using the same rules as
+// encoding/json uses for map keys.
+func jsonObjectKey(key interface{}) ([]byte, error) {
+	v := reflect.ValueOf(key)
+	if v.Kind() == reflect.String {
+		return json.Marshal(v.String())
+	}
+
+	if tm, ok := key.(encoding.TextMarshaler); ok {
+		text, err := tm.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(string(text))
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return json.Marshal(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return json.Marshal(strconv.FormatUint(v.Uint(), 10))
+	}
+
+	return nil, fmt.Errorf("unsupported JSON object key type %T", key)
+}
+
+// jsonObjectKeys returns true if keys of the type pointed to by keyPtr are encoded as
+// JSON object keys, that is if encoding/json would accept them as map keys. Other keys
+// are encoded as arrays of [key, value] pairs.
+func jsonObjectKeys(keyPtr interface{}) bool {
+	t := reflect.TypeOf(keyPtr).Elem()
+	switch t.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+
+	return t.Implements(reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem())
+}
+
+// jsonDecodeObjectKey decodes s, a JSON object key, into the value pointed to by key
+// using the same rules as encoding/json uses for map keys.
+func jsonDecodeObjectKey(s string, key interface{}) error {
+	if tu, ok := key.(encoding.TextUnmarshaler); ok {
+		return tu.UnmarshalText([]byte(s))
+	}
+
+	v := reflect.ValueOf(key).Elem()
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || v.OverflowInt(n) {
+			return fmt.Errorf("invalid JSON object key %q for type %s", s, v.Type())
+		}
+
+		v.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil || v.OverflowUint(n) {
+			return fmt.Errorf("invalid JSON object key %q for type %s", s, v.Type())
+		}
+
+		v.SetUint(n)
+		return nil
+	}
+
+	return fmt.Errorf("unsupported JSON object key type %s", v.Type())
+}
+
+// jsonEncodeMap returns the JSON encoding of the entries passed by rangeEntries to its
+// argument. The entries are encoded as an object if objectKeys is true, otherwise as an
+// array of [key, value] arrays. Pointers to the keys and values are passed so that
+// MarshalJSON methods with pointer receivers, such as those of the containers, are used.
+func jsonEncodeMap(objectKeys bool, rangeEntries func(entry func(key, value interface{}) bool)) ([]byte, error) {
+	start, sep, end := byte('['), byte(','), byte(']')
+	if objectKeys {
+		start, sep, end = '{', ':', '}'
+	}
+
+	result := []byte{start}
+	var err error
+	rangeEntries(func(key, value interface{}) bool {
+		var k, v []byte
+		if objectKeys {
+			k, err = jsonObjectKey(reflect.ValueOf(key).Elem().Interface())
+		} else {
+			k, err = json.Marshal(key)
+		}
+
+		if err != nil {
+			return false
+		}
+
+		if v, err = json.Marshal(value); err != nil {
+			return false
+		}
+
+		if len(result) > 1 {
+			result = append(result, ',')
+		}
+
+		if !objectKeys {
+			result = append(result, '[')
+		}
+
+		result = append(append(append(result, k...), sep), v...)
+		if !objectKeys {
+			result = append(result, ']')
+		}
+
+		return true
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(result, end), nil
+}
+
+// jsonDecodeArray decodes data, a JSON array or null, calling decodeItem with a decoder
+// positioned at each element of the array.
+func jsonDecodeArray(data []byte, decodeItem func(dec *json.Decoder) error) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil || tok == nil {
+		return err
+	}
+
+	if tok != json.Delim('[') {
+		return fmt.Errorf("expected a JSON array, got %v", tok)
+	}
+
+	for dec.More() {
+		if err := decodeItem(dec); err != nil {
+			return err
+		}
+	}
+
+	_, err = dec.Token()
+	return err
+}
+
+// jsonDecodeMap decodes data, a JSON object, an array of [key, value] arrays or null.
+// decodeEntry is called for each entry with a function that decodes the key into the
+// value pointed to by its argument, and a decoder positioned at the value.
+func jsonDecodeMap(data []byte, decodeEntry func(decodeKey func(key interface{}) error, dec *json.Decoder) error) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil || tok == nil {
+		return err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+
+			decodeKey := func(key interface{}) error { return jsonDecodeObjectKey(keyTok.(string), key) }
+			if err := decodeEntry(decodeKey, dec); err != nil {
+				return err
+			}
+		}
+	case json.Delim('['):
+		for dec.More() {
+			if err := jsonExpectDelim(dec, '['); err != nil {
+				return err
+			}
+
+			if err := decodeEntry(dec.Decode, dec); err != nil {
+				return err
+			}
+
+			if err := jsonExpectDelim(dec, ']'); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("expected a JSON object or array, got %v", tok)
+	}
+
+	_, err = dec.Token()
+	return err
+}
+
+func jsonExpectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	if tok != delim {
+		return fmt.Errorf("expected %v in JSON key value pair, got %v", delim, tok)
+	}
+
+	return nil
+}
+
+// binaryFormatVersion is the first byte of the binary encoding of containers.
+const binaryFormatVersion = 1
+
+// binaryMarshal returns the binary encoding of a container with count items. The encoding
+// is a version byte, the number of items as a uvarint and the items in gob encoded chunks.
+// encodeChunks is called with a function gob encoding a chunk, a slice of items.
+func binaryMarshal(count int, encodeChunks func(encode func(chunk interface{}) error) error) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteByte(binaryFormatVersion)
+	var scratch [binary.MaxVarintLen64]byte
+	buf.Write(scratch[:binary.PutUvarint(scratch[:], uint64(count))])
+	if err := encodeChunks(gob.NewEncoder(buf).Encode); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// binaryUnmarshal decodes data written by binaryMarshal. decodeChunk is called with a function
+// gob decoding into its argument, a pointer to a slice of items, until all items have been
+// decoded. decodeChunk returns the number of items decoded.
+func binaryUnmarshal(data []byte, decodeChunk func(decode func(chunk interface{}) error) (int, error)) error {
+	r := bytes.NewReader(data)
+	version, err := r.ReadByte()
+	if err != nil {
+		return io.ErrUnexpectedEOF
+	}
+
+	if version != binaryFormatVersion {
+		return fmt.Errorf("unsupported binary format version %d", version)
+	}
+
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return io.ErrUnexpectedEOF
+	}
+
+	dec := gob.NewDecoder(r)
+	for decoded := uint64(0); decoded < count; {
+		n, err := decodeChunk(dec.Decode)
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if n == 0 || decoded+uint64(n) > count {
+			return fmt.Errorf("invalid binary encoding, chunk of %d items after %d of %d items", n, decoded, count)
+		}
+
+		decoded += uint64(n)
+	}
+
+	if r.Len() > 0 {
+		return fmt.Errorf("invalid binary encoding, %d bytes left after the last item", r.Len())
+	}
+
+	return nil
+}
 
 //////////////////////////
 //// Hash functions //////
@@ -142,367 +471,3244 @@ func float32Hash(x float32) uint32 {
 	return uint32Hash(math.Float32bits(x))
 }
 
+// loadHash returns the container hash kept in cache, zero if it has not been computed yet.
+// Each version of a container has its own cache which is shared by all copies of it, zero
+// containers have no cache.
+func loadHash(cache *uint32) uint32 {
+	if cache == nil {
+		return 0
+	}
+
+	return atomic.LoadUint32(cache)
+}
+
+// cacheHash stores h, a computed container hash, in cache and returns it. Zero marks
+// hashes that have not been computed yet, computed hashes that are zero are stored as one.
+func cacheHash(cache *uint32, h uint32) uint32 {
+	if h == 0 {
+		h = 1
+	}
+
+	if cache != nil {
+		atomic.StoreUint32(cache, h)
+	}
+
+	return h
+}
+
 `
-const PrivateMapTemplate string = `
-///////////
-/// Map ///
-///////////
+const ComparableValueOpsTemplate string = `
+func {{.ValueHashFunc}}(x *{{.ValueTypeName}}) uint32 {
+	return {{.BasicHashFunc}}(*x)
+}
 
-//////////////////////
-/// Backing vector ///
-//////////////////////
+func {{.ValueEqualFunc}}(a, b *{{.ValueTypeName}}) bool {
+	return *a == *b
+}
 
-type private{{.MapItemTypeName}}BucketVector struct {
-	tail  []private{{.MapItemTypeName}}Bucket
-	root  commonNode
-	len   uint
-	shift uint
+`
+const ContainerKeyOpsTemplate string = `
+func {{.ValueHashFunc}}(x {{.ValueTypeName}}) uint32 {
+	return x.Hash()
 }
 
-type {{.MapItemTypeName}} struct {
-	Key   {{.MapKeyTypeName}}
-	Value {{.MapValueTypeName}}
+func {{.ValueEqualFunc}}(a, b {{.ValueTypeName}}) bool {
+	return a.Equals(&b)
+}
+`
+const ContainerValueOpsTemplate string = `
+func {{.ValueHashFunc}}(x *{{.ValueTypeName}}) uint32 {
+	return x.Hash()
 }
 
-type private{{.MapItemTypeName}}Bucket []{{.MapItemTypeName}}
+func {{.ValueEqualFunc}}(a, b *{{.ValueTypeName}}) bool {
+	return a.Equals(b)
+}
 
-var empty{{.MapItemTypeName}}BucketVectorTail = make([]private{{.MapItemTypeName}}Bucket, 0)
-var empty{{.MapItemTypeName}}BucketVector *private{{.MapItemTypeName}}BucketVector = &private{{.MapItemTypeName}}BucketVector{root: emptyCommonNode, shift: shiftSize, tail: empty{{.MapItemTypeName}}BucketVectorTail}
+`
+const DefaultHeapLessTemplate string = `
+func {{.HeapLessFunc}}(a, b {{.TypeName}}) bool {
+	return a < b
+}
 
-func (v *private{{.MapItemTypeName}}BucketVector) Get(i int) private{{.MapItemTypeName}}Bucket {
-	if i < 0 || uint(i) >= v.len {
-		panic("Index out of bounds")
-	}
+`
+const DefaultKeyEqualTemplate string = `
+func {{.MapKeyEqFunc}}(a, b {{.MapKeyTypeName}}) bool {
+	return a == b
+}
 
-	return v.sliceFor(uint(i))[i&shiftBitMask]
+`
+const DefaultKeyLessTemplate string = `
+func {{.MapKeyLessFunc}}(a, b {{.MapKeyTypeName}}) bool {
+	return a < b
 }
 
-func (v *private{{.MapItemTypeName}}BucketVector) sliceFor(i uint) []private{{.MapItemTypeName}}Bucket {
-	if i >= v.tailOffset() {
-		return v.tail
+`
+const DequeTemplate string = `
+/////////////
+/// Deque ///
+/////////////
+
+// private{{.DequeTypeName}}List is a, possibly lazily evaluated, list of elements. A lazy
+// list is evaluated at most once, the first time it is forced, after which the result
+// is shared by all deques referring to it. A nil list is the empty list.
+type private{{.DequeTypeName}}List struct {
+	item {{.TypeName}}
+	next *private{{.DequeTypeName}}List
+
+	// The fields above, and empty, are only valid once a lazy list has been forced
+	lazy    bool
+	once    sync.Once
+	compute func() ({{.TypeName}}, *private{{.DequeTypeName}}List, bool)
+	empty   bool
+}
+
+// force evaluates l, if needed, and returns false if l is empty.
+func (l *private{{.DequeTypeName}}List) force() bool {
+	if l == nil {
+		return false
 	}
 
-	node := v.root
-	for level := v.shift; level > 0; level -= shiftSize {
-		node = node.([]commonNode)[(i>>level)&shiftBitMask]
+	if l.lazy {
+		l.once.Do(l.evaluate)
 	}
 
-	return node.([]private{{.MapItemTypeName}}Bucket)
+	return !l.empty
 }
 
-func (v *private{{.MapItemTypeName}}BucketVector) tailOffset() uint {
-	if v.len < nodeSize {
-		return 0
-	}
+func (l *private{{.DequeTypeName}}List) evaluate() {
+	var ok bool
+	l.item, l.next, ok = l.compute()
+	l.empty, l.compute = !ok, nil
+}
 
-	return ((v.len - 1) >> shiftSize) << shiftSize
+func private{{.DequeTypeName}}Cons(item {{.TypeName}}, l *private{{.DequeTypeName}}List) *private{{.DequeTypeName}}List {
+	return &private{{.DequeTypeName}}List{item: item, next: l}
 }
 
-func (v *private{{.MapItemTypeName}}BucketVector) Set(i int, item private{{.MapItemTypeName}}Bucket) *private{{.MapItemTypeName}}BucketVector {
-	if i < 0 || uint(i) >= v.len {
-		panic("Index out of bounds")
-	}
+func private{{.DequeTypeName}}Lazy(compute func() ({{.TypeName}}, *private{{.DequeTypeName}}List, bool)) *private{{.DequeTypeName}}List {
+	return &private{{.DequeTypeName}}List{lazy: true, compute: compute}
+}
 
-	if uint(i) >= v.tailOffset() {
-		newTail := make([]private{{.MapItemTypeName}}Bucket, len(v.tail))
-		copy(newTail, v.tail)
-		newTail[i&shiftBitMask] = item
-		return &private{{.MapItemTypeName}}BucketVector{root: v.root, tail: newTail, len: v.len, shift: v.shift}
+// private{{.DequeTypeName}}Take returns the first n elements of l, n must not be larger
+// than the length of l.
+func private{{.DequeTypeName}}Take(n uint, l *private{{.DequeTypeName}}List) *private{{.DequeTypeName}}List {
+	if n == 0 {
+		return nil
 	}
 
-	return &private{{.MapItemTypeName}}BucketVector{root: v.doAssoc(v.shift, v.root, uint(i), item), tail: v.tail, len: v.len, shift: v.shift}
+	return private{{.DequeTypeName}}Lazy(func() ({{.TypeName}}, *private{{.DequeTypeName}}List, bool) {
+		l.force()
+		return l.item, private{{.DequeTypeName}}Take(n-1, l.next), true
+	})
 }
 
-func (v *private{{.MapItemTypeName}}BucketVector) doAssoc(level uint, node commonNode, i uint, item private{{.MapItemTypeName}}Bucket) commonNode {
-	if level == 0 {
-		ret := make([]private{{.MapItemTypeName}}Bucket, nodeSize)
-		copy(ret, node.([]private{{.MapItemTypeName}}Bucket))
-		ret[i&shiftBitMask] = item
-		return ret
+// private{{.DequeTypeName}}Append returns the elements of l1 followed by those of l2.
+func private{{.DequeTypeName}}Append(l1, l2 *private{{.DequeTypeName}}List) *private{{.DequeTypeName}}List {
+	if l1 == nil {
+		return l2
 	}
 
-	ret := make([]commonNode, nodeSize)
-	copy(ret, node.([]commonNode))
-	subidx := (i >> level) & shiftBitMask
-	ret[subidx] = v.doAssoc(level-shiftSize, ret[subidx], i, item)
-	return ret
-}
-
-func (v *private{{.MapItemTypeName}}BucketVector) pushTail(level uint, parent commonNode, tailNode []private{{.MapItemTypeName}}Bucket) commonNode {
-	subIdx := ((v.len - 1) >> level) & shiftBitMask
-	parentNode := parent.([]commonNode)
-	ret := make([]commonNode, subIdx+1)
-	copy(ret, parentNode)
-	var nodeToInsert commonNode
+	return private{{.DequeTypeName}}Lazy(func() ({{.TypeName}}, *private{{.DequeTypeName}}List, bool) {
+		if !l1.force() {
+			if !l2.force() {
+				var item {{.TypeName}}
+				return item, nil, false
+			}
 
-	if level == shiftSize {
-		nodeToInsert = tailNode
-	} else if subIdx < uint(len(parentNode)) {
-		nodeToInsert = v.pushTail(level-shiftSize, parentNode[subIdx], tailNode)
-	} else {
-		nodeToInsert = newPath(level-shiftSize, tailNode)
-	}
+			return l2.item, l2.next, true
+		}
 
-	ret[subIdx] = nodeToInsert
-	return ret
+		return l1.item, private{{.DequeTypeName}}Append(l1.next, l2), true
+	})
 }
 
-func (v *private{{.MapItemTypeName}}BucketVector) Append(item ...private{{.MapItemTypeName}}Bucket) *private{{.MapItemTypeName}}BucketVector {
-	result := v
-	itemLen := uint(len(item))
-	for insertOffset := uint(0); insertOffset < itemLen; {
-		tailLen := result.len - result.tailOffset()
-		tailFree := nodeSize - tailLen
-		if tailFree == 0 {
-			result = result.pushLeafNode(result.tail)
-			result.tail = empty{{.MapItemTypeName}}BucketVector.tail
-			tailFree = nodeSize
-			tailLen = 0
+// private{{.DequeTypeName}}DropReverse returns all but the first n elements of l in reverse
+// order. Unlike the other list functions the whole list is computed once forced.
+func private{{.DequeTypeName}}DropReverse(n uint, l *private{{.DequeTypeName}}List) *private{{.DequeTypeName}}List {
+	return private{{.DequeTypeName}}Lazy(func() ({{.TypeName}}, *private{{.DequeTypeName}}List, bool) {
+		for ; n > 0; n-- {
+			l.force()
+			l = l.next
 		}
 
-		batchLen := uintMin(itemLen-insertOffset, tailFree)
-		newTail := make([]private{{.MapItemTypeName}}Bucket, 0, tailLen+batchLen)
-		newTail = append(newTail, result.tail...)
-		newTail = append(newTail, item[insertOffset:insertOffset+batchLen]...)
-		result = &private{{.MapItemTypeName}}BucketVector{root: result.root, tail: newTail, len: result.len + batchLen, shift: result.shift}
-		insertOffset += batchLen
-	}
+		var result *private{{.DequeTypeName}}List
+		for ; l.force(); l = l.next {
+			result = private{{.DequeTypeName}}Cons(l.item, result)
+		}
 
-	return result
+		if result == nil {
+			var item {{.TypeName}}
+			return item, nil, false
+		}
+
+		return result.item, result.next, true
+	})
 }
 
-func (v *private{{.MapItemTypeName}}BucketVector) pushLeafNode(node []private{{.MapItemTypeName}}Bucket) *private{{.MapItemTypeName}}BucketVector {
-	var newRoot commonNode
-	newShift := v.shift
+// A {{.DequeTypeName}} is a persistent/immutable double ended queue. All operations, except
+// for Range and ToNativeSlice, run in amortized constant time, also when old versions of
+// a deque are reused.
+//
+// The deque is a banker's deque as described by Okasaki. The elements are kept in a front
+// list and a reversed back list. When one of them grows too large compared to the other,
+// half of the elements are moved to the other list. The move is done lazily, piece by
+// piece, as elements are popped, which spreads the cost of it over those operations.
+type {{.DequeTypeName}} struct {
+	front, back       *private{{.DequeTypeName}}List
+	frontLen, backLen uint
+}
 
-	// Root overflow?
-	if (v.len >> shiftSize) > (1 << v.shift) {
-		newNode := newPath(v.shift, node)
-		newRoot = commonNode([]commonNode{v.root, newNode})
-		newShift = v.shift + shiftSize
-	} else {
-		newRoot = v.pushTail(v.shift, v.root, node)
+var empty{{.DequeTypeName}} = &{{.DequeTypeName}}{}
+
+// New{{.DequeTypeName}} returns a new {{.DequeTypeName}} containing the items provided in items,
+// with the first item at the front.
+func New{{.DequeTypeName}}(items ...{{.TypeName}}) *{{.DequeTypeName}} {
+	half := len(items) / 2
+	result := &{{.DequeTypeName}}{frontLen: uint(half), backLen: uint(len(items) - half)}
+	for i := half - 1; i >= 0; i-- {
+		result.front = private{{.DequeTypeName}}Cons(items[i], result.front)
 	}
 
-	return &private{{.MapItemTypeName}}BucketVector{root: newRoot, tail: v.tail, len: v.len, shift: newShift}
-}
+	for _, item := range items[half:] {
+		result.back = private{{.DequeTypeName}}Cons(item, result.back)
+	}
 
-func (v *private{{.MapItemTypeName}}BucketVector) Len() int {
-	return int(v.len)
+	return result
 }
 
-func (v *private{{.MapItemTypeName}}BucketVector) Range(f func(private{{.MapItemTypeName}}Bucket) bool) {
-	var currentNode []private{{.MapItemTypeName}}Bucket
-	for i := uint(0); i < v.len; i++ {
-		if i&shiftBitMask == 0 {
-			currentNode = v.sliceFor(uint(i))
-		}
+// new{{.DequeTypeName}} returns a deque of front and back with the elements balanced
+// between the two lists.
+func new{{.DequeTypeName}}(front *private{{.DequeTypeName}}List, frontLen uint, back *private{{.DequeTypeName}}List, backLen uint) *{{.DequeTypeName}} {
+	size := frontLen + backLen
+	if frontLen > dequeBalance*backLen+1 {
+		half := size / 2
+		return &{{.DequeTypeName}}{
+			front:    private{{.DequeTypeName}}Take(half, front),
+			frontLen: half,
+			back:     private{{.DequeTypeName}}Append(back, private{{.DequeTypeName}}DropReverse(half, front)),
+			backLen:  size - half}
+	}
 
-		if !f(currentNode[i&shiftBitMask]) {
-			return
-		}
+	if backLen > dequeBalance*frontLen+1 {
+		half := size / 2
+		return &{{.DequeTypeName}}{
+			front:    private{{.DequeTypeName}}Append(front, private{{.DequeTypeName}}DropReverse(half, back)),
+			frontLen: size - half,
+			back:     private{{.DequeTypeName}}Take(half, back),
+			backLen:  half}
 	}
-}
 
-// {{.MapTypeName}} is a persistent key - value map
-type {{.MapTypeName}} struct {
-	backingVector *private{{.MapItemTypeName}}BucketVector
-	len           int
+	return &{{.DequeTypeName}}{front: front, frontLen: frontLen, back: back, backLen: backLen}
 }
 
-func (m *{{.MapTypeName}}) pos(key {{.MapKeyTypeName}}) int {
-	return int(uint64({{.MapKeyHashFunc}}(key)) % uint64(m.backingVector.Len()))
+// Len returns the number of elements in d.
+func (d *{{.DequeTypeName}}) Len() int {
+	return int(d.frontLen + d.backLen)
 }
 
-// Helper type used during map creation and reallocation
-type private{{.MapItemTypeName}}Buckets struct {
-	buckets []private{{.MapItemTypeName}}Bucket
-	length  int
+// PushFront returns a new deque with item added to the front.
+func (d *{{.DequeTypeName}}) PushFront(item {{.TypeName}}) *{{.DequeTypeName}} {
+	return new{{.DequeTypeName}}(private{{.DequeTypeName}}Cons(item, d.front), d.frontLen+1, d.back, d.backLen)
 }
 
-func newPrivate{{.MapItemTypeName}}Buckets(itemCount int) *private{{.MapItemTypeName}}Buckets {
-	size := int(float64(itemCount)/initialMapLoadFactor) + 1
-	buckets := make([]private{{.MapItemTypeName}}Bucket, size)
-	return &private{{.MapItemTypeName}}Buckets{buckets: buckets}
+// PushBack returns a new deque with item added to the back.
+func (d *{{.DequeTypeName}}) PushBack(item {{.TypeName}}) *{{.DequeTypeName}} {
+	return new{{.DequeTypeName}}(d.front, d.frontLen, private{{.DequeTypeName}}Cons(item, d.back), d.backLen+1)
 }
 
-func (b *private{{.MapItemTypeName}}Buckets) AddItem(item {{.MapItemTypeName}}) {
-	ix := int(uint64({{.MapKeyHashFunc}}(item.Key)) % uint64(len(b.buckets)))
-	bucket := b.buckets[ix]
-	if bucket != nil {
-		// Hash collision, merge with existing bucket
-		for keyIx, bItem := range bucket {
-			if item.Key == bItem.Key {
-				bucket[keyIx] = item
-				return
-			}
+// PopFront returns a new deque with the front element removed, and the removed element.
+func (d *{{.DequeTypeName}}) PopFront() (*{{.DequeTypeName}}, {{.TypeName}}) {
+	if d.frontLen == 0 {
+		if d.backLen == 0 {
+			panic("Pop on empty deque")
 		}
 
-		b.buckets[ix] = append(bucket, {{.MapItemTypeName}}{Key: item.Key, Value: item.Value})
-		b.length++
-	} else {
-		bucket := make(private{{.MapItemTypeName}}Bucket, 0, int(math.Max(initialMapLoadFactor, 1.0)))
-		b.buckets[ix] = append(bucket, item)
-		b.length++
+		// The balance between the lists guarantees that this is the only element
+		d.back.force()
+		return empty{{.DequeTypeName}}, d.back.item
 	}
+
+	d.front.force()
+	return new{{.DequeTypeName}}(d.front.next, d.frontLen-1, d.back, d.backLen), d.front.item
 }
 
-func (b *private{{.MapItemTypeName}}Buckets) AddItemsFromMap(m *{{.MapTypeName}}) {
-	m.backingVector.Range(func(bucket private{{.MapItemTypeName}}Bucket) bool {
-		for _, item := range bucket {
-			b.AddItem(item)
+// PopBack returns a new deque with the back element removed, and the removed element.
+func (d *{{.DequeTypeName}}) PopBack() (*{{.DequeTypeName}}, {{.TypeName}}) {
+	if d.backLen == 0 {
+		if d.frontLen == 0 {
+			panic("Pop on empty deque")
 		}
-		return true
-	})
-}
 
-func new{{.MapTypeName}}(items []{{.MapItemTypeName}}) *{{.MapTypeName}} {
-	buckets := newPrivate{{.MapItemTypeName}}Buckets(len(items))
-	for _, item := range items {
-		buckets.AddItem(item)
+		// The balance between the lists guarantees that this is the only element
+		d.front.force()
+		return empty{{.DequeTypeName}}, d.front.item
 	}
 
-	return &{{.MapTypeName}}{backingVector: empty{{.MapItemTypeName}}BucketVector.Append(buckets.buckets...), len: buckets.length}
+	d.back.force()
+	return new{{.DequeTypeName}}(d.front, d.frontLen, d.back.next, d.backLen-1), d.back.item
+}
+
+// PeekFront returns the front element of d. ok is false if d is empty.
+func (d *{{.DequeTypeName}}) PeekFront() (item {{.TypeName}}, ok bool) {
+	l := d.front
+	if d.frontLen == 0 {
+		l = d.back
+	}
+
+	if !l.force() {
+		return item, false
+	}
+
+	return l.item, true
+}
+
+// PeekBack returns the back element of d. ok is false if d is empty.
+func (d *{{.DequeTypeName}}) PeekBack() (item {{.TypeName}}, ok bool) {
+	l := d.back
+	if d.backLen == 0 {
+		l = d.front
+	}
+
+	if !l.force() {
+		return item, false
+	}
+
+	return l.item, true
+}
+
+// Range calls f repeatedly passing it each element in d, from front to back, as argument
+// until either all elements have been visited or f returns false.
+func (d *{{.DequeTypeName}}) Range(f func({{.TypeName}}) bool) {
+	for l := d.front; l.force(); l = l.next {
+		if !f(l.item) {
+			return
+		}
+	}
+
+	back := d.backSlice()
+	for i := len(back) - 1; i >= 0; i-- {
+		if !f(back[i]) {
+			return
+		}
+	}
+}
+
+// backSlice returns the elements of the back list, from back to front.
+func (d *{{.DequeTypeName}}) backSlice() []{{.TypeName}} {
+	result := make([]{{.TypeName}}, 0, d.backLen)
+	for l := d.back; l.force(); l = l.next {
+		result = append(result, l.item)
+	}
+
+	return result
+}
+
+// ToNativeSlice returns a Go slice containing all elements of d, from front to back.
+func (d *{{.DequeTypeName}}) ToNativeSlice() []{{.TypeName}} {
+	result := make([]{{.TypeName}}, d.Len())
+	i := 0
+	for l := d.front; l.force(); l = l.next {
+		result[i] = l.item
+		i++
+	}
+
+	for l, j := d.back, len(result)-1; l.force(); l, j = l.next, j-1 {
+		result[j] = l.item
+	}
+
+	return result
+}
+
+// MarshalJSON encodes d as a JSON array, from front to back.
+func (d *{{.DequeTypeName}}) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.ToNativeSlice())
+}
+
+// UnmarshalJSON sets d to the elements of data, a JSON array. d must not be used by
+// anyone else when this is called, it is meant to be used by encoding/json only.
+func (d *{{.DequeTypeName}}) UnmarshalJSON(data []byte) error {
+	result := empty{{.DequeTypeName}}
+	err := jsonDecodeArray(data, func(dec *json.Decoder) error {
+		var item {{.TypeName}}
+		if err := dec.Decode(&item); err != nil {
+			return err
+		}
+
+		result = result.PushBack(item)
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	*d = *result
+	return nil
+}
+
+`
+const HeapTemplate string = `
+////////////
+/// Heap ///
+////////////
+
+// private{{.HeapTypeName}}Node is a node in a leftist heap. The rank of a node is the
+// length of its right spine, the rank of the left child of a node is never smaller
+// than that of the right child. The right spine is therefore at most logarithmic in
+// the size of the heap.
+type private{{.HeapTypeName}}Node struct {
+	item        {{.TypeName}}
+	left, right *private{{.HeapTypeName}}Node
+	rank        int
+}
+
+func (n *private{{.HeapTypeName}}Node) rankOf() int {
+	if n == nil {
+		return 0
+	}
+
+	return n.rank
+}
+
+// private{{.HeapTypeName}}Merge returns a heap containing the items of both n1 and n2.
+// Only nodes on the right spines of the heaps are copied.
+func private{{.HeapTypeName}}Merge(n1, n2 *private{{.HeapTypeName}}Node) *private{{.HeapTypeName}}Node {
+	if n1 == nil {
+		return n2
+	}
+
+	if n2 == nil {
+		return n1
+	}
+
+	if {{.HeapLessFunc}}(n2.item, n1.item) {
+		n1, n2 = n2, n1
+	}
+
+	left, right := n1.left, private{{.HeapTypeName}}Merge(n1.right, n2)
+	if left.rankOf() < right.rankOf() {
+		left, right = right, left
+	}
+
+	return &private{{.HeapTypeName}}Node{item: n1.item, left: left, right: right, rank: right.rankOf() + 1}
+}
+
+// A {{.HeapTypeName}} is a persistent/immutable priority queue implemented as a leftist heap.
+// Push, Pop and Merge run in logarithmic time, Peek and Len in constant time.
+type {{.HeapTypeName}} struct {
+	root *private{{.HeapTypeName}}Node
+	len  int
+}
+
+var empty{{.HeapTypeName}} = &{{.HeapTypeName}}{}
+
+// New{{.HeapTypeName}} returns a new {{.HeapTypeName}} containing the items provided in items.
+func New{{.HeapTypeName}}(items ...{{.TypeName}}) *{{.HeapTypeName}} {
+	if len(items) == 0 {
+		return empty{{.HeapTypeName}}
+	}
+
+	// Merging the items pairwise builds the heap in linear time
+	nodes := make([]*private{{.HeapTypeName}}Node, len(items))
+	for i, item := range items {
+		nodes[i] = &private{{.HeapTypeName}}Node{item: item, rank: 1}
+	}
+
+	for len(nodes) > 1 {
+		merged := nodes[:0]
+		for i := 0; i < len(nodes); i += 2 {
+			if i+1 < len(nodes) {
+				merged = append(merged, private{{.HeapTypeName}}Merge(nodes[i], nodes[i+1]))
+			} else {
+				merged = append(merged, nodes[i])
+			}
+		}
+
+		nodes = merged
+	}
+
+	return &{{.HeapTypeName}}{root: nodes[0], len: len(items)}
+}
+
+// Len returns the number of items in h.
+func (h *{{.HeapTypeName}}) Len() int {
+	return h.len
+}
+
+// Push returns a new heap with item added to it.
+func (h *{{.HeapTypeName}}) Push(item {{.TypeName}}) *{{.HeapTypeName}} {
+	node := &private{{.HeapTypeName}}Node{item: item, rank: 1}
+	return &{{.HeapTypeName}}{root: private{{.HeapTypeName}}Merge(h.root, node), len: h.len + 1}
+}
+
+// Peek returns the smallest item in h. ok is false if h is empty.
+func (h *{{.HeapTypeName}}) Peek() (item {{.TypeName}}, ok bool) {
+	if h.root == nil {
+		return item, false
+	}
+
+	return h.root.item, true
+}
+
+// Pop returns a new heap with the smallest item removed, and the removed item.
+func (h *{{.HeapTypeName}}) Pop() (*{{.HeapTypeName}}, {{.TypeName}}) {
+	if h.root == nil {
+		panic("Pop on empty heap")
+	}
+
+	return &{{.HeapTypeName}}{root: private{{.HeapTypeName}}Merge(h.root.left, h.root.right), len: h.len - 1}, h.root.item
+}
+
+// Merge returns a new heap containing the items of both h and other.
+func (h *{{.HeapTypeName}}) Merge(other *{{.HeapTypeName}}) *{{.HeapTypeName}} {
+	return &{{.HeapTypeName}}{root: private{{.HeapTypeName}}Merge(h.root, other.root), len: h.len + other.len}
+}
+
+// Range calls f repeatedly passing it each item in h as argument until either all items
+// have been visited or f returns false. The items are visited in no particular order,
+// use ToNativeSlice to get them in ascending order.
+func (h *{{.HeapTypeName}}) Range(f func({{.TypeName}}) bool) {
+	if h.root == nil {
+		return
+	}
+
+	stack := []*private{{.HeapTypeName}}Node{h.root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !f(node.item) {
+			return
+		}
+
+		if node.right != nil {
+			stack = append(stack, node.right)
+		}
+
+		if node.left != nil {
+			stack = append(stack, node.left)
+		}
+	}
+}
+
+// ToNativeSlice returns a Go slice containing all items of h in ascending order.
+func (h *{{.HeapTypeName}}) ToNativeSlice() []{{.TypeName}} {
+	result := make([]{{.TypeName}}, 0, h.len)
+	for root := h.root; root != nil; root = private{{.HeapTypeName}}Merge(root.left, root.right) {
+		result = append(result, root.item)
+	}
+
+	return result
+}
+
+// MarshalJSON encodes h as a JSON array in ascending order.
+func (h *{{.HeapTypeName}}) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.ToNativeSlice())
+}
+
+// UnmarshalJSON sets h to the elements of data, a JSON array. h must not be used by
+// anyone else when this is called, it is meant to be used by encoding/json only.
+func (h *{{.HeapTypeName}}) UnmarshalJSON(data []byte) error {
+	items := make([]{{.TypeName}}, 0)
+	err := jsonDecodeArray(data, func(dec *json.Decoder) error {
+		var item {{.TypeName}}
+		if err := dec.Decode(&item); err != nil {
+			return err
+		}
+
+		items = append(items, item)
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	*h = *New{{.HeapTypeName}}(items...)
+	return nil
+}
+`
+const IterImportsTemplate string = `
+import "iter"
+
+`
+const MapHashTemplate string = `
+// Equals returns true if m and other contain the same keys mapped to equal values, false otherwise.
+func (m *{{.MapTypeName}}) Equals(other *{{.MapTypeName}}) bool {
+	if m.root == other.root {
+		return true
+	}
+
+	if m.len != other.len {
+		return false
+	}
+
+	equal := true
+	m.Range(func(key {{.MapKeyTypeName}}, value {{.MapValueTypeName}}) bool {
+		otherValue, ok := other.Load(key)
+		equal = ok && {{.MapValueEqFunc}}(&value, &otherValue)
+		return equal
+	})
+
+	return equal
+}
+
+// Hash returns a hash of the items in m. Maps that are equal according to Equals have
+// the same hash. The hash is computed the first time it is needed and is then kept for m
+// and all copies of it, including copies stored in other containers.
+func (m *{{.MapTypeName}}) Hash() uint32 {
+	if h := loadHash(m.hash); h != 0 {
+		return h
+	}
+
+	// The items are summed since the order in which they are visited depends on the tree
+	h := uint32(0)
+	m.Range(func(key {{.MapKeyTypeName}}, value {{.MapValueTypeName}}) bool {
+		h += 31*{{.MapKeyHashFunc}}(key) + {{.MapValueHashFunc}}(&value)
+		return true
+	})
+
+	return cacheHash(m.hash, h)
+}
+
+`
+const MapIterTemplate string = `
+// All returns an iterator over the keys and values in m. The order is unspecified.
+func (m *{{.MapTypeName}}) All() iter.Seq2[{{.MapKeyTypeName}}, {{.MapValueTypeName}}] {
+	return func(yield func({{.MapKeyTypeName}}, {{.MapValueTypeName}}) bool) {
+		m.Range(yield)
+	}
+}
+
+// Keys returns an iterator over the keys in m. The order is unspecified.
+func (m *{{.MapTypeName}}) Keys() iter.Seq[{{.MapKeyTypeName}}] {
+	return func(yield func({{.MapKeyTypeName}}) bool) {
+		m.Range(func(key {{.MapKeyTypeName}}, _ {{.MapValueTypeName}}) bool {
+			return yield(key)
+		})
+	}
+}
+
+// Values returns an iterator over the values in m. The order is unspecified.
+func (m *{{.MapTypeName}}) Values() iter.Seq[{{.MapValueTypeName}}] {
+	return func(yield func({{.MapValueTypeName}}) bool) {
+		m.Range(func(_ {{.MapKeyTypeName}}, value {{.MapValueTypeName}}) bool {
+			return yield(value)
+		})
+	}
+}
+
+`
+const MapSnapshotTemplate string = `
+// EncodeSnapshot writes m to the snapshot stream of e. Nodes shared with containers
+// previously written to e are written as references to those.
+func (m *{{.MapTypeName}}) EncodeSnapshot(e *SnapshotEncoder) error {
+	return e.encode(snapshotMapTag, func() error {
+		return m.encodeSnapshot(e)
+	})
+}
+
+func (m *{{.MapTypeName}}) encodeSnapshot(e *SnapshotEncoder) error {
+	e.writeUvarint(uint64(m.len))
+	return m.root.encodeSnapshot(e)
+}
+
+func (n *private{{.MapItemTypeName}}Node) encodeSnapshot(e *SnapshotEncoder) error {
+	return e.writeNode(n, func() error {
+		e.writeUvarint(uint64(n.dataMap))
+		e.writeUvarint(uint64(n.nodeMap))
+		if err := e.writeElements(n.items); err != nil {
+			return err
+		}
+
+		e.writeUvarint(uint64(len(n.children)))
+		for _, child := range n.children {
+			if err := child.encodeSnapshot(e); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// DecodeSnapshot sets m to the next container in the snapshot stream of d, which must
+// be a map. io.EOF is returned if there are no more containers in the stream. m must
+// not be used by anyone else when this is called, decode into a new zero {{.MapTypeName}}.
+func (m *{{.MapTypeName}}) DecodeSnapshot(d *SnapshotDecoder) error {
+	return d.decode(snapshotMapTag, func() error {
+		return m.decodeSnapshot(d)
+	})
+}
+
+func (m *{{.MapTypeName}}) decodeSnapshot(d *SnapshotDecoder) error {
+	length, err := d.readLength(math.MaxInt32)
+	if err != nil {
+		return err
+	}
+
+	root, err := decode{{.MapItemTypeName}}NodeSnapshot(d, 0)
+	if err != nil {
+		return err
+	}
+
+	*m = {{.MapTypeName}}{root: root, len: length, hash: new(uint32)}
+	return nil
+}
+
+func decode{{.MapItemTypeName}}NodeSnapshot(d *SnapshotDecoder, shift uint) (*private{{.MapItemTypeName}}Node, error) {
+	node, err := d.readNode(func() (interface{}, error) {
+		dataMap, err := d.readUint32()
+		if err != nil {
+			return nil, err
+		}
+
+		nodeMap, err := d.readUint32()
+		if err != nil {
+			return nil, err
+		}
+
+		n := &private{{.MapItemTypeName}}Node{dataMap: dataMap, nodeMap: nodeMap}
+		if err := d.readElements(&n.items); err != nil {
+			return nil, err
+		}
+
+		count, err := d.readLength(nodeSize)
+		if err != nil {
+			return nil, err
+		}
+
+		n.children = make([]*private{{.MapItemTypeName}}Node, count)
+		for i := range n.children {
+			if n.children[i], err = decode{{.MapItemTypeName}}NodeSnapshot(d, shift+shiftSize); err != nil {
+				return nil, err
+			}
+		}
+
+		collision := shift >= champHashBits && n.dataMap == 0 && n.nodeMap == 0 && count == 0
+		if !collision && (bits.OnesCount32(n.dataMap) != len(n.items) || bits.OnesCount32(n.nodeMap) != count) {
+			return nil, fmt.Errorf("invalid snapshot, map node with %d items and %d children does not match its bitmaps", len(n.items), count)
+		}
+
+		return n, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if n, ok := node.(*private{{.MapItemTypeName}}Node); ok {
+		return n, nil
+	}
+
+	return nil, invalidSnapshotNode(node)
+}
+
+`
+const NativeMapTemplate string = `
+// ToNativeMap returns a native Go map containing all elements of m.
+func (m *{{.MapTypeName}}) ToNativeMap() map[{{.MapKeyTypeName}}]{{.MapValueTypeName}} {
+	result := make(map[{{.MapKeyTypeName}}]{{.MapValueTypeName}})
+	m.Range(func(key {{.MapKeyTypeName}}, value {{.MapValueTypeName}}) bool {
+		result[key] = value
+		return true
+	})
+
+	return result
+}
+
+// New{{.MapTypeName}}FromNativeMap returns a new {{.MapTypeName}} containing all items in m.
+func New{{.MapTypeName}}FromNativeMap(m map[{{.MapKeyTypeName}}]{{.MapValueTypeName}}) *{{.MapTypeName}} {
+	t := empty{{.MapTypeName}}.AsTransient()
+	for key, value := range m {
+		t.Store(key, value)
+	}
+
+	return t.Persistent()
+}
+
+`
+const OrderedMapTemplate string = `
+///////////////////
+/// Ordered map ///
+///////////////////
+
+type {{.MapItemTypeName}} struct {
+	Key   {{.MapKeyTypeName}}
+	Value {{.MapValueTypeName}}
+}
+
+// private{{.MapTypeName}}Entry is the value stored for each key in {{.MapTypeName}}.
+// seq is the position of the key in the insertion order.
+type private{{.MapTypeName}}Entry struct {
+	value {{.MapValueTypeName}}
+	seq   int
+}
+
+// {{.MapTypeName}} is a persistent key - value map that remembers the order in which
+// keys were first inserted. Keys and values are kept in a hash map, the insertion order
+// in a sorted map from insertion sequence number to key.
+type {{.MapTypeName}} struct {
+	entries *private{{.MapTypeName}}Entries
+	order   *private{{.MapTypeName}}Order
+	nextSeq int
+}
+
+var empty{{.MapTypeName}} = &{{.MapTypeName}}{entries: emptyprivate{{.MapTypeName}}Entries, order: emptyprivate{{.MapTypeName}}Order}
+
+// New{{.MapTypeName}} returns a new {{.MapTypeName}} containing all items in items,
+// in the order given.
+func New{{.MapTypeName}}(items ...{{.MapItemTypeName}}) *{{.MapTypeName}} {
+	return new{{.MapTypeName}}(items)
+}
+
+// new{{.MapTypeName}} returns a map containing items, in the order given. The hash map
+// is built using a transient and the insertion order from the sequence numbers in order.
+func new{{.MapTypeName}}(items []{{.MapItemTypeName}}) *{{.MapTypeName}} {
+	if len(items) == 0 {
+		return empty{{.MapTypeName}}
+	}
+
+	entries := emptyprivate{{.MapTypeName}}Entries.AsTransient()
+	order := make([]private{{.MapTypeName}}OrderItem, 0, len(items))
+	for _, item := range items {
+		seq := len(order)
+		if entry, ok := entries.Load(item.Key); ok {
+			seq = entry.seq
+		} else {
+			order = append(order, private{{.MapTypeName}}OrderItem{Key: seq, Value: item.Key})
+		}
+
+		entries.Store(item.Key, private{{.MapTypeName}}Entry{value: item.Value, seq: seq})
+	}
+
+	return &{{.MapTypeName}}{
+		entries: entries.Persistent(),
+		order:   newprivate{{.MapTypeName}}Order(order),
+		nextSeq: len(order)}
 }
 
 // Len returns the number of items in m.
 func (m *{{.MapTypeName}}) Len() int {
-	return int(m.len)
+	return m.entries.Len()
+}
+
+// Load returns value identified by key. ok is set to true if key exists in the map, false otherwise.
+func (m *{{.MapTypeName}}) Load(key {{.MapKeyTypeName}}) (value {{.MapValueTypeName}}, ok bool) {
+	entry, ok := m.entries.Load(key)
+	return entry.value, ok
+}
+
+// Store returns a new {{.MapTypeName}} containing value identified by key. A key that
+// already exists in m keeps its position in the insertion order, a new key is placed last.
+func (m *{{.MapTypeName}}) Store(key {{.MapKeyTypeName}}, value {{.MapValueTypeName}}) *{{.MapTypeName}} {
+	if entry, ok := m.entries.Load(key); ok {
+		entries := m.entries.Store(key, private{{.MapTypeName}}Entry{value: value, seq: entry.seq})
+		return &{{.MapTypeName}}{entries: entries, order: m.order, nextSeq: m.nextSeq}
+	}
+
+	return &{{.MapTypeName}}{
+		entries: m.entries.Store(key, private{{.MapTypeName}}Entry{value: value, seq: m.nextSeq}),
+		order:   m.order.Store(m.nextSeq, key),
+		nextSeq: m.nextSeq + 1}
+}
+
+// Delete returns a new {{.MapTypeName}} without the element identified by key.
+func (m *{{.MapTypeName}}) Delete(key {{.MapKeyTypeName}}) *{{.MapTypeName}} {
+	entry, ok := m.entries.Load(key)
+	if !ok {
+		return m
+	}
+
+	return &{{.MapTypeName}}{entries: m.entries.Delete(key), order: m.order.Delete(entry.seq), nextSeq: m.nextSeq}
+}
+
+// Range calls f repeatedly passing it each key and value as argument, in insertion order,
+// until either all elements have been visited or f returns false.
+func (m *{{.MapTypeName}}) Range(f func({{.MapKeyTypeName}}, {{.MapValueTypeName}}) bool) {
+	m.order.Range(func(_ int, key {{.MapKeyTypeName}}) bool {
+		entry, _ := m.entries.Load(key)
+		return f(key, entry.value)
+	})
+}
+
+// ToNativeSlice returns a native Go slice containing all items of m in insertion order.
+func (m *{{.MapTypeName}}) ToNativeSlice() []{{.MapItemTypeName}} {
+	result := make([]{{.MapItemTypeName}}, 0, m.Len())
+	m.Range(func(key {{.MapKeyTypeName}}, value {{.MapValueTypeName}}) bool {
+		result = append(result, {{.MapItemTypeName}}{Key: key, Value: value})
+		return true
+	})
+
+	return result
+}
+
+// ToNativeMap returns a native Go map containing all elements of m.
+func (m *{{.MapTypeName}}) ToNativeMap() map[{{.MapKeyTypeName}}]{{.MapValueTypeName}} {
+	result := make(map[{{.MapKeyTypeName}}]{{.MapValueTypeName}})
+	m.Range(func(key {{.MapKeyTypeName}}, value {{.MapValueTypeName}}) bool {
+		result[key] = value
+		return true
+	})
+
+	return result
+}
+
+// MarshalJSON encodes m with the keys in insertion order. m is encoded as a JSON object
+// if the keys are strings, integers or implement encoding.TextMarshaler, following the
+// same rules as encoding/json uses for map keys. Other keys are encoded as an array of
+// [key, value] arrays.
+func (m *{{.MapTypeName}}) MarshalJSON() ([]byte, error) {
+	return jsonEncodeMap(jsonObjectKeys((*{{.MapKeyTypeName}})(nil)), func(entry func(key, value interface{}) bool) {
+		m.Range(func(key {{.MapKeyTypeName}}, value {{.MapValueTypeName}}) bool {
+			return entry(&key, &value)
+		})
+	})
+}
+
+// UnmarshalJSON sets m to the items of data, either a JSON object or an array of [key, value]
+// arrays, keeping the order of the keys in data. m must not be used by anyone else when this
+// is called, it is meant to be used by encoding/json only.
+func (m *{{.MapTypeName}}) UnmarshalJSON(data []byte) error {
+	items := make([]{{.MapItemTypeName}}, 0)
+	err := jsonDecodeMap(data, func(decodeKey func(interface{}) error, dec *json.Decoder) error {
+		var item {{.MapItemTypeName}}
+		if err := decodeKey(&item.Key); err != nil {
+			return err
+		}
+
+		if err := dec.Decode(&item.Value); err != nil {
+			return err
+		}
+
+		items = append(items, item)
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	*m = *new{{.MapTypeName}}(items)
+	return nil
+}
+`
+const PrivateMapTemplate string = `
+///////////
+/// Map ///
+///////////
+
+type {{.MapItemTypeName}} struct {
+	Key   {{.MapKeyTypeName}}
+	Value {{.MapValueTypeName}}
+}
+
+// private{{.MapItemTypeName}}Node is a node in a compressed hash-array mapped prefix tree (CHAMP).
+// Items stored directly in the node are kept in items and their positions are marked in
+// dataMap. Sub nodes are kept in children and their positions are marked in nodeMap. Nodes
+// below the last level of the hash are collision nodes that only contain items.
+type private{{.MapItemTypeName}}Node struct {
+	dataMap  uint32
+	nodeMap  uint32
+	items    []{{.MapItemTypeName}}
+	children []*private{{.MapItemTypeName}}Node
+	owner    *transientOwner
+}
+
+var empty{{.MapItemTypeName}}Node = &private{{.MapItemTypeName}}Node{}
+
+// editable returns n if it is owned by owner, otherwise a copy of n owned by owner.
+// owner is nil for persistent updates in which case a copy is always returned.
+func (n *private{{.MapItemTypeName}}Node) editable(owner *transientOwner) *private{{.MapItemTypeName}}Node {
+	if owner != nil && n.owner == owner {
+		return n
+	}
+
+	items := make([]{{.MapItemTypeName}}, len(n.items), len(n.items)+1)
+	copy(items, n.items)
+	children := make([]*private{{.MapItemTypeName}}Node, len(n.children), len(n.children)+1)
+	copy(children, n.children)
+	return &private{{.MapItemTypeName}}Node{dataMap: n.dataMap, nodeMap: n.nodeMap, items: items, children: children, owner: owner}
+}
+
+func (n *private{{.MapItemTypeName}}Node) isSingleItem() bool {
+	return len(n.items) == 1 && len(n.children) == 0
+}
+
+func (n *private{{.MapItemTypeName}}Node) insertItem(ix int, item {{.MapItemTypeName}}) {
+	var zeroItem {{.MapItemTypeName}}
+	n.items = append(n.items, zeroItem)
+	copy(n.items[ix+1:], n.items[ix:])
+	n.items[ix] = item
+}
+
+func (n *private{{.MapItemTypeName}}Node) removeItem(ix int) {
+	var zeroItem {{.MapItemTypeName}}
+	copy(n.items[ix:], n.items[ix+1:])
+	n.items[len(n.items)-1] = zeroItem
+	n.items = n.items[:len(n.items)-1]
+}
+
+func (n *private{{.MapItemTypeName}}Node) insertChild(ix int, child *private{{.MapItemTypeName}}Node) {
+	n.children = append(n.children, nil)
+	copy(n.children[ix+1:], n.children[ix:])
+	n.children[ix] = child
+}
+
+func (n *private{{.MapItemTypeName}}Node) removeChild(ix int) {
+	copy(n.children[ix:], n.children[ix+1:])
+	n.children[len(n.children)-1] = nil
+	n.children = n.children[:len(n.children)-1]
+}
+
+func (n *private{{.MapItemTypeName}}Node) load(key {{.MapKeyTypeName}}, hash uint32) (value {{.MapValueTypeName}}, ok bool) {
+	for shift := uint(0); ; shift += shiftSize {
+		if shift >= champHashBits {
+			for _, item := range n.items {
+				if {{.MapKeyEqFunc}}(item.Key, key) {
+					return item.Value, true
+				}
+			}
+
+			break
+		}
+
+		bit := champBitpos(hash, shift)
+		if n.dataMap&bit != 0 {
+			item := n.items[champIndex(n.dataMap, bit)]
+			if {{.MapKeyEqFunc}}(item.Key, key) {
+				return item.Value, true
+			}
+
+			break
+		}
+
+		if n.nodeMap&bit == 0 {
+			break
+		}
+
+		n = n.children[champIndex(n.nodeMap, bit)]
+	}
+
+	var zeroValue {{.MapValueTypeName}}
+	return zeroValue, false
+}
+
+// store returns a node with item stored in it and true if the item was added rather
+// than replacing an existing item.
+func (n *private{{.MapItemTypeName}}Node) store(item {{.MapItemTypeName}}, hash uint32, shift uint, owner *transientOwner) (*private{{.MapItemTypeName}}Node, bool) {
+	if shift >= champHashBits {
+		// Collision node
+		for ix, existing := range n.items {
+			if {{.MapKeyEqFunc}}(existing.Key, item.Key) {
+				newNode := n.editable(owner)
+				newNode.items[ix] = item
+				return newNode, false
+			}
+		}
+
+		newNode := n.editable(owner)
+		newNode.items = append(newNode.items, item)
+		return newNode, true
+	}
+
+	bit := champBitpos(hash, shift)
+	if n.dataMap&bit != 0 {
+		ix := champIndex(n.dataMap, bit)
+		existing := n.items[ix]
+		newNode := n.editable(owner)
+		if {{.MapKeyEqFunc}}(existing.Key, item.Key) {
+			newNode.items[ix] = item
+			return newNode, false
+		}
+
+		// Push both items down into a new sub node
+		child := new{{.MapItemTypeName}}Node(existing, {{.MapKeyHashFunc}}(existing.Key), item, hash, shift+shiftSize, owner)
+		newNode.removeItem(ix)
+		newNode.dataMap &^= bit
+		newNode.nodeMap |= bit
+		newNode.insertChild(champIndex(newNode.nodeMap, bit), child)
+		return newNode, true
+	}
+
+	if n.nodeMap&bit != 0 {
+		ix := champIndex(n.nodeMap, bit)
+		child, added := n.children[ix].store(item, hash, shift+shiftSize, owner)
+		newNode := n
+		if child != n.children[ix] {
+			newNode = n.editable(owner)
+			newNode.children[ix] = child
+		}
+
+		return newNode, added
+	}
+
+	newNode := n.editable(owner)
+	newNode.dataMap |= bit
+	newNode.insertItem(champIndex(newNode.dataMap, bit), item)
+	return newNode, true
+}
+
+func new{{.MapItemTypeName}}Node(item1 {{.MapItemTypeName}}, hash1 uint32, item2 {{.MapItemTypeName}}, hash2 uint32, shift uint, owner *transientOwner) *private{{.MapItemTypeName}}Node {
+	if shift >= champHashBits {
+		return &private{{.MapItemTypeName}}Node{items: []{{.MapItemTypeName}}{item1, item2}, owner: owner}
+	}
+
+	bit1, bit2 := champBitpos(hash1, shift), champBitpos(hash2, shift)
+	if bit1 == bit2 {
+		child := new{{.MapItemTypeName}}Node(item1, hash1, item2, hash2, shift+shiftSize, owner)
+		return &private{{.MapItemTypeName}}Node{nodeMap: bit1, children: []*private{{.MapItemTypeName}}Node{child}, owner: owner}
+	}
+
+	items := []{{.MapItemTypeName}}{item1, item2}
+	if bit2 < bit1 {
+		items[0], items[1] = item2, item1
+	}
+
+	return &private{{.MapItemTypeName}}Node{dataMap: bit1 | bit2, items: items, owner: owner}
+}
+
+// delete returns a node without key and true if the key was found. A node that only
+// contains a single item is returned as is to let the parent inline the item.
+func (n *private{{.MapItemTypeName}}Node) delete(key {{.MapKeyTypeName}}, hash uint32, shift uint, owner *transientOwner) (*private{{.MapItemTypeName}}Node, bool) {
+	if shift >= champHashBits {
+		// Collision node
+		for ix, existing := range n.items {
+			if {{.MapKeyEqFunc}}(existing.Key, key) {
+				newNode := n.editable(owner)
+				newNode.removeItem(ix)
+				return newNode, true
+			}
+		}
+
+		return n, false
+	}
+
+	bit := champBitpos(hash, shift)
+	if n.dataMap&bit != 0 {
+		ix := champIndex(n.dataMap, bit)
+		if !{{.MapKeyEqFunc}}(n.items[ix].Key, key) {
+			return n, false
+		}
+
+		newNode := n.editable(owner)
+		newNode.removeItem(ix)
+		newNode.dataMap &^= bit
+		return newNode, true
+	}
+
+	if n.nodeMap&bit != 0 {
+		ix := champIndex(n.nodeMap, bit)
+		child, deleted := n.children[ix].delete(key, hash, shift+shiftSize, owner)
+		if !deleted {
+			return n, false
+		}
+
+		if child.isSingleItem() {
+			if shift > 0 && len(n.items) == 0 && len(n.children) == 1 {
+				// Let the parent inline the remaining item
+				return child, true
+			}
+
+			// Inline the remaining item of the child in this node
+			newNode := n.editable(owner)
+			newNode.removeChild(ix)
+			newNode.nodeMap &^= bit
+			newNode.dataMap |= bit
+			newNode.insertItem(champIndex(newNode.dataMap, bit), child.items[0])
+			return newNode, true
+		}
+
+		newNode := n.editable(owner)
+		newNode.children[ix] = child
+		return newNode, true
+	}
+
+	return n, false
+}
+
+func (n *private{{.MapItemTypeName}}Node) rangeItems(f func({{.MapKeyTypeName}}, {{.MapValueTypeName}}) bool) bool {
+	for _, item := range n.items {
+		if !f(item.Key, item.Value) {
+			return false
+		}
+	}
+
+	for _, child := range n.children {
+		if !child.rangeItems(f) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// {{.MapTypeName}} is a persistent key - value map
+type {{.MapTypeName}} struct {
+	root *private{{.MapItemTypeName}}Node
+	len  int
+	hash *uint32
+}
+
+var empty{{.MapTypeName}} = &{{.MapTypeName}}{root: empty{{.MapItemTypeName}}Node, hash: new(uint32)}
+
+func new{{.MapTypeName}}(items []{{.MapItemTypeName}}) *{{.MapTypeName}} {
+	t := empty{{.MapTypeName}}.AsTransient()
+	for _, item := range items {
+		t.Store(item.Key, item.Value)
+	}
+
+	return t.Persistent()
+}
+
+// Len returns the number of items in m.
+func (m *{{.MapTypeName}}) Len() int {
+	return int(m.len)
+}
+
+// Load returns value identified by key. ok is set to true if key exists in the map, false otherwise.
+func (m *{{.MapTypeName}}) Load(key {{.MapKeyTypeName}}) (value {{.MapValueTypeName}}, ok bool) {
+	return m.root.load(key, {{.MapKeyHashFunc}}(key))
+}
+
+// Store returns a new {{.MapTypeName}} containing value identified by key.
+func (m *{{.MapTypeName}}) Store(key {{.MapKeyTypeName}}, value {{.MapValueTypeName}}) *{{.MapTypeName}} {
+	root, added := m.root.store({{.MapItemTypeName}}{Key: key, Value: value}, {{.MapKeyHashFunc}}(key), 0, nil)
+	if added {
+		return &{{.MapTypeName}}{root: root, len: m.len + 1, hash: new(uint32)}
+	}
+
+	return &{{.MapTypeName}}{root: root, len: m.len, hash: new(uint32)}
+}
+
+// Delete returns a new {{.MapTypeName}} without the element identified by key.
+func (m *{{.MapTypeName}}) Delete(key {{.MapKeyTypeName}}) *{{.MapTypeName}} {
+	root, deleted := m.root.delete(key, {{.MapKeyHashFunc}}(key), 0, nil)
+	if !deleted {
+		return m
+	}
+
+	return &{{.MapTypeName}}{root: root, len: m.len - 1, hash: new(uint32)}
+}
+
+// Range calls f repeatedly passing it each key and value as argument until either
+// all elements have been visited or f returns false.
+func (m *{{.MapTypeName}}) Range(f func({{.MapKeyTypeName}}, {{.MapValueTypeName}}) bool) {
+	m.root.rangeItems(f)
+}
+
+// MarshalJSON encodes m as a JSON object if the keys are strings, integers or implement
+// encoding.TextMarshaler, following the same rules as encoding/json uses for map keys.
+// Other keys are encoded as an array of [key, value] arrays.
+func (m *{{.MapTypeName}}) MarshalJSON() ([]byte, error) {
+	return jsonEncodeMap(jsonObjectKeys((*{{.MapKeyTypeName}})(nil)), func(entry func(key, value interface{}) bool) {
+		m.Range(func(key {{.MapKeyTypeName}}, value {{.MapValueTypeName}}) bool {
+			return entry(&key, &value)
+		})
+	})
+}
+
+// UnmarshalJSON sets m to the items of data, either a JSON object or an array of [key, value]
+// arrays. m must not be used by anyone else when this is called, it is meant to be used by
+// encoding/json only.
+func (m *{{.MapTypeName}}) UnmarshalJSON(data []byte) error {
+	t := empty{{.MapTypeName}}.AsTransient()
+	err := jsonDecodeMap(data, func(decodeKey func(interface{}) error, dec *json.Decoder) error {
+		var item {{.MapItemTypeName}}
+		if err := decodeKey(&item.Key); err != nil {
+			return err
+		}
+
+		if err := dec.Decode(&item.Value); err != nil {
+			return err
+		}
+
+		t.Store(item.Key, item.Value)
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	*m = *t.Persistent()
+	return nil
+}
+
+// MarshalBinary returns the binary encoding of m. The keys and values are gob encoded.
+func (m *{{.MapTypeName}}) MarshalBinary() ([]byte, error) {
+	return binaryMarshal(m.Len(), func(encode func(interface{}) error) error {
+		chunk := make([]{{.MapItemTypeName}}, 0, nodeSize)
+		var err error
+		m.Range(func(key {{.MapKeyTypeName}}, value {{.MapValueTypeName}}) bool {
+			chunk = append(chunk, {{.MapItemTypeName}}{Key: key, Value: value})
+			if len(chunk) == nodeSize {
+				err = encode(chunk)
+				chunk = chunk[:0]
+			}
+
+			return err == nil
+		})
+
+		if err == nil && len(chunk) > 0 {
+			err = encode(chunk)
+		}
+
+		return err
+	})
+}
+
+// UnmarshalBinary sets m to the map encoded in data by MarshalBinary. m must not
+// be used by anyone else when this is called, decode into a new zero {{.MapTypeName}}.
+func (m *{{.MapTypeName}}) UnmarshalBinary(data []byte) error {
+	t := empty{{.MapTypeName}}.AsTransient()
+	err := binaryUnmarshal(data, func(decode func(interface{}) error) (int, error) {
+		var chunk []{{.MapItemTypeName}}
+		err := decode(&chunk)
+		for _, item := range chunk {
+			t.Store(item.Key, item.Value)
+		}
+
+		return len(chunk), err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	*m = *t.Persistent()
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder using the same encoding as MarshalBinary.
+func (m *{{.MapTypeName}}) GobEncode() ([]byte, error) {
+	return m.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder using the same encoding as UnmarshalBinary.
+func (m *{{.MapTypeName}}) GobDecode(data []byte) error {
+	return m.UnmarshalBinary(data)
+}
+
+/////////////////
+/// Transient ///
+/////////////////
+
+// {{.MapTypeName}}Transient is a mutable builder for {{.MapTypeName}}. Nodes created by the
+// transient are owned by it and edited in place rather than copied. Call Persistent
+// to turn it into a {{.MapTypeName}}, the transient cannot be used after that.
+type {{.MapTypeName}}Transient struct {
+	root  *private{{.MapItemTypeName}}Node
+	len   int
+	owner *transientOwner
+}
+
+// AsTransient returns a transient containing all items of m. m is left untouched.
+func (m *{{.MapTypeName}}) AsTransient() *{{.MapTypeName}}Transient {
+	return &{{.MapTypeName}}Transient{root: m.root, len: m.len, owner: &transientOwner{}}
+}
+
+// Persistent returns a {{.MapTypeName}} containing all items of t. t cannot be used
+// after this call.
+func (t *{{.MapTypeName}}Transient) Persistent() *{{.MapTypeName}} {
+	assertTransientEditable(t.owner != nil)
+	t.owner = nil
+	return &{{.MapTypeName}}{root: t.root, len: t.len, hash: new(uint32)}
+}
+
+// Len returns the number of items in t.
+func (t *{{.MapTypeName}}Transient) Len() int {
+	assertTransientEditable(t.owner != nil)
+	return t.len
+}
+
+// Load returns value identified by key. ok is set to true if key exists in the map, false otherwise.
+func (t *{{.MapTypeName}}Transient) Load(key {{.MapKeyTypeName}}) (value {{.MapValueTypeName}}, ok bool) {
+	assertTransientEditable(t.owner != nil)
+	return t.root.load(key, {{.MapKeyHashFunc}}(key))
+}
+
+// Store sets the value identified by key to value.
+func (t *{{.MapTypeName}}Transient) Store(key {{.MapKeyTypeName}}, value {{.MapValueTypeName}}) {
+	assertTransientEditable(t.owner != nil)
+	root, added := t.root.store({{.MapItemTypeName}}{Key: key, Value: value}, {{.MapKeyHashFunc}}(key), 0, t.owner)
+	t.root = root
+	if added {
+		t.len++
+	}
+}
+
+// Delete removes the item identified by key.
+func (t *{{.MapTypeName}}Transient) Delete(key {{.MapKeyTypeName}}) {
+	assertTransientEditable(t.owner != nil)
+	root, deleted := t.root.delete(key, {{.MapKeyHashFunc}}(key), 0, t.owner)
+	t.root = root
+	if deleted {
+		t.len--
+	}
+}
+
+`
+const PrivateSortedMapTemplate string = `
+//////////////////
+/// Sorted map ///
+//////////////////
+
+type {{.MapItemTypeName}} struct {
+	Key   {{.MapKeyTypeName}}
+	Value {{.MapValueTypeName}}
+}
+
+// private{{.MapItemTypeName}}Node is a node in a persistent B-tree. Items are kept in
+// key order. children is nil for leaf nodes, internal nodes have one child more than
+// they have items. size is the total number of items in the subtree rooted at the node.
+// Nodes are never modified once created, all updates copy the path from the root.
+type private{{.MapItemTypeName}}Node struct {
+	items    []{{.MapItemTypeName}}
+	children []*private{{.MapItemTypeName}}Node
+	size     int
+}
+
+var empty{{.MapItemTypeName}}Node = &private{{.MapItemTypeName}}Node{}
+
+func new{{.MapItemTypeName}}Node(items []{{.MapItemTypeName}}, children []*private{{.MapItemTypeName}}Node) *private{{.MapItemTypeName}}Node {
+	size := len(items)
+	for _, child := range children {
+		size += child.size
+	}
+
+	return &private{{.MapItemTypeName}}Node{items: items, children: children, size: size}
+}
+
+func (n *private{{.MapItemTypeName}}Node) isLeaf() bool {
+	return n.children == nil
+}
+
+// search returns the index of the first item in n with a key that is not less than
+// key and whether the key of that item is equal to key.
+func (n *private{{.MapItemTypeName}}Node) search(key {{.MapKeyTypeName}}) (int, bool) {
+	lo, hi := 0, len(n.items)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if {{.MapKeyLessFunc}}(n.items[mid].Key, key) {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+
+	return lo, lo < len(n.items) && !{{.MapKeyLessFunc}}(key, n.items[lo].Key)
+}
+
+func (n *private{{.MapItemTypeName}}Node) load(key {{.MapKeyTypeName}}) (value {{.MapValueTypeName}}, ok bool) {
+	for {
+		i, found := n.search(key)
+		if found {
+			return n.items[i].Value, true
+		}
+
+		if n.isLeaf() {
+			return value, false
+		}
+
+		n = n.children[i]
+	}
+}
+
+// store returns a copy of n containing item. The returned node may contain one item more
+// than btreeMaxItems in which case it has to be split by the caller.
+func (n *private{{.MapItemTypeName}}Node) store(item {{.MapItemTypeName}}) (*private{{.MapItemTypeName}}Node, bool) {
+	i, found := n.search(item.Key)
+	if found {
+		items := append([]{{.MapItemTypeName}}(nil), n.items...)
+		items[i] = item
+		return &private{{.MapItemTypeName}}Node{items: items, children: n.children, size: n.size}, false
+	}
+
+	if n.isLeaf() {
+		return &private{{.MapItemTypeName}}Node{items: insert{{.MapItemTypeName}}(n.items, i, item), size: n.size + 1}, true
+	}
+
+	child, added := n.children[i].store(item)
+	children := append([]*private{{.MapItemTypeName}}Node(nil), n.children...)
+	children[i] = child
+	if len(child.items) <= btreeMaxItems {
+		size := n.size
+		if added {
+			size++
+		}
+
+		return &private{{.MapItemTypeName}}Node{items: n.items, children: children, size: size}, added
+	}
+
+	left, median, right := child.split()
+	children[i] = left
+	children = append(children, nil)
+	copy(children[i+2:], children[i+1:])
+	children[i+1] = right
+	return new{{.MapItemTypeName}}Node(insert{{.MapItemTypeName}}(n.items, i, median), children), added
+}
+
+// split splits an overfull node into two nodes and the item separating them.
+func (n *private{{.MapItemTypeName}}Node) split() (*private{{.MapItemTypeName}}Node, {{.MapItemTypeName}}, *private{{.MapItemTypeName}}Node) {
+	m := len(n.items) / 2
+	var leftChildren, rightChildren []*private{{.MapItemTypeName}}Node
+	if !n.isLeaf() {
+		leftChildren, rightChildren = n.children[:m+1:m+1], n.children[m+1:]
+	}
+
+	return new{{.MapItemTypeName}}Node(n.items[:m:m], leftChildren), n.items[m], new{{.MapItemTypeName}}Node(n.items[m+1:], rightChildren)
+}
+
+func insert{{.MapItemTypeName}}(items []{{.MapItemTypeName}}, i int, item {{.MapItemTypeName}}) []{{.MapItemTypeName}} {
+	result := make([]{{.MapItemTypeName}}, len(items)+1)
+	copy(result, items[:i])
+	result[i] = item
+	copy(result[i+1:], items[i:])
+	return result
+}
+
+func remove{{.MapItemTypeName}}(items []{{.MapItemTypeName}}, i int) []{{.MapItemTypeName}} {
+	result := make([]{{.MapItemTypeName}}, len(items)-1)
+	copy(result, items[:i])
+	copy(result[i:], items[i+1:])
+	return result
+}
+
+// delete returns a copy of n without the item identified by key. The returned node may
+// contain fewer than btreeMinItems items in which case it has to be rebalanced by the caller.
+func (n *private{{.MapItemTypeName}}Node) delete(key {{.MapKeyTypeName}}) (*private{{.MapItemTypeName}}Node, bool) {
+	i, found := n.search(key)
+	if n.isLeaf() {
+		if !found {
+			return n, false
+		}
+
+		return &private{{.MapItemTypeName}}Node{items: remove{{.MapItemTypeName}}(n.items, i), size: n.size - 1}, true
+	}
+
+	items := n.items
+	var child *private{{.MapItemTypeName}}Node
+	if found {
+		// Replace the deleted item with its predecessor
+		var predecessor {{.MapItemTypeName}}
+		child, predecessor = n.children[i].deleteMax()
+		items = append([]{{.MapItemTypeName}}(nil), n.items...)
+		items[i] = predecessor
+	} else {
+		var deleted bool
+		child, deleted = n.children[i].delete(key)
+		if !deleted {
+			return n, false
+		}
+	}
+
+	children := append([]*private{{.MapItemTypeName}}Node(nil), n.children...)
+	children[i] = child
+	result := &private{{.MapItemTypeName}}Node{items: items, children: children, size: n.size - 1}
+	if len(child.items) < btreeMinItems {
+		result = result.rebalance(i)
+	}
+
+	return result, true
+}
+
+// deleteMax returns a copy of n without its largest item together with that item.
+func (n *private{{.MapItemTypeName}}Node) deleteMax() (*private{{.MapItemTypeName}}Node, {{.MapItemTypeName}}) {
+	last := len(n.items) - 1
+	if n.isLeaf() {
+		return &private{{.MapItemTypeName}}Node{items: n.items[:last:last], size: n.size - 1}, n.items[last]
+	}
+
+	child, item := n.children[last+1].deleteMax()
+	children := append([]*private{{.MapItemTypeName}}Node(nil), n.children...)
+	children[last+1] = child
+	result := &private{{.MapItemTypeName}}Node{items: n.items, children: children, size: n.size - 1}
+	if len(child.items) < btreeMinItems {
+		result = result.rebalance(last + 1)
+	}
+
+	return result, item
+}
+
+// rebalance restores the minimum number of items in child i of n by moving an item
+// from one of its siblings or by merging it with a sibling. The children of n must
+// not be shared with any other node, they are updated in place.
+func (n *private{{.MapItemTypeName}}Node) rebalance(i int) *private{{.MapItemTypeName}}Node {
+	child := n.children[i]
+	items := append([]{{.MapItemTypeName}}(nil), n.items...)
+	if i > 0 && len(n.children[i-1].items) > btreeMinItems {
+		// Rotate the last item of the left sibling through n into child
+		left := n.children[i-1]
+		last := len(left.items) - 1
+		var leftChildren, childChildren []*private{{.MapItemTypeName}}Node
+		if !child.isLeaf() {
+			leftChildren = left.children[: last+1 : last+1]
+			childChildren = append([]*private{{.MapItemTypeName}}Node{left.children[last+1]}, child.children...)
+		}
+
+		n.children[i-1] = new{{.MapItemTypeName}}Node(left.items[:last:last], leftChildren)
+		n.children[i] = new{{.MapItemTypeName}}Node(insert{{.MapItemTypeName}}(child.items, 0, items[i-1]), childChildren)
+		items[i-1] = left.items[last]
+		return &private{{.MapItemTypeName}}Node{items: items, children: n.children, size: n.size}
+	}
+
+	if i < len(n.children)-1 && len(n.children[i+1].items) > btreeMinItems {
+		// Rotate the first item of the right sibling through n into child
+		right := n.children[i+1]
+		var rightChildren, childChildren []*private{{.MapItemTypeName}}Node
+		if !child.isLeaf() {
+			rightChildren = right.children[1:]
+			childChildren = append(append([]*private{{.MapItemTypeName}}Node(nil), child.children...), right.children[0])
+		}
+
+		n.children[i] = new{{.MapItemTypeName}}Node(insert{{.MapItemTypeName}}(child.items, len(child.items), items[i]), childChildren)
+		n.children[i+1] = new{{.MapItemTypeName}}Node(right.items[1:], rightChildren)
+		items[i] = right.items[0]
+		return &private{{.MapItemTypeName}}Node{items: items, children: n.children, size: n.size}
+	}
+
+	// Merge child with one of its siblings and the item separating them
+	if i == len(n.children)-1 {
+		i--
+	}
+
+	left, right := n.children[i], n.children[i+1]
+	mergedItems := make([]{{.MapItemTypeName}}, 0, len(left.items)+len(right.items)+1)
+	mergedItems = append(append(append(mergedItems, left.items...), items[i]), right.items...)
+	var mergedChildren []*private{{.MapItemTypeName}}Node
+	if !left.isLeaf() {
+		mergedChildren = make([]*private{{.MapItemTypeName}}Node, 0, len(left.children)+len(right.children))
+		mergedChildren = append(append(mergedChildren, left.children...), right.children...)
+	}
+
+	children := append(n.children[:i+1], n.children[i+2:]...)
+	children[i] = &private{{.MapItemTypeName}}Node{items: mergedItems, children: mergedChildren, size: left.size + right.size + 1}
+	return &private{{.MapItemTypeName}}Node{items: remove{{.MapItemTypeName}}(items, i), children: children, size: n.size}
+}
+
+// get returns the item with rank i in the subtree rooted at n.
+func (n *private{{.MapItemTypeName}}Node) get(i int) {{.MapItemTypeName}} {
+	for !n.isLeaf() {
+		j := 0
+		for ; i >= n.children[j].size; j++ {
+			i -= n.children[j].size
+			if i == 0 {
+				return n.items[j]
+			}
+
+			i--
+		}
+
+		n = n.children[j]
+	}
+
+	return n.items[i]
+}
+
+func (n *private{{.MapItemTypeName}}Node) rangeItems(f func({{.MapKeyTypeName}}, {{.MapValueTypeName}}) bool) bool {
+	for i, item := range n.items {
+		if !n.isLeaf() && !n.children[i].rangeItems(f) {
+			return false
+		}
+
+		if !f(item.Key, item.Value) {
+			return false
+		}
+	}
+
+	return n.isLeaf() || n.children[len(n.items)].rangeItems(f)
+}
+
+// rangeFrom calls f for all items in the subtree rooted at n, in order, starting at
+// the first item with a key that is not less than key.
+func (n *private{{.MapItemTypeName}}Node) rangeFrom(key {{.MapKeyTypeName}}, f func({{.MapKeyTypeName}}, {{.MapValueTypeName}}) bool) bool {
+	i, found := n.search(key)
+	if !n.isLeaf() && !found && !n.children[i].rangeFrom(key, f) {
+		return false
+	}
+
+	for ; i < len(n.items); i++ {
+		if !f(n.items[i].Key, n.items[i].Value) {
+			return false
+		}
+
+		if !n.isLeaf() && !n.children[i+1].rangeItems(f) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// {{.MapTypeName}} is a persistent key - value map ordered by key
+type {{.MapTypeName}} struct {
+	root *private{{.MapItemTypeName}}Node
+}
+
+var empty{{.MapTypeName}} = &{{.MapTypeName}}{root: empty{{.MapItemTypeName}}Node}
+
+// new{{.MapTypeName}} returns a map containing items. For items with equal keys the
+// last one is kept. The tree is built bottom up from the sorted items rather than by
+// storing the items one at a time.
+func new{{.MapTypeName}}(items []{{.MapItemTypeName}}) *{{.MapTypeName}} {
+	if len(items) == 0 {
+		return empty{{.MapTypeName}}
+	}
+
+	sorted := make([]{{.MapItemTypeName}}, len(items))
+	copy(sorted, items)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return {{.MapKeyLessFunc}}(sorted[i].Key, sorted[j].Key)
+	})
+
+	unique := sorted[:0]
+	for i, item := range sorted {
+		if i+1 < len(sorted) && !{{.MapKeyLessFunc}}(item.Key, sorted[i+1].Key) {
+			continue
+		}
+
+		unique = append(unique, item)
+	}
+
+	height := 0
+	for btreeCapacity(height) < len(unique) {
+		height++
+	}
+
+	return &{{.MapTypeName}}{root: build{{.MapItemTypeName}}Node(unique, height)}
+}
+
+// build{{.MapItemTypeName}}Node returns a node of the given height holding the sorted
+// items. The items are spread evenly over as few children as possible, which keeps all
+// nodes within the B-tree limits.
+func build{{.MapItemTypeName}}Node(items []{{.MapItemTypeName}}, height int) *private{{.MapItemTypeName}}Node {
+	if height == 0 {
+		return new{{.MapItemTypeName}}Node(items[:len(items):len(items)], nil)
+	}
+
+	childCapacity := btreeCapacity(height - 1)
+	count := (len(items) + childCapacity + 1) / (childCapacity + 1)
+	if count < 2 {
+		count = 2
+	}
+
+	// The items not in the children separate them
+	childItems := len(items) - (count - 1)
+	nodeItems := make([]{{.MapItemTypeName}}, 0, count-1)
+	children := make([]*private{{.MapItemTypeName}}Node, 0, count)
+	start := 0
+	for i := 0; i < count; i++ {
+		size := childItems / count
+		if i < childItems%count {
+			size++
+		}
+
+		children = append(children, build{{.MapItemTypeName}}Node(items[start:start+size], height-1))
+		start += size
+		if i < count-1 {
+			nodeItems = append(nodeItems, items[start])
+			start++
+		}
+	}
+
+	return new{{.MapItemTypeName}}Node(nodeItems, children)
+}
+
+// Len returns the number of items in m.
+func (m *{{.MapTypeName}}) Len() int {
+	return m.root.size
+}
+
+// Load returns value identified by key. ok is set to true if key exists in the map, false otherwise.
+func (m *{{.MapTypeName}}) Load(key {{.MapKeyTypeName}}) (value {{.MapValueTypeName}}, ok bool) {
+	return m.root.load(key)
+}
+
+// Store returns a new {{.MapTypeName}} containing value identified by key.
+func (m *{{.MapTypeName}}) Store(key {{.MapKeyTypeName}}, value {{.MapValueTypeName}}) *{{.MapTypeName}} {
+	root, _ := m.root.store({{.MapItemTypeName}}{Key: key, Value: value})
+	if len(root.items) > btreeMaxItems {
+		left, median, right := root.split()
+		root = new{{.MapItemTypeName}}Node([]{{.MapItemTypeName}}{median}, []*private{{.MapItemTypeName}}Node{left, right})
+	}
+
+	return &{{.MapTypeName}}{root: root}
+}
+
+// Delete returns a new {{.MapTypeName}} without the element identified by key.
+func (m *{{.MapTypeName}}) Delete(key {{.MapKeyTypeName}}) *{{.MapTypeName}} {
+	root, deleted := m.root.delete(key)
+	if !deleted {
+		return m
+	}
+
+	if len(root.items) == 0 && !root.isLeaf() {
+		root = root.children[0]
+	}
+
+	return &{{.MapTypeName}}{root: root}
+}
+
+// Get returns the key and value of the item at position i in key order.
+func (m *{{.MapTypeName}}) Get(i int) ({{.MapKeyTypeName}}, {{.MapValueTypeName}}) {
+	if i < 0 || i >= m.root.size {
+		panic("Index out of bounds")
+	}
+
+	item := m.root.get(i)
+	return item.Key, item.Value
+}
+
+// Min returns the item with the smallest key. ok is set to false if m is empty.
+func (m *{{.MapTypeName}}) Min() (key {{.MapKeyTypeName}}, value {{.MapValueTypeName}}, ok bool) {
+	if m.root.size == 0 {
+		return key, value, false
+	}
+
+	n := m.root
+	for !n.isLeaf() {
+		n = n.children[0]
+	}
+
+	return n.items[0].Key, n.items[0].Value, true
+}
+
+// Max returns the item with the largest key. ok is set to false if m is empty.
+func (m *{{.MapTypeName}}) Max() (key {{.MapKeyTypeName}}, value {{.MapValueTypeName}}, ok bool) {
+	if m.root.size == 0 {
+		return key, value, false
+	}
+
+	n := m.root
+	for !n.isLeaf() {
+		n = n.children[len(n.children)-1]
+	}
+
+	item := n.items[len(n.items)-1]
+	return item.Key, item.Value, true
+}
+
+// Floor returns the item with the largest key that is less than or equal to key.
+// ok is set to false if there is no such item.
+func (m *{{.MapTypeName}}) Floor(key {{.MapKeyTypeName}}) (floorKey {{.MapKeyTypeName}}, value {{.MapValueTypeName}}, ok bool) {
+	n := m.root
+	for {
+		i, found := n.search(key)
+		if found {
+			return n.items[i].Key, n.items[i].Value, true
+		}
+
+		if i > 0 {
+			floorKey, value, ok = n.items[i-1].Key, n.items[i-1].Value, true
+		}
+
+		if n.isLeaf() {
+			return floorKey, value, ok
+		}
+
+		n = n.children[i]
+	}
+}
+
+// Ceiling returns the item with the smallest key that is greater than or equal to key.
+// ok is set to false if there is no such item.
+func (m *{{.MapTypeName}}) Ceiling(key {{.MapKeyTypeName}}) (ceilingKey {{.MapKeyTypeName}}, value {{.MapValueTypeName}}, ok bool) {
+	n := m.root
+	for {
+		i, found := n.search(key)
+		if i < len(n.items) {
+			ceilingKey, value, ok = n.items[i].Key, n.items[i].Value, true
+		}
+
+		if found || n.isLeaf() {
+			return ceilingKey, value, ok
+		}
+
+		n = n.children[i]
+	}
+}
+
+// Range calls f repeatedly passing it each key and value as argument, in key order,
+// until either all elements have been visited or f returns false.
+func (m *{{.MapTypeName}}) Range(f func({{.MapKeyTypeName}}, {{.MapValueTypeName}}) bool) {
+	m.root.rangeItems(f)
+}
+
+// RangeFrom works like Range but starts at the first item with a key that is greater
+// than or equal to key.
+func (m *{{.MapTypeName}}) RangeFrom(key {{.MapKeyTypeName}}, f func({{.MapKeyTypeName}}, {{.MapValueTypeName}}) bool) {
+	m.root.rangeFrom(key, f)
+}
+
+// RangeBetween works like Range but only visits the items with a key that is greater
+// than or equal to lo and less than hi.
+func (m *{{.MapTypeName}}) RangeBetween(lo, hi {{.MapKeyTypeName}}, f func({{.MapKeyTypeName}}, {{.MapValueTypeName}}) bool) {
+	m.root.rangeFrom(lo, func(key {{.MapKeyTypeName}}, value {{.MapValueTypeName}}) bool {
+		return {{.MapKeyLessFunc}}(key, hi) && f(key, value)
+	})
+}
+
+// ToNativeMap returns a native Go map containing all elements of m.
+func (m *{{.MapTypeName}}) ToNativeMap() map[{{.MapKeyTypeName}}]{{.MapValueTypeName}} {
+	result := make(map[{{.MapKeyTypeName}}]{{.MapValueTypeName}})
+	m.Range(func(key {{.MapKeyTypeName}}, value {{.MapValueTypeName}}) bool {
+		result[key] = value
+		return true
+	})
+
+	return result
+}
+
+// MarshalJSON encodes m in key order as a JSON object if the keys are strings, integers or
+// implement encoding.TextMarshaler, following the same rules as encoding/json uses for map
+// keys. Other keys are encoded as an array of [key, value] arrays.
+func (m *{{.MapTypeName}}) MarshalJSON() ([]byte, error) {
+	return jsonEncodeMap(jsonObjectKeys((*{{.MapKeyTypeName}})(nil)), func(entry func(key, value interface{}) bool) {
+		m.Range(func(key {{.MapKeyTypeName}}, value {{.MapValueTypeName}}) bool {
+			return entry(&key, &value)
+		})
+	})
+}
+
+// UnmarshalJSON sets m to the items of data, either a JSON object or an array of [key, value]
+// arrays. m must not be used by anyone else when this is called, it is meant to be used by
+// encoding/json only.
+func (m *{{.MapTypeName}}) UnmarshalJSON(data []byte) error {
+	items := make([]{{.MapItemTypeName}}, 0)
+	err := jsonDecodeMap(data, func(decodeKey func(interface{}) error, dec *json.Decoder) error {
+		var item {{.MapItemTypeName}}
+		if err := decodeKey(&item.Key); err != nil {
+			return err
+		}
+
+		if err := dec.Decode(&item.Value); err != nil {
+			return err
+		}
+
+		items = append(items, item)
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	*m = *new{{.MapTypeName}}(items)
+	return nil
+}
+
+`
+const PublicMapTemplate string = `
+////////////////////
+/// Constructors ///
+////////////////////
+
+// New{{.MapTypeName}} returns a new {{.MapTypeName}} containing all items in items.
+func New{{.MapTypeName}}(items ...{{.MapItemTypeName}}) *{{.MapTypeName}} {
+	return new{{.MapTypeName}}(items)
+}
+
+`
+const PublicSortedMapTemplate string = `
+////////////////////
+/// Constructors ///
+////////////////////
+
+// New{{.MapTypeName}} returns a new {{.MapTypeName}} containing all items in items.
+func New{{.MapTypeName}}(items ...{{.MapItemTypeName}}) *{{.MapTypeName}} {
+	return new{{.MapTypeName}}(items)
+}
+
+// New{{.MapTypeName}}FromNativeMap returns a new {{.MapTypeName}} containing all items in m.
+func New{{.MapTypeName}}FromNativeMap(m map[{{.MapKeyTypeName}}]{{.MapValueTypeName}}) *{{.MapTypeName}} {
+	items := make([]{{.MapItemTypeName}}, 0, len(m))
+	for key, value := range m {
+		items = append(items, {{.MapItemTypeName}}{Key: key, Value: value})
+	}
+
+	return new{{.MapTypeName}}(items)
+}
+
+`
+const RRBCommonTemplate string = `
+////////////////
+/// RRB tree ///
+////////////////
+
+// Number of slots that a node may be short of a full node and still be
+// considered to satisfy the search step invariant.
+const rrbInvariant = 1
+
+// Number of extra slots allowed, compared to the optimal number of slots,
+// before nodes are redistributed during concatenation.
+const rrbExtras = 2
+
+// rrbNode is an internal node in a relaxed radix balanced tree. sizes contains
+// the cumulative number of elements in the children of the node. It is nil if all
+// children but the last one are full in which case radix indexing can be used.
+type rrbNode struct {
+	children []commonNode
+	sizes    []uint
+	len      uint
+}
+
+func (n *rrbNode) childIndex(i, shift uint) (int, uint) {
+	idx := i >> shift
+	if n.sizes == nil {
+		return int(idx), i - (idx << shift)
+	}
+
+	for n.sizes[idx] <= i {
+		idx++
+	}
+
+	if idx > 0 {
+		i -= n.sizes[idx-1]
+	}
+
+	return int(idx), i
+}
+
+func uintMax(a, b uint) uint {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+// rrbConcatPlan returns the slot counts that nodes with the slot counts in counts
+// should be redistributed into to satisfy the search step invariant. counts is
+// modified in the process.
+func rrbConcatPlan(counts []int) []int {
+	total := 0
+	for _, c := range counts {
+		total += c
+	}
+
+	optimal := (total-1)/nodeSize + 1
+	n := len(counts)
+	i := 0
+	for optimal+rrbExtras < n {
+		// Skip all nodes that already satisfy the invariant
+		for counts[i] > nodeSize-rrbInvariant {
+			i++
+		}
+
+		// Found a short node, spread its content over the following nodes
+		remaining := counts[i]
+		for remaining > 0 {
+			size := remaining + counts[i+1]
+			if size > nodeSize {
+				size = nodeSize
+			}
+
+			counts[i] = size
+			remaining = remaining + counts[i+1] - size
+			i++
+		}
+
+		// Node i is now empty, remove it
+		copy(counts[i:n-1], counts[i+1:n])
+		n--
+		i--
+	}
+
+	return counts[:n]
+}
+
+`
+const RRBVectorTemplate string = `
+//////////////////
+/// RRB Vector ///
+//////////////////
+
+// A {{.VectorTypeName}} is an ordered persistent/immutable collection of items backed by a
+// relaxed radix balanced tree. Apart from the operations of a regular vector it supports
+// concatenation, insertion and removal at arbitrary positions in logarithmic time.
+type {{.VectorTypeName}} struct {
+	root  commonNode
+	len   uint
+	shift uint
+}
+
+var empty{{.VectorTypeName}} *{{.VectorTypeName}} = &{{.VectorTypeName}}{root: []{{.TypeName}}{}}
+
+// New{{.VectorTypeName}} returns a new {{.VectorTypeName}} containing the items provided in items.
+func New{{.VectorTypeName}}(items ...{{.TypeName}}) *{{.VectorTypeName}} {
+	return new{{.VectorTypeName}}Tree(items)
+}
+
+// new{{.VectorTypeName}}Tree builds a balanced tree bottom up from items.
+func new{{.VectorTypeName}}Tree(items []{{.TypeName}}) *{{.VectorTypeName}} {
+	if len(items) == 0 {
+		return empty{{.VectorTypeName}}
+	}
+
+	itemLen := uint(len(items))
+	level := make([]commonNode, 0, (itemLen+nodeSize-1)/nodeSize)
+	for start := uint(0); start < itemLen; start += nodeSize {
+		leaf := make([]{{.TypeName}}, uintMin(itemLen-start, nodeSize))
+		copy(leaf, items[start:])
+		level = append(level, leaf)
+	}
+
+	shift := uint(0)
+	for len(level) > 1 {
+		shift += shiftSize
+		levelLen := uint(len(level))
+		parents := make([]commonNode, 0, (levelLen+nodeSize-1)/nodeSize)
+		for start := uint(0); start < levelLen; start += nodeSize {
+			children := make([]commonNode, uintMin(levelLen-start, nodeSize))
+			copy(children, level[start:])
+			parents = append(parents, new{{.VectorTypeName}}Node(children, shift))
+		}
+
+		level = parents
+	}
+
+	return &{{.VectorTypeName}}{root: level[0], len: itemLen, shift: shift}
+}
+
+// new{{.VectorTypeName}}Node creates a new node at level shift with children. A size table is
+// only created if the children cannot be indexed using plain radix indexing.
+func new{{.VectorTypeName}}Node(children []commonNode, shift uint) *rrbNode {
+	childShift := shift - shiftSize
+	var sizes []uint
+	total := uint(0)
+	for i, child := range children {
+		childLen := len{{.VectorTypeName}}Node(child, childShift)
+		if sizes == nil && i < len(children)-1 && childLen != 1<<shift {
+			sizes = make([]uint, len(children))
+			for j := 0; j < i; j++ {
+				sizes[j] = uint(j+1) << shift
+			}
+		}
+
+		total += childLen
+		if sizes != nil {
+			sizes[i] = total
+		}
+	}
+
+	return &rrbNode{children: children, sizes: sizes, len: total}
+}
+
+func len{{.VectorTypeName}}Node(node commonNode, shift uint) uint {
+	if shift == 0 {
+		return uint(len(node.([]{{.TypeName}})))
+	}
+
+	return node.(*rrbNode).len
+}
+
+func slots{{.VectorTypeName}}Node(node commonNode, shift uint) int {
+	if shift == 0 {
+		return len(node.([]{{.TypeName}}))
+	}
+
+	return len(node.(*rrbNode).children)
+}
+
+// Get returns the element at position i.
+func (v *{{.VectorTypeName}}) Get(i int) {{.TypeName}} {
+	if i < 0 || uint(i) >= v.len {
+		panic("Index out of bounds")
+	}
+
+	leaf, leafIx := v.leafFor(uint(i))
+	return leaf[leafIx]
+}
+
+func (v *{{.VectorTypeName}}) leafFor(i uint) ([]{{.TypeName}}, uint) {
+	node := v.root
+	for shift := v.shift; shift > 0; shift -= shiftSize {
+		n := node.(*rrbNode)
+		var idx int
+		idx, i = n.childIndex(i, shift)
+		node = n.children[idx]
+	}
+
+	return node.([]{{.TypeName}}), i
+}
+
+// Set returns a new vector with the element at position i set to item.
+func (v *{{.VectorTypeName}}) Set(i int, item {{.TypeName}}) *{{.VectorTypeName}} {
+	if i < 0 || uint(i) >= v.len {
+		panic("Index out of bounds")
+	}
+
+	return &{{.VectorTypeName}}{root: v.doAssoc(v.shift, v.root, uint(i), item), len: v.len, shift: v.shift}
+}
+
+func (v *{{.VectorTypeName}}) doAssoc(shift uint, node commonNode, i uint, item {{.TypeName}}) commonNode {
+	if shift == 0 {
+		leaf := node.([]{{.TypeName}})
+		ret := make([]{{.TypeName}}, len(leaf))
+		copy(ret, leaf)
+		ret[i] = item
+		return ret
+	}
+
+	n := node.(*rrbNode)
+	idx, subI := n.childIndex(i, shift)
+	children := make([]commonNode, len(n.children))
+	copy(children, n.children)
+	children[idx] = v.doAssoc(shift-shiftSize, children[idx], subI, item)
+	return &rrbNode{children: children, sizes: n.sizes, len: n.len}
+}
+
+// Append returns a new vector with item(s) appended to it.
+func (v *{{.VectorTypeName}}) Append(items ...{{.TypeName}}) *{{.VectorTypeName}} {
+	return v.Concat(new{{.VectorTypeName}}Tree(items))
+}
+
+// Concat returns a new vector containing all elements in v followed by all elements in other.
+func (v *{{.VectorTypeName}}) Concat(other *{{.VectorTypeName}}) *{{.VectorTypeName}} {
+	if other.len == 0 {
+		return v
+	}
+
+	if v.len == 0 {
+		return other
+	}
+
+	nodes := concat{{.VectorTypeName}}Nodes(v.root, v.shift, other.root, other.shift)
+	shift := uintMax(v.shift, other.shift)
+	if len(nodes) == 1 {
+		return &{{.VectorTypeName}}{root: nodes[0], len: v.len + other.len, shift: shift}
+	}
+
+	shift += shiftSize
+	return &{{.VectorTypeName}}{root: new{{.VectorTypeName}}Node(nodes, shift), len: v.len + other.len, shift: shift}
+}
+
+// concat{{.VectorTypeName}}Nodes merges the right edge of left with the left edge of right.
+// The result is one or two nodes at the level of the highest of left and right.
+func concat{{.VectorTypeName}}Nodes(left commonNode, leftShift uint, right commonNode, rightShift uint) []commonNode {
+	if leftShift > rightShift {
+		l := left.(*rrbNode)
+		last := len(l.children) - 1
+		middle := concat{{.VectorTypeName}}Nodes(l.children[last], leftShift-shiftSize, right, rightShift)
+		return rebalance{{.VectorTypeName}}Nodes(l.children[:last], middle, nil, leftShift)
+	}
+
+	if leftShift < rightShift {
+		r := right.(*rrbNode)
+		middle := concat{{.VectorTypeName}}Nodes(left, leftShift, r.children[0], rightShift-shiftSize)
+		return rebalance{{.VectorTypeName}}Nodes(nil, middle, r.children[1:], rightShift)
+	}
+
+	if leftShift == 0 {
+		l, r := left.([]{{.TypeName}}), right.([]{{.TypeName}})
+		if len(l)+len(r) > nodeSize {
+			return []commonNode{left, right}
+		}
+
+		leaf := make([]{{.TypeName}}, 0, len(l)+len(r))
+		leaf = append(leaf, l...)
+		leaf = append(leaf, r...)
+		return []commonNode{leaf}
+	}
+
+	l, r := left.(*rrbNode), right.(*rrbNode)
+	last := len(l.children) - 1
+	middle := concat{{.VectorTypeName}}Nodes(l.children[last], leftShift-shiftSize, r.children[0], rightShift-shiftSize)
+	return rebalance{{.VectorTypeName}}Nodes(l.children[:last], middle, r.children[1:], leftShift)
+}
+
+// rebalance{{.VectorTypeName}}Nodes redistributes the children in left, middle and right
+// into one or two new nodes at level shift.
+func rebalance{{.VectorTypeName}}Nodes(left, middle, right []commonNode, shift uint) []commonNode {
+	all := make([]commonNode, 0, len(left)+len(middle)+len(right))
+	all = append(all, left...)
+	all = append(all, middle...)
+	all = append(all, right...)
+	all = redistribute{{.VectorTypeName}}Nodes(all, shift-shiftSize)
+	if len(all) <= nodeSize {
+		return []commonNode{new{{.VectorTypeName}}Node(all, shift)}
+	}
+
+	return []commonNode{
+		new{{.VectorTypeName}}Node(all[:nodeSize:nodeSize], shift),
+		new{{.VectorTypeName}}Node(all[nodeSize:], shift)}
+}
+
+func redistribute{{.VectorTypeName}}Nodes(nodes []commonNode, shift uint) []commonNode {
+	counts := make([]int, len(nodes))
+	for i, node := range nodes {
+		counts[i] = slots{{.VectorTypeName}}Node(node, shift)
+	}
+
+	plan := rrbConcatPlan(counts)
+	if len(plan) == len(nodes) {
+		return nodes
+	}
+
+	result := make([]commonNode, len(plan))
+	nodeIx, offset := 0, 0
+	for i, size := range plan {
+		if offset == 0 && size == slots{{.VectorTypeName}}Node(nodes[nodeIx], shift) {
+			result[i] = nodes[nodeIx]
+			nodeIx++
+			continue
+		}
+
+		if shift == 0 {
+			leaf := make([]{{.TypeName}}, 0, size)
+			for len(leaf) < size {
+				old := nodes[nodeIx].([]{{.TypeName}})
+				end := offset + size - len(leaf)
+				if end > len(old) {
+					end = len(old)
+				}
+
+				leaf = append(leaf, old[offset:end]...)
+				offset = end
+				if offset == len(old) {
+					nodeIx++
+					offset = 0
+				}
+			}
+
+			result[i] = leaf
+		} else {
+			children := make([]commonNode, 0, size)
+			for len(children) < size {
+				old := nodes[nodeIx].(*rrbNode).children
+				end := offset + size - len(children)
+				if end > len(old) {
+					end = len(old)
+				}
+
+				children = append(children, old[offset:end]...)
+				offset = end
+				if offset == len(old) {
+					nodeIx++
+					offset = 0
+				}
+			}
+
+			result[i] = new{{.VectorTypeName}}Node(children, shift)
+		}
+	}
+
+	return result
+}
+
+// take{{.VectorTypeName}}Node returns a node containing the first n elements of node.
+func take{{.VectorTypeName}}Node(node commonNode, shift, n uint) commonNode {
+	if shift == 0 {
+		leaf := make([]{{.TypeName}}, n)
+		copy(leaf, node.([]{{.TypeName}}))
+		return leaf
+	}
+
+	nd := node.(*rrbNode)
+	idx, subI := nd.childIndex(n-1, shift)
+	children := make([]commonNode, idx+1)
+	copy(children, nd.children)
+	children[idx] = take{{.VectorTypeName}}Node(children[idx], shift-shiftSize, subI+1)
+	return new{{.VectorTypeName}}Node(children, shift)
+}
+
+// drop{{.VectorTypeName}}Node returns a node containing all but the first n elements of node.
+func drop{{.VectorTypeName}}Node(node commonNode, shift, n uint) commonNode {
+	if shift == 0 {
+		leaf := node.([]{{.TypeName}})
+		ret := make([]{{.TypeName}}, uint(len(leaf))-n)
+		copy(ret, leaf[n:])
+		return ret
+	}
+
+	nd := node.(*rrbNode)
+	idx, subI := nd.childIndex(n, shift)
+	children := make([]commonNode, len(nd.children)-idx)
+	copy(children, nd.children[idx:])
+	if subI > 0 {
+		children[0] = drop{{.VectorTypeName}}Node(children[0], shift-shiftSize, subI)
+	}
+
+	return new{{.VectorTypeName}}Node(children, shift)
+}
+
+// Slice returns a new {{.VectorTypeName}} containing the elements [start,stop) in v.
+// Contrary to the slice of a regular vector no reference to elements outside of
+// the slice is kept.
+func (v *{{.VectorTypeName}}) Slice(start, stop int) *{{.VectorTypeName}} {
+	assertSliceOk(start, stop, v.Len())
+	if start == stop {
+		return empty{{.VectorTypeName}}
+	}
+
+	if start == 0 && uint(stop) == v.len {
+		return v
+	}
+
+	root, shift := v.root, v.shift
+	if uint(stop) < v.len {
+		root = take{{.VectorTypeName}}Node(root, shift, uint(stop))
+	}
+
+	if start > 0 {
+		root = drop{{.VectorTypeName}}Node(root, shift, uint(start))
+	}
+
+	// Remove levels that have become redundant
+	for shift > 0 && len(root.(*rrbNode).children) == 1 {
+		root = root.(*rrbNode).children[0]
+		shift -= shiftSize
+	}
+
+	return &{{.VectorTypeName}}{root: root, len: uint(stop - start), shift: shift}
+}
+
+// InsertAt returns a new vector with item(s) inserted before the element at position i.
+// If i equals the length of v the items are appended to the vector.
+func (v *{{.VectorTypeName}}) InsertAt(i int, items ...{{.TypeName}}) *{{.VectorTypeName}} {
+	if i < 0 || uint(i) > v.len {
+		panic("Index out of bounds")
+	}
+
+	if len(items) == 0 {
+		return v
+	}
+
+	return v.Slice(0, i).Concat(new{{.VectorTypeName}}Tree(items)).Concat(v.Slice(i, v.Len()))
+}
+
+// RemoveAt returns a new vector with the n elements starting at position i removed.
+func (v *{{.VectorTypeName}}) RemoveAt(i, n int) *{{.VectorTypeName}} {
+	assertSliceOk(i, i+n, v.Len())
+	if n == 0 {
+		return v
+	}
+
+	return v.Slice(0, i).Concat(v.Slice(i+n, v.Len()))
+}
+
+// Len returns the length of v.
+func (v *{{.VectorTypeName}}) Len() int {
+	return int(v.len)
+}
+
+// Range calls f repeatedly passing it each element in v in order as argument until either
+// all elements have been visited or f returns false.
+func (v *{{.VectorTypeName}}) Range(f func({{.TypeName}}) bool) {
+	range{{.VectorTypeName}}Node(v.root, v.shift, f)
+}
+
+func range{{.VectorTypeName}}Node(node commonNode, shift uint, f func({{.TypeName}}) bool) bool {
+	if shift == 0 {
+		for _, item := range node.([]{{.TypeName}}) {
+			if !f(item) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	for _, child := range node.(*rrbNode).children {
+		if !range{{.VectorTypeName}}Node(child, shift-shiftSize, f) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ToNativeSlice returns a Go slice containing all elements of v
+func (v *{{.VectorTypeName}}) ToNativeSlice() []{{.TypeName}} {
+	result := make([]{{.TypeName}}, 0, v.len)
+	return append{{.VectorTypeName}}Node(result, v.root, v.shift)
+}
+
+// MarshalJSON encodes v as a JSON array.
+func (v *{{.VectorTypeName}}) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.ToNativeSlice())
+}
+
+// UnmarshalJSON sets v to the elements of data, a JSON array. v must not be used by
+// anyone else when this is called, it is meant to be used by encoding/json only.
+func (v *{{.VectorTypeName}}) UnmarshalJSON(data []byte) error {
+	items := make([]{{.TypeName}}, 0)
+	err := jsonDecodeArray(data, func(dec *json.Decoder) error {
+		var item {{.TypeName}}
+		if err := dec.Decode(&item); err != nil {
+			return err
+		}
+
+		items = append(items, item)
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	*v = *New{{.VectorTypeName}}(items...)
+	return nil
+}
+
+func append{{.VectorTypeName}}Node(dst []{{.TypeName}}, node commonNode, shift uint) []{{.TypeName}} {
+	if shift == 0 {
+		return append(dst, node.([]{{.TypeName}})...)
+	}
+
+	for _, child := range node.(*rrbNode).children {
+		dst = append{{.VectorTypeName}}Node(dst, child, shift-shiftSize)
+	}
+
+	return dst
+}
+`
+const SetIterTemplate string = `
+// All returns an iterator over the elements in s. The order is unspecified.
+func (s *{{.SetTypeName}}) All() iter.Seq[{{.MapKeyTypeName}}] {
+	return func(yield func({{.MapKeyTypeName}}) bool) {
+		s.Range(yield)
+	}
+}
+`
+const SetTemplate string = `
+// {{.SetTypeName}} is a persistent set
+type {{.SetTypeName}} struct {
+	backingMap *{{.MapTypeName}}
+}
+
+// New{{.SetTypeName}} returns a new {{.SetTypeName}} containing items.
+func New{{.SetTypeName}}(items ...{{.MapKeyTypeName}}) *{{.SetTypeName}} {
+	mapItems := make([]{{.MapItemTypeName}}, 0, len(items))
+	var mapValue {{.MapValueTypeName}}
+	for _, x := range items {
+		mapItems = append(mapItems, {{.MapItemTypeName}}{Key: x, Value: mapValue})
+	}
+
+	return &{{.SetTypeName}}{backingMap: new{{.MapTypeName}}(mapItems)}
+}
+
+// Add returns a new {{.SetTypeName}} containing item.
+func (s *{{.SetTypeName}}) Add(item {{.MapKeyTypeName}}) *{{.SetTypeName}} {
+	var mapValue {{.MapValueTypeName}}
+	return &{{.SetTypeName}}{backingMap: s.backingMap.Store(item, mapValue)}
+}
+
+// Delete returns a new {{.SetTypeName}} without item.
+func (s *{{.SetTypeName}}) Delete(item {{.MapKeyTypeName}}) *{{.SetTypeName}} {
+	newMap := s.backingMap.Delete(item)
+	if newMap == s.backingMap {
+		return s
+	}
+
+	return &{{.SetTypeName}}{backingMap: newMap}
+}
+
+// Contains returns true if item is present in s, false otherwise.
+func (s *{{.SetTypeName}}) Contains(item {{.MapKeyTypeName}}) bool {
+	_, ok := s.backingMap.Load(item)
+	return ok
+}
+
+// Range calls f repeatedly passing it each element in s as argument until either
+// all elements have been visited or f returns false.
+func (s *{{.SetTypeName}}) Range(f func({{.MapKeyTypeName}}) bool) {
+	s.backingMap.Range(func(k {{.MapKeyTypeName}}, _ {{.MapValueTypeName}}) bool {
+		return f(k)
+	})
+}
+
+// IsSubset returns true if all elements in s are present in other, false otherwise.
+func (s *{{.SetTypeName}}) IsSubset(other *{{.SetTypeName}}) bool {
+	if other.Len() < s.Len() {
+		return false
+	}
+
+	isSubset := true
+	s.Range(func(item {{.MapKeyTypeName}}) bool {
+		if !other.Contains(item) {
+			isSubset = false
+		}
+
+		return isSubset
+	})
+
+	return isSubset
+}
+
+// IsSuperset returns true if all elements in other are present in s, false otherwise.
+func (s *{{.SetTypeName}}) IsSuperset(other *{{.SetTypeName}}) bool {
+	return other.IsSubset(s)
+}
+
+// Union returns a new {{.SetTypeName}} containing all elements present
+// in either s or other.
+func (s *{{.SetTypeName}}) Union(other *{{.SetTypeName}}) *{{.SetTypeName}} {
+	result := s
+
+	// Simplest possible solution right now. Would probable be more efficient
+	// to concatenate two slices of elements from the two sets and create a
+	// new set from that slice for many cases.
+	other.Range(func(item {{.MapKeyTypeName}}) bool {
+		result = result.Add(item)
+		return true
+	})
+
+	return result
+}
+
+// Equals returns true if s and other contains the same elements, false otherwise.
+func (s *{{.SetTypeName}}) Equals(other *{{.SetTypeName}}) bool {
+	return s.Len() == other.Len() && s.IsSubset(other)
+}
+
+// Hash returns a hash of the elements in s. Sets that are equal according to Equals have
+// the same hash.
+func (s *{{.SetTypeName}}) Hash() uint32 {
+	return s.backingMap.Hash()
+}
+
+func (s *{{.SetTypeName}}) difference(other *{{.SetTypeName}}) []{{.MapKeyTypeName}} {
+	items := make([]{{.MapKeyTypeName}}, 0)
+	s.Range(func(item {{.MapKeyTypeName}}) bool {
+		if !other.Contains(item) {
+			items = append(items, item)
+		}
+
+		return true
+	})
+
+	return items
+}
+
+// Difference returns a new {{.SetTypeName}} containing all elements present
+// in s but not in other.
+func (s *{{.SetTypeName}}) Difference(other *{{.SetTypeName}}) *{{.SetTypeName}} {
+	return New{{.SetTypeName}}(s.difference(other)...)
+}
+
+// SymmetricDifference returns a new {{.SetTypeName}} containing all elements present
+// in either s or other but not both.
+func (s *{{.SetTypeName}}) SymmetricDifference(other *{{.SetTypeName}}) *{{.SetTypeName}} {
+	items := s.difference(other)
+	items = append(items, other.difference(s)...)
+	return New{{.SetTypeName}}(items...)
+}
+
+// Intersection returns a new {{.SetTypeName}} containing all elements present in both
+// s and other.
+func (s *{{.SetTypeName}}) Intersection(other *{{.SetTypeName}}) *{{.SetTypeName}} {
+	items := make([]{{.MapKeyTypeName}}, 0)
+	s.Range(func(item {{.MapKeyTypeName}}) bool {
+		if other.Contains(item) {
+			items = append(items, item)
+		}
+
+		return true
+	})
+
+	return New{{.SetTypeName}}(items...)
+}
+
+// Len returns the number of elements in s.
+func (s *{{.SetTypeName}}) Len() int {
+	return s.backingMap.Len()
+}
+
+// ToNativeSlice returns a native Go slice containing all elements of s.
+func (s *{{.SetTypeName}}) ToNativeSlice() []{{.MapKeyTypeName}} {
+	items := make([]{{.MapKeyTypeName}}, 0, s.Len())
+	s.Range(func(item {{.MapKeyTypeName}}) bool {
+		items = append(items, item)
+		return true
+	})
+
+	return items
+}
+
+// MarshalJSON encodes s as a JSON array.
+func (s *{{.SetTypeName}}) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.ToNativeSlice())
+}
+
+// UnmarshalJSON sets s to the elements of data, a JSON array. s must not be used by
+// anyone else when this is called, it is meant to be used by encoding/json only.
+func (s *{{.SetTypeName}}) UnmarshalJSON(data []byte) error {
+	t := New{{.SetTypeName}}().AsTransient()
+	err := jsonDecodeArray(data, func(dec *json.Decoder) error {
+		var item {{.MapKeyTypeName}}
+		if err := dec.Decode(&item); err != nil {
+			return err
+		}
+
+		t.Add(item)
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	*s = *t.Persistent()
+	return nil
+}
+
+// MarshalBinary returns the binary encoding of s. The items are gob encoded.
+func (s *{{.SetTypeName}}) MarshalBinary() ([]byte, error) {
+	return s.backingMap.MarshalBinary()
+}
+
+// UnmarshalBinary sets s to the set encoded in data by MarshalBinary. s must not
+// be used by anyone else when this is called, decode into a new zero {{.SetTypeName}}.
+func (s *{{.SetTypeName}}) UnmarshalBinary(data []byte) error {
+	m := &{{.MapTypeName}}{}
+	if err := m.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	*s = {{.SetTypeName}}{backingMap: m}
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder using the same encoding as MarshalBinary.
+func (s *{{.SetTypeName}}) GobEncode() ([]byte, error) {
+	return s.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder using the same encoding as UnmarshalBinary.
+func (s *{{.SetTypeName}}) GobDecode(data []byte) error {
+	return s.UnmarshalBinary(data)
+}
+
+// EncodeSnapshot writes s to the snapshot stream of e. Nodes shared with containers
+// previously written to e are written as references to those.
+func (s *{{.SetTypeName}}) EncodeSnapshot(e *SnapshotEncoder) error {
+	return e.encode(snapshotSetTag, func() error {
+		return s.backingMap.encodeSnapshot(e)
+	})
+}
+
+// DecodeSnapshot sets s to the next container in the snapshot stream of d, which must
+// be a set. io.EOF is returned if there are no more containers in the stream. s must
+// not be used by anyone else when this is called, decode into a new zero {{.SetTypeName}}.
+func (s *{{.SetTypeName}}) DecodeSnapshot(d *SnapshotDecoder) error {
+	return d.decode(snapshotSetTag, func() error {
+		m := &{{.MapTypeName}}{}
+		if err := m.decodeSnapshot(d); err != nil {
+			return err
+		}
+
+		*s = {{.SetTypeName}}{backingMap: m}
+		return nil
+	})
+}
+
+// {{.SetTypeName}}Transient is a mutable builder for {{.SetTypeName}}. Call Persistent
+// to turn it into a {{.SetTypeName}}, the transient cannot be used after that.
+type {{.SetTypeName}}Transient struct {
+	backingMap *{{.MapTypeName}}Transient
+}
+
+// AsTransient returns a transient containing all elements of s. s is left untouched.
+func (s *{{.SetTypeName}}) AsTransient() *{{.SetTypeName}}Transient {
+	return &{{.SetTypeName}}Transient{backingMap: s.backingMap.AsTransient()}
+}
+
+// Persistent returns a {{.SetTypeName}} containing all elements of t. t cannot be used
+// after this call.
+func (t *{{.SetTypeName}}Transient) Persistent() *{{.SetTypeName}} {
+	return &{{.SetTypeName}}{backingMap: t.backingMap.Persistent()}
+}
+
+// Add adds item to t.
+func (t *{{.SetTypeName}}Transient) Add(item {{.MapKeyTypeName}}) {
+	var mapValue {{.MapValueTypeName}}
+	t.backingMap.Store(item, mapValue)
+}
+
+// Delete removes item from t.
+func (t *{{.SetTypeName}}Transient) Delete(item {{.MapKeyTypeName}}) {
+	t.backingMap.Delete(item)
+}
+
+// Contains returns true if item is present in t, false otherwise.
+func (t *{{.SetTypeName}}Transient) Contains(item {{.MapKeyTypeName}}) bool {
+	_, ok := t.backingMap.Load(item)
+	return ok
+}
+
+// Len returns the number of elements in t.
+func (t *{{.SetTypeName}}Transient) Len() int {
+	return t.backingMap.Len()
+}
+
+`
+const SliceTemplate string = `
+////////////////
+//// Slice /////
+////////////////
+
+// {{.VectorTypeName}}Slice is a slice type backed by a {{.VectorTypeName}}.
+type {{.VectorTypeName}}Slice struct {
+	vector      *{{.VectorTypeName}}
+	start, stop int
+	hash        *uint32
+}
+
+// New{{.VectorTypeName}}Slice returns a new New{{.VectorTypeName}}Slice containing the items provided in items.
+func New{{.VectorTypeName}}Slice(items ...{{.TypeName}}) *{{.VectorTypeName}}Slice {
+	return &{{.VectorTypeName}}Slice{vector: empty{{.VectorTypeName}}.Append(items...), start: 0, stop: len(items), hash: new(uint32)}
+}
+
+// Len returns the length of s.
+func (s *{{.VectorTypeName}}Slice) Len() int {
+	return s.stop - s.start
+}
+
+// Get returns the element at position i.
+func (s *{{.VectorTypeName}}Slice) Get(i int) {{.TypeName}} {
+	if i < 0 || s.start+i >= s.stop {
+		panic("Index out of bounds")
+	}
+
+	return s.vector.Get(s.start + i)
+}
+
+// Set returns a new slice with the element at position i set to item.
+func (s *{{.VectorTypeName}}Slice) Set(i int, item {{.TypeName}}) *{{.VectorTypeName}}Slice {
+	if i < 0 || s.start+i >= s.stop {
+		panic("Index out of bounds")
+	}
+
+	return s.vector.Set(s.start+i, item).Slice(s.start, s.stop)
+}
+
+// Append returns a new slice with item(s) appended to it.
+func (s *{{.VectorTypeName}}Slice) Append(items ...{{.TypeName}}) *{{.VectorTypeName}}Slice {
+	newSlice := {{.VectorTypeName}}Slice{vector: s.vector, start: s.start, stop: s.stop + len(items), hash: new(uint32)}
+
+	// If this is v slice that has an upper bound that is lower than the backing
+	// vector then set the values in the backing vector to achieve some structural
+	// sharing.
+	itemPos := 0
+	for ; s.stop+itemPos < s.vector.Len() && itemPos < len(items); itemPos++ {
+		newSlice.vector = newSlice.vector.Set(s.stop+itemPos, items[itemPos])
+	}
+
+	// For the rest just append it to the underlying vector
+	newSlice.vector = newSlice.vector.Append(items[itemPos:]...)
+	return &newSlice
+}
+
+// Slice returns a {{.VectorTypeName}}Slice that refers to all elements [start,stop) in s.
+func (s *{{.VectorTypeName}}Slice) Slice(start, stop int) *{{.VectorTypeName}}Slice {
+	assertSliceOk(start, stop, s.stop-s.start)
+	return &{{.VectorTypeName}}Slice{vector: s.vector, start: s.start + start, stop: s.start + stop, hash: new(uint32)}
+}
+
+// Range calls f repeatedly passing it each element in s in order as argument until either
+// all elements have been visited or f returns false.
+func (s *{{.VectorTypeName}}Slice) Range(f func({{.TypeName}}) bool) {
+	var currentNode []{{.TypeName}}
+	for i := uint(s.start); i < uint(s.stop); i++ {
+		if i&shiftBitMask == 0 || i == uint(s.start) {
+			currentNode = s.vector.sliceFor(uint(i))
+		}
+
+		if !f(currentNode[i&shiftBitMask]) {
+			return
+		}
+	}
+}
+
+// MarshalJSON encodes s as a JSON array.
+func (s *{{.VectorTypeName}}Slice) MarshalJSON() ([]byte, error) {
+	items := make([]{{.TypeName}}, 0, s.Len())
+	s.Range(func(item {{.TypeName}}) bool {
+		items = append(items, item)
+		return true
+	})
+
+	return json.Marshal(items)
+}
+
+// UnmarshalJSON sets s to the elements of data, a JSON array. s must not be used by
+// anyone else when this is called, it is meant to be used by encoding/json only.
+func (s *{{.VectorTypeName}}Slice) UnmarshalJSON(data []byte) error {
+	v := &{{.VectorTypeName}}{}
+	if err := v.UnmarshalJSON(data); err != nil {
+		return err
+	}
+
+	*s = {{.VectorTypeName}}Slice{vector: v, start: 0, stop: v.Len(), hash: new(uint32)}
+	return nil
+}
+
+// MarshalBinary returns the binary encoding of s, the same as that of a vector with
+// the items of s. The items are gob encoded.
+func (s *{{.VectorTypeName}}Slice) MarshalBinary() ([]byte, error) {
+	return binaryMarshal(s.Len(), func(encode func(interface{}) error) error {
+		chunk := make([]{{.TypeName}}, 0, nodeSize)
+		var err error
+		s.Range(func(item {{.TypeName}}) bool {
+			chunk = append(chunk, item)
+			if len(chunk) == nodeSize {
+				err = encode(chunk)
+				chunk = chunk[:0]
+			}
+
+			return err == nil
+		})
+
+		if err == nil && len(chunk) > 0 {
+			err = encode(chunk)
+		}
+
+		return err
+	})
+}
+
+// UnmarshalBinary sets s to the items encoded in data by MarshalBinary. s must not
+// be used by anyone else when this is called, decode into a new zero {{.VectorTypeName}}Slice.
+func (s *{{.VectorTypeName}}Slice) UnmarshalBinary(data []byte) error {
+	v := &{{.VectorTypeName}}{}
+	if err := v.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	*s = {{.VectorTypeName}}Slice{vector: v, start: 0, stop: v.Len(), hash: new(uint32)}
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder using the same encoding as MarshalBinary.
+func (s *{{.VectorTypeName}}Slice) GobEncode() ([]byte, error) {
+	return s.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder using the same encoding as UnmarshalBinary.
+func (s *{{.VectorTypeName}}Slice) GobDecode(data []byte) error {
+	return s.UnmarshalBinary(data)
+}
+
+`
+const SnapshotTemplate string = `
+/////////////////
+/// Snapshots ///
+/////////////////
+
+// snapshotVersion is written at the start of snapshot streams to identify the format.
+const snapshotVersion = 1
+
+// Tags written before each container in a snapshot stream.
+const (
+	snapshotVectorTag = 'v'
+	snapshotMapTag    = 'm'
+	snapshotSetTag    = 's'
+)
+
+func snapshotKind(tag byte) string {
+	switch tag {
+	case snapshotVectorTag:
+		return "vector"
+	case snapshotMapTag:
+		return "map"
+	case snapshotSetTag:
+		return "set"
+	}
+
+	return fmt.Sprintf("unknown container %q", tag)
 }
 
-// Load returns value identified by key. ok is set to true if key exists in the map, false otherwise.
-func (m *{{.MapTypeName}}) Load(key {{.MapKeyTypeName}}) (value {{.MapValueTypeName}}, ok bool) {
-	bucket := m.backingVector.Get(m.pos(key))
-	if bucket != nil {
-		for _, item := range bucket {
-			if item.Key == key {
-				return item.Value, true
-			}
-		}
+// A SnapshotElementEncoder encodes the items of the containers written to a snapshot. Encode
+// is called with slices of elements, and with slices of key value items for maps and sets.
+// *gob.Encoder is a SnapshotElementEncoder.
+type SnapshotElementEncoder interface {
+	Encode(elements interface{}) error
+}
+
+// A SnapshotElementDecoder decodes the items written by a SnapshotElementEncoder. Decode is
+// called with pointers to slices of the same types as were passed to Encode.
+// *gob.Decoder is a SnapshotElementDecoder.
+type SnapshotElementDecoder interface {
+	Decode(elements interface{}) error
+}
+
+// snapshotNodeKey identifies a node of a vector by the address of its first item and its length.
+type snapshotNodeKey struct {
+	first interface{}
+	len   int
+}
+
+// A SnapshotEncoder writes vectors, maps and sets to a stream. Every node of the containers
+// is written only once, nodes shared with previously written containers, eg. older versions
+// of the same vector, are written as references to the already written nodes. Decoding the
+// stream using a SnapshotDecoder restores the structure sharing between the containers.
+type SnapshotEncoder struct {
+	w        *bufio.Writer
+	elements SnapshotElementEncoder
+	buf      bytes.Buffer
+	ids      map[interface{}]uint64
+	nodes    uint64
+	started  bool
+	err      error
+	scratch  [binary.MaxVarintLen64]byte
+}
+
+// NewSnapshotEncoder returns a SnapshotEncoder writing to w. The items of the containers are
+// encoded by the SnapshotElementEncoder returned by newElementEncoder for the writer given to
+// it. If newElementEncoder is nil the items are gob encoded, gob uses the GobEncoder or
+// encoding.BinaryMarshaler implementations of types that have one.
+func NewSnapshotEncoder(w io.Writer, newElementEncoder func(w io.Writer) SnapshotElementEncoder) *SnapshotEncoder {
+	e := &SnapshotEncoder{w: bufio.NewWriter(w), ids: make(map[interface{}]uint64)}
+	if newElementEncoder == nil {
+		e.elements = gob.NewEncoder(&e.buf)
+	} else {
+		e.elements = newElementEncoder(&e.buf)
 	}
 
-	var zeroValue {{.MapValueTypeName}}
-	return zeroValue, false
+	return e
 }
 
-// Store returns a new {{.MapTypeName}} containing value identified by key.
-func (m *{{.MapTypeName}}) Store(key {{.MapKeyTypeName}}, value {{.MapValueTypeName}}) *{{.MapTypeName}} {
-	// Grow backing vector if load factor is too high
-	if m.Len() >= m.backingVector.Len()*int(upperMapLoadFactor) {
-		buckets := newPrivate{{.MapItemTypeName}}Buckets(m.Len() + 1)
-		buckets.AddItemsFromMap(m)
-		buckets.AddItem({{.MapItemTypeName}}{Key: key, Value: value})
-		return &{{.MapTypeName}}{backingVector: empty{{.MapItemTypeName}}BucketVector.Append(buckets.buckets...), len: buckets.length}
+// encode writes the tag of a container followed by the container, written by writeContainer,
+// and flushes the stream. Once an error has occurred it is returned by all following calls
+// since the stream can no longer be decoded.
+func (e *SnapshotEncoder) encode(tag byte, writeContainer func() error) error {
+	if e.err != nil {
+		return e.err
 	}
 
-	pos := m.pos(key)
-	bucket := m.backingVector.Get(pos)
-	if bucket != nil {
-		for ix, item := range bucket {
-			if item.Key == key {
-				// Overwrite existing item
-				newBucket := make(private{{.MapItemTypeName}}Bucket, len(bucket))
-				copy(newBucket, bucket)
-				newBucket[ix] = {{.MapItemTypeName}}{Key: key, Value: value}
-				return &{{.MapTypeName}}{backingVector: m.backingVector.Set(pos, newBucket), len: m.len}
-			}
-		}
+	if !e.started {
+		e.writeUvarint(snapshotVersion)
+		e.started = true
+	}
 
-		// Add new item to bucket
-		newBucket := make(private{{.MapItemTypeName}}Bucket, len(bucket), len(bucket)+1)
-		copy(newBucket, bucket)
-		newBucket = append(newBucket, {{.MapItemTypeName}}{Key: key, Value: value})
-		return &{{.MapTypeName}}{backingVector: m.backingVector.Set(pos, newBucket), len: m.len + 1}
+	e.w.WriteByte(tag)
+	e.err = writeContainer()
+	if e.err == nil {
+		// Write errors are kept by the buffered writer and returned here
+		e.err = e.w.Flush()
 	}
 
-	item := {{.MapItemTypeName}}{Key: key, Value: value}
-	newBucket := private{{.MapItemTypeName}}Bucket{item}
-	return &{{.MapTypeName}}{backingVector: m.backingVector.Set(pos, newBucket), len: m.len + 1}
+	return e.err
 }
 
-// Delete returns a new {{.MapTypeName}} without the element identified by key.
-func (m *{{.MapTypeName}}) Delete(key {{.MapKeyTypeName}}) *{{.MapTypeName}} {
-	pos := m.pos(key)
-	bucket := m.backingVector.Get(pos)
-	if bucket != nil {
-		newBucket := make(private{{.MapItemTypeName}}Bucket, 0)
-		for _, item := range bucket {
-			if item.Key != key {
-				newBucket = append(newBucket, item)
-			}
-		}
+func (e *SnapshotEncoder) writeUvarint(x uint64) {
+	n := binary.PutUvarint(e.scratch[:], x)
+	e.w.Write(e.scratch[:n])
+}
 
-		removedItemCount := len(bucket) - len(newBucket)
-		if removedItemCount == 0 {
-			return m
+// writeElements writes elements, a slice of items, using the element encoder.
+func (e *SnapshotEncoder) writeElements(elements interface{}) error {
+	e.buf.Reset()
+	if err := e.elements.Encode(elements); err != nil {
+		return err
+	}
+
+	e.writeUvarint(uint64(e.buf.Len()))
+	e.w.Write(e.buf.Bytes())
+	return nil
+}
+
+// writeNode writes a reference to the node identified by key. Nodes that have not been written
+// before are written by writeBody following a zero reference. Nodes with a nil key are always
+// written.
+func (e *SnapshotEncoder) writeNode(key interface{}, writeBody func() error) error {
+	if id, ok := e.ids[key]; ok && key != nil {
+		e.writeUvarint(id)
+		return nil
+	}
+
+	e.writeUvarint(0)
+	if err := writeBody(); err != nil {
+		return err
+	}
+
+	// Nodes are numbered in the order that they are completed, same as when decoding
+	e.nodes++
+	if key != nil {
+		e.ids[key] = e.nodes
+	}
+
+	return nil
+}
+
+// A SnapshotDecoder reads the vectors, maps and sets written by a SnapshotEncoder. The
+// containers must be read in the order that they were written, using the methods of the
+// same container types.
+type SnapshotDecoder struct {
+	r        *bufio.Reader
+	elements SnapshotElementDecoder
+	buf      bytes.Buffer
+	nodes    []interface{}
+	started  bool
+	err      error
+}
+
+// NewSnapshotDecoder returns a SnapshotDecoder reading from r. The items of the containers
+// are decoded by the SnapshotElementDecoder returned by newElementDecoder for the reader given
+// to it. If newElementDecoder is nil the items are gob decoded. The decoder may read beyond
+// the end of the snapshot stream in r.
+func NewSnapshotDecoder(r io.Reader, newElementDecoder func(r io.Reader) SnapshotElementDecoder) *SnapshotDecoder {
+	d := &SnapshotDecoder{r: bufio.NewReader(r)}
+	if newElementDecoder == nil {
+		d.elements = gob.NewDecoder(&d.buf)
+	} else {
+		d.elements = newElementDecoder(&d.buf)
+	}
+
+	return d
+}
+
+// decode reads the tag of the next container, verifies that it is tag, and reads the container
+// using readContainer. io.EOF is returned if there are no more containers in the stream. Once
+// any other error has occurred it is returned by all following calls.
+func (d *SnapshotDecoder) decode(tag byte, readContainer func() error) error {
+	if d.err != nil {
+		return d.err
+	}
+
+	d.err = d.doDecode(tag, readContainer)
+	return d.err
+}
+
+func (d *SnapshotDecoder) doDecode(tag byte, readContainer func() error) error {
+	if !d.started {
+		version, err := binary.ReadUvarint(d.r)
+		if err != nil {
+			return err
 		}
 
-		if len(newBucket) == 0 {
-			newBucket = nil
+		if version != snapshotVersion {
+			return fmt.Errorf("unsupported snapshot version %d", version)
 		}
 
-		newMap := &{{.MapTypeName}}{backingVector: m.backingVector.Set(pos, newBucket), len: m.len - removedItemCount}
-		if newMap.backingVector.Len() > 1 && newMap.Len() < newMap.backingVector.Len()*int(lowerMapLoadFactor) {
-			// Shrink backing vector if needed to avoid occupying excessive space
-			buckets := newPrivate{{.MapItemTypeName}}Buckets(newMap.Len())
-			buckets.AddItemsFromMap(newMap)
-			return &{{.MapTypeName}}{backingVector: empty{{.MapItemTypeName}}BucketVector.Append(buckets.buckets...), len: buckets.length}
+		d.started = true
+	}
+
+	actual, err := d.r.ReadByte()
+	if err != nil {
+		return err
+	}
+
+	if actual != tag {
+		return fmt.Errorf("expected a %s in snapshot, found a %s", snapshotKind(tag), snapshotKind(actual))
+	}
+
+	if err := readContainer(); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
 		}
 
-		return newMap
+		return err
 	}
 
-	return m
+	return nil
 }
 
-// Range calls f repeatedly passing it each key and value as argument until either
-// all elements have been visited or f returns false.
-func (m *{{.MapTypeName}}) Range(f func({{.MapKeyTypeName}}, {{.MapValueTypeName}}) bool) {
-	m.backingVector.Range(func(bucket private{{.MapItemTypeName}}Bucket) bool {
-		for _, item := range bucket {
-			if !f(item.Key, item.Value) {
-				return false
-			}
-		}
-		return true
-	})
+func (d *SnapshotDecoder) readUvarint() (uint64, error) {
+	return binary.ReadUvarint(d.r)
 }
 
-// ToNativeMap returns a native Go map containing all elements of m.
-func (m *{{.MapTypeName}}) ToNativeMap() map[{{.MapKeyTypeName}}]{{.MapValueTypeName}} {
-	result := make(map[{{.MapKeyTypeName}}]{{.MapValueTypeName}})
-	m.Range(func(key {{.MapKeyTypeName}}, value {{.MapValueTypeName}}) bool {
-		result[key] = value
-		return true
-	})
+// readLength reads a length that is at most max.
+func (d *SnapshotDecoder) readLength(max uint64) (int, error) {
+	x, err := d.readUvarint()
+	if err != nil {
+		return 0, err
+	}
 
-	return result
+	if x > max {
+		return 0, fmt.Errorf("invalid snapshot, length %d is larger than %d", x, max)
+	}
+
+	return int(x), nil
 }
 
-`
-const PublicMapTemplate string = `
-////////////////////
-/// Constructors ///
-////////////////////
+func (d *SnapshotDecoder) readUint32() (uint32, error) {
+	x, err := d.readUvarint()
+	if err != nil {
+		return 0, err
+	}
 
-// New{{.MapTypeName}} returns a new {{.MapTypeName}} containing all items in items.
-func New{{.MapTypeName}}(items ...{{.MapItemTypeName}}) *{{.MapTypeName}} {
-	return new{{.MapTypeName}}(items)
+	if x > math.MaxUint32 {
+		return 0, fmt.Errorf("invalid snapshot, %d does not fit in 32 bits", x)
+	}
+
+	return uint32(x), nil
 }
 
-// New{{.MapTypeName}}FromNativeMap returns a new {{.MapTypeName}} containing all items in m.
-func New{{.MapTypeName}}FromNativeMap(m map[{{.MapKeyTypeName}}]{{.MapValueTypeName}}) *{{.MapTypeName}} {
-	buckets := newPrivate{{.MapItemTypeName}}Buckets(len(m))
-	for key, value := range m {
-		buckets.AddItem({{.MapItemTypeName}}{Key: key, Value: value})
+// readElements reads items written by writeElements into the slice pointed to by elements.
+func (d *SnapshotDecoder) readElements(elements interface{}) error {
+	n, err := d.readUvarint()
+	if err != nil {
+		return err
+	}
+
+	d.buf.Reset()
+	if _, err := io.CopyN(&d.buf, d.r, int64(n)); err != nil {
+		return err
 	}
 
-	return &{{.MapTypeName}}{backingVector: empty{{.MapItemTypeName}}BucketVector.Append(buckets.buckets...), len: buckets.length}
+	if err := d.elements.Decode(elements); err != nil {
+		return err
+	}
+
+	if d.buf.Len() > 0 {
+		return fmt.Errorf("invalid snapshot, %d bytes left after decoding elements", d.buf.Len())
+	}
+
+	return nil
+}
+
+// readNode reads a node written by writeNode. New nodes are read by readBody, previously
+// read nodes are returned from the nodes read so far.
+func (d *SnapshotDecoder) readNode(readBody func() (interface{}, error)) (interface{}, error) {
+	id, err := d.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+
+	if id == 0 {
+		node, err := readBody()
+		if err != nil {
+			return nil, err
+		}
+
+		d.nodes = append(d.nodes, node)
+		return node, nil
+	}
+
+	if id > uint64(len(d.nodes)) {
+		return nil, fmt.Errorf("invalid snapshot, reference to unknown node %d", id)
+	}
+
+	return d.nodes[id-1], nil
+}
+
+func invalidSnapshotNode(node interface{}) error {
+	return fmt.Errorf("invalid snapshot, unexpected node of type %T", node)
 }
 
+// vectorSnapshotChildCount returns the number of children of a vector node at level
+// holding count elements.
+func vectorSnapshotChildCount(level uint, count uint64) uint64 {
+	if count == 0 {
+		return 0
+	}
+
+	return (count-1)>>level + 1
+}
+
+func checkVectorSnapshotNodeLen(length int, expected uint64) error {
+	if uint64(length) != expected {
+		return fmt.Errorf("invalid snapshot, vector node of length %d where %d was expected", length, expected)
+	}
+
+	return nil
+}
 `
-const SetTemplate string = `
-// {{.SetTypeName}} is a persistent set
+const SortedSetTemplate string = `
+// {{.SetTypeName}} is a persistent set ordered by element
 type {{.SetTypeName}} struct {
 	backingMap *{{.MapTypeName}}
 }
@@ -540,190 +3746,239 @@ func (s *{{.SetTypeName}}) Contains(item {{.MapKeyTypeName}}) bool {
 	return ok
 }
 
-// Range calls f repeatedly passing it each element in s as argument until either
-// all elements have been visited or f returns false.
-func (s *{{.SetTypeName}}) Range(f func({{.MapKeyTypeName}}) bool) {
-	s.backingMap.Range(func(k {{.MapKeyTypeName}}, _ {{.MapValueTypeName}}) bool {
-		return f(k)
-	})
+// Len returns the number of elements in s.
+func (s *{{.SetTypeName}}) Len() int {
+	return s.backingMap.Len()
 }
 
-// IsSubset returns true if all elements in s are present in other, false otherwise.
-func (s *{{.SetTypeName}}) IsSubset(other *{{.SetTypeName}}) bool {
-	if other.Len() < s.Len() {
-		return false
-	}
-
-	isSubset := true
-	s.Range(func(item {{.MapKeyTypeName}}) bool {
-		if !other.Contains(item) {
-			isSubset = false
-		}
+// Get returns the element at position i in sort order.
+func (s *{{.SetTypeName}}) Get(i int) {{.MapKeyTypeName}} {
+	item, _ := s.backingMap.Get(i)
+	return item
+}
 
-		return isSubset
-	})
+// Min returns the smallest element in s. ok is set to false if s is empty.
+func (s *{{.SetTypeName}}) Min() (item {{.MapKeyTypeName}}, ok bool) {
+	item, _, ok = s.backingMap.Min()
+	return item, ok
+}
 
-	return isSubset
+// Max returns the largest element in s. ok is set to false if s is empty.
+func (s *{{.SetTypeName}}) Max() (item {{.MapKeyTypeName}}, ok bool) {
+	item, _, ok = s.backingMap.Max()
+	return item, ok
 }
 
-// IsSuperset returns true if all elements in other are present in s, false otherwise.
-func (s *{{.SetTypeName}}) IsSuperset(other *{{.SetTypeName}}) bool {
-	return other.IsSubset(s)
+// Floor returns the largest element in s that is less than or equal to item.
+// ok is set to false if there is no such element.
+func (s *{{.SetTypeName}}) Floor(item {{.MapKeyTypeName}}) (floorItem {{.MapKeyTypeName}}, ok bool) {
+	floorItem, _, ok = s.backingMap.Floor(item)
+	return floorItem, ok
 }
 
-// Union returns a new {{.SetTypeName}} containing all elements present
-// in either s or other.
-func (s *{{.SetTypeName}}) Union(other *{{.SetTypeName}}) *{{.SetTypeName}} {
-	result := s
+// Ceiling returns the smallest element in s that is greater than or equal to item.
+// ok is set to false if there is no such element.
+func (s *{{.SetTypeName}}) Ceiling(item {{.MapKeyTypeName}}) (ceilingItem {{.MapKeyTypeName}}, ok bool) {
+	ceilingItem, _, ok = s.backingMap.Ceiling(item)
+	return ceilingItem, ok
+}
 
-	// Simplest possible solution right now. Would probable be more efficient
-	// to concatenate two slices of elements from the two sets and create a
-	// new set from that slice for many cases.
-	other.Range(func(item {{.MapKeyTypeName}}) bool {
-		result = result.Add(item)
-		return true
+// Range calls f repeatedly passing it each element in s as argument, in order,
+// until either all elements have been visited or f returns false.
+func (s *{{.SetTypeName}}) Range(f func({{.MapKeyTypeName}}) bool) {
+	s.backingMap.Range(func(k {{.MapKeyTypeName}}, _ {{.MapValueTypeName}}) bool {
+		return f(k)
 	})
+}
 
-	return result
+// RangeFrom works like Range but starts at the first element that is greater than
+// or equal to item.
+func (s *{{.SetTypeName}}) RangeFrom(item {{.MapKeyTypeName}}, f func({{.MapKeyTypeName}}) bool) {
+	s.backingMap.RangeFrom(item, func(k {{.MapKeyTypeName}}, _ {{.MapValueTypeName}}) bool {
+		return f(k)
+	})
 }
 
-// Equals returns true if s and other contains the same elements, false otherwise.
-func (s *{{.SetTypeName}}) Equals(other *{{.SetTypeName}}) bool {
-	return s.Len() == other.Len() && s.IsSubset(other)
+// RangeBetween works like Range but only visits the elements that are greater than
+// or equal to lo and less than hi.
+func (s *{{.SetTypeName}}) RangeBetween(lo, hi {{.MapKeyTypeName}}, f func({{.MapKeyTypeName}}) bool) {
+	s.backingMap.RangeBetween(lo, hi, func(k {{.MapKeyTypeName}}, _ {{.MapValueTypeName}}) bool {
+		return f(k)
+	})
 }
 
-func (s *{{.SetTypeName}}) difference(other *{{.SetTypeName}}) []{{.MapKeyTypeName}} {
-	items := make([]{{.MapKeyTypeName}}, 0)
+// ToNativeSlice returns a native Go slice containing all elements of s in order.
+func (s *{{.SetTypeName}}) ToNativeSlice() []{{.MapKeyTypeName}} {
+	items := make([]{{.MapKeyTypeName}}, 0, s.Len())
 	s.Range(func(item {{.MapKeyTypeName}}) bool {
-		if !other.Contains(item) {
-			items = append(items, item)
-		}
-
+		items = append(items, item)
 		return true
 	})
 
 	return items
 }
 
-// Difference returns a new {{.SetTypeName}} containing all elements present
-// in s but not in other.
-func (s *{{.SetTypeName}}) Difference(other *{{.SetTypeName}}) *{{.SetTypeName}} {
-	return New{{.SetTypeName}}(s.difference(other)...)
-}
-
-// SymmetricDifference returns a new {{.SetTypeName}} containing all elements present
-// in either s or other but not both.
-func (s *{{.SetTypeName}}) SymmetricDifference(other *{{.SetTypeName}}) *{{.SetTypeName}} {
-	items := s.difference(other)
-	items = append(items, other.difference(s)...)
-	return New{{.SetTypeName}}(items...)
+// MarshalJSON encodes s as a JSON array in element order.
+func (s *{{.SetTypeName}}) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.ToNativeSlice())
 }
 
-// Intersection returns a new {{.SetTypeName}} containing all elements present in both
-// s and other.
-func (s *{{.SetTypeName}}) Intersection(other *{{.SetTypeName}}) *{{.SetTypeName}} {
+// UnmarshalJSON sets s to the elements of data, a JSON array. s must not be used by
+// anyone else when this is called, it is meant to be used by encoding/json only.
+func (s *{{.SetTypeName}}) UnmarshalJSON(data []byte) error {
 	items := make([]{{.MapKeyTypeName}}, 0)
-	s.Range(func(item {{.MapKeyTypeName}}) bool {
-		if other.Contains(item) {
-			items = append(items, item)
+	err := jsonDecodeArray(data, func(dec *json.Decoder) error {
+		var item {{.MapKeyTypeName}}
+		if err := dec.Decode(&item); err != nil {
+			return err
 		}
 
-		return true
+		items = append(items, item)
+		return nil
 	})
 
-	return New{{.SetTypeName}}(items...)
-}
+	if err != nil {
+		return err
+	}
 
-// Len returns the number of elements in s.
-func (s *{{.SetTypeName}}) Len() int {
-	return s.backingMap.Len()
+	*s = *New{{.SetTypeName}}(items...)
+	return nil
 }
-
-// ToNativeSlice returns a native Go slice containing all elements of s.
-func (s *{{.SetTypeName}}) ToNativeSlice() []{{.MapKeyTypeName}} {
-	items := make([]{{.MapKeyTypeName}}, 0, s.Len())
-	s.Range(func(item {{.MapKeyTypeName}}) bool {
-		items = append(items, item)
+`
+const VectorHashTemplate string = `
+// Equals returns true if v and other contain equal elements in the same order, false
+// otherwise. Parts of the trie shared between v and other are not compared.
+func (v *{{.VectorTypeName}}) Equals(other *{{.VectorTypeName}}) bool {
+	if v == other {
 		return true
-	})
+	}
 
-	return items
-}
+	if v.len != other.len {
+		return false
+	}
 
-`
-const SliceTemplate string = `
-////////////////
-//// Slice /////
-////////////////
+	for i := uint(0); i < v.len; i += nodeSize {
+		leaf, otherLeaf := v.sliceFor(i), other.sliceFor(i)
+		if &leaf[0] == &otherLeaf[0] {
+			continue
+		}
 
-// {{.VectorTypeName}}Slice is a slice type backed by a {{.VectorTypeName}}.
-type {{.VectorTypeName}}Slice struct {
-	vector      *{{.VectorTypeName}}
-	start, stop int
-}
+		for j := uint(0); j < uintMin(nodeSize, v.len-i); j++ {
+			if !{{.ElementEqualFunc}}(&leaf[j], &otherLeaf[j]) {
+				return false
+			}
+		}
+	}
 
-// New{{.VectorTypeName}}Slice returns a new New{{.VectorTypeName}}Slice containing the items provided in items.
-func New{{.VectorTypeName}}Slice(items ...{{.TypeName}}) *{{.VectorTypeName}}Slice {
-	return &{{.VectorTypeName}}Slice{vector: empty{{.VectorTypeName}}.Append(items...), start: 0, stop: len(items)}
+	return true
 }
 
-// Len returns the length of s.
-func (s *{{.VectorTypeName}}Slice) Len() int {
-	return s.stop - s.start
+// Hash returns a hash of the elements in v. Vectors that are equal according to Equals
+// have the same hash. The hash is computed the first time it is needed and is then kept
+// for v and all copies of it, including copies stored in other containers.
+func (v *{{.VectorTypeName}}) Hash() uint32 {
+	if h := loadHash(v.hash); h != 0 {
+		return h
+	}
+
+	h := uint32(1)
+	for i := uint(0); i < v.len; i += nodeSize {
+		leaf := v.sliceFor(i)
+		for j := uint(0); j < uintMin(nodeSize, v.len-i); j++ {
+			h = 31*h + {{.ElementHashFunc}}(&leaf[j])
+		}
+	}
+
+	return cacheHash(v.hash, h)
 }
 
-// Get returns the element at position i.
-func (s *{{.VectorTypeName}}Slice) Get(i int) {{.TypeName}} {
-	if i < 0 || s.start+i >= s.stop {
-		panic("Index out of bounds")
+// Equals returns true if s and other contain equal elements in the same order, false otherwise.
+func (s *{{.VectorTypeName}}Slice) Equals(other *{{.VectorTypeName}}Slice) bool {
+	if s.Len() != other.Len() {
+		return false
+	}
+
+	for i := 0; i < s.Len(); i++ {
+		a, b := s.Get(i), other.Get(i)
+		if !{{.ElementEqualFunc}}(&a, &b) {
+			return false
+		}
 	}
 
-	return s.vector.Get(s.start + i)
+	return true
 }
 
-// Set returns a new slice with the element at position i set to item.
-func (s *{{.VectorTypeName}}Slice) Set(i int, item {{.TypeName}}) *{{.VectorTypeName}}Slice {
-	if i < 0 || s.start+i >= s.stop {
-		panic("Index out of bounds")
+// Hash returns a hash of the elements in s. Slices that are equal according to Equals
+// have the same hash, which is also the hash of a {{.VectorTypeName}} with the same elements.
+// The hash is computed the first time it is needed and is then kept for s and all copies of it.
+func (s *{{.VectorTypeName}}Slice) Hash() uint32 {
+	if h := loadHash(s.hash); h != 0 {
+		return h
 	}
 
-	return s.vector.Set(s.start+i, item).Slice(s.start, s.stop)
-}
+	h := uint32(1)
+	s.Range(func(item {{.TypeName}}) bool {
+		h = 31*h + {{.ElementHashFunc}}(&item)
+		return true
+	})
 
-// Append returns a new slice with item(s) appended to it.
-func (s *{{.VectorTypeName}}Slice) Append(items ...{{.TypeName}}) *{{.VectorTypeName}}Slice {
-	newSlice := {{.VectorTypeName}}Slice{vector: s.vector, start: s.start, stop: s.stop + len(items)}
+	return cacheHash(s.hash, h)
+}
 
-	// If this is v slice that has an upper bound that is lower than the backing
-	// vector then set the values in the backing vector to achieve some structural
-	// sharing.
-	itemPos := 0
-	for ; s.stop+itemPos < s.vector.Len() && itemPos < len(items); itemPos++ {
-		newSlice.vector = newSlice.vector.Set(s.stop+itemPos, items[itemPos])
+`
+const VectorIterTemplate string = `
+// All returns an iterator over the indexes and elements of v, in order.
+func (v *{{.VectorTypeName}}) All() iter.Seq2[int, {{.TypeName}}] {
+	return func(yield func(int, {{.TypeName}}) bool) {
+		i := 0
+		v.Range(func(item {{.TypeName}}) bool {
+			ok := yield(i, item)
+			i++
+			return ok
+		})
 	}
+}
 
-	// For the rest just append it to the underlying vector
-	newSlice.vector = newSlice.vector.Append(items[itemPos:]...)
-	return &newSlice
+// Backward returns an iterator over the indexes and elements of v, in reverse order.
+func (v *{{.VectorTypeName}}) Backward() iter.Seq2[int, {{.TypeName}}] {
+	return func(yield func(int, {{.TypeName}}) bool) {
+		var currentNode []{{.TypeName}}
+		for i := int(v.len) - 1; i >= 0; i-- {
+			if i&shiftBitMask == shiftBitMask || i == int(v.len)-1 {
+				currentNode = v.sliceFor(uint(i))
+			}
+
+			if !yield(i, currentNode[i&shiftBitMask]) {
+				return
+			}
+		}
+	}
 }
 
-// Slice returns a {{.VectorTypeName}}Slice that refers to all elements [start,stop) in s.
-func (s *{{.VectorTypeName}}Slice) Slice(start, stop int) *{{.VectorTypeName}}Slice {
-	assertSliceOk(start, stop, s.stop-s.start)
-	return &{{.VectorTypeName}}Slice{vector: s.vector, start: s.start + start, stop: s.start + stop}
+// All returns an iterator over the indexes and elements of s, in order.
+func (s *{{.VectorTypeName}}Slice) All() iter.Seq2[int, {{.TypeName}}] {
+	return func(yield func(int, {{.TypeName}}) bool) {
+		i := 0
+		s.Range(func(item {{.TypeName}}) bool {
+			ok := yield(i, item)
+			i++
+			return ok
+		})
+	}
 }
 
-// Range calls f repeatedly passing it each element in s in order as argument until either
-// all elements have been visited or f returns false.
-func (s *{{.VectorTypeName}}Slice) Range(f func({{.TypeName}}) bool) {
-	var currentNode []{{.TypeName}}
-	for i := uint(s.start); i < uint(s.stop); i++ {
-		if i&shiftBitMask == 0 || i == uint(s.start) {
-			currentNode = s.vector.sliceFor(uint(i))
-		}
+// Backward returns an iterator over the indexes and elements of s, in reverse order.
+func (s *{{.VectorTypeName}}Slice) Backward() iter.Seq2[int, {{.TypeName}}] {
+	return func(yield func(int, {{.TypeName}}) bool) {
+		var currentNode []{{.TypeName}}
+		for i := s.stop - 1; i >= s.start; i-- {
+			if i&shiftBitMask == shiftBitMask || i == s.stop-1 {
+				currentNode = s.vector.sliceFor(uint(i))
+			}
 
-		if !f(currentNode[i&shiftBitMask]) {
-			return
+			if !yield(i-s.start, currentNode[i&shiftBitMask]) {
+				return
+			}
 		}
 	}
 }
@@ -741,10 +3996,11 @@ type {{.VectorTypeName}} struct {
 	root  commonNode
 	len   uint
 	shift uint
+	hash  *uint32
 }
 
 var empty{{.VectorTypeName}}Tail = make([]{{.TypeName}}, 0)
-var empty{{.VectorTypeName}} *{{.VectorTypeName}} = &{{.VectorTypeName}}{root: emptyCommonNode, shift: shiftSize, tail: empty{{.VectorTypeName}}Tail}
+var empty{{.VectorTypeName}} *{{.VectorTypeName}} = &{{.VectorTypeName}}{root: emptyCommonNode, shift: shiftSize, tail: empty{{.VectorTypeName}}Tail, hash: new(uint32)}
 
 // New{{.VectorTypeName}} returns a new {{.VectorTypeName}} containing the items provided in items.
 func New{{.VectorTypeName}}(items ...{{.TypeName}}) *{{.VectorTypeName}} {
@@ -791,22 +4047,25 @@ func (v *{{.VectorTypeName}}) Set(i int, item {{.TypeName}}) *{{.VectorTypeName}
 		newTail := make([]{{.TypeName}}, len(v.tail))
 		copy(newTail, v.tail)
 		newTail[i&shiftBitMask] = item
-		return &{{.VectorTypeName}}{root: v.root, tail: newTail, len: v.len, shift: v.shift}
+		return &{{.VectorTypeName}}{root: v.root, tail: newTail, len: v.len, shift: v.shift, hash: new(uint32)}
 	}
 
-	return &{{.VectorTypeName}}{root: v.doAssoc(v.shift, v.root, uint(i), item), tail: v.tail, len: v.len, shift: v.shift}
+	return &{{.VectorTypeName}}{root: v.doAssoc(v.shift, v.root, uint(i), item), tail: v.tail, len: v.len, shift: v.shift, hash: new(uint32)}
 }
 
 func (v *{{.VectorTypeName}}) doAssoc(level uint, node commonNode, i uint, item {{.TypeName}}) commonNode {
 	if level == 0 {
-		ret := make([]{{.TypeName}}, nodeSize)
-		copy(ret, node.([]{{.TypeName}}))
+		leaf := node.([]{{.TypeName}})
+		ret := make([]{{.TypeName}}, len(leaf))
+		copy(ret, leaf)
 		ret[i&shiftBitMask] = item
 		return ret
 	}
 
-	ret := make([]commonNode, nodeSize)
-	copy(ret, node.([]commonNode))
+	// Copies keep the length of the original, children beyond it are added by pushTail
+	children := node.([]commonNode)
+	ret := make([]commonNode, len(children))
+	copy(ret, children)
 	subidx := (i >> level) & shiftBitMask
 	ret[subidx] = v.doAssoc(level-shiftSize, ret[subidx], i, item)
 	return ret
@@ -849,7 +4108,7 @@ func (v *{{.VectorTypeName}}) Append(item ...{{.TypeName}}) *{{.VectorTypeName}}
 		newTail := make([]{{.TypeName}}, 0, tailLen+batchLen)
 		newTail = append(newTail, result.tail...)
 		newTail = append(newTail, item[insertOffset:insertOffset+batchLen]...)
-		result = &{{.VectorTypeName}}{root: result.root, tail: newTail, len: result.len + batchLen, shift: result.shift}
+		result = &{{.VectorTypeName}}{root: result.root, tail: newTail, len: result.len + batchLen, shift: result.shift, hash: new(uint32)}
 		insertOffset += batchLen
 	}
 
@@ -869,13 +4128,92 @@ func (v *{{.VectorTypeName}}) pushLeafNode(node []{{.TypeName}}) *{{.VectorTypeN
 		newRoot = v.pushTail(v.shift, v.root, node)
 	}
 
-	return &{{.VectorTypeName}}{root: newRoot, tail: v.tail, len: v.len, shift: newShift}
+	return &{{.VectorTypeName}}{root: newRoot, tail: v.tail, len: v.len, shift: newShift, hash: new(uint32)}
+}
+
+// Pop returns a new vector with the last element removed, and the removed element.
+func (v *{{.VectorTypeName}}) Pop() (*{{.VectorTypeName}}, {{.TypeName}}) {
+	if v.len == 0 {
+		panic("Pop on empty vector")
+	}
+
+	item := v.tail[len(v.tail)-1]
+	return v.DropLast(1), item
+}
+
+// DropLast returns a new vector with the last n elements removed. Nodes no longer
+// needed are released rather than kept alive as they would be by Slice.
+func (v *{{.VectorTypeName}}) DropLast(n int) *{{.VectorTypeName}} {
+	if n < 0 || uint(n) > v.len {
+		panic("Index out of bounds")
+	}
+
+	if n == 0 {
+		return v
+	}
+
+	newLen := v.len - uint(n)
+	if newLen == 0 {
+		return empty{{.VectorTypeName}}
+	}
+
+	result := &{{.VectorTypeName}}{len: newLen, hash: new(uint32)}
+	newTailOffset := result.tailOffset()
+	newTail := make([]{{.TypeName}}, newLen-newTailOffset)
+	copy(newTail, v.sliceFor(newLen-1))
+	result.tail = newTail
+	if newTailOffset == 0 {
+		result.root, result.shift = emptyCommonNode, shiftSize
+		return result
+	}
+
+	result.root, result.shift = v.popTail(v.shift, v.root, newTailOffset-1), v.shift
+
+	// Root underflow?
+	for result.shift > shiftSize && len(result.root.([]commonNode)) == 1 {
+		result.root = result.root.([]commonNode)[0]
+		result.shift -= shiftSize
+	}
+
+	return result
+}
+
+// popTail returns a copy of the path from node down to the leaf holding element
+// last with all nodes after that path removed.
+func (v *{{.VectorTypeName}}) popTail(level uint, node commonNode, last uint) commonNode {
+	subIdx := (last >> level) & shiftBitMask
+	ret := make([]commonNode, subIdx+1)
+	copy(ret, node.([]commonNode))
+	if level > shiftSize {
+		ret[subIdx] = v.popTail(level-shiftSize, ret[subIdx], last)
+	}
+
+	return ret
+}
+
+// DropFirst returns a new vector with the first n elements removed. Unlike Slice the
+// result does not share any structure with v, it takes time proportional to its length.
+func (v *{{.VectorTypeName}}) DropFirst(n int) *{{.VectorTypeName}} {
+	if n < 0 || uint(n) > v.len {
+		panic("Index out of bounds")
+	}
+
+	if n == 0 {
+		return v
+	}
+
+	t := empty{{.VectorTypeName}}.AsTransient()
+	for i := uint(n); i < v.len; i = (i | shiftBitMask) + 1 {
+		t.Append(v.sliceFor(i)[i&shiftBitMask:]...)
+	}
+
+	return t.Persistent()
 }
 
 // Slice returns a {{.VectorTypeName}}Slice that refers to all elements [start,stop) in v.
 func (v *{{.VectorTypeName}}) Slice(start, stop int) *{{.VectorTypeName}}Slice {
 	assertSliceOk(start, stop, v.Len())
-	return &{{.VectorTypeName}}Slice{vector: v, start: start, stop: stop}
+	return &{{.VectorTypeName}}Slice{vector: v, start: start, stop: stop, hash: new(uint32)}
 }
 
 // Len returns the length of v.
@@ -908,8 +4246,442 @@ func (v *{{.VectorTypeName}}) ToNativeSlice() []{{.TypeName}} {
 	return result
 }
 
+// MarshalJSON encodes v as a JSON array.
+func (v *{{.VectorTypeName}}) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.ToNativeSlice())
+}
+
+// UnmarshalJSON sets v to the elements of data, a JSON array. v must not be used by
+// anyone else when this is called, it is meant to be used by encoding/json only.
+func (v *{{.VectorTypeName}}) UnmarshalJSON(data []byte) error {
+	t := empty{{.VectorTypeName}}.AsTransient()
+	err := jsonDecodeArray(data, func(dec *json.Decoder) error {
+		var item {{.TypeName}}
+		if err := dec.Decode(&item); err != nil {
+			return err
+		}
+
+		t.Append(item)
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	*v = *t.Persistent()
+	return nil
+}
+
+// MarshalBinary returns the binary encoding of v. The items are gob encoded.
+func (v *{{.VectorTypeName}}) MarshalBinary() ([]byte, error) {
+	return binaryMarshal(v.Len(), func(encode func(interface{}) error) error {
+		for i := 0; i < v.Len(); i += nodeSize {
+			if err := encode(v.sliceFor(uint(i))); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// UnmarshalBinary sets v to the vector encoded in data by MarshalBinary. v must not
+// be used by anyone else when this is called, decode into a new zero {{.VectorTypeName}}.
+func (v *{{.VectorTypeName}}) UnmarshalBinary(data []byte) error {
+	t := empty{{.VectorTypeName}}.AsTransient()
+	err := binaryUnmarshal(data, func(decode func(interface{}) error) (int, error) {
+		var chunk []{{.TypeName}}
+		err := decode(&chunk)
+		t.Append(chunk...)
+		return len(chunk), err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	*v = *t.Persistent()
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder using the same encoding as MarshalBinary.
+func (v *{{.VectorTypeName}}) GobEncode() ([]byte, error) {
+	return v.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder using the same encoding as UnmarshalBinary.
+func (v *{{.VectorTypeName}}) GobDecode(data []byte) error {
+	return v.UnmarshalBinary(data)
+}
+
+// EncodeSnapshot writes v to the snapshot stream of e. Nodes shared with containers
+// previously written to e are written as references to those.
+func (v *{{.VectorTypeName}}) EncodeSnapshot(e *SnapshotEncoder) error {
+	return e.encode(snapshotVectorTag, func() error {
+		e.writeUvarint(uint64(v.len))
+		e.writeUvarint(uint64(v.shift))
+		if err := v.encodeSnapshotNode(e, v.shift, v.root); err != nil {
+			return err
+		}
+
+		return v.encodeSnapshotNode(e, 0, v.tail)
+	})
+}
+
+func (v *{{.VectorTypeName}}) encodeSnapshotNode(e *SnapshotEncoder, level uint, node commonNode) error {
+	if level == 0 {
+		leaf := node.([]{{.TypeName}})
+		var key interface{}
+		if len(leaf) > 0 {
+			key = snapshotNodeKey{first: &leaf[0], len: len(leaf)}
+		}
+
+		return e.writeNode(key, func() error {
+			return e.writeElements(leaf)
+		})
+	}
+
+	children := node.([]commonNode)
+	var key interface{}
+	if len(children) > 0 {
+		key = snapshotNodeKey{first: &children[0], len: len(children)}
+	}
+
+	return e.writeNode(key, func() error {
+		e.writeUvarint(uint64(len(children)))
+		for _, child := range children {
+			if err := v.encodeSnapshotNode(e, level-shiftSize, child); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// DecodeSnapshot sets v to the next container in the snapshot stream of d, which must
+// be a vector. io.EOF is returned if there are no more containers in the stream. v must
+// not be used by anyone else when this is called, decode into a new zero {{.VectorTypeName}}.
+func (v *{{.VectorTypeName}}) DecodeSnapshot(d *SnapshotDecoder) error {
+	return d.decode(snapshotVectorTag, func() error {
+		length, err := d.readUvarint()
+		if err != nil {
+			return err
+		}
+
+		shift, err := d.readUvarint()
+		if err != nil {
+			return err
+		}
+
+		if shift == 0 || shift%shiftSize != 0 || shift > 64 {
+			return fmt.Errorf("invalid snapshot, vector shift %d", shift)
+		}
+
+		// The root holds all but the elements in the tail, it has at least two children
+		// unless it is at the lowest level
+		result := {{.VectorTypeName}}{len: uint(length), shift: uint(shift), hash: new(uint32)}
+		rootLen := uint64(result.tailOffset())
+		if (shift > shiftSize && rootLen <= 1<<shift) || vectorSnapshotChildCount(uint(shift), rootLen) > nodeSize {
+			return fmt.Errorf("invalid snapshot, vector shift %d in vector of length %d", shift, length)
+		}
+
+		root, err := decode{{.VectorTypeName}}SnapshotNode(d, uint(shift), rootLen)
+		if err != nil {
+			return err
+		}
+
+		tail, err := decode{{.VectorTypeName}}SnapshotNode(d, 0, length-rootLen)
+		if err != nil {
+			return err
+		}
+
+		result.root, result.tail = root, tail.([]{{.TypeName}})
+		*v = result
+		return nil
+	})
+}
+
+// decode{{.VectorTypeName}}SnapshotNode reads a node at level holding count elements. All
+// nodes but the rightmost ones on each level must be full.
+func decode{{.VectorTypeName}}SnapshotNode(d *SnapshotDecoder, level uint, count uint64) (commonNode, error) {
+	shared := true
+	node, err := d.readNode(func() (interface{}, error) {
+		shared = false
+		if level == 0 {
+			var leaf []{{.TypeName}}
+			if err := d.readElements(&leaf); err != nil {
+				return nil, err
+			}
+
+			return leaf, checkVectorSnapshotNodeLen(len(leaf), count)
+		}
+
+		n, err := d.readLength(nodeSize)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := checkVectorSnapshotNodeLen(n, vectorSnapshotChildCount(level, count)); err != nil {
+			return nil, err
+		}
+
+		children := make([]commonNode, n)
+		for i := range children {
+			childCount := uint64(1) << level
+			if i == n-1 {
+				childCount = count - uint64(i)<<level
+			}
+
+			if children[i], err = decode{{.VectorTypeName}}SnapshotNode(d, level-shiftSize, childCount); err != nil {
+				return nil, err
+			}
+		}
+
+		return children, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !shared {
+		return node, nil
+	}
+
+	// The nodes left of the rightmost path of a shared node are full, that was checked
+	// when it was first read. Only the rightmost path has to be checked against count.
+	for n := node; ; level -= shiftSize {
+		switch typed := n.(type) {
+		case []{{.TypeName}}:
+			if level == 0 {
+				if err := checkVectorSnapshotNodeLen(len(typed), count); err != nil {
+					return nil, err
+				}
+
+				return node, nil
+			}
+		case []commonNode:
+			if level > 0 {
+				if err := checkVectorSnapshotNodeLen(len(typed), vectorSnapshotChildCount(level, count)); err != nil {
+					return nil, err
+				}
+
+				if len(typed) == 0 {
+					return node, nil
+				}
+
+				count -= uint64(len(typed)-1) << level
+				n = typed[len(typed)-1]
+				continue
+			}
+		}
+
+		return nil, invalidSnapshotNode(n)
+	}
+}
+
+/////////////////
+/// Transient ///
+/////////////////
+
+// {{.VectorTypeName}}Transient is a mutable builder for {{.VectorTypeName}}. Nodes created by
+// the transient are owned by it and edited in place rather than copied. Call Persistent
+// to turn it into a {{.VectorTypeName}}, the transient cannot be used after that.
+type {{.VectorTypeName}}Transient struct {
+	tail  []{{.TypeName}}
+	root  commonNode
+	len   uint
+	shift uint
+	owner *transientOwner
+}
+
+// AsTransient returns a transient containing all elements of v. v is left untouched.
+func (v *{{.VectorTypeName}}) AsTransient() *{{.VectorTypeName}}Transient {
+	tail := make([]{{.TypeName}}, len(v.tail), nodeSize)
+	copy(tail, v.tail)
+	return &{{.VectorTypeName}}Transient{
+		tail:  tail,
+		root:  v.root,
+		len:   v.len,
+		shift: v.shift,
+		owner: &transientOwner{}}
+}
+
+// Persistent returns a {{.VectorTypeName}} containing all elements of t. t cannot be used
+// after this call.
+func (t *{{.VectorTypeName}}Transient) Persistent() *{{.VectorTypeName}} {
+	assertTransientEditable(t.owner != nil)
+	t.owner = nil
+	return &{{.VectorTypeName}}{tail: t.tail, root: t.root, len: t.len, shift: t.shift, hash: new(uint32)}
+}
+
+// Len returns the length of t.
+func (t *{{.VectorTypeName}}Transient) Len() int {
+	assertTransientEditable(t.owner != nil)
+	return int(t.len)
+}
+
+func (t *{{.VectorTypeName}}Transient) tailOffset() uint {
+	if t.len < nodeSize {
+		return 0
+	}
+
+	return ((t.len - 1) >> shiftSize) << shiftSize
+}
+
+// Get returns the element at position i.
+func (t *{{.VectorTypeName}}Transient) Get(i int) {{.TypeName}} {
+	assertTransientEditable(t.owner != nil)
+	if i < 0 || uint(i) >= t.len {
+		panic("Index out of bounds")
+	}
+
+	if uint(i) >= t.tailOffset() {
+		return t.tail[i&shiftBitMask]
+	}
+
+	node := t.root
+	for level := t.shift; level > 0; level -= shiftSize {
+		node = node.([]commonNode)[(uint(i)>>level)&shiftBitMask]
+	}
+
+	return node.([]{{.TypeName}})[i&shiftBitMask]
+}
+
+// nodeOwner returns the owner of node if node is owned by t, otherwise nil.
+func (t *{{.VectorTypeName}}Transient) nodeOwner(node []commonNode) *vectorNodeOwner {
+	if cap(node) > nodeSize {
+		if owner, ok := node[:nodeSize+1][nodeSize].(*vectorNodeOwner); ok && owner.transient == t.owner {
+			return owner
+		}
+	}
+
+	return nil
+}
+
+// editableNode returns node if it is owned by t, otherwise an owned copy of it.
+func (t *{{.VectorTypeName}}Transient) editableNode(node []commonNode) []commonNode {
+	if t.nodeOwner(node) != nil {
+		return node
+	}
+
+	newNode := make([]commonNode, len(node), nodeSize+1)
+	copy(newNode, node)
+	newNode[:nodeSize+1][nodeSize] = &vectorNodeOwner{transient: t.owner}
+	return newNode
+}
+
+// editableLeaf returns the leaf at position ix of parent, a node owned by t, after
+// replacing it with an owned copy unless it is owned by t already.
+func (t *{{.VectorTypeName}}Transient) editableLeaf(parent []commonNode, ix uint) []{{.TypeName}} {
+	owner := t.nodeOwner(parent)
+	leaf := parent[ix].([]{{.TypeName}})
+	if owner.leaves&(1<<ix) != 0 {
+		return leaf
+	}
+
+	newLeaf := make([]{{.TypeName}}, len(leaf))
+	copy(newLeaf, leaf)
+	parent[ix] = newLeaf
+	owner.leaves |= 1 << ix
+	return newLeaf
+}
+
+// Set sets the element at position i to item.
+func (t *{{.VectorTypeName}}Transient) Set(i int, item {{.TypeName}}) {
+	assertTransientEditable(t.owner != nil)
+	if i < 0 || uint(i) >= t.len {
+		panic("Index out of bounds")
+	}
+
+	if uint(i) >= t.tailOffset() {
+		t.tail[i&shiftBitMask] = item
+		return
+	}
+
+	t.root = t.doAssoc(t.shift, t.root, uint(i), item)
+}
+
+func (t *{{.VectorTypeName}}Transient) doAssoc(level uint, node commonNode, i uint, item {{.TypeName}}) commonNode {
+	ret := t.editableNode(node.([]commonNode))
+	subidx := (i >> level) & shiftBitMask
+	if level == shiftSize {
+		t.editableLeaf(ret, subidx)[i&shiftBitMask] = item
+		return ret
+	}
+
+	ret[subidx] = t.doAssoc(level-shiftSize, ret[subidx], i, item)
+	return ret
+}
+
+// Append adds item(s) to the end of t.
+func (t *{{.VectorTypeName}}Transient) Append(items ...{{.TypeName}}) {
+	assertTransientEditable(t.owner != nil)
+	for _, item := range items {
+		if uint(len(t.tail)) == nodeSize {
+			t.pushLeafNode(t.tail)
+			t.tail = make([]{{.TypeName}}, 0, nodeSize)
+		}
+
+		t.tail = append(t.tail, item)
+		t.len++
+	}
+}
+
+func (t *{{.VectorTypeName}}Transient) newPath(shift uint, node commonNode) commonNode {
+	if shift == 0 {
+		return node
+	}
+
+	newNode := append(t.editableNode(nil), node)
+	if _, ok := node.([]{{.TypeName}}); ok {
+		t.nodeOwner(newNode).leaves = 1
+	}
+
+	return t.newPath(shift-shiftSize, commonNode(newNode))
+}
+
+func (t *{{.VectorTypeName}}Transient) pushTail(level uint, parent commonNode, tailNode []{{.TypeName}}) commonNode {
+	subIdx := ((t.len - 1) >> level) & shiftBitMask
+	ret := t.editableNode(parent.([]commonNode))
+	var nodeToInsert commonNode
+
+	if level == shiftSize {
+		nodeToInsert = tailNode
+		t.nodeOwner(ret).leaves |= 1 << subIdx
+	} else if subIdx < uint(len(ret)) {
+		nodeToInsert = t.pushTail(level-shiftSize, ret[subIdx], tailNode)
+	} else {
+		nodeToInsert = t.newPath(level-shiftSize, tailNode)
+	}
+
+	if subIdx < uint(len(ret)) {
+		ret[subIdx] = nodeToInsert
+		return ret
+	}
+
+	return append(ret, nodeToInsert)
+}
+
+func (t *{{.VectorTypeName}}Transient) pushLeafNode(node []{{.TypeName}}) {
+	// Root overflow?
+	if (t.len >> shiftSize) > (1 << t.shift) {
+		newRoot := t.editableNode(nil)
+		t.root = commonNode(append(newRoot, t.root, t.newPath(t.shift, node)))
+		t.shift += shiftSize
+	} else {
+		t.root = t.pushTail(t.shift, t.root, node)
+	}
+}
+
 `
 const commentsNotWantedInGeneratedCode string = `
+// Common imports that are only used by templates in other files of this package
+var _ = sort.SliceStable
+var _ = bufio.NewReader
+
 // peds -maps "FooMap<int, string>;BarMap<int16, int32>"
 //      -sets "FooSet<mypackage.MyType>"
 //      -vectors "FooVec<io.Bar>"
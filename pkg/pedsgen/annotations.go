@@ -0,0 +1,211 @@
+package pedsgen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Containers can be declared by annotating type declarations with directives
+// on the form:
+//
+//	//peds:<kind> <Name> [option=value ...]
+//
+// The annotated type is the element type of the container. For the map kinds
+// one of key and value is given as an option, the annotated type is the other.
+//
+//	//peds:vector PersonVec
+//	//peds:map ByID key=string methods=Load,Store
+//	type Person struct { ... }
+//
+// The kind is one of the Kind constants.
+const directivePrefix = "//peds:"
+
+// ScanPackage parses the non test go files in dir, except for outputFile, and returns
+// the package name and the containers declared by peds directives. Imports needed by
+// the types and functions given in the directives are taken from the files in which
+// the directives are found.
+func ScanPackage(dir, outputFile string) (Config, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return Config{}, err
+	}
+
+	fset := token.NewFileSet()
+	files, err := parsePackageFiles(fset, dir, outputFile, parser.ParseComments)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg := Config{}
+	imports := make(map[string]bool)
+	for _, f := range files {
+		if cfg.Package == "" {
+			cfg.Package = f.Name.Name
+		}
+
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+
+			for _, s := range gen.Specs {
+				typeSpec := s.(*ast.TypeSpec)
+				doc := typeSpec.Doc
+				if doc == nil && len(gen.Specs) == 1 {
+					doc = gen.Doc
+				}
+
+				if doc == nil {
+					continue
+				}
+
+				for _, comment := range doc.List {
+					if !strings.HasPrefix(comment.Text, directivePrefix) {
+						continue
+					}
+
+					pos := fset.Position(comment.Pos())
+					if typeSpec.TypeParams != nil {
+						return Config{}, fmt.Errorf("%s: peds directives are not supported on generic types", pos)
+					}
+
+					kind, c, err := parseDirective(comment.Text, typeSpec.Name.Name)
+					if err != nil {
+						return Config{}, errors.Wrapf(err, "%s", pos)
+					}
+
+					if err := c.addTo(&cfg, kind, string(kind)); err != nil {
+						return Config{}, errors.Wrapf(err, "%s: %s", pos, c.Name)
+					}
+
+					for _, q := range c.qualifiers() {
+						importPath, err := resolveQualifier(f, q)
+						if err != nil {
+							return Config{}, errors.Wrapf(err, "%s: %s", pos, c.Name)
+						}
+
+						imports[importPath] = true
+					}
+				}
+			}
+		}
+	}
+
+	importList := make([]string, 0, len(imports))
+	for imp := range imports {
+		importList = append(importList, imp)
+	}
+
+	sort.Strings(importList)
+	cfg.Imports = importList
+	return cfg, nil
+}
+
+// parseDirective parses a directive found on the type typeName.
+func parseDirective(text, typeName string) (Kind, containerConfig, error) {
+	fields := strings.Fields(strings.TrimPrefix(text, directivePrefix))
+	if len(fields) < 2 {
+		return "", containerConfig{}, fmt.Errorf("expected %s<kind> <Name> [option=value ...]", directivePrefix)
+	}
+
+	kind := Kind(fields[0])
+	if _, ok := kinds[kind]; !ok {
+		return "", containerConfig{}, fmt.Errorf("unknown container kind %q", fields[0])
+	}
+
+	c := containerConfig{Name: fields[1]}
+	for _, o := range fields[2:] {
+		parts := strings.SplitN(o, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return "", containerConfig{}, fmt.Errorf("invalid option %q, expected option=value", o)
+		}
+
+		if parts[0] == "methods" {
+			if c.Methods != nil {
+				return "", containerConfig{}, fmt.Errorf("option %q given more than once", parts[0])
+			}
+
+			c.Methods = splitMethods(parts[1])
+			continue
+		}
+
+		var target *string
+		switch parts[0] {
+		case "key":
+			target = &c.Key
+		case "value":
+			target = &c.Value
+		case "hash":
+			target = &c.Hash
+		case "eq":
+			target = &c.Eq
+		case "less":
+			target = &c.Less
+		default:
+			return "", containerConfig{}, fmt.Errorf("unknown option %q", parts[0])
+		}
+
+		if *target != "" {
+			return "", containerConfig{}, fmt.Errorf("option %q given more than once", parts[0])
+		}
+
+		*target = parts[1]
+	}
+
+	if kinds[kind].keyed {
+		if (c.Key == "") == (c.Value == "") {
+			return "", containerConfig{}, errors.New("exactly one of key and value must be given")
+		}
+
+		if c.Key == "" {
+			c.Key = typeName
+		} else {
+			c.Value = typeName
+		}
+	} else {
+		c.Type = typeName
+	}
+
+	return kind, c, nil
+}
+
+// qualifiers returns the package names used in the types and functions of c.
+func (c containerConfig) qualifiers() []string {
+	result := make([]string, 0)
+	for _, n := range []string{c.Type, c.Key, c.Value, c.Hash, c.Eq, c.Less} {
+		if n != "" {
+			result = append(result, typeQualifiers(n)...)
+		}
+	}
+
+	return result
+}
+
+// resolveQualifier returns the import in f that is referred to as q.
+func resolveQualifier(f *ast.File, q string) (string, error) {
+	for _, imp := range f.Imports {
+		importPath, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			return "", err
+		}
+
+		name := packageName(importPath)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+
+		if name == q {
+			return importEntry(name, importPath), nil
+		}
+	}
+
+	return "", fmt.Errorf("package %s is not imported", q)
+}
@@ -0,0 +1,207 @@
+package pedsgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// config is the content of a peds configuration file. It can be written in either
+// YAML or JSON, the file extension decides which.
+//
+//	files:
+//	  - file: collections_gen.go
+//	    package: collections
+//	    imports: [github.com/my/types]
+//	    vectors:
+//	      - {name: IntVector, type: int}
+//	    maps:
+//	      - {name: ByName, key: types.Name, value: types.Person, hash: types.NameHash}
+//	    sets:
+//	      - {name: IntSet, type: int, methods: [minimal, Union]}
+type config struct {
+	Files []fileConfig `json:"files" yaml:"files"`
+}
+
+type fileConfig struct {
+	File        string            `json:"file" yaml:"file"`
+	Package     string            `json:"package" yaml:"package"`
+	Imports     []string          `json:"imports" yaml:"imports"`
+	CommonFile  string            `json:"commonfile" yaml:"commonfile"`
+	GoVersion   string            `json:"goversion" yaml:"goversion"`
+	Vectors     []containerConfig `json:"vectors" yaml:"vectors"`
+	RRBVectors  []containerConfig `json:"rrbvectors" yaml:"rrbvectors"`
+	Maps        []containerConfig `json:"maps" yaml:"maps"`
+	Sets        []containerConfig `json:"sets" yaml:"sets"`
+	SortedMaps  []containerConfig `json:"sortedmaps" yaml:"sortedmaps"`
+	SortedSets  []containerConfig `json:"sortedsets" yaml:"sortedsets"`
+	OrderedMaps []containerConfig `json:"orderedmaps" yaml:"orderedmaps"`
+	Deques      []containerConfig `json:"deques" yaml:"deques"`
+	Heaps       []containerConfig `json:"heaps" yaml:"heaps"`
+}
+
+// containerConfig describes one container. Which fields that are required, and
+// which options that are allowed, depend on the kind of container.
+type containerConfig struct {
+	Name  string `json:"name" yaml:"name"`
+	Type  string `json:"type" yaml:"type"`
+	Key   string `json:"key" yaml:"key"`
+	Value string `json:"value" yaml:"value"`
+	Hash  string `json:"hash" yaml:"hash"`
+	Eq    string `json:"eq" yaml:"eq"`
+	Less  string `json:"less" yaml:"less"`
+
+	Methods []string `json:"methods" yaml:"methods"`
+}
+
+// LoadConfigFile returns the files described by the configuration file at path.
+// Paths in the configuration file are relative to the directory of the file.
+func LoadConfigFile(path string) ([]Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg config
+	switch ext := filepath.Ext(path); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&cfg)
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&cfg)
+	default:
+		return nil, fmt.Errorf("Unsupported config file extension %q, expected .yaml, .yml or .json", ext)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid config file %s", path)
+	}
+
+	if len(cfg.Files) == 0 {
+		return nil, fmt.Errorf("Invalid config file %s: no files given", path)
+	}
+
+	baseDir := filepath.Dir(path)
+	result := make([]Config, 0, len(cfg.Files))
+	for i, f := range cfg.Files {
+		c, err := f.config(baseDir)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid config file %s, files[%d]", path, i)
+		}
+
+		result = append(result, c)
+	}
+
+	return result, nil
+}
+
+func (f fileConfig) config(baseDir string) (Config, error) {
+	if f.File == "" {
+		return Config{}, errors.New("file is required")
+	}
+
+	if !identifierRegex.MatchString(f.Package) {
+		return Config{}, fmt.Errorf("package %q is not a valid package name", f.Package)
+	}
+
+	for i, imp := range f.Imports {
+		if imp == "" || strings.ContainsAny(imp, "; \t\"") {
+			return Config{}, fmt.Errorf("imports[%d]: invalid import path %q", i, imp)
+		}
+	}
+
+	cfg := Config{
+		File:      resolvePath(baseDir, f.File),
+		Package:   f.Package,
+		Imports:   f.Imports,
+		GoVersion: f.GoVersion}
+
+	if f.CommonFile != "" {
+		cfg.CommonFile = resolvePath(baseDir, f.CommonFile)
+	}
+
+	for _, list := range []struct {
+		kind       Kind
+		name       string
+		containers []containerConfig
+	}{
+		{VectorKind, "vectors", f.Vectors},
+		{RRBVectorKind, "rrbvectors", f.RRBVectors},
+		{MapKind, "maps", f.Maps},
+		{SetKind, "sets", f.Sets},
+		{SortedMapKind, "sortedmaps", f.SortedMaps},
+		{SortedSetKind, "sortedsets", f.SortedSets},
+		{OrderedMapKind, "orderedmaps", f.OrderedMaps},
+		{DequeKind, "deques", f.Deques},
+		{HeapKind, "heaps", f.Heaps},
+	} {
+		for i, c := range list.containers {
+			if err := c.addTo(&cfg, list.kind, list.name); err != nil {
+				if c.Name != "" {
+					return Config{}, errors.Wrapf(err, "%s[%d] (%s)", list.name, i, c.Name)
+				}
+
+				return Config{}, errors.Wrapf(err, "%s[%d]", list.name, i)
+			}
+		}
+	}
+
+	return cfg, nil
+}
+
+func resolvePath(baseDir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(baseDir, path)
+}
+
+// addTo validates the container and adds it to cfg as a container of kind. kindName
+// is the name of the kind used in error messages.
+func (c containerConfig) addTo(cfg *Config, kind Kind, kindName string) error {
+	if !identifierRegex.MatchString(c.Name) {
+		return fmt.Errorf("name %q is not a valid type name", c.Name)
+	}
+
+	var types []string
+	if kinds[kind].keyed {
+		if c.Type != "" {
+			return errors.New("type is not supported, use key and value")
+		}
+
+		if c.Key == "" || c.Value == "" {
+			return errors.New("key and value are required")
+		}
+
+		types = []string{c.Key, c.Value}
+	} else {
+		if c.Key != "" || c.Value != "" {
+			return errors.New("key and value are not supported, use type")
+		}
+
+		if c.Type == "" {
+			return errors.New("type is required")
+		}
+
+		types = []string{c.Type}
+	}
+
+	opts := options("hash", c.Hash, "eq", c.Eq, "less", c.Less, "methods", joinMethods(c.Methods))
+	for _, name := range []string{"hash", "eq", "less"} {
+		if _, ok := opts[name]; ok && !containsString(kinds[kind].options, name) {
+			return fmt.Errorf("option %s is not supported for %s", name, kindName)
+		}
+	}
+
+	return cfg.add(kind, Spec{Name: c.Name, Types: types, Options: opts})
+}
@@ -0,0 +1,89 @@
+package pedsgen
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// generatedHeader returns the comment that starts a generated file. It marks the file
+// as generated and contains the command, with normalized flags, that reproduces it
+// when run in the directory of the file.
+func generatedHeader(cfg Config) string {
+	return fmt.Sprintf("// Code generated by peds %s; DO NOT EDIT.\n//\n// Generated in the directory of this file by:\n// %s\n\n",
+		Version, cfg.command())
+}
+
+// commonFileHeader returns the comment that starts a common file. The common file is
+// the same for all files sharing it, so no command is included.
+func commonFileHeader(file string) string {
+	return fmt.Sprintf("// Code generated by peds %s; DO NOT EDIT.\n//\n// Common code for the containers generated with -commonfile=%s.\n\n",
+		Version, filepath.Base(file))
+}
+
+// command returns the peds command generating c with normalized flags. Paths are
+// relative to the directory of the generated file.
+func (c Config) command() string {
+	args := []string{"peds"}
+	for _, f := range []struct{ name, specs string }{
+		{"vectors", joinSpecs(c.Vectors)},
+		{"rrbvectors", joinSpecs(c.RRBVectors)},
+		{"maps", joinSpecs(c.Maps)},
+		{"sets", joinSpecs(c.Sets)},
+		{"sortedmaps", joinSpecs(c.SortedMaps)},
+		{"sortedsets", joinSpecs(c.SortedSets)},
+		{"orderedmaps", joinSpecs(c.OrderedMaps)},
+		{"deques", joinSpecs(c.Deques)},
+		{"heaps", joinSpecs(c.Heaps)},
+	} {
+		if f.specs != "" {
+			args = append(args, shellQuote("-"+f.name+"="+f.specs))
+		}
+	}
+
+	if imports := strings.Join(splitImportEntries(c.Imports), ";"); imports != "" {
+		args = append(args, shellQuote("-imports="+imports))
+	}
+
+	args = append(args, shellQuote("-pkg="+removeWhiteSpaces(c.Package)))
+	if c.GoVersion != "" {
+		args = append(args, shellQuote("-goversion="+c.GoVersion))
+	}
+
+	if c.File != "" {
+		args = append(args, shellQuote("-file="+filepath.Base(c.File)))
+	}
+
+	if c.CommonFile != "" {
+		commonFile, err := filepath.Rel(filepath.Dir(c.File), c.CommonFile)
+		if err != nil {
+			commonFile = c.CommonFile
+		}
+
+		args = append(args, shellQuote("-commonfile="+filepath.ToSlash(commonFile)))
+	}
+
+	return strings.Join(args, " ")
+}
+
+// joinSpecs joins specs using ';', the same way as they are given on the command line.
+func joinSpecs[T fmt.Stringer](specs []T) string {
+	result := make([]string, 0, len(specs))
+	for _, s := range specs {
+		result = append(result, s.String())
+	}
+
+	return strings.Join(result, ";")
+}
+
+var shellSafeRegex = regexp.MustCompile(`^[A-Za-z0-9_./=,:-]+$`)
+
+// shellQuote quotes arg for a POSIX shell if needed.
+func shellQuote(arg string) string {
+	if shellSafeRegex.MatchString(arg) {
+		return arg
+	}
+
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
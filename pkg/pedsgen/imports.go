@@ -0,0 +1,130 @@
+package pedsgen
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"github.com/tobgu/peds/internal/templates"
+)
+
+// commonImports returns the paths of the packages imported by the common imports
+// template and the iterator imports template.
+func commonImports() (map[string]bool, error) {
+	return templateImports(templates.CommonImportsTemplate + templates.IterImportsTemplate)
+}
+
+// templateImports returns the paths of the packages imported by src, which holds
+// import declarations only.
+func templateImports(src string) (map[string]bool, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), "", "package p\n"+src, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]bool)
+	for _, spec := range f.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		result[path] = true
+	}
+
+	return result, nil
+}
+
+// formatSource formats src the same way as "go fmt" does. Packages imported by the
+// common imports template that are not referenced in src are removed since the code
+// using them may have been written to a different file.
+func formatSource(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	removable, err := commonImports()
+	if err != nil {
+		return nil, err
+	}
+
+	used := make(map[string]bool)
+	ast.Inspect(f, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				used[id.Name] = true
+			}
+		}
+
+		return true
+	})
+
+	decls := f.Decls[:0]
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			decls = append(decls, decl)
+			continue
+		}
+
+		// Kept imports are moved up one line for every removed import before them,
+		// otherwise the printer leaves blank lines where the removed imports were.
+		removed := 0
+		specs := gen.Specs[:0]
+		for _, spec := range gen.Specs {
+			imp := spec.(*ast.ImportSpec)
+			path, err := strconv.Unquote(imp.Path.Value)
+			if err != nil {
+				return nil, err
+			}
+
+			// All common imports are standard library packages named after the last path element
+			name := path[strings.LastIndex(path, "/")+1:]
+			if removable[path] && imp.Name == nil && !used[name] {
+				removed++
+				continue
+			}
+
+			if removed > 0 {
+				if imp.Name != nil {
+					imp.Name.NamePos = moveUp(fset, imp.Name.NamePos, removed)
+				}
+
+				imp.Path.ValuePos = moveUp(fset, imp.Path.ValuePos, removed)
+				imp.EndPos = token.NoPos
+			}
+
+			specs = append(specs, spec)
+		}
+
+		if removed > 0 && gen.Rparen.IsValid() {
+			gen.Rparen = moveUp(fset, gen.Rparen, removed)
+		}
+
+		if len(specs) > 0 {
+			gen.Specs = specs
+			decls = append(decls, gen)
+		}
+	}
+
+	f.Decls = decls
+	f.Imports = nil
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, f); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// moveUp returns the start of the line the given number of lines above pos.
+func moveUp(fset *token.FileSet, pos token.Pos, lines int) token.Pos {
+	file := fset.File(pos)
+	return file.LineStart(file.Line(pos) - lines)
+}
@@ -0,0 +1,372 @@
+package pedsgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+// Method profiles that can be given instead of, or together with, method names.
+const (
+	// MinimalMethods are the methods needed to build, read and iterate over a container
+	MinimalMethods = "minimal"
+
+	// StandardMethods are all methods except for those of transients
+	StandardMethods = "standard"
+
+	// FullMethods are all methods, this is the default
+	FullMethods = "full"
+)
+
+var minimalMethods = []string{"Len", "Get", "Set", "Append", "Load", "Store", "Delete", "Add", "Contains", "Range"}
+
+var methodRegex = regexp.MustCompile(`^[A-Z][A-Za-z0-9_]*$`)
+
+func isProfile(method string) bool {
+	return method == MinimalMethods || method == StandardMethods || method == FullMethods
+}
+
+func splitMethods(methods string) []string {
+	if methods == "" {
+		return nil
+	}
+
+	return strings.Split(methods, ",")
+}
+
+func joinMethods(methods []string) string {
+	return strings.Join(methods, ",")
+}
+
+// normalizeMethods validates methods and returns them without duplicates.
+func normalizeMethods(methods []string) ([]string, error) {
+	var result []string
+	for _, m := range methods {
+		m = strings.TrimSpace(m)
+		if !isProfile(m) && !methodRegex.MatchString(m) {
+			return nil, fmt.Errorf("invalid method %q, expected an exported method name or one of %s, %s and %s",
+				m, MinimalMethods, StandardMethods, FullMethods)
+		}
+
+		if !containsString(result, m) {
+			result = append(result, m)
+		}
+	}
+
+	return result, nil
+}
+
+// methodSelection returns a function deciding if a method is selected by methods, and
+// the method names, as opposed to profiles, in methods. A nil function is returned
+// if all methods are selected.
+func methodSelection(methods []string) (func(string) bool, []string) {
+	if len(methods) == 0 || containsString(methods, FullMethods) {
+		return nil, nil
+	}
+
+	names := make([]string, 0, len(methods))
+	for _, m := range methods {
+		if !isProfile(m) {
+			names = append(names, m)
+		}
+	}
+
+	return func(method string) bool {
+		switch {
+		case containsString(names, method):
+			return true
+		case containsString(methods, StandardMethods):
+			return method != "AsTransient"
+		case containsString(methods, MinimalMethods):
+			return containsString(minimalMethods, method)
+		}
+
+		return false
+	}, names
+}
+
+// prunedDecl is a top level declaration that is subject to pruning.
+type prunedDecl struct {
+	names []string
+
+	// recv is the receiver type of methods and the result type of constructors
+	recv        string
+	method      string
+	constructor bool
+
+	refs      []string
+	reachable bool
+}
+
+// pruneMethods removes the methods of container name that are not selected by
+// methods from src, which holds the declarations of the container. Declarations
+// that are no longer used, eg. private helpers and types, are removed as well.
+//
+// Methods are kept on the public types of the container, name and its transient and
+// slice types, if they are selected or referred to by kept code. Other declarations
+// are kept if referred to by kept code. References to methods are found by name
+// only, which may keep more methods than strictly needed but never too few.
+func pruneMethods(src []byte, name string, methods []string) ([]byte, error) {
+	selected, names := methodSelection(methods)
+	if selected == nil {
+		return src, nil
+	}
+
+	publicTypes := []string{name, name + "Transient", name + "Slice"}
+	isPublic := func(typ string) bool { return containsString(publicTypes, typ) }
+	return pruneDecls(src, map[string]bool{name: true}, isPublic, selected, names)
+}
+
+// pruneCommon removes the declarations of common, which holds the common code, that
+// are not referred to by src, holding the code of the containers, directly or through
+// other kept declarations. The constructors of kept exported types are kept as well.
+func pruneCommon(common, src []byte) ([]byte, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), "", append([]byte("package p\n\n"), src...), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := make(map[string]bool)
+	for _, id := range identifiers(f, f.Name) {
+		referenced[id] = true
+	}
+
+	return pruneDecls(common, referenced, ast.IsExported, func(string) bool { return false }, nil)
+}
+
+// pruneDecls removes the declarations of src that are not reachable from the identifiers
+// in referenced. Methods on public types are reachable if selected, all names in names
+// must be methods on public types. Exported functions returning a public type are
+// considered constructors of that type and are reachable if the type is.
+func pruneDecls(src []byte, referenced map[string]bool, isPublic, selected func(string) bool, names []string) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", append([]byte("package p\n\n"), src...), parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	decls := make(map[ast.Node]*prunedDecl)
+	available := make(map[string]bool)
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			pd := &prunedDecl{refs: identifiers(d, d.Name)}
+			if d.Recv != nil {
+				pd.recv, pd.method = baseTypeName(d.Recv.List[0].Type), d.Name.Name
+				if isPublic(pd.recv) {
+					available[pd.method] = true
+				}
+			} else {
+				pd.names = []string{d.Name.Name}
+				if d.Name.IsExported() && d.Type.Results != nil && len(d.Type.Results.List) == 1 {
+					if result := baseTypeName(d.Type.Results.List[0].Type); isPublic(result) {
+						pd.recv, pd.constructor = result, true
+					}
+				}
+			}
+
+			decls[d] = pd
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				pd := &prunedDecl{refs: identifiers(spec, nil)}
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					pd.names = []string{s.Name.Name}
+				case *ast.ValueSpec:
+					for _, n := range s.Names {
+						pd.names = append(pd.names, n.Name)
+					}
+				}
+
+				decls[spec] = pd
+			}
+		}
+	}
+
+	for _, m := range names {
+		if !available[m] {
+			return nil, fmt.Errorf("unknown method %q", m)
+		}
+	}
+
+	// Mark declarations as reachable, starting from referenced, until nothing changes
+	for changed := true; changed; {
+		changed = false
+		for _, pd := range decls {
+			if pd.reachable || !pd.isReachable(referenced, isPublic, selected) {
+				continue
+			}
+
+			pd.reachable, changed = true, true
+			for _, r := range pd.refs {
+				referenced[r] = true
+			}
+		}
+	}
+
+	cmap := ast.NewCommentMap(fset, f, f.Comments)
+	result := f.Decls[:0]
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if decls[d].reachable {
+				result = append(result, d)
+			}
+		case *ast.GenDecl:
+			specs := d.Specs[:0]
+			for _, spec := range d.Specs {
+				if decls[spec].reachable {
+					specs = append(specs, spec)
+				}
+			}
+
+			if len(specs) > 0 {
+				d.Specs = specs
+				result = append(result, d)
+			}
+		}
+	}
+
+	f.Decls = result
+	f.Comments = cmap.Filter(f).Comments()
+
+	buf := &bytes.Buffer{}
+	if err := format.Node(buf, fset, f); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimPrefix(buf.Bytes(), []byte("package p\n")), nil
+}
+
+func (pd *prunedDecl) isReachable(referenced map[string]bool, isPublic, selected func(string) bool) bool {
+	if pd.constructor {
+		return referenced[pd.recv]
+	}
+
+	if pd.method != "" {
+		if !referenced[pd.recv] {
+			return false
+		}
+
+		return referenced[pd.method] || isPublic(pd.recv) && selected(pd.method)
+	}
+
+	for _, n := range pd.names {
+		if referenced[n] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// identifiers returns the names of all identifiers in node except for skip.
+func identifiers(node ast.Node, skip *ast.Ident) []string {
+	result := make([]string, 0)
+	ast.Inspect(node, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id != skip {
+			result = append(result, id.Name)
+		}
+
+		return true
+	})
+
+	return result
+}
+
+// baseTypeName returns the name of typ with any pointer removed, or an empty
+// string if typ is not a, possibly pointer to a, named type.
+func baseTypeName(typ ast.Expr) string {
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+
+	if id, ok := typ.(*ast.Ident); ok {
+		return id.Name
+	}
+
+	return ""
+}
+
+// restrictsMethods returns true if any container of cfg is generated with a selection
+// of its methods rather than all of them.
+func restrictsMethods(cfg Config) bool {
+	var methods [][]string
+	for _, specs := range [][]VectorSpec{cfg.Vectors, cfg.RRBVectors, cfg.Deques} {
+		for _, s := range specs {
+			methods = append(methods, s.Methods)
+		}
+	}
+
+	for _, specs := range [][]MapSpec{cfg.Maps, cfg.OrderedMaps} {
+		for _, s := range specs {
+			methods = append(methods, s.Methods)
+		}
+	}
+
+	for _, s := range cfg.Sets {
+		methods = append(methods, s.Methods)
+	}
+
+	for _, s := range cfg.SortedMaps {
+		methods = append(methods, s.Methods)
+	}
+
+	for _, s := range cfg.SortedSets {
+		methods = append(methods, s.Methods)
+	}
+
+	for _, s := range cfg.Heaps {
+		methods = append(methods, s.Methods)
+	}
+
+	for _, m := range methods {
+		if selected, _ := methodSelection(m); selected != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withHashMethods returns cfg with the Hash and Equals methods selected for the vectors,
+// maps and sets in cfg that are used as elements, keys or values of the containers in
+// cfg, since those are hashed and compared using the methods.
+func withHashMethods(cfg Config, traits typeTraits) Config {
+	used := make(map[string]bool)
+	for _, u := range typeUses(cfg) {
+		if u.funcType == "" && traits.of(u.expr) == hashableType {
+			used[u.expr] = true
+		}
+	}
+
+	withMethods := func(methods []string, names ...string) []string {
+		selected, _ := methodSelection(methods)
+		for _, name := range names {
+			if used[name] && selected != nil && !(selected("Hash") && selected("Equals")) {
+				return append(append([]string{}, methods...), "Hash", "Equals")
+			}
+		}
+
+		return methods
+	}
+
+	for i, v := range cfg.Vectors {
+		cfg.Vectors[i].Methods = withMethods(v.Methods, v.Name, v.Name+"Slice")
+	}
+
+	for i, m := range cfg.Maps {
+		cfg.Maps[i].Methods = withMethods(m.Methods, m.Name)
+	}
+
+	for i, s := range cfg.Sets {
+		cfg.Sets[i].Methods = withMethods(s.Methods, s.Name)
+	}
+
+	return cfg
+}
@@ -0,0 +1,262 @@
+// Package pedsgen generates statically type safe code for persistent data structures.
+// It is the library behind the peds command and can be used to generate containers
+// from other generators, build systems and tests.
+//
+//	cfg := pedsgen.Config{
+//		Package: "collections",
+//		File:    "collections/collections_gen.go",
+//		Vectors: []pedsgen.VectorSpec{{Name: "IntVector", Type: "int"}},
+//		Maps:    []pedsgen.MapSpec{{Name: "StringIntMap", Key: "string", Value: "int"}},
+//	}
+//
+//	src, err := pedsgen.Generate(cfg)
+package pedsgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/build"
+	"go/version"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// Version is written to the header of the generated files.
+const Version = "v0.5.0"
+
+// Config describes the containers of one generated file.
+type Config struct {
+	// Package is the name of the package that the file belongs to.
+	Package string
+
+	// File is the path of the generated file. The package in its directory is used
+	// to verify the types and functions of the containers and to find the imports
+	// needed by them. If empty, no such checks are made.
+	File string
+
+	// Imports are the packages imported by the file, each given as an import path
+	// or, for packages imported under another name, as name=path. Packages referred
+	// to by the containers that are not given here are looked up automatically.
+	Imports []string
+
+	// CommonFile is the path of a file holding the code shared between the files
+	// generated into a package. If given, the shared code is left out of the generated
+	// file and is instead generated by GenerateCommon.
+	CommonFile string
+
+	// DirectiveFile is the path of the file holding the go:generate directive, if any.
+	// Its imports are used before anything else to resolve package qualifiers.
+	DirectiveFile string
+
+	// GoVersion is the Go version, eg. 1.23, that the generated file is compiled with.
+	// Iterator methods are generated for Go 1.23 and later. If empty, the version in
+	// the go.mod of the module containing File is used, or the version of the running
+	// Go if there is no such module.
+	GoVersion string
+
+	Vectors     []VectorSpec
+	RRBVectors  []VectorSpec
+	Maps        []MapSpec
+	Sets        []SetSpec
+	SortedMaps  []SortedMapSpec
+	SortedSets  []SortedSetSpec
+	OrderedMaps []MapSpec
+	Deques      []VectorSpec
+	Heaps       []HeapSpec
+}
+
+// SpecError is returned for a container specification that is invalid, or that
+// does not match the package that it is generated into.
+type SpecError struct {
+	Kind Kind
+	Name string
+
+	// Spec is the specification on the form Name<Type;option=value>
+	Spec string
+	Err  error
+}
+
+func (e *SpecError) Error() string {
+	return fmt.Sprintf("Invalid %s specification: %s: %s", e.Kind.title(), e.Spec, e.Err)
+}
+
+func (e *SpecError) Unwrap() error {
+	return e.Err
+}
+
+// Generate returns the formatted source of the file described by cfg.
+func Generate(cfg Config) ([]byte, error) {
+	cfg, err := cfg.normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	dir := ""
+	if cfg.File != "" {
+		dir = filepath.Dir(cfg.File)
+	}
+
+	funcs, err := loadPackageFuncs(dir, cfg.File)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Imports, err = resolveImports(cfg); err != nil {
+		return nil, err
+	}
+
+	traits, err := typeCheck(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// The header is made before methods needed by other containers are added, these are
+	// added again when the command in it is run.
+	header := generatedHeader(cfg)
+	cfg = withHashMethods(cfg, traits)
+
+	// With a separate common file the common code is written there instead of to the
+	// output file. This allows multiple output files in the same package.
+	sharedCommon := cfg.CommonFile != ""
+	iterators := supportsIterators(cfg)
+
+	buf := &bytes.Buffer{}
+	if err := renderHeader(buf, header, cfg.Package, cfg.Imports); err != nil {
+		return nil, err
+	}
+
+	if iterators {
+		if err := renderIterImports(buf); err != nil {
+			return nil, err
+		}
+	}
+
+	common := &bytes.Buffer{}
+	if !sharedCommon {
+		if err := renderCommon(common); err != nil {
+			return nil, err
+		}
+
+		if hasSnapshots(cfg) {
+			if err := renderSnapshot(common); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	containers := &bytes.Buffer{}
+	if err := renderVectors(containers, cfg.Vectors, traits, iterators); err != nil {
+		return nil, err
+	}
+
+	if err := renderRRBVectors(containers, cfg.RRBVectors, !sharedCommon); err != nil {
+		return nil, err
+	}
+
+	if err := renderMaps(containers, cfg.Maps, funcs, traits, iterators); err != nil {
+		return nil, err
+	}
+
+	if err := renderSets(containers, cfg.Sets, funcs, traits, iterators); err != nil {
+		return nil, err
+	}
+
+	if err := renderSortedMaps(containers, cfg.SortedMaps, funcs); err != nil {
+		return nil, err
+	}
+
+	if err := renderSortedSets(containers, cfg.SortedSets, funcs); err != nil {
+		return nil, err
+	}
+
+	if err := renderOrderedMaps(containers, cfg.OrderedMaps, funcs, traits); err != nil {
+		return nil, err
+	}
+
+	if err := renderDeques(containers, cfg.Deques); err != nil {
+		return nil, err
+	}
+
+	if err := renderHeaps(containers, cfg.Heaps, funcs); err != nil {
+		return nil, err
+	}
+
+	// Only the common code used by the containers is kept if methods are left out of them
+	commonSrc := common.Bytes()
+	if !sharedCommon && restrictsMethods(cfg) {
+		if commonSrc, err = pruneCommon(commonSrc, containers.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+
+	buf.Write(commonSrc)
+	buf.Write(containers.Bytes())
+	return formatGenerated(buf)
+}
+
+// hasSnapshots returns true if cfg has containers that can be written to snapshots.
+func hasSnapshots(cfg Config) bool {
+	return len(cfg.Vectors) > 0 || len(cfg.Maps) > 0 || len(cfg.Sets) > 0
+}
+
+// iteratorsGoVersion is the first Go version with the iter package and range over functions.
+const iteratorsGoVersion = "go1.23"
+
+// supportsIterators returns true if the file described by cfg is compiled with a Go
+// version supporting iterators.
+func supportsIterators(cfg Config) bool {
+	goVersion := cfg.GoVersion
+	if goVersion == "" && cfg.File != "" {
+		goVersion = moduleGoVersion(filepath.Dir(cfg.File))
+	}
+
+	if goVersion == "" {
+		return containsString(build.Default.ReleaseTags, iteratorsGoVersion)
+	}
+
+	return version.Compare("go"+goVersion, iteratorsGoVersion) >= 0
+}
+
+// GenerateCommon returns the formatted source of the common file of cfg. It holds
+// all common code, including that needed by optional containers, so that it can
+// be shared by all files generated into the package with the same common file.
+func GenerateCommon(cfg Config) ([]byte, error) {
+	if cfg.CommonFile == "" {
+		return nil, errors.New("common file is not given")
+	}
+
+	pkg, err := checkPackage(cfg.Package)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	if err := renderHeader(buf, commonFileHeader(cfg.CommonFile), pkg, nil); err != nil {
+		return nil, err
+	}
+
+	if err := renderCommon(buf); err != nil {
+		return nil, err
+	}
+
+	if err := renderSnapshot(buf); err != nil {
+		return nil, err
+	}
+
+	if err := renderRRBCommon(buf); err != nil {
+		return nil, err
+	}
+
+	return formatGenerated(buf)
+}
+
+func formatGenerated(buf *bytes.Buffer) ([]byte, error) {
+	// The equivalent of "go fmt"
+	src, err := formatSource(buf.Bytes())
+	if err != nil {
+		return nil, errors.Wrap(err, "Format error in generated code")
+	}
+
+	return src, nil
+}
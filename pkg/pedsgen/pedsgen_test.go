@@ -0,0 +1,425 @@
+package pedsgen_test
+
+import (
+	"errors"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tobgu/peds/pkg/pedsgen"
+)
+
+func TestGenerate(t *testing.T) {
+	src, err := pedsgen.Generate(pedsgen.Config{
+		Package:    "collections",
+		Vectors:    []pedsgen.VectorSpec{{Name: "IntVector", Type: "int"}},
+		Maps:       []pedsgen.MapSpec{{Name: "StringIntMap", Key: "string", Value: "[] int"}},
+		SortedSets: []pedsgen.SortedSetSpec{{Name: "StringSet", Type: "string"}},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.Name.Name != "collections" {
+		t.Errorf("Unexpected package %s", f.Name.Name)
+	}
+
+	for _, s := range []string{
+		"type IntVector struct",
+		"type StringIntMap struct",
+		"type StringSet struct",
+		"peds '-vectors=IntVector<int>' '-maps=StringIntMap<string,[]int>' '-sortedsets=StringSet<string>' -pkg=collections\n",
+	} {
+		if !strings.Contains(string(src), s) {
+			t.Errorf("Expected generated code to contain %q", s)
+		}
+	}
+}
+
+func TestGenerateInvalidSpec(t *testing.T) {
+	_, err := pedsgen.Generate(pedsgen.Config{
+		Package: "collections",
+		Sets:    []pedsgen.SetSpec{{Name: "Slices", Type: "[]int"}},
+	})
+
+	var specErr *pedsgen.SpecError
+	if !errors.As(err, &specErr) {
+		t.Fatalf("Expected a SpecError, got %v", err)
+	}
+
+	if specErr.Kind != pedsgen.SetKind || specErr.Name != "Slices" || specErr.Spec != "Slices<[]int>" {
+		t.Errorf("Unexpected error %#v", specErr)
+	}
+
+	if expected := "Invalid set specification: Slices<[]int>: key type []int is not comparable"; err.Error() != expected {
+		t.Errorf("Expected %q, got %q", expected, err.Error())
+	}
+}
+
+func TestParseSpec(t *testing.T) {
+	spec, err := pedsgen.ParseSpec("ByName< Name, map[string] []int ;hash=NameHash;eq=NameEq>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := pedsgen.Spec{
+		Name:    "ByName",
+		Types:   []string{"Name", "map[string][]int"},
+		Options: map[string]string{"hash": "NameHash", "eq": "NameEq"}}
+
+	if !reflect.DeepEqual(spec, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, spec)
+	}
+
+	if s := spec.String(); s != "ByName<Name,map[string][]int;hash=NameHash;eq=NameEq>" {
+		t.Errorf("Unexpected string %s", s)
+	}
+
+	cfg := pedsgen.Config{}
+	if err := cfg.Add(pedsgen.MapKind, spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cfg.Maps) != 1 || cfg.Maps[0].Hash != "NameHash" || cfg.Maps[0].Value != "map[string][]int" {
+		t.Errorf("Unexpected maps %#v", cfg.Maps)
+	}
+}
+
+func TestConfigParse(t *testing.T) {
+	cfg := pedsgen.Config{}
+	if err := cfg.Parse(pedsgen.VectorKind, `"IntVector<int>;StringVector<string>"`); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cfg.Vectors) != 2 || !reflect.DeepEqual(cfg.Vectors[1], pedsgen.VectorSpec{Name: "StringVector", Type: "string"}) {
+		t.Errorf("Unexpected vectors %#v", cfg.Vectors)
+	}
+
+	err := cfg.Parse(pedsgen.SortedMapKind, "ByKey<int,string;hash=KeyHash>")
+	if expected := `Invalid sorted map specification: ByKey<int,string;hash=KeyHash>: unknown option "hash"`; err == nil || err.Error() != expected {
+		t.Errorf("Expected %q, got %v", expected, err)
+	}
+
+	err = cfg.Parse(pedsgen.HeapKind, "SliceHeap<[]int>")
+	if expected := `Invalid heap specification: SliceHeap<[]int>: element type []int is not ordered, a less function is required`; err == nil || err.Error() != expected {
+		t.Errorf("Expected %q, got %v", expected, err)
+	}
+}
+
+func TestGenerateSelectedMethods(t *testing.T) {
+	src, err := pedsgen.Generate(pedsgen.Config{
+		Package: "collections",
+		Vectors: []pedsgen.VectorSpec{{Name: "IntVector", Type: "int", Methods: []string{"Get", "Append"}}},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for s, expected := range map[string]bool{
+		"func (v *IntVector) Get(":    true,
+		"func (v *IntVector) Append(": true,
+		"func (v *IntVector) Set(":    false,
+		"func (v *IntVector) Slice(":  false,
+		"type IntVectorSlice struct":  false,
+	} {
+		if strings.Contains(string(src), s) != expected {
+			t.Errorf("Expected generated code to contain %q: %t", s, expected)
+		}
+	}
+
+	_, err = pedsgen.Generate(pedsgen.Config{
+		Package: "collections",
+		Vectors: []pedsgen.VectorSpec{{Name: "IntVector", Type: "int", Methods: []string{"minimal", "Load"}}},
+	})
+
+	if expected := `Invalid vector specification: IntVector<int;methods=minimal,Load>: unknown method "Load"`; err == nil || err.Error() != expected {
+		t.Errorf("Expected %q, got %v", expected, err)
+	}
+}
+
+func TestGenerateContainerKeys(t *testing.T) {
+	src, err := pedsgen.Generate(pedsgen.Config{
+		Package: "collections",
+		Vectors: []pedsgen.VectorSpec{{Name: "IntVector", Type: "int", Methods: []string{"minimal"}}},
+		Maps:    []pedsgen.MapSpec{{Name: "VectorCounts", Key: "IntVector", Value: "int"}},
+		Sets:    []pedsgen.SetSpec{{Name: "Paths", Type: "IntVector"}},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for s, expected := range map[string]bool{
+		// Hash and Equals are added to the methods of the vector since it is used as a key
+		"func (v *IntVector) Hash() uint32":                true,
+		"func (v *IntVector) Equals(other *IntVector)":     true,
+		"func (v *IntVector) Slice(":                       false,
+		"'-vectors=IntVector<int;methods=minimal>'":        true,
+		"func privatePathsMapKeyHash(x IntVector)":         true,
+		"func privateVectorCountsKeyEqual(a, b IntVector)": true,
+
+		// Native maps cannot have vector keys
+		"func (m *VectorCounts) ToNativeMap()": false,
+	} {
+		if strings.Contains(string(src), s) != expected {
+			t.Errorf("Expected generated code to contain %q: %t", s, expected)
+		}
+	}
+}
+
+func TestGenerateIterators(t *testing.T) {
+	cfg := pedsgen.Config{
+		Package: "collections",
+		Vectors: []pedsgen.VectorSpec{{Name: "IntVector", Type: "int"}},
+		Maps:    []pedsgen.MapSpec{{Name: "StringIntMap", Key: "string", Value: "int"}},
+	}
+
+	for version, expected := range map[string]bool{"1.23": true, "go1.24": true, "1.22": false} {
+		cfg.GoVersion = version
+		src, err := pedsgen.Generate(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, s := range []string{
+			"func (v *IntVector) All() iter.Seq2[int, int]",
+			"func (v *IntVector) Backward() iter.Seq2[int, int]",
+			"func (m *StringIntMap) Keys() iter.Seq[string]",
+			"import \"iter\"",
+		} {
+			if strings.Contains(string(src), s) != expected {
+				t.Errorf("Expected generated code for %s to contain %q: %t", version, s, expected)
+			}
+		}
+	}
+
+	cfg.GoVersion = "go1.23"
+	src, err := pedsgen.Generate(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := "-goversion=1.23"; !strings.Contains(string(src), s) {
+		t.Errorf("Expected generated code to contain %q", s)
+	}
+
+	cfg.GoVersion = "x"
+	_, err = pedsgen.Generate(cfg)
+	if expected := `"x" is not a valid Go version`; err == nil || !strings.Contains(err.Error(), expected) {
+		t.Errorf("Expected %q, got %v", expected, err)
+	}
+}
+
+func TestGenerateUncomparableValues(t *testing.T) {
+	cfg := pedsgen.Config{
+		Package: "collections",
+		Vectors: []pedsgen.VectorSpec{{Name: "Blobs", Type: "[]byte"}, {Name: "BlobsVector", Type: "Blobs"}},
+		Maps:    []pedsgen.MapSpec{{Name: "Counters", Key: "string", Value: "map[string]int"}},
+	}
+
+	src, err := pedsgen.Generate(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []string{
+		"func (v *Blobs) Equals(",
+		"func (v *Blobs) Hash(",
+		"func (s *BlobsSlice) Equals(",
+		"func (v *BlobsVector) Equals(",
+		"func (m *Counters) Equals(",
+		"func (m *Counters) Hash(",
+	} {
+		if strings.Contains(string(src), s) {
+			t.Errorf("Expected generated code not to contain %q", s)
+		}
+	}
+
+	cfg.Sets = []pedsgen.SetSpec{{Name: "BlobsSet", Type: "BlobsVector"}}
+	_, err = pedsgen.Generate(cfg)
+	if expected := "Invalid set specification: BlobsSet<BlobsVector>: key type BlobsVector is not comparable"; err == nil || err.Error() != expected {
+		t.Errorf("Expected %q, got %v", expected, err)
+	}
+}
+
+func TestGenerateRemovesUnusedImports(t *testing.T) {
+	src, err := pedsgen.Generate(pedsgen.Config{
+		Package: "collections",
+		Vectors: []pedsgen.VectorSpec{{Name: "IntVector", Type: "int"}},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := strings.Index(string(src), "import (")
+	end := strings.Index(string(src)[start:], ")")
+	imports := string(src)[start : start+end]
+	if strings.Contains(imports, `"sort"`) {
+		t.Errorf("Expected unused imports to be removed:\n%s", imports)
+	}
+
+	if strings.Contains(imports, "\n\n") {
+		t.Errorf("Expected no blank lines where imports were removed:\n%s", imports)
+	}
+}
+
+func TestGeneratePackagesImportedByGeneratedCode(t *testing.T) {
+	file := tempModuleFile(t)
+	dir := filepath.Dir(file)
+	cfg := pedsgen.Config{
+		Package: "collections",
+		File:    file,
+		Imports: []string{"encoding/json"},
+		Vectors: []pedsgen.VectorSpec{
+			{Name: "Readers", Type: "io.Reader"},
+			{Name: "Stringers", Type: "fmt.Stringer"},
+			{Name: "Messages", Type: "json.RawMessage"},
+			{Name: "Types", Type: "reflect.Type"},
+		},
+		Maps: []pedsgen.MapSpec{{Name: "Numbers", Key: "string", Value: "json.Number"}},
+	}
+
+	for _, goVersion := range []string{"1.22", "1.23"} {
+		cfg.GoVersion = goVersion
+		src, err := pedsgen.Generate(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertCompiles(t, src)
+	}
+
+	cfg.Imports = []string{"json=example.com/json"}
+	_, err := pedsgen.Generate(cfg)
+	if expected := "import example.com/json conflicts with the import of encoding/json by the generated code"; err == nil || err.Error() != expected {
+		t.Errorf("Expected %q, got %v", expected, err)
+	}
+
+	cfg.Imports, cfg.DirectiveFile = nil, filepath.Join(dir, "types.go")
+	if err := os.WriteFile(cfg.DirectiveFile, []byte("package collections\n\nimport json \"example.com/json\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = pedsgen.Generate(cfg)
+	if expected := "Invalid vector specification: Messages<json.RawMessage>: package json (example.com/json) conflicts with the import of encoding/json by the generated code"; err == nil || err.Error() != expected {
+		t.Errorf("Expected %q, got %v", expected, err)
+	}
+}
+
+func TestGenerateMinimalMethodsLeavesOutUnusedCommonCode(t *testing.T) {
+	src, err := pedsgen.Generate(pedsgen.Config{
+		Package: "collections",
+		Sets:    []pedsgen.SetSpec{{Name: "IntSet", Type: "int", Methods: []string{"minimal"}}},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertCompiles(t, src)
+	for s, expected := range map[string]bool{
+		"func champBitpos(":        true,
+		"func intHash(":            true,
+		"func jsonEncodeMap(":      false,
+		"func jsonDecodeMap(":      false,
+		"func binaryMarshal(":      false,
+		"type SnapshotEncoder ":    false,
+		"func NewSnapshotDecoder(": false,
+		"func cacheHash(":          false,
+		"func stringHash(":         false,
+	} {
+		if strings.Contains(string(src), s) != expected {
+			t.Errorf("Expected generated code to contain %q: %t", s, expected)
+		}
+	}
+}
+
+func TestGenerateCommonImportQualifiers(t *testing.T) {
+	src, err := pedsgen.Generate(pedsgen.Config{
+		Package: "collections",
+		File:    tempModuleFile(t),
+		Vectors: []pedsgen.VectorSpec{
+			{Name: "Buffers", Type: "*bytes.Buffer"},
+			{Name: "Types", Type: "reflect.Type"},
+			{Name: "NumErrors", Type: "strconv.NumError"},
+			{Name: "IntSlices", Type: "sort.IntSlice"},
+		},
+		Maps:       []pedsgen.MapSpec{{Name: "Kinds", Key: "reflect.Kind", Value: "json.RawMessage"}},
+		SortedMaps: []pedsgen.SortedMapSpec{{Name: "Numbers", Key: "json.Number", Value: "reflect.Type"}},
+		Heaps:      []pedsgen.HeapSpec{{Name: "NumberHeap", Type: "json.Number"}},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertCompiles(t, src)
+}
+
+func TestGenerateSnapshotsOnlyWithContainersSupportingThem(t *testing.T) {
+	cfg := pedsgen.Config{
+		Package:     "collections",
+		Deques:      []pedsgen.VectorSpec{{Name: "IntDeque", Type: "int"}},
+		Heaps:       []pedsgen.HeapSpec{{Name: "IntHeap", Type: "int"}},
+		OrderedMaps: []pedsgen.MapSpec{{Name: "StringIntOrderedMap", Key: "string", Value: "int"}},
+	}
+
+	for _, withVector := range []bool{false, true} {
+		if withVector {
+			cfg.Vectors = []pedsgen.VectorSpec{{Name: "IntVector", Type: "int"}}
+		}
+
+		src, err := pedsgen.Generate(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertCompiles(t, src)
+		for _, s := range []string{"type SnapshotEncoder struct", "func NewSnapshotDecoder(", "EncodeSnapshot("} {
+			if strings.Contains(string(src), s) != withVector {
+				t.Errorf("Expected generated code with a vector to contain %q: %t", s, withVector)
+			}
+		}
+	}
+}
+
+// tempModuleFile returns the path of a generated file in a new module. The types of
+// the containers are only checked, eg. that json.RawMessage is not comparable, in a module.
+func tempModuleFile(t *testing.T) string {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/collections\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return filepath.Join(dir, "collections_gen.go")
+}
+
+// assertCompiles type checks src, a generated file importing the standard library only.
+func assertCompiles(t *testing.T, src []byte) {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check(f.Name.Name, fset, []*ast.File{f}, nil); err != nil {
+		t.Errorf("Generated code does not compile: %v", err)
+	}
+}
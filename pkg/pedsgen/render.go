@@ -0,0 +1,596 @@
+package pedsgen
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/tobgu/peds/internal/templates"
+)
+
+type templateSpec struct {
+	name     string
+	template string
+
+	// data is used instead of the data of the other templates if set
+	data interface{}
+}
+
+// renderContainer renders a container using render and writes it to buf without the
+// methods that are not selected by methods.
+func renderContainer(buf *bytes.Buffer, name string, methods []string, render func(*bytes.Buffer) error) error {
+	container := &bytes.Buffer{}
+	if err := render(container); err != nil {
+		return err
+	}
+
+	src, err := pruneMethods(container.Bytes(), name, methods)
+	if err != nil {
+		return err
+	}
+
+	buf.Write(src)
+	return nil
+}
+
+func renderTemplates(specs []templateSpec, templateData interface{}, dst io.Writer) error {
+	for _, s := range specs {
+		t := template.New(s.name)
+		t, err := t.Parse(s.template)
+		if err != nil {
+			return err
+		}
+
+		data := templateData
+		if s.data != nil {
+			data = s.data
+		}
+
+		err = t.Execute(dst, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//////////////
+/// Common ///
+//////////////
+
+func renderHeader(buf *bytes.Buffer, header, pkgName string, imports []string) error {
+	importTemplate := `{{if .Imports}}
+import (
+{{range $imp := .Imports}}
+	{{$imp}}
+{{end}}
+)
+{{end}}`
+
+	buf.WriteString(header)
+	return renderTemplates([]templateSpec{
+		{name: "pkg", template: "package {{index .PackageName 0}}\n"},
+		{name: "imports", template: importTemplate},
+		{name: "common_imports", template: templates.CommonImportsTemplate}},
+		map[string][]string{"PackageName": {strings.TrimSpace(pkgName)}, "Imports": importSpecs(imports)}, buf)
+}
+
+func renderCommon(buf *bytes.Buffer) error {
+	return renderTemplates([]templateSpec{{name: "common", template: templates.CommonTemplate}}, nil, buf)
+}
+
+// renderSnapshot renders the snapshot encoder and decoder used by vectors, maps and sets.
+func renderSnapshot(buf *bytes.Buffer) error {
+	return renderTemplates([]templateSpec{{name: "snapshot", template: templates.SnapshotTemplate}}, nil, buf)
+}
+
+// renderIterImports renders the imports needed by the iterator methods.
+func renderIterImports(buf *bytes.Buffer) error {
+	return renderTemplates([]templateSpec{{name: "iter_imports", template: templates.IterImportsTemplate}}, nil, buf)
+}
+
+// renderRRBCommon renders the tree internals shared between all RRB vectors.
+func renderRRBCommon(buf *bytes.Buffer) error {
+	return renderTemplates([]templateSpec{{name: "rrb_common", template: templates.RRBCommonTemplate}}, nil, buf)
+}
+
+////////////////////////
+/// Value operations ///
+////////////////////////
+
+// valueOpsSpec describes the functions hashing and comparing values of a type.
+type valueOpsSpec struct {
+	ValueTypeName  string
+	ValueHashFunc  string
+	ValueEqualFunc string
+	BasicHashFunc  string
+}
+
+// newValueOpsSpec returns the template data of the functions hashing and comparing values
+// of typ, named hashFuncName and equalFuncName, and the template generating them.
+func newValueOpsSpec(typ, hashFuncName, equalFuncName string, traits typeTraits) templateSpec {
+	spec := valueOpsSpec{
+		ValueTypeName:  typ,
+		ValueHashFunc:  hashFuncName,
+		ValueEqualFunc: equalFuncName,
+		BasicHashFunc:  hashFunc(typ)}
+
+	if traits.of(typ) == hashableType {
+		return templateSpec{name: "container_value_ops", template: templates.ContainerValueOpsTemplate, data: spec}
+	}
+
+	return templateSpec{name: "comparable_value_ops", template: templates.ComparableValueOpsTemplate, data: spec}
+}
+
+// hashSpecs returns the templates of the Hash and Equals methods of a container holding
+// values of typ, hashed and compared by valueOps. Nothing is returned if the values
+// cannot be compared, the container has no Hash and Equals methods then.
+func hashSpecs(typ string, traits typeTraits, valueOps templateSpec, hashTemplates ...templateSpec) []templateSpec {
+	if traits.of(typ) == uncomparableType {
+		return nil
+	}
+
+	return append(hashTemplates, valueOps)
+}
+
+//////////////
+/// Vector ///
+//////////////
+
+type vectorSpec struct {
+	VectorTypeName   string
+	TypeName         string
+	ElementHashFunc  string
+	ElementEqualFunc string
+}
+
+func renderVectors(buf *bytes.Buffer, vectors []VectorSpec, traits typeTraits, iterators bool) error {
+	for _, v := range vectors {
+		spec := vectorSpec{
+			VectorTypeName:   v.Name,
+			TypeName:         v.Type,
+			ElementHashFunc:  privateFuncName(v.Name, "ElementHash"),
+			ElementEqualFunc: privateFuncName(v.Name, "ElementEqual")}
+
+		err := renderContainer(buf, v.Name, v.Methods, func(buf *bytes.Buffer) error {
+			specs := []templateSpec{
+				{name: "vector", template: templates.VectorTemplate},
+				{name: "slice", template: templates.SliceTemplate}}
+			specs = append(specs, hashSpecs(v.Type, traits,
+				newValueOpsSpec(v.Type, spec.ElementHashFunc, spec.ElementEqualFunc, traits),
+				templateSpec{name: "vector_hash", template: templates.VectorHashTemplate})...)
+			if iterators {
+				specs = append(specs, templateSpec{name: "vector_iter", template: templates.VectorIterTemplate})
+			}
+
+			return renderTemplates(specs, spec, buf)
+		})
+
+		if err != nil {
+			return &SpecError{Kind: VectorKind, Name: v.Name, Spec: v.String(), Err: err}
+		}
+	}
+
+	return nil
+}
+
+//////////////////
+/// RRB Vector ///
+//////////////////
+
+func renderRRBVectors(buf *bytes.Buffer, vectors []VectorSpec, includeCommon bool) error {
+	if len(vectors) == 0 {
+		return nil
+	}
+
+	if includeCommon {
+		if err := renderRRBCommon(buf); err != nil {
+			return err
+		}
+	}
+
+	for _, v := range vectors {
+		err := renderContainer(buf, v.Name, v.Methods, func(buf *bytes.Buffer) error {
+			return renderTemplates([]templateSpec{
+				{name: "rrb_vector", template: templates.RRBVectorTemplate}},
+				vectorSpec{VectorTypeName: v.Name, TypeName: v.Type}, buf)
+		})
+
+		if err != nil {
+			return &SpecError{Kind: RRBVectorKind, Name: v.Name, Spec: v.String(), Err: err}
+		}
+	}
+
+	return nil
+}
+
+/////////////
+/// Deque ///
+/////////////
+
+type dequeSpec struct {
+	DequeTypeName string
+	TypeName      string
+}
+
+func renderDeques(buf *bytes.Buffer, deques []VectorSpec) error {
+	for _, d := range deques {
+		err := renderContainer(buf, d.Name, d.Methods, func(buf *bytes.Buffer) error {
+			return renderTemplates([]templateSpec{
+				{name: "deque", template: templates.DequeTemplate}},
+				dequeSpec{DequeTypeName: d.Name, TypeName: d.Type}, buf)
+		})
+
+		if err != nil {
+			return &SpecError{Kind: DequeKind, Name: d.Name, Spec: d.String(), Err: err}
+		}
+	}
+
+	return nil
+}
+
+////////////
+/// Heap ///
+////////////
+
+type heapSpec struct {
+	HeapTypeName   string
+	TypeName       string
+	HeapLessFunc   string
+	customLessFunc bool
+}
+
+func renderHeaps(buf *bytes.Buffer, heaps []HeapSpec, funcs packageFuncs) error {
+	for _, h := range heaps {
+		spec := heapSpec{HeapTypeName: h.Name, TypeName: h.Type, HeapLessFunc: privateFuncName(h.Name, "Less")}
+		if h.Less != "" {
+			if err := funcs.checkSignature(h.Less, []string{h.Type, h.Type}, "bool"); err != nil {
+				return &SpecError{Kind: HeapKind, Name: h.Name, Spec: h.String(), Err: err}
+			}
+
+			spec.HeapLessFunc = h.Less
+			spec.customLessFunc = true
+		}
+
+		err := renderContainer(buf, h.Name, h.Methods, func(buf *bytes.Buffer) error {
+			specs := []templateSpec{{name: "heap", template: templates.HeapTemplate}}
+			if !spec.customLessFunc {
+				specs = append(specs, templateSpec{name: "default_heap_less", template: templates.DefaultHeapLessTemplate})
+			}
+
+			return renderTemplates(specs, spec, buf)
+		})
+
+		if err != nil {
+			return &SpecError{Kind: HeapKind, Name: h.Name, Spec: h.String(), Err: err}
+		}
+	}
+
+	return nil
+}
+
+///////////
+/// Map ///
+///////////
+
+func renderMaps(buf *bytes.Buffer, maps []MapSpec, funcs packageFuncs, traits typeTraits, iterators bool) error {
+	for _, m := range maps {
+		spec, err := newMapSpec(m.Name, m.Key, m.Value, m.Hash, m.Eq, funcs, traits)
+		if err != nil {
+			return &SpecError{Kind: MapKind, Name: m.Name, Spec: m.String(), Err: err}
+		}
+
+		err = renderContainer(buf, m.Name, m.Methods, func(buf *bytes.Buffer) error {
+			specs := append(spec.privateMapTemplates(), spec.hashSpecs(traits)...)
+			specs = append(specs,
+				templateSpec{name: "map_snapshot_template", template: templates.MapSnapshotTemplate},
+				templateSpec{name: "public_map_template", template: templates.PublicMapTemplate})
+			if spec.keyOps == nil {
+				// Native maps cannot hold keys that are compared using Equals
+				specs = append(specs, templateSpec{name: "native_map_template", template: templates.NativeMapTemplate})
+			}
+
+			if iterators {
+				specs = append(specs, templateSpec{name: "map_iter_template", template: templates.MapIterTemplate})
+			}
+
+			return renderTemplates(specs, spec, buf)
+		})
+
+		if err != nil {
+			return &SpecError{Kind: MapKind, Name: m.Name, Spec: m.String(), Err: err}
+		}
+	}
+
+	return nil
+}
+
+type mapSpec struct {
+	MapTypeName      string
+	MapItemTypeName  string
+	MapKeyTypeName   string
+	MapValueTypeName string
+	MapKeyHashFunc   string
+	MapKeyEqFunc     string
+	MapValueHashFunc string
+	MapValueEqFunc   string
+	customKeyEqFunc  bool
+
+	// keyOps is set for keys that are hashed and compared using their Hash and Equals methods
+	keyOps   *templateSpec
+	valueOps templateSpec
+}
+
+// newMapSpec returns the template data of a map. The types are expected to have been
+// validated, the custom functions, if any, are verified against funcs.
+func newMapSpec(mapTypeName, keyTypeName, valueTypeName, hash, eq string, funcs packageFuncs, traits typeTraits) (mapSpec, error) {
+	spec := mapSpec{
+		MapTypeName:      mapTypeName,
+		MapItemTypeName:  mapTypeName + "Item",
+		MapKeyTypeName:   keyTypeName,
+		MapValueTypeName: valueTypeName,
+		MapKeyHashFunc:   hashFunc(keyTypeName),
+		MapKeyEqFunc:     privateFuncName(mapTypeName, "KeyEqual"),
+		MapValueHashFunc: privateFuncName(mapTypeName, "ValueHash"),
+		MapValueEqFunc:   privateFuncName(mapTypeName, "ValueEqual")}
+
+	spec.valueOps = newValueOpsSpec(valueTypeName, spec.MapValueHashFunc, spec.MapValueEqFunc, traits)
+	if traits.of(keyTypeName) == hashableType {
+		spec.keyOps = &templateSpec{
+			name:     "container_key_ops",
+			template: templates.ContainerKeyOpsTemplate,
+			data: valueOpsSpec{
+				ValueTypeName:  keyTypeName,
+				ValueHashFunc:  privateFuncName(mapTypeName, "KeyHash"),
+				ValueEqualFunc: spec.MapKeyEqFunc}}
+		spec.MapKeyHashFunc = privateFuncName(mapTypeName, "KeyHash")
+		spec.customKeyEqFunc = true
+	}
+
+	if hash != "" {
+		if err := funcs.checkSignature(hash, []string{keyTypeName}, "uint32"); err != nil {
+			return mapSpec{}, err
+		}
+
+		spec.MapKeyHashFunc = hash
+	}
+
+	if eq != "" {
+		if err := funcs.checkSignature(eq, []string{keyTypeName, keyTypeName}, "bool"); err != nil {
+			return mapSpec{}, err
+		}
+
+		spec.MapKeyEqFunc = eq
+		spec.customKeyEqFunc = true
+	}
+
+	return spec, nil
+}
+
+// privateMapTemplates returns the templates needed for the private part of a map.
+func (s mapSpec) privateMapTemplates() []templateSpec {
+	result := []templateSpec{{name: "private_map_template", template: templates.PrivateMapTemplate}}
+	if s.keyOps != nil {
+		result = append(result, *s.keyOps)
+	} else if !s.customKeyEqFunc {
+		result = append(result, templateSpec{name: "default_key_equal_template", template: templates.DefaultKeyEqualTemplate})
+	}
+
+	return result
+}
+
+// hashSpecs returns the templates of the Hash and Equals methods of the map.
+func (s mapSpec) hashSpecs(traits typeTraits) []templateSpec {
+	return hashSpecs(s.MapValueTypeName, traits, s.valueOps, templateSpec{name: "map_hash_template", template: templates.MapHashTemplate})
+}
+
+func hashFunc(typ string) string {
+	for _, hashTyp := range []string{"byte", "bool", "rune", "string",
+		"int8", "uint8", "int16", "uint16", "int32", "uint32", "int64", "uint64", "int", "uint", "float32", "float64"} {
+		if typ == hashTyp {
+			return typ + "Hash"
+		}
+	}
+
+	return "interfaceHash"
+}
+
+// privateFuncName returns the name of a generated, unexported, function belonging to mapTypeName
+func privateFuncName(mapTypeName, name string) string {
+	if strings.HasPrefix(mapTypeName, "private") {
+		return mapTypeName + name
+	}
+
+	return "private" + mapTypeName + name
+}
+
+///////////
+/// Set ///
+///////////
+
+type setSpec struct {
+	mapSpec
+	SetTypeName string
+}
+
+func renderSets(buf *bytes.Buffer, sets []SetSpec, funcs packageFuncs, traits typeTraits, iterators bool) error {
+	for _, s := range sets {
+		mSpec, err := newMapSpec("private"+s.Name+"Map", s.Type, "struct{}", s.Hash, s.Eq, funcs, traits)
+		if err != nil {
+			return &SpecError{Kind: SetKind, Name: s.Name, Spec: s.String(), Err: err}
+		}
+
+		spec := setSpec{mapSpec: mSpec, SetTypeName: s.Name}
+		err = renderContainer(buf, s.Name, s.Methods, func(buf *bytes.Buffer) error {
+			specs := append(spec.privateMapTemplates(), spec.hashSpecs(traits)...)
+			specs = append(specs,
+				templateSpec{name: "map_snapshot_template", template: templates.MapSnapshotTemplate},
+				templateSpec{name: "set_template", template: templates.SetTemplate})
+			if iterators {
+				specs = append(specs, templateSpec{name: "set_iter_template", template: templates.SetIterTemplate})
+			}
+
+			return renderTemplates(specs, spec, buf)
+		})
+
+		if err != nil {
+			return &SpecError{Kind: SetKind, Name: s.Name, Spec: s.String(), Err: err}
+		}
+	}
+
+	return nil
+}
+
+//////////////////
+/// Sorted map ///
+//////////////////
+
+func renderSortedMaps(buf *bytes.Buffer, maps []SortedMapSpec, funcs packageFuncs) error {
+	for _, m := range maps {
+		spec, err := newSortedMapSpec(m.Name, m.Key, m.Value, m.Less, funcs)
+		if err != nil {
+			return &SpecError{Kind: SortedMapKind, Name: m.Name, Spec: m.String(), Err: err}
+		}
+
+		err = renderContainer(buf, m.Name, m.Methods, func(buf *bytes.Buffer) error {
+			return renderTemplates(append(spec.privateMapTemplates(),
+				templateSpec{name: "public_sorted_map_template", template: templates.PublicSortedMapTemplate}),
+				spec, buf)
+		})
+
+		if err != nil {
+			return &SpecError{Kind: SortedMapKind, Name: m.Name, Spec: m.String(), Err: err}
+		}
+	}
+
+	return nil
+}
+
+type sortedMapSpec struct {
+	MapTypeName       string
+	MapItemTypeName   string
+	MapKeyTypeName    string
+	MapValueTypeName  string
+	MapKeyLessFunc    string
+	customKeyLessFunc bool
+}
+
+// newSortedMapSpec returns the template data of a sorted map. The types are expected to
+// have been validated, the custom less function, if any, is verified against funcs.
+func newSortedMapSpec(mapTypeName, keyTypeName, valueTypeName, less string, funcs packageFuncs) (sortedMapSpec, error) {
+	spec := sortedMapSpec{
+		MapTypeName:      mapTypeName,
+		MapItemTypeName:  mapTypeName + "Item",
+		MapKeyTypeName:   keyTypeName,
+		MapValueTypeName: valueTypeName,
+		MapKeyLessFunc:   privateFuncName(mapTypeName, "KeyLess")}
+
+	if less != "" {
+		if err := funcs.checkSignature(less, []string{keyTypeName, keyTypeName}, "bool"); err != nil {
+			return sortedMapSpec{}, err
+		}
+
+		spec.MapKeyLessFunc = less
+		spec.customKeyLessFunc = true
+	}
+
+	return spec, nil
+}
+
+// privateMapTemplates returns the templates needed for the private part of a sorted map.
+func (s sortedMapSpec) privateMapTemplates() []templateSpec {
+	result := []templateSpec{{name: "private_sorted_map_template", template: templates.PrivateSortedMapTemplate}}
+	if !s.customKeyLessFunc {
+		result = append(result, templateSpec{name: "default_key_less_template", template: templates.DefaultKeyLessTemplate})
+	}
+
+	return result
+}
+
+//////////////////
+/// Sorted set ///
+//////////////////
+
+type sortedSetSpec struct {
+	sortedMapSpec
+	SetTypeName string
+}
+
+func renderSortedSets(buf *bytes.Buffer, sets []SortedSetSpec, funcs packageFuncs) error {
+	for _, s := range sets {
+		mSpec, err := newSortedMapSpec("private"+s.Name+"Map", s.Type, "struct{}", s.Less, funcs)
+		if err != nil {
+			return &SpecError{Kind: SortedSetKind, Name: s.Name, Spec: s.String(), Err: err}
+		}
+
+		spec := sortedSetSpec{sortedMapSpec: mSpec, SetTypeName: s.Name}
+		err = renderContainer(buf, s.Name, s.Methods, func(buf *bytes.Buffer) error {
+			return renderTemplates(append(spec.privateMapTemplates(),
+				templateSpec{name: "sorted_set_template", template: templates.SortedSetTemplate}),
+				spec, buf)
+		})
+
+		if err != nil {
+			return &SpecError{Kind: SortedSetKind, Name: s.Name, Spec: s.String(), Err: err}
+		}
+	}
+
+	return nil
+}
+
+///////////////////
+/// Ordered map ///
+///////////////////
+
+// orderedMapSpec describes an ordered map and the private hash map, holding the
+// values, and sorted map, holding the insertion order, that it is built from.
+type orderedMapSpec struct {
+	MapTypeName      string
+	MapItemTypeName  string
+	MapKeyTypeName   string
+	MapValueTypeName string
+	entries          mapSpec
+	order            sortedMapSpec
+}
+
+func renderOrderedMaps(buf *bytes.Buffer, maps []MapSpec, funcs packageFuncs, traits typeTraits) error {
+	for _, m := range maps {
+		entries, err := newMapSpec("private"+m.Name+"Entries", m.Key, "private"+m.Name+"Entry", m.Hash, m.Eq, funcs, traits)
+		if err != nil {
+			return &SpecError{Kind: OrderedMapKind, Name: m.Name, Spec: m.String(), Err: err}
+		}
+
+		order, err := newSortedMapSpec("private"+m.Name+"Order", "int", m.Key, "", funcs)
+		if err != nil {
+			return &SpecError{Kind: OrderedMapKind, Name: m.Name, Spec: m.String(), Err: err}
+		}
+
+		spec := orderedMapSpec{
+			MapTypeName:      m.Name,
+			MapItemTypeName:  m.Name + "Item",
+			MapKeyTypeName:   m.Key,
+			MapValueTypeName: m.Value,
+			entries:          entries,
+			order:            order}
+
+		err = renderContainer(buf, m.Name, m.Methods, func(buf *bytes.Buffer) error {
+			if err := renderTemplates(spec.entries.privateMapTemplates(), spec.entries, buf); err != nil {
+				return err
+			}
+
+			if err := renderTemplates(spec.order.privateMapTemplates(), spec.order, buf); err != nil {
+				return err
+			}
+
+			return renderTemplates([]templateSpec{{name: "ordered_map_template", template: templates.OrderedMapTemplate}}, spec, buf)
+		})
+
+		if err != nil {
+			return &SpecError{Kind: OrderedMapKind, Name: m.Name, Spec: m.String(), Err: err}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,391 @@
+package pedsgen
+
+import (
+	"fmt"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tobgu/peds/internal/templates"
+	"golang.org/x/mod/modfile"
+)
+
+// Each import is either an import path or, for packages imported under another
+// name, name=path. An entry may also hold a list of imports separated by ';', the
+// same way as they are given on the command line.
+
+// splitImports splits imports into their import paths and names.
+func splitImports(imports []string) [][2]string {
+	result := make([][2]string, 0)
+	for _, entry := range imports {
+		for _, imp := range strings.Split(strings.Trim(entry, `"`), ";") {
+			if imp = removeWhiteSpaces(imp); imp == "" {
+				continue
+			}
+
+			name, importPath := "", imp
+			if ix := strings.Index(imp, "="); ix >= 0 {
+				name, importPath = imp[:ix], imp[ix+1:]
+			}
+
+			result = append(result, [2]string{name, importPath})
+		}
+	}
+
+	return result
+}
+
+// splitImportEntries returns imports with one import per entry.
+func splitImportEntries(imports []string) []string {
+	result := make([]string, 0)
+	for _, imp := range splitImports(imports) {
+		if imp[0] != "" {
+			result = append(result, imp[0]+"="+imp[1])
+		} else {
+			result = append(result, imp[1])
+		}
+	}
+
+	return result
+}
+
+// importSpecs returns the imports as they are written in an import declaration.
+func importSpecs(imports []string) []string {
+	result := make([]string, 0)
+	for _, imp := range splitImports(imports) {
+		spec := strconv.Quote(imp[1])
+		if imp[0] != "" {
+			spec = imp[0] + " " + spec
+		}
+
+		result = append(result, spec)
+	}
+
+	return result
+}
+
+func importEntry(name, importPath string) string {
+	if name == packageName(importPath) {
+		return importPath
+	}
+
+	return name + "=" + importPath
+}
+
+var versionSuffixRegex = regexp.MustCompile(`^v[0-9]+$`)
+
+// packageName returns the name that the package with importPath is expected to have,
+// which is the last element of the path ignoring any major version suffix.
+func packageName(importPath string) string {
+	name := path.Base(importPath)
+	if versionSuffixRegex.MatchString(name) && path.Dir(importPath) != "." {
+		name = path.Base(path.Dir(importPath))
+	}
+
+	if ix := strings.Index(name, ".v"); ix > 0 {
+		name = name[:ix]
+	}
+
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+// resolveImports returns the imports of cfg extended with the imports needed by
+// the package qualifiers used in its containers. A qualifier already given in
+// the imports of cfg is left as is. Other qualifiers are looked up, in order, among:
+//   - The imports of the file holding the go:generate directive.
+//   - The packages in the module of the generated file.
+//   - The top level packages of the standard library.
+//
+// Packages that are imported by the generated code anyway are left out, a qualifier
+// with the name of such a package refers to it.
+func resolveImports(cfg Config) ([]string, error) {
+	generated, err := generatedImports(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	imports := make([]string, 0)
+	known := make(map[string]bool)
+	for _, imp := range splitImports(cfg.Imports) {
+		name := imp[0]
+		if name == "" {
+			name = packageName(imp[1])
+		}
+
+		if known[name] {
+			continue
+		}
+
+		known[name] = true
+		if generatedPath, ok := generated[name]; ok {
+			if generatedPath != imp[1] {
+				return nil, fmt.Errorf("import %s conflicts with the import of %s by the generated code", importEntry(name, imp[1]), generatedPath)
+			}
+
+			continue
+		}
+
+		imports = append(imports, importEntry(name, imp[1]))
+	}
+
+	r := &importResolver{file: cfg.File, directiveFile: cfg.DirectiveFile}
+	for _, u := range typeUses(cfg) {
+		for _, q := range typeQualifiers(u.expr) {
+			if known[q] {
+				continue
+			}
+
+			if generatedPath, ok := generated[q]; ok {
+				// Only an import in the directive file can make q refer to another package
+				if importPath, ok := r.directiveImports()[q]; ok && importPath != generatedPath {
+					err := fmt.Errorf("package %s (%s) conflicts with the import of %s by the generated code", q, importPath, generatedPath)
+					return nil, &SpecError{Kind: u.kind, Name: u.name, Spec: u.spec, Err: err}
+				}
+
+				known[q] = true
+				continue
+			}
+
+			importPath, err := r.resolve(q)
+			if err != nil {
+				return nil, &SpecError{Kind: u.kind, Name: u.name, Spec: u.spec, Err: err}
+			}
+
+			known[q] = true
+			imports = append(imports, importEntry(q, importPath))
+		}
+	}
+
+	return imports, nil
+}
+
+// generatedImports returns the paths, by package name, of the packages imported by
+// the generated code of cfg.
+func generatedImports(cfg Config) (map[string]string, error) {
+	paths, err := templateImports(generatedImportDecls(cfg))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string, len(paths))
+	for p := range paths {
+		result[packageName(p)] = p
+	}
+
+	return result, nil
+}
+
+// generatedImportDecls returns the import declarations written to the generated file of cfg
+// in addition to those of the imports of cfg.
+func generatedImportDecls(cfg Config) string {
+	if supportsIterators(cfg) {
+		return templates.CommonImportsTemplate + templates.IterImportsTemplate
+	}
+
+	return templates.CommonImportsTemplate
+}
+
+type importResolver struct {
+	file, directiveFile string
+
+	fileImports    map[string]string
+	modulePackages map[string][]string
+}
+
+// directiveImports returns the imports of the file holding the go:generate directive, by name.
+func (r *importResolver) directiveImports() map[string]string {
+	if r.fileImports == nil {
+		r.fileImports = directiveFileImports(r.directiveFile)
+	}
+
+	return r.fileImports
+}
+
+func (r *importResolver) resolve(q string) (string, error) {
+	if importPath, ok := r.directiveImports()[q]; ok {
+		return importPath, nil
+	}
+
+	if r.modulePackages == nil {
+		r.modulePackages = make(map[string][]string)
+		if r.file != "" {
+			r.modulePackages = modulePackages(filepath.Dir(r.file))
+		}
+	}
+
+	switch candidates := r.modulePackages[q]; len(candidates) {
+	case 0:
+	case 1:
+		return candidates[0], nil
+	default:
+		return "", fmt.Errorf("package %s is ambiguous (%s), add it to -imports", q, strings.Join(candidates, ", "))
+	}
+
+	if p, err := build.Default.Import(q, "", build.FindOnly); err == nil && p.Goroot {
+		return q, nil
+	}
+
+	return "", fmt.Errorf("cannot find package %s, add it to -imports", q)
+}
+
+// directiveFileImports returns the imports of the file holding the go:generate
+// directive, by name.
+func directiveFileImports(file string) map[string]string {
+	result := make(map[string]string)
+	if file == "" {
+		return result
+	}
+
+	f, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.ImportsOnly)
+	if err != nil {
+		return result
+	}
+
+	for _, imp := range f.Imports {
+		importPath, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+
+		name := packageName(importPath)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+
+		result[name] = importPath
+	}
+
+	return result
+}
+
+// modulePackages returns the import paths of the packages, by name, in the module
+// containing dir. dir itself is left out since a package cannot import itself.
+func modulePackages(dir string) map[string][]string {
+	result := make(map[string][]string)
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return result
+	}
+
+	root, data := moduleFile(absDir)
+	if data == nil {
+		return result
+	}
+
+	modulePath := modfile.ModulePath(data)
+	if modulePath == "" {
+		return result
+	}
+
+	filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+
+		name := d.Name()
+		if p != root {
+			if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor" {
+				return filepath.SkipDir
+			}
+
+			if _, err := os.Stat(filepath.Join(p, "go.mod")); err == nil {
+				// Nested module
+				return filepath.SkipDir
+			}
+		}
+
+		if p == absDir {
+			return nil
+		}
+
+		if pkgName := dirPackageName(p); pkgName != "" {
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return nil
+			}
+
+			importPath := path.Join(modulePath, filepath.ToSlash(rel))
+			result[pkgName] = append(result[pkgName], importPath)
+		}
+
+		return nil
+	})
+
+	return result
+}
+
+// moduleFile returns the root directory and the go.mod file of the module containing
+// dir, which must be absolute. A nil file is returned if there is no such module.
+func moduleFile(dir string) (string, []byte) {
+	root := dir
+	for {
+		if _, err := os.Stat(filepath.Join(root, "go.mod")); err == nil {
+			break
+		}
+
+		parent := filepath.Dir(root)
+		if parent == root {
+			return "", nil
+		}
+
+		root = parent
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return "", nil
+	}
+
+	return root, data
+}
+
+// moduleGoVersion returns the Go version in the go directive of the module containing
+// dir, or an empty string if there is no such module or directive.
+func moduleGoVersion(dir string) string {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+
+	_, data := moduleFile(absDir)
+	if data == nil {
+		return ""
+	}
+
+	f, err := modfile.ParseLax("go.mod", data, nil)
+	if err != nil || f.Go == nil {
+		return ""
+	}
+
+	return f.Go.Version
+}
+
+// dirPackageName returns the name of the package in dir, or an empty string
+// if dir does not contain a package.
+func dirPackageName(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+
+		f, err := parser.ParseFile(token.NewFileSet(), filepath.Join(dir, name), nil, parser.PackageClauseOnly)
+		if err == nil && f.Name.Name != "main" {
+			return f.Name.Name
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,138 @@
+package pedsgen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// packageFuncs holds the top level functions declared in the package that the
+// generated code is written to. It is used to verify user supplied functions.
+type packageFuncs map[string]*ast.FuncType
+
+// loadPackageFuncs parses all non test go files in dir, except for the file
+// that is about to be generated, and collects the top level functions.
+func loadPackageFuncs(dir, outputFile string) (packageFuncs, error) {
+	result := make(packageFuncs)
+	if _, err := os.Stat(dir); err != nil {
+		// Nothing to check against, the compiler will have the final say.
+		return result, nil
+	}
+
+	files, err := parsePackageFiles(token.NewFileSet(), dir, outputFile, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil {
+				result[fn.Name.Name] = fn.Type
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// parsePackageFiles parses all non test go files in dir except for outputFile.
+func parsePackageFiles(fset *token.FileSet, dir, outputFile string, mode parser.Mode) ([]*ast.File, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	outputPath, err := filepath.Abs(outputFile)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*ast.File, 0)
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+
+		path, err := filepath.Abs(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		if path == outputPath {
+			continue
+		}
+
+		f, err := parser.ParseFile(fset, path, nil, mode)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Could not parse %s", path)
+		}
+
+		result = append(result, f)
+	}
+
+	return result, nil
+}
+
+// checkSignature verifies that the function name takes the parameters params and
+// returns result. Functions from other packages cannot be verified and are accepted as is.
+func (p packageFuncs) checkSignature(name string, params []string, result string) error {
+	if strings.Contains(name, ".") {
+		return nil
+	}
+
+	expected := fmt.Sprintf("func(%s) %s", strings.Join(params, ", "), result)
+	fn, ok := p[name]
+	if !ok {
+		return fmt.Errorf("function %s not found, expected %s", name, expected)
+	}
+
+	if actual := signature(fn); actual != expected {
+		return fmt.Errorf("function %s has signature %s, expected %s", name, actual, expected)
+	}
+
+	return nil
+}
+
+func signature(fn *ast.FuncType) string {
+	params := fieldTypes(fn.Params)
+	results := fieldTypes(fn.Results)
+	s := fmt.Sprintf("func(%s)", strings.Join(params, ", "))
+	switch len(results) {
+	case 0:
+		return s
+	case 1:
+		return s + " " + results[0]
+	default:
+		return fmt.Sprintf("%s (%s)", s, strings.Join(results, ", "))
+	}
+}
+
+func fieldTypes(fields *ast.FieldList) []string {
+	result := make([]string, 0)
+	if fields == nil {
+		return result
+	}
+
+	for _, field := range fields.List {
+		typ := types.ExprString(field.Type)
+		count := len(field.Names)
+		if count == 0 {
+			count = 1
+		}
+
+		for i := 0; i < count; i++ {
+			result = append(result, typ)
+		}
+	}
+
+	return result
+}
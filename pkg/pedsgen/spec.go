@@ -0,0 +1,706 @@
+package pedsgen
+
+import (
+	"fmt"
+	"go/version"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Kind is a kind of container.
+type Kind string
+
+const (
+	VectorKind     Kind = "vector"
+	RRBVectorKind  Kind = "rrbvector"
+	MapKind        Kind = "map"
+	SetKind        Kind = "set"
+	SortedMapKind  Kind = "sortedmap"
+	SortedSetKind  Kind = "sortedset"
+	OrderedMapKind Kind = "orderedmap"
+	DequeKind      Kind = "deque"
+	HeapKind       Kind = "heap"
+)
+
+type kindInfo struct {
+	title string
+
+	// keyed kinds take a key and a value type, other kinds a single type
+	keyed   bool
+	options []string
+}
+
+// Options available to the different kinds of specifications
+var (
+	vectorOptions = []string{"methods"}
+	mapOptions    = []string{"hash", "eq", "methods"}
+	sortedOptions = []string{"less", "methods"}
+)
+
+var kinds = map[Kind]kindInfo{
+	VectorKind:     {title: "vector", options: vectorOptions},
+	RRBVectorKind:  {title: "RRB vector", options: vectorOptions},
+	MapKind:        {title: "map", keyed: true, options: mapOptions},
+	SetKind:        {title: "set", options: mapOptions},
+	SortedMapKind:  {title: "sorted map", keyed: true, options: sortedOptions},
+	SortedSetKind:  {title: "sorted set", options: sortedOptions},
+	OrderedMapKind: {title: "ordered map", keyed: true, options: mapOptions},
+	DequeKind:      {title: "deque", options: vectorOptions},
+	HeapKind:       {title: "heap", options: sortedOptions},
+}
+
+func (k Kind) title() string {
+	if info, ok := kinds[k]; ok {
+		return info.title
+	}
+
+	return string(k)
+}
+
+// Spec is a container specification, on the form Name<Type1,Type2;option=value>,
+// that is not yet bound to a kind of container. The types can be any non generic
+// Go type expressions, eg. []byte or map[string]func(int) error.
+type Spec struct {
+	Name    string
+	Types   []string
+	Options map[string]string
+}
+
+var (
+	specRegex   = regexp.MustCompile(`^([A-Za-z0-9_]+)<(.+)>$`)
+	optionRegex = regexp.MustCompile(`^([a-z]+)=([A-Za-z0-9_.,]+)$`)
+)
+
+// ParseSpec parses a specification on the form Name<Type1,Type2;option=value>.
+// The types are returned in canonical form, eg. "map[string] int" is returned
+// as "map[string]int".
+func ParseSpec(s string) (Spec, error) {
+	m := specRegex.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return Spec{}, errors.New("expected Name<Type> or Name<Type;option=value>")
+	}
+
+	parts := splitTopLevel(m[2], ';')
+	types := splitTopLevel(parts[0], ',')
+	for i, t := range types {
+		typ, err := parseType(strings.TrimSpace(t))
+		if err != nil {
+			return Spec{}, err
+		}
+
+		types[i] = typ
+	}
+
+	options := make(map[string]string)
+	for _, o := range parts[1:] {
+		o = strings.TrimSpace(o)
+		om := optionRegex.FindStringSubmatch(o)
+		if om == nil {
+			return Spec{}, fmt.Errorf("invalid option %q, expected option=value", o)
+		}
+
+		if _, ok := options[om[1]]; ok {
+			return Spec{}, fmt.Errorf("option %q given more than once", om[1])
+		}
+
+		options[om[1]] = om[2]
+	}
+
+	return Spec{Name: m[1], Types: types, Options: options}, nil
+}
+
+// String returns the specification with its options in a fixed order.
+func (s Spec) String() string {
+	names := make([]string, 0, len(s.Options))
+	for name := range s.Options {
+		names = append(names, name)
+	}
+
+	// The function options come first, in the order they are documented
+	rank := func(name string) int {
+		for i, o := range []string{"hash", "eq", "less"} {
+			if name == o {
+				return i
+			}
+		}
+
+		return 3
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		if ri, rj := rank(names[i]), rank(names[j]); ri != rj {
+			return ri < rj
+		}
+
+		return names[i] < names[j]
+	})
+
+	parts := []string{strings.Join(s.Types, ",")}
+	for _, name := range names {
+		parts = append(parts, name+"="+s.Options[name])
+	}
+
+	return fmt.Sprintf("%s<%s>", s.Name, strings.Join(parts, ";"))
+}
+
+// splitSpecs splits a list of container specifications separated by ';'. A ';' within
+// the angle brackets of a specification instead separates the options of that specification.
+func splitSpecs(specs string) []string {
+	// Quotes may be left around the list when the arguments are quoted in a go:generate line
+	specs = strings.Trim(strings.TrimSpace(specs), `"`)
+	if strings.TrimSpace(specs) == "" {
+		return nil
+	}
+
+	return splitTopLevel(specs, ';')
+}
+
+// Parse parses a list of specifications separated by ';', the same way as they are
+// given on the command line, and adds them to c as containers of kind.
+func (c *Config) Parse(kind Kind, specs string) error {
+	for _, d := range splitSpecs(specs) {
+		s, err := ParseSpec(d)
+		if err == nil {
+			err = c.add(kind, s)
+		}
+
+		if err != nil {
+			return &SpecError{Kind: kind, Name: s.Name, Spec: strings.TrimSpace(d), Err: err}
+		}
+	}
+
+	return nil
+}
+
+// Add adds the container s of kind to c.
+func (c *Config) Add(kind Kind, s Spec) error {
+	if err := c.add(kind, s); err != nil {
+		return &SpecError{Kind: kind, Name: s.Name, Spec: s.String(), Err: err}
+	}
+
+	return nil
+}
+
+func (c *Config) add(kind Kind, s Spec) error {
+	info, ok := kinds[kind]
+	if !ok {
+		return fmt.Errorf("unknown container kind %q", kind)
+	}
+
+	typeCount := 1
+	if info.keyed {
+		typeCount = 2
+	}
+
+	if len(s.Types) != typeCount {
+		return fmt.Errorf("expected %d type(s), got %d", typeCount, len(s.Types))
+	}
+
+	for name := range s.Options {
+		if !containsString(info.options, name) {
+			return fmt.Errorf("unknown option %q", name)
+		}
+	}
+
+	var err error
+	o := s.Options
+	methods := splitMethods(o["methods"])
+	switch kind {
+	case VectorKind, RRBVectorKind, DequeKind:
+		var v VectorSpec
+		if v, err = (VectorSpec{Name: s.Name, Type: s.Types[0], Methods: methods}).normalize(); err == nil {
+			switch kind {
+			case VectorKind:
+				c.Vectors = append(c.Vectors, v)
+			case RRBVectorKind:
+				c.RRBVectors = append(c.RRBVectors, v)
+			default:
+				c.Deques = append(c.Deques, v)
+			}
+		}
+	case MapKind, OrderedMapKind:
+		var m MapSpec
+		if m, err = (MapSpec{Name: s.Name, Key: s.Types[0], Value: s.Types[1], Hash: o["hash"], Eq: o["eq"], Methods: methods}).normalize(); err == nil {
+			if kind == MapKind {
+				c.Maps = append(c.Maps, m)
+			} else {
+				c.OrderedMaps = append(c.OrderedMaps, m)
+			}
+		}
+	case SetKind:
+		var set SetSpec
+		if set, err = (SetSpec{Name: s.Name, Type: s.Types[0], Hash: o["hash"], Eq: o["eq"], Methods: methods}).normalize(); err == nil {
+			c.Sets = append(c.Sets, set)
+		}
+	case SortedMapKind:
+		var m SortedMapSpec
+		if m, err = (SortedMapSpec{Name: s.Name, Key: s.Types[0], Value: s.Types[1], Less: o["less"], Methods: methods}).normalize(); err == nil {
+			c.SortedMaps = append(c.SortedMaps, m)
+		}
+	case SortedSetKind:
+		var set SortedSetSpec
+		if set, err = (SortedSetSpec{Name: s.Name, Type: s.Types[0], Less: o["less"], Methods: methods}).normalize(); err == nil {
+			c.SortedSets = append(c.SortedSets, set)
+		}
+	case HeapKind:
+		var h HeapSpec
+		if h, err = (HeapSpec{Name: s.Name, Type: s.Types[0], Less: o["less"], Methods: methods}).normalize(); err == nil {
+			c.Heaps = append(c.Heaps, h)
+		}
+	}
+
+	return err
+}
+
+// normalize validates c and returns it with all types in canonical form.
+func (c Config) normalize() (Config, error) {
+	pkg, err := checkPackage(c.Package)
+	if err != nil {
+		return Config{}, err
+	}
+
+	result := c
+	result.Package = pkg
+	result.Imports = splitImportEntries(c.Imports)
+	if result.GoVersion, err = checkGoVersion(c.GoVersion); err != nil {
+		return Config{}, err
+	}
+
+	specError := func(kind Kind, s fmt.Stringer, name string, err error) error {
+		return &SpecError{Kind: kind, Name: name, Spec: s.String(), Err: err}
+	}
+
+	result.Vectors = make([]VectorSpec, len(c.Vectors))
+	for i, s := range c.Vectors {
+		v, err := s.normalize()
+		if err != nil {
+			return Config{}, specError(VectorKind, s, s.Name, err)
+		}
+
+		result.Vectors[i] = v
+	}
+
+	result.RRBVectors = make([]VectorSpec, len(c.RRBVectors))
+	for i, s := range c.RRBVectors {
+		v, err := s.normalize()
+		if err != nil {
+			return Config{}, specError(RRBVectorKind, s, s.Name, err)
+		}
+
+		result.RRBVectors[i] = v
+	}
+
+	result.Maps = make([]MapSpec, len(c.Maps))
+	for i, s := range c.Maps {
+		m, err := s.normalize()
+		if err != nil {
+			return Config{}, specError(MapKind, s, s.Name, err)
+		}
+
+		result.Maps[i] = m
+	}
+
+	result.Sets = make([]SetSpec, len(c.Sets))
+	for i, s := range c.Sets {
+		set, err := s.normalize()
+		if err != nil {
+			return Config{}, specError(SetKind, s, s.Name, err)
+		}
+
+		result.Sets[i] = set
+	}
+
+	result.SortedMaps = make([]SortedMapSpec, len(c.SortedMaps))
+	for i, s := range c.SortedMaps {
+		m, err := s.normalize()
+		if err != nil {
+			return Config{}, specError(SortedMapKind, s, s.Name, err)
+		}
+
+		result.SortedMaps[i] = m
+	}
+
+	result.SortedSets = make([]SortedSetSpec, len(c.SortedSets))
+	for i, s := range c.SortedSets {
+		set, err := s.normalize()
+		if err != nil {
+			return Config{}, specError(SortedSetKind, s, s.Name, err)
+		}
+
+		result.SortedSets[i] = set
+	}
+
+	result.OrderedMaps = make([]MapSpec, len(c.OrderedMaps))
+	for i, s := range c.OrderedMaps {
+		m, err := s.normalize()
+		if err != nil {
+			return Config{}, specError(OrderedMapKind, s, s.Name, err)
+		}
+
+		result.OrderedMaps[i] = m
+	}
+
+	result.Deques = make([]VectorSpec, len(c.Deques))
+	for i, s := range c.Deques {
+		d, err := s.normalize()
+		if err != nil {
+			return Config{}, specError(DequeKind, s, s.Name, err)
+		}
+
+		result.Deques[i] = d
+	}
+
+	result.Heaps = make([]HeapSpec, len(c.Heaps))
+	for i, s := range c.Heaps {
+		h, err := s.normalize()
+		if err != nil {
+			return Config{}, specError(HeapKind, s, s.Name, err)
+		}
+
+		result.Heaps[i] = h
+	}
+
+	return result, nil
+}
+
+//////////////////////
+/// Specifications ///
+//////////////////////
+
+// All specifications take an optional list of Methods to generate. Each entry is
+// either the name of a method or one of the profiles MinimalMethods, StandardMethods
+// and FullMethods. Methods needed by the selected methods are also generated,
+// as are the constructors. If no methods are given all methods are generated.
+
+// VectorSpec describes a vector, an RRB vector or a deque with elements of Type.
+type VectorSpec struct {
+	Name    string
+	Type    string
+	Methods []string
+}
+
+func (s VectorSpec) String() string {
+	return Spec{Name: s.Name, Types: []string{s.Type}, Options: options("methods", joinMethods(s.Methods))}.String()
+}
+
+func (s VectorSpec) normalize() (VectorSpec, error) {
+	if err := checkName(s.Name); err != nil {
+		return VectorSpec{}, err
+	}
+
+	typ, err := parseType(s.Type)
+	if err != nil {
+		return VectorSpec{}, err
+	}
+
+	methods, err := normalizeMethods(s.Methods)
+	if err != nil {
+		return VectorSpec{}, err
+	}
+
+	return VectorSpec{Name: s.Name, Type: typ, Methods: methods}, nil
+}
+
+// MapSpec describes a map, or an ordered map, from Key to Value. Hash and Eq are
+// optional functions used to hash and compare keys, an Eq function requires a Hash function.
+type MapSpec struct {
+	Name    string
+	Key     string
+	Value   string
+	Hash    string
+	Eq      string
+	Methods []string
+}
+
+func (s MapSpec) String() string {
+	return Spec{Name: s.Name, Types: []string{s.Key, s.Value}, Options: options("hash", s.Hash, "eq", s.Eq, "methods", joinMethods(s.Methods))}.String()
+}
+
+func (s MapSpec) normalize() (MapSpec, error) {
+	if err := checkName(s.Name); err != nil {
+		return MapSpec{}, err
+	}
+
+	key, err := parseKeyType(s.Key)
+	if err != nil {
+		return MapSpec{}, err
+	}
+
+	value, err := parseType(s.Value)
+	if err != nil {
+		return MapSpec{}, err
+	}
+
+	if err := checkHashFuncs(s.Hash, s.Eq); err != nil {
+		return MapSpec{}, err
+	}
+
+	methods, err := normalizeMethods(s.Methods)
+	if err != nil {
+		return MapSpec{}, err
+	}
+
+	return MapSpec{Name: s.Name, Key: key, Value: value, Hash: s.Hash, Eq: s.Eq, Methods: methods}, nil
+}
+
+// SetSpec describes a set with elements of Type. Hash and Eq are optional functions
+// used to hash and compare elements, an Eq function requires a Hash function.
+type SetSpec struct {
+	Name    string
+	Type    string
+	Hash    string
+	Eq      string
+	Methods []string
+}
+
+func (s SetSpec) String() string {
+	return Spec{Name: s.Name, Types: []string{s.Type}, Options: options("hash", s.Hash, "eq", s.Eq, "methods", joinMethods(s.Methods))}.String()
+}
+
+func (s SetSpec) normalize() (SetSpec, error) {
+	if err := checkName(s.Name); err != nil {
+		return SetSpec{}, err
+	}
+
+	typ, err := parseKeyType(s.Type)
+	if err != nil {
+		return SetSpec{}, err
+	}
+
+	if err := checkHashFuncs(s.Hash, s.Eq); err != nil {
+		return SetSpec{}, err
+	}
+
+	methods, err := normalizeMethods(s.Methods)
+	if err != nil {
+		return SetSpec{}, err
+	}
+
+	return SetSpec{Name: s.Name, Type: typ, Hash: s.Hash, Eq: s.Eq, Methods: methods}, nil
+}
+
+// SortedMapSpec describes a sorted map from Key to Value. Less is an optional function
+// used to order keys, it is required for keys that cannot be ordered using <.
+type SortedMapSpec struct {
+	Name    string
+	Key     string
+	Value   string
+	Less    string
+	Methods []string
+}
+
+func (s SortedMapSpec) String() string {
+	return Spec{Name: s.Name, Types: []string{s.Key, s.Value}, Options: options("less", s.Less, "methods", joinMethods(s.Methods))}.String()
+}
+
+func (s SortedMapSpec) normalize() (SortedMapSpec, error) {
+	if err := checkName(s.Name); err != nil {
+		return SortedMapSpec{}, err
+	}
+
+	key, err := parseOrderedKeyType(s.Key, s.Less)
+	if err != nil {
+		return SortedMapSpec{}, err
+	}
+
+	value, err := parseType(s.Value)
+	if err != nil {
+		return SortedMapSpec{}, err
+	}
+
+	methods, err := normalizeMethods(s.Methods)
+	if err != nil {
+		return SortedMapSpec{}, err
+	}
+
+	return SortedMapSpec{Name: s.Name, Key: key, Value: value, Less: s.Less, Methods: methods}, nil
+}
+
+// SortedSetSpec describes a sorted set with elements of Type. Less is an optional
+// function used to order elements, it is required for types that cannot be ordered using <.
+type SortedSetSpec struct {
+	Name    string
+	Type    string
+	Less    string
+	Methods []string
+}
+
+func (s SortedSetSpec) String() string {
+	return Spec{Name: s.Name, Types: []string{s.Type}, Options: options("less", s.Less, "methods", joinMethods(s.Methods))}.String()
+}
+
+func (s SortedSetSpec) normalize() (SortedSetSpec, error) {
+	if err := checkName(s.Name); err != nil {
+		return SortedSetSpec{}, err
+	}
+
+	typ, err := parseOrderedKeyType(s.Type, s.Less)
+	if err != nil {
+		return SortedSetSpec{}, err
+	}
+
+	methods, err := normalizeMethods(s.Methods)
+	if err != nil {
+		return SortedSetSpec{}, err
+	}
+
+	return SortedSetSpec{Name: s.Name, Type: typ, Less: s.Less, Methods: methods}, nil
+}
+
+// HeapSpec describes a heap with elements of Type. Less is an optional function used to
+// order elements, it is required for types that cannot be ordered using <.
+type HeapSpec struct {
+	Name    string
+	Type    string
+	Less    string
+	Methods []string
+}
+
+func (s HeapSpec) String() string {
+	return Spec{Name: s.Name, Types: []string{s.Type}, Options: options("less", s.Less, "methods", joinMethods(s.Methods))}.String()
+}
+
+func (s HeapSpec) normalize() (HeapSpec, error) {
+	if err := checkName(s.Name); err != nil {
+		return HeapSpec{}, err
+	}
+
+	typ, err := parseType(s.Type)
+	if err != nil {
+		return HeapSpec{}, err
+	}
+
+	if err := checkFuncName(s.Less); err != nil {
+		return HeapSpec{}, err
+	}
+
+	if s.Less == "" && !isOrdered(typeExpr(typ)) {
+		return HeapSpec{}, fmt.Errorf("element type %s is not ordered, a less function is required", typ)
+	}
+
+	methods, err := normalizeMethods(s.Methods)
+	if err != nil {
+		return HeapSpec{}, err
+	}
+
+	return HeapSpec{Name: s.Name, Type: typ, Less: s.Less, Methods: methods}, nil
+}
+
+///////////////
+/// Helpers ///
+///////////////
+
+var (
+	identifierRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	funcNameRegex   = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*\.)?[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+func checkPackage(pkg string) (string, error) {
+	pkg = strings.TrimSpace(pkg)
+	if pkg == "" {
+		return "", errors.New("pkg is required")
+	}
+
+	if !identifierRegex.MatchString(pkg) {
+		return "", fmt.Errorf("package %q is not a valid package name", pkg)
+	}
+
+	return pkg, nil
+}
+
+// checkGoVersion returns goVersion, eg. 1.23 or go1.23, without the go prefix.
+func checkGoVersion(goVersion string) (string, error) {
+	goVersion = strings.TrimPrefix(strings.TrimSpace(goVersion), "go")
+	if goVersion != "" && !version.IsValid("go"+goVersion) {
+		return "", fmt.Errorf("%q is not a valid Go version", goVersion)
+	}
+
+	return goVersion, nil
+}
+
+func checkName(name string) error {
+	if !identifierRegex.MatchString(name) {
+		return fmt.Errorf("name %q is not a valid type name", name)
+	}
+
+	return nil
+}
+
+func checkFuncName(name string) error {
+	if name != "" && !funcNameRegex.MatchString(name) {
+		return fmt.Errorf("%q is not a valid function name", name)
+	}
+
+	return nil
+}
+
+func checkHashFuncs(hash, eq string) error {
+	if err := checkFuncName(hash); err != nil {
+		return err
+	}
+
+	if err := checkFuncName(eq); err != nil {
+		return err
+	}
+
+	if eq != "" && hash == "" {
+		return errors.New("a custom eq function requires a custom hash function")
+	}
+
+	return nil
+}
+
+func parseKeyType(s string) (string, error) {
+	typ, err := parseType(s)
+	if err != nil {
+		return "", err
+	}
+
+	return typ, checkKeyType(typ)
+}
+
+func parseOrderedKeyType(s, less string) (string, error) {
+	typ, err := parseKeyType(s)
+	if err != nil {
+		return "", err
+	}
+
+	if err := checkFuncName(less); err != nil {
+		return "", err
+	}
+
+	if less == "" {
+		return typ, checkOrderedKeyType(typ)
+	}
+
+	return typ, nil
+}
+
+// options returns the non empty options given as name, value pairs.
+func options(nameValues ...string) map[string]string {
+	result := make(map[string]string)
+	for i := 0; i < len(nameValues); i += 2 {
+		if nameValues[i+1] != "" {
+			result[nameValues[i]] = nameValues[i+1]
+		}
+	}
+
+	return result
+}
+
+func removeWhiteSpaces(s string) string {
+	return strings.Join(strings.Fields(s), "")
+}
+
+func containsString(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,433 @@
+package pedsgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/packages"
+)
+
+// typeUse is a use of a type, or function, in a container specification that is
+// verified against the package that the containers are generated into.
+type typeUse struct {
+	kind Kind
+	name string
+	spec string
+
+	// expr is either a type or, if funcType is set, a function of that type
+	expr     string
+	funcType string
+
+	comparable bool
+	ordered    bool
+
+	// hashKey is set for keys that may also be containers, or other types, with Hash
+	// and Equals methods
+	hashKey bool
+}
+
+func (u typeUse) declaration() string {
+	if u.funcType != "" {
+		return fmt.Sprintf("var _ %s = %s", u.funcType, u.expr)
+	}
+
+	return fmt.Sprintf("var _ %s", u.expr)
+}
+
+func (u typeUse) error(err error) error {
+	return &SpecError{Kind: u.kind, Name: u.name, Spec: u.spec, Err: err}
+}
+
+// typeUses returns the types and functions used in the containers of cfg.
+func typeUses(cfg Config) []typeUse {
+	result := make([]typeUse, 0)
+	add := func(kind Kind, s Spec) {
+		info := kinds[kind]
+		_, customLess := s.Options["less"]
+		for i, typ := range s.Types {
+			isKey := info.keyed && i == 0 || kind == SetKind || kind == SortedSetKind
+			result = append(result, typeUse{
+				kind:       kind,
+				name:       s.Name,
+				spec:       s.String(),
+				expr:       typ,
+				comparable: isKey,
+				ordered:    (isKey && (kind == SortedMapKind || kind == SortedSetKind) || kind == HeapKind) && !customLess,
+				hashKey:    isKey && (kind == MapKind || kind == SetKind)})
+		}
+
+		key := s.Types[0]
+		funcTypes := map[string]string{
+			"hash": fmt.Sprintf("func(%s) uint32", key),
+			"eq":   fmt.Sprintf("func(%s, %s) bool", key, key),
+			"less": fmt.Sprintf("func(%s, %s) bool", key, key)}
+
+		for _, name := range []string{"hash", "eq", "less"} {
+			if f, ok := s.Options[name]; ok {
+				result = append(result, typeUse{kind: kind, name: s.Name, spec: s.String(), expr: f, funcType: funcTypes[name]})
+			}
+		}
+	}
+
+	for _, s := range cfg.Vectors {
+		add(VectorKind, Spec{Name: s.Name, Types: []string{s.Type}})
+	}
+
+	for _, s := range cfg.RRBVectors {
+		add(RRBVectorKind, Spec{Name: s.Name, Types: []string{s.Type}})
+	}
+
+	for _, s := range cfg.Maps {
+		add(MapKind, Spec{Name: s.Name, Types: []string{s.Key, s.Value}, Options: options("hash", s.Hash, "eq", s.Eq)})
+	}
+
+	for _, s := range cfg.Sets {
+		add(SetKind, Spec{Name: s.Name, Types: []string{s.Type}, Options: options("hash", s.Hash, "eq", s.Eq)})
+	}
+
+	for _, s := range cfg.SortedMaps {
+		add(SortedMapKind, Spec{Name: s.Name, Types: []string{s.Key, s.Value}, Options: options("less", s.Less)})
+	}
+
+	for _, s := range cfg.SortedSets {
+		add(SortedSetKind, Spec{Name: s.Name, Types: []string{s.Type}, Options: options("less", s.Less)})
+	}
+
+	for _, s := range cfg.OrderedMaps {
+		add(OrderedMapKind, Spec{Name: s.Name, Types: []string{s.Key, s.Value}, Options: options("hash", s.Hash, "eq", s.Eq)})
+	}
+
+	for _, s := range cfg.Deques {
+		add(DequeKind, Spec{Name: s.Name, Types: []string{s.Type}})
+	}
+
+	for _, s := range cfg.Heaps {
+		add(HeapKind, Spec{Name: s.Name, Types: []string{s.Type}, Options: options("less", s.Less)})
+	}
+
+	return result
+}
+
+// typeTrait tells how values of a type are hashed and compared by the generated code.
+type typeTrait int
+
+const (
+	// unknownType values are compared using ==, the compiler rejects the generated code
+	// if they turn out not to be comparable
+	unknownType typeTrait = iota
+
+	comparableType
+
+	// hashableType values are compared and hashed using their Equals and Hash methods
+	hashableType
+
+	// uncomparableType values cannot be compared, containers holding them have no Hash
+	// and Equals methods
+	uncomparableType
+)
+
+// typeTraits holds the traits of the types used by containers, by type expression.
+type typeTraits map[string]typeTrait
+
+func (t typeTraits) of(typ string) typeTrait {
+	if hashFunc(typ) != "interfaceHash" || typ == "struct{}" {
+		return comparableType
+	}
+
+	return t[typ]
+}
+
+// hashableContainers returns the names of the containers in cfg that have Hash and
+// Equals methods.
+func hashableContainers(cfg Config) []string {
+	result := make([]string, 0)
+	for _, v := range cfg.Vectors {
+		result = append(result, v.Name, v.Name+"Slice")
+	}
+
+	for _, m := range cfg.Maps {
+		result = append(result, m.Name)
+	}
+
+	for _, s := range cfg.Sets {
+		result = append(result, s.Name)
+	}
+
+	return result
+}
+
+// containerStubs returns declarations standing in for the containers generated from
+// cfg while type checking. The stubs are not comparable, those of containers with
+// Hash and Equals methods have such methods as well.
+func containerStubs(cfg Config) string {
+	hashable := hashableContainers(cfg)
+	buf := &bytes.Buffer{}
+	for _, name := range hashable {
+		fmt.Fprintf(buf, "type %s struct{ _ []byte }\n", name)
+		fmt.Fprintf(buf, "func (*%s) Hash() uint32 { return 0 }\n", name)
+		fmt.Fprintf(buf, "func (*%s) Equals(*%s) bool { return false }\n", name, name)
+	}
+
+	var others []string
+	for _, vs := range [][]VectorSpec{cfg.RRBVectors, cfg.Deques} {
+		for _, v := range vs {
+			others = append(others, v.Name)
+		}
+	}
+
+	for _, m := range cfg.SortedMaps {
+		others = append(others, m.Name)
+	}
+
+	for _, s := range cfg.SortedSets {
+		others = append(others, s.Name)
+	}
+
+	for _, m := range cfg.OrderedMaps {
+		others = append(others, m.Name)
+	}
+
+	for _, h := range cfg.Heaps {
+		others = append(others, h.Name)
+	}
+
+	for _, name := range others {
+		fmt.Fprintf(buf, "type %s struct{ _ []byte }\n", name)
+	}
+
+	return buf.String()
+}
+
+// resolveContainerTraits marks the vectors and maps in cfg holding elements, or values,
+// that cannot be compared as not comparable either since they have no Hash and Equals
+// methods. Containers holding those are marked in turn.
+func resolveContainerTraits(cfg Config, traits typeTraits) {
+	for changed := true; changed; {
+		changed = false
+		mark := func(typ string, names ...string) {
+			if traits.of(typ) != uncomparableType || traits[names[0]] == uncomparableType {
+				return
+			}
+
+			for _, name := range names {
+				traits[name] = uncomparableType
+			}
+
+			changed = true
+		}
+
+		for _, v := range cfg.Vectors {
+			mark(v.Type, v.Name, v.Name+"Slice")
+		}
+
+		for _, m := range cfg.Maps {
+			mark(m.Value, m.Name)
+		}
+	}
+}
+
+// typeCheck verifies that the types and functions used by the containers in cfg
+// exist in the package that they are generated into, and that keys can be used
+// as keys. The package is loaded with the output file replaced by declarations
+// using each of the types, and stubs for the containers in cfg. If the package
+// cannot be loaded, eg. since it is not part of a module, no checks are made and
+// the compiler will have the final say.
+//
+// The traits of the types used are returned. Only the containers in cfg, basic types
+// and types that are not comparable by their expression alone, eg. slices, are known
+// if the package cannot be loaded.
+func typeCheck(cfg Config) (typeTraits, error) {
+	traits := make(typeTraits)
+	for _, name := range hashableContainers(cfg) {
+		traits[name] = hashableType
+	}
+
+	uses := typeUses(cfg)
+	for _, u := range uses {
+		if u.funcType == "" && !isComparable(typeExpr(u.expr)) {
+			traits[u.expr] = uncomparableType
+		}
+	}
+
+	resolveContainerTraits(cfg, traits)
+	for _, u := range uses {
+		if u.comparable && traits.of(u.expr) == uncomparableType {
+			return nil, u.error(fmt.Errorf("key type %s is not comparable", u.expr))
+		}
+	}
+
+	if len(uses) == 0 || cfg.File == "" {
+		return traits, nil
+	}
+
+	file, err := filepath.Abs(cfg.File)
+	if err != nil {
+		return nil, err
+	}
+
+	src := &bytes.Buffer{}
+	fmt.Fprintf(src, "package %s\n\n", cfg.Package)
+	for _, imp := range importSpecs(cfg.Imports) {
+		fmt.Fprintf(src, "import %s\n", imp)
+	}
+
+	// Unused imports are soft errors which are ignored below
+	src.WriteString(generatedImportDecls(cfg))
+
+	// Each use is declared on a line of its own, starting at firstLine
+	firstLine := strings.Count(src.String(), "\n") + 1
+	for _, u := range uses {
+		src.WriteString(u.declaration() + "\n")
+	}
+
+	src.WriteString(containerStubs(cfg))
+
+	loadCfg := &packages.Config{
+		Mode:    packages.NeedName | packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo,
+		Dir:     filepath.Dir(file),
+		Overlay: map[string][]byte{file: src.Bytes()}}
+
+	pkgs, err := packages.Load(loadCfg, ".")
+	if err != nil || len(pkgs) != 1 || pkgs[0].Types == nil || pkgs[0].TypesInfo == nil {
+		return traits, nil
+	}
+
+	pkg := pkgs[0]
+	for _, e := range pkg.Errors {
+		line, ok := lineInFile(e.Pos, file)
+		if !ok {
+			// Errors in other files are left for the compiler
+			continue
+		}
+
+		if tErr, ok := typeError(pkg, e); ok && tErr.Soft {
+			// Unused imports
+			continue
+		}
+
+		if ix := line - firstLine; ix >= 0 && ix < len(uses) {
+			return nil, uses[ix].error(errors.New(e.Msg))
+		}
+
+		return nil, fmt.Errorf("Invalid imports: %s", e.Msg)
+	}
+
+	return traits, checkKeys(pkg, file, cfg, uses, traits)
+}
+
+// checkKeys verifies that the keys in uses can be used as keys, and records the traits
+// of the types used in traits.
+func checkKeys(pkg *packages.Package, file string, cfg Config, uses []typeUse, traits typeTraits) error {
+	var f *ast.File
+	for _, s := range pkg.Syntax {
+		if pkg.Fset.Position(s.Pos()).Filename == file {
+			f = s
+		}
+	}
+
+	if f == nil {
+		return nil
+	}
+
+	// The types of uses, in order, nil for types that could not be resolved
+	useTypes := make([]types.Type, 0, len(uses))
+	for _, decl := range f.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.VAR {
+			useTypes = append(useTypes, pkg.TypesInfo.TypeOf(gen.Specs[0].(*ast.ValueSpec).Type))
+		}
+	}
+
+	for i, typ := range useTypes {
+		if typ == nil || uses[i].funcType != "" {
+			continue
+		}
+
+		switch {
+		case hasHashMethods(typ):
+			traits[uses[i].expr] = hashableType
+		case types.Comparable(typ):
+			traits[uses[i].expr] = comparableType
+		default:
+			traits[uses[i].expr] = uncomparableType
+		}
+	}
+
+	// Containers found to hold values that are not comparable lose their Hash and Equals
+	// methods, that the stubs have
+	resolveContainerTraits(cfg, traits)
+	for i, typ := range useTypes {
+		u := uses[i]
+		if typ == nil {
+			continue
+		}
+
+		if u.comparable && !types.Comparable(typ) && !(u.hashKey && traits[u.expr] == hashableType) {
+			return u.error(fmt.Errorf("key type %s is not comparable", u.expr))
+		}
+
+		if u.ordered && !isOrderedType(typ) {
+			role := "key"
+			if u.kind == HeapKind {
+				role = "element"
+			}
+
+			return u.error(fmt.Errorf("%s type %s is not ordered, a less function is required", role, u.expr))
+		}
+	}
+
+	return nil
+}
+
+// hasHashMethods returns true if pointers to typ have the methods Hash() uint32 and
+// Equals(*typ) bool, as the vectors, slices, maps and sets do.
+func hasHashMethods(typ types.Type) bool {
+	ptr := types.NewPointer(typ)
+	methods := types.NewMethodSet(ptr)
+	signature := func(name string) *types.Signature {
+		sel := methods.Lookup(nil, name)
+		if sel == nil {
+			return nil
+		}
+
+		return sel.Type().(*types.Signature)
+	}
+
+	hash, equals := signature("Hash"), signature("Equals")
+	return hash != nil && hash.Params().Len() == 0 && hash.Results().Len() == 1 &&
+		types.Identical(hash.Results().At(0).Type(), types.Typ[types.Uint32]) &&
+		equals != nil && equals.Params().Len() == 1 && types.Identical(equals.Params().At(0).Type(), ptr) &&
+		equals.Results().Len() == 1 && types.Identical(equals.Results().At(0).Type(), types.Typ[types.Bool])
+}
+
+func isOrderedType(typ types.Type) bool {
+	basic, ok := typ.Underlying().(*types.Basic)
+	return ok && basic.Info()&types.IsOrdered != 0
+}
+
+// lineInFile returns the line of a "file:line:column" position if it is within file.
+func lineInFile(pos, file string) (int, bool) {
+	rest := strings.TrimPrefix(pos, file+":")
+	if rest == pos {
+		return 0, false
+	}
+
+	line, err := strconv.Atoi(strings.Split(rest, ":")[0])
+	return line, err == nil
+}
+
+func typeError(pkg *packages.Package, e packages.Error) (types.Error, bool) {
+	for _, tErr := range pkg.TypeErrors {
+		if tErr.Msg == e.Msg && tErr.Fset.Position(tErr.Pos).String() == e.Pos {
+			return tErr, true
+		}
+	}
+
+	return types.Error{}, false
+}
@@ -0,0 +1,215 @@
+package pedsgen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// parseType parses s as a Go type expression and returns it in canonical form,
+// eg. "map[string] int" is returned as "map[string]int".
+func parseType(s string) (string, error) {
+	expr, err := parser.ParseExpr(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid type %q", s)
+	}
+
+	if err := checkTypeExpr(expr); err != nil {
+		return "", fmt.Errorf("invalid type %q: %s", s, err)
+	}
+
+	return types.ExprString(expr), nil
+}
+
+// checkTypeExpr verifies that expr is a type expression that can be used as the
+// type of a container element.
+func checkTypeExpr(expr ast.Expr) error {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return nil
+	case *ast.SelectorExpr:
+		if _, ok := e.X.(*ast.Ident); ok {
+			return nil
+		}
+	case *ast.ParenExpr:
+		return checkTypeExpr(e.X)
+	case *ast.StarExpr:
+		return checkTypeExpr(e.X)
+	case *ast.ArrayType:
+		if e.Len != nil {
+			if _, ok := e.Len.(*ast.Ellipsis); ok {
+				return fmt.Errorf("array length must be given")
+			}
+		}
+
+		return checkTypeExpr(e.Elt)
+	case *ast.MapType:
+		if err := checkTypeExpr(e.Key); err != nil {
+			return err
+		}
+
+		if !isComparable(e.Key) {
+			return fmt.Errorf("map key type %s is not comparable", types.ExprString(e.Key))
+		}
+
+		return checkTypeExpr(e.Value)
+	case *ast.ChanType:
+		return checkTypeExpr(e.Value)
+	case *ast.FuncType:
+		if e.TypeParams != nil {
+			return fmt.Errorf("type parameters are not supported")
+		}
+
+		return checkFieldTypes(e.Params, e.Results)
+	case *ast.StructType:
+		return checkFieldTypes(e.Fields)
+	case *ast.InterfaceType:
+		return nil
+	case *ast.IndexExpr, *ast.IndexListExpr:
+		return fmt.Errorf("generic types are not supported")
+	}
+
+	return fmt.Errorf("%s is not a type", types.ExprString(expr))
+}
+
+func checkFieldTypes(lists ...*ast.FieldList) error {
+	for _, list := range lists {
+		if list == nil {
+			continue
+		}
+
+		for _, field := range list.List {
+			typ := field.Type
+			if ellipsis, ok := typ.(*ast.Ellipsis); ok {
+				typ = ellipsis.Elt
+			}
+
+			if err := checkTypeExpr(typ); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// isComparable returns false if expr is a type that cannot be compared using ==.
+// Named types cannot be resolved here and are assumed to be comparable.
+func isComparable(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return isComparable(e.X)
+	case *ast.ArrayType:
+		return e.Len != nil && isComparable(e.Elt)
+	case *ast.MapType, *ast.FuncType:
+		return false
+	case *ast.StructType:
+		for _, field := range e.Fields.List {
+			if !isComparable(field.Type) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// isOrdered returns false if expr is a type that cannot be ordered using <.
+// Named types cannot be resolved here and are assumed to be ordered.
+func isOrdered(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name != "bool" && e.Name != "error" && e.Name != "any"
+	case *ast.SelectorExpr:
+		return true
+	case *ast.ParenExpr:
+		return isOrdered(e.X)
+	}
+
+	return false
+}
+
+// typeExpr returns the expression of a type previously validated by parseType.
+func typeExpr(typ string) ast.Expr {
+	expr, err := parser.ParseExpr(typ)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid type %s", typ))
+	}
+
+	return expr
+}
+
+// checkKeyType verifies that typ can be used as the key of a map or set.
+func checkKeyType(typ string) error {
+	if !isComparable(typeExpr(typ)) {
+		return fmt.Errorf("key type %s is not comparable", typ)
+	}
+
+	return nil
+}
+
+// checkOrderedKeyType verifies that typ can be used as the key of a sorted map or
+// set without a custom less function.
+func checkOrderedKeyType(typ string) error {
+	if err := checkKeyType(typ); err != nil {
+		return err
+	}
+
+	if !isOrdered(typeExpr(typ)) {
+		return fmt.Errorf("key type %s is not ordered, a less function is required", typ)
+	}
+
+	return nil
+}
+
+// typeQualifiers returns the names of the packages referred to in typ.
+func typeQualifiers(typ string) []string {
+	expr, err := parser.ParseExpr(typ)
+	if err != nil {
+		return nil
+	}
+
+	result := make([]string, 0)
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if x, ok := sel.X.(*ast.Ident); ok {
+				result = append(result, x.Name)
+			}
+
+			return false
+		}
+
+		return true
+	})
+
+	return result
+}
+
+// splitTopLevel splits s on sep where sep is not nested within brackets.
+// The '<' of a channel arrow, "<-", is not counted as a bracket.
+func splitTopLevel(s string, sep rune) []string {
+	result := make([]string, 0)
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}', '>':
+			depth--
+		case '<':
+			if !strings.HasPrefix(s[i:], token.ARROW.String()) {
+				depth++
+			}
+		case sep:
+			if depth == 0 {
+				result = append(result, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(result, s[start:])
+}
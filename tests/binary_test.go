@@ -0,0 +1,185 @@
+package peds_testing
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	othersubpackage "github.com/tobgu/peds/tests/other/subpackage"
+	"github.com/tobgu/peds/tests/subpackage"
+	"github.com/tobgu/peds/tests/subpackage2"
+	"github.com/tobgu/peds/tests/subpackage4"
+)
+
+func gobRoundTrip(t *testing.T, in, out interface{}) {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	if err := gob.NewEncoder(buf).Encode(in); err != nil {
+		t.Fatalf("Unexpected encoding error: %v", err)
+	}
+
+	if err := gob.NewDecoder(buf).Decode(out); err != nil {
+		t.Fatalf("Unexpected decoding error: %v", err)
+	}
+}
+
+func binaryRoundTrip(t *testing.T, in encoding.BinaryMarshaler, out encoding.BinaryUnmarshaler) {
+	t.Helper()
+	data, err := in.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Unexpected encoding error: %v", err)
+	}
+
+	if err := out.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Unexpected decoding error: %v", err)
+	}
+}
+
+func TestVectorBinaryRoundTrip(t *testing.T) {
+	for _, l := range testSizes {
+		t.Run(fmt.Sprintf("Vector %d", l), func(t *testing.T) {
+			v := &IntVector{}
+			binaryRoundTrip(t, NewIntVector(inputSlice(0, l)...), v)
+			assertEqual(t, l, v.Len())
+			for i := 0; i < l; i++ {
+				assertEqual(t, i, v.Get(i))
+			}
+
+			// The decoded vector is a regular vector
+			v = v.Append(-1)
+			assertEqual(t, -1, v.Get(l))
+		})
+	}
+}
+
+func TestSliceBinaryRoundTrip(t *testing.T) {
+	s := &IntVectorSlice{}
+	binaryRoundTrip(t, NewIntVector(inputSlice(0, 1000)...).Slice(10, 990), s)
+	assertEqual(t, 980, s.Len())
+	assertEqual(t, 10, s.Get(0))
+	assertEqual(t, 989, s.Get(979))
+
+	// Slices and vectors share the same encoding
+	v := &IntVector{}
+	binaryRoundTrip(t, s, v)
+	assertEqual(t, 980, v.Len())
+	assertEqual(t, 989, v.Get(979))
+}
+
+func TestMapAndSetBinaryRoundTrip(t *testing.T) {
+	m := NewIntStringMap()
+	for i := 0; i < 1000; i++ {
+		m = m.Store(i, fmt.Sprint(i))
+	}
+
+	decodedMap := &IntStringMap{}
+	binaryRoundTrip(t, m, decodedMap)
+	assertEqual(t, 1000, decodedMap.Len())
+	value, ok := decodedMap.Load(999)
+	assertEqualBool(t, true, ok)
+	assertEqualString(t, "999", value)
+
+	s := NewIntSet(inputSlice(0, 1000)...)
+	decodedSet := &IntSet{}
+	binaryRoundTrip(t, s, decodedSet)
+	assertEqualBool(t, true, s.Equals(decodedSet))
+
+	// Custom hash and equality functions are used for the decoded set
+	names := &NameSet{}
+	binaryRoundTrip(t, NewNameSet("Anna", "Bob"), names)
+	assertEqualBool(t, true, names.Contains("BOB"))
+}
+
+type gobMessage struct {
+	Numbers *IntVector
+	Window  *IntVectorSlice
+	Counts  StringIntMap
+	Tags    *FooSet
+	Bazs    *ImportVector
+	Others  *subpackage2.OtherVector
+	Quxs    *QuxVector
+	People  *subpackage4.PersonByName
+	Empty   *IntVector
+	Missing *StringIntMap
+}
+
+func TestGobRoundTrip(t *testing.T) {
+	in := gobMessage{
+		Numbers: NewIntVector(inputSlice(0, 100)...),
+		Window:  NewIntVector(inputSlice(0, 100)...).Slice(50, 60),
+		Counts:  *NewStringIntMap().Store("a", 1).Store("b", 2),
+		Tags:    NewFooSet(1, 2, 3),
+		Bazs:    NewImportVector(subpackage.Baz(1), subpackage.Baz(2)),
+		Others:  subpackage2.NewOtherVector(subpackage.Baz(3)),
+		Quxs:    NewQuxVector(othersubpackage.Qux("q")),
+		People: subpackage4.NewPersonByName().Store("anna",
+			subpackage4.Person{Name: "Anna", Age: 42, Baz: subpackage.Baz(4)}),
+		Empty: NewIntVector(),
+	}
+
+	// Passed by pointer to make the Counts value field addressable
+	out := gobMessage{}
+	gobRoundTrip(t, &in, &out)
+
+	assertEqual(t, 100, out.Numbers.Len())
+	assertEqual(t, 99, out.Numbers.Get(99))
+	assertEqual(t, 10, out.Window.Len())
+	assertEqual(t, 59, out.Window.Get(9))
+	assertEqual(t, 2, out.Counts.Len())
+	count, _ := out.Counts.Load("b")
+	assertEqual(t, 2, count)
+	assertEqualBool(t, true, out.Tags.Equals(in.Tags))
+	assertEqual(t, 2, int(out.Bazs.Get(1)))
+	assertEqual(t, 3, int(out.Others.Get(0)))
+	assertEqualString(t, "q", string(out.Quxs.Get(0)))
+	person, ok := out.People.Load("anna")
+	assertEqualBool(t, true, ok)
+	assertEqual(t, 42, person.Age)
+	assertEqual(t, 4, int(person.Baz))
+	if out.Missing != nil {
+		t.Error("Expected nil map")
+	}
+
+	assertEqual(t, 0, out.Empty.Len())
+}
+
+func TestUnmarshalBinaryInvalidData(t *testing.T) {
+	data, err := NewIntVector(inputSlice(0, 100)...).MarshalBinary()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{name: "empty", data: []byte{}, expected: io.ErrUnexpectedEOF.Error()},
+		{name: "version", data: append([]byte{2}, data[1:]...), expected: "unsupported binary format version 2"},
+		{name: "truncated", data: data[:len(data)-10], expected: "unexpected EOF"},
+		{name: "trailing data", data: append(append([]byte{}, data...), 0), expected: "1 bytes left after the last item"},
+		{name: "wrong count", data: append([]byte{1, 10}, data[2:]...), expected: "chunk of 32 items after 0 of 10 items"},
+		{name: "wrong type", data: mustMarshalBinary(t, NewStringIntMap().Store("a", 1)), expected: "gob"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := (&IntVector{}).UnmarshalBinary(tc.data)
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected error containing %q, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func mustMarshalBinary(t *testing.T, m encoding.BinaryMarshaler) []byte {
+	t.Helper()
+	data, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	return data
+}
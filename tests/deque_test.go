@@ -0,0 +1,210 @@
+package peds_testing
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/tobgu/peds/tests/subpackage6"
+)
+
+func assertDequeEqual(t *testing.T, expected []int, d *IntDeque) {
+	t.Helper()
+	assertEqual(t, len(expected), d.Len())
+
+	actual := d.ToNativeSlice()
+	assertEqual(t, len(expected), len(actual))
+	for i, x := range expected {
+		assertEqual(t, x, actual[i])
+	}
+
+	ranged := make([]int, 0, d.Len())
+	d.Range(func(x int) bool {
+		ranged = append(ranged, x)
+		return true
+	})
+
+	assertEqual(t, len(expected), len(ranged))
+	for i, x := range expected {
+		assertEqual(t, x, ranged[i])
+	}
+
+	front, ok := d.PeekFront()
+	assertEqualBool(t, len(expected) > 0, ok)
+	back, ok := d.PeekBack()
+	assertEqualBool(t, len(expected) > 0, ok)
+	if len(expected) > 0 {
+		assertEqual(t, expected[0], front)
+		assertEqual(t, expected[len(expected)-1], back)
+	}
+}
+
+func TestPropertiesOfNewDeque(t *testing.T) {
+	for _, l := range testSizes {
+		t.Run(fmt.Sprintf("NewDeque %d", l), func(t *testing.T) {
+			assertDequeEqual(t, inputSlice(0, l), NewIntDeque(inputSlice(0, l)...))
+		})
+	}
+}
+
+func TestDequeAsQueue(t *testing.T) {
+	d := NewIntDeque()
+	for i := 0; i < 1000; i++ {
+		d = d.PushBack(i)
+	}
+
+	assertDequeEqual(t, inputSlice(0, 1000), d)
+	for i := 0; i < 1000; i++ {
+		var x int
+		d, x = d.PopFront()
+		assertEqual(t, i, x)
+	}
+
+	assertDequeEqual(t, []int{}, d)
+}
+
+func TestDequeAsStack(t *testing.T) {
+	d := NewIntDeque()
+	for i := 0; i < 1000; i++ {
+		d = d.PushFront(i)
+	}
+
+	for i := 999; i >= 0; i-- {
+		var x int
+		d, x = d.PopFront()
+		assertEqual(t, i, x)
+	}
+
+	for i := 0; i < 1000; i++ {
+		d = d.PushBack(i)
+	}
+
+	for i := 999; i >= 0; i-- {
+		var x int
+		d, x = d.PopBack()
+		assertEqual(t, i, x)
+	}
+
+	assertDequeEqual(t, []int{}, d)
+}
+
+func TestDequeOldVersionsAreUnchanged(t *testing.T) {
+	d := NewIntDeque(inputSlice(0, 100)...)
+	for i := 0; i < 3; i++ {
+		popped := d
+		for j := 0; j < 99; j++ {
+			popped, _ = popped.PopFront()
+		}
+
+		assertDequeEqual(t, []int{99}, popped)
+		assertDequeEqual(t, []int{-1, 99, 100}, popped.PushFront(-1).PushBack(100))
+	}
+
+	assertDequeEqual(t, inputSlice(0, 100), d)
+}
+
+func TestDequeRandomOperations(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	expected := make([]int, 0)
+	d := NewIntDeque()
+	versions := []*IntDeque{d}
+	expectedVersions := [][]int{expected}
+	for i := 0; i < 10000; i++ {
+		if r.Intn(10) == 0 {
+			// Continue from an old version
+			v := r.Intn(len(versions))
+			d, expected = versions[v], expectedVersions[v]
+		}
+
+		var x int
+		switch r.Intn(4) {
+		case 0:
+			d = d.PushFront(i)
+			expected = append([]int{i}, expected...)
+		case 1:
+			d = d.PushBack(i)
+			expected = append(expected[:len(expected):len(expected)], i)
+		case 2:
+			if len(expected) > 0 {
+				d, x = d.PopFront()
+				assertEqual(t, expected[0], x)
+				expected = expected[1:]
+			}
+		case 3:
+			if len(expected) > 0 {
+				d, x = d.PopBack()
+				assertEqual(t, expected[len(expected)-1], x)
+				expected = expected[:len(expected)-1]
+			}
+		}
+
+		if i%100 == 0 {
+			versions = append(versions, d)
+			expectedVersions = append(expectedVersions, expected)
+		}
+	}
+
+	for i, v := range versions {
+		assertDequeEqual(t, expectedVersions[i], v)
+	}
+}
+
+func TestDequeConcurrentReaders(t *testing.T) {
+	// Elements are moved between the front and the back lazily, make sure that
+	// evaluating the moves from multiple goroutines is safe.
+	d := NewIntDeque()
+	for i := 0; i < 1000; i++ {
+		d = d.PushFront(i)
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			popped := d
+			for j := 0; j < 1000; j++ {
+				var x int
+				popped, x = popped.PopBack()
+				assertEqual(t, j, x)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestDequePopEmpty(t *testing.T) {
+	for name, pop := range map[string]func(d *IntDeque){
+		"front": func(d *IntDeque) { d.PopFront() },
+		"back":  func(d *IntDeque) { d.PopBack() },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer assertPanic(t, "Pop on empty deque")
+			pop(NewIntDeque())
+		})
+	}
+}
+
+func TestDequeCanceledIteration(t *testing.T) {
+	count := 0
+	NewIntDeque(inputSlice(0, 1000)...).Range(func(elem int) bool {
+		count++
+		return count < 5
+	})
+
+	assertEqual(t, 5, count)
+}
+
+func TestDequeWithSelectedMethods(t *testing.T) {
+	assertMethods(t, &subpackage6.IntQueue{},
+		[]string{"PushBack", "PopFront"},
+		[]string{"PushFront", "PopBack", "PeekFront", "Range", "ToNativeSlice"})
+
+	q := subpackage6.NewIntQueue(1, 2).PushBack(3)
+	q, x := q.PopFront()
+	assertEqual(t, 1, x)
+	_, x = q.PopFront()
+	assertEqual(t, 2, x)
+}
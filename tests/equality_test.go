@@ -0,0 +1,182 @@
+package peds_testing
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/tobgu/peds/tests/subpackage"
+	"github.com/tobgu/peds/tests/subpackage2"
+	"github.com/tobgu/peds/tests/subpackage5"
+	"github.com/tobgu/peds/tests/subpackage7"
+)
+
+func TestVectorEquals(t *testing.T) {
+	for _, l := range testSizes {
+		t.Run(fmt.Sprintf("Vector %d", l), func(t *testing.T) {
+			v := NewIntVector(inputSlice(0, l)...)
+			other := NewIntVector(inputSlice(0, l)...)
+			assertEqualBool(t, true, v.Equals(other))
+			assertEqualBool(t, true, v.Equals(v))
+			assertEqual(t, int(v.Hash()), int(other.Hash()))
+
+			longer := v.Append(l)
+			assertEqualBool(t, false, v.Equals(longer))
+			if l > 0 {
+				changed := v.Set(l-1, -1)
+				assertEqualBool(t, false, v.Equals(changed))
+				assertEqualBool(t, true, v.Equals(changed.Set(l-1, l-1)))
+			}
+		})
+	}
+}
+
+func TestVectorHashIsCached(t *testing.T) {
+	v := NewIntVector(inputSlice(0, 1000)...)
+	h := v.Hash()
+	assertEqualBool(t, true, *v.hash != 0)
+	assertEqual(t, int(h), int(v.Hash()))
+
+	// New versions compute their own hash
+	assertEqualBool(t, true, v.Set(0, 1).Hash() != h)
+	assertEqual(t, 0, int(*v.Set(0, 1).hash))
+}
+
+func TestVectorHashIsCachedInSet(t *testing.T) {
+	v := NewIntVector(inputSlice(0, 1000)...)
+	paths := NewPaths(*v)
+	paths.Hash()
+
+	// The vector stored in the set shares the hash kept for v
+	assertEqualBool(t, true, *v.hash != 0)
+	paths.Range(func(stored IntVector) bool {
+		assertEqualBool(t, true, stored.hash == v.hash)
+		return true
+	})
+
+	h := v.Hash()
+	other := NewIntVector(inputSlice(0, 1000)...)
+	assertEqualBool(t, true, paths.Contains(*other))
+	assertEqual(t, int(h), int(*other.hash))
+}
+
+func TestSliceEqualsAndHash(t *testing.T) {
+	v := NewIntVector(inputSlice(0, 100)...)
+	s1 := v.Slice(10, 50)
+	s2 := NewIntVector(inputSlice(10, 40)...).Slice(0, 40)
+	assertEqualBool(t, true, s1.Equals(s2))
+	assertEqual(t, int(s1.Hash()), int(s2.Hash()))
+	assertEqualBool(t, false, s1.Equals(v.Slice(11, 51)))
+	assertEqualBool(t, false, s1.Equals(v.Slice(10, 51)))
+
+	// Slices and vectors with the same elements have the same hash
+	assertEqual(t, int(NewIntVector(inputSlice(10, 40)...).Hash()), int(s1.Hash()))
+}
+
+func TestMapEqualsAndHash(t *testing.T) {
+	m1, m2 := NewStringIntMap(), NewStringIntMap()
+	for i := 0; i < 1000; i++ {
+		m1 = m1.Store(fmt.Sprint(i), i)
+		m2 = m2.Store(fmt.Sprint(999-i), 999-i)
+	}
+
+	assertEqualBool(t, true, m1.Equals(m2))
+	assertEqual(t, int(m1.Hash()), int(m2.Hash()))
+	assertEqualBool(t, false, m1.Equals(m2.Store("1", 2)))
+	assertEqualBool(t, false, m1.Equals(m2.Delete("1")))
+	assertEqualBool(t, false, m1.Equals(m2.Delete("1").Store("x", 1)))
+	assertEqualBool(t, true, NewStringIntMap().Equals(NewStringIntMap()))
+}
+
+func TestSetHash(t *testing.T) {
+	s1 := NewIntSet(inputSlice(0, 100)...)
+	s2 := NewIntSet(inputSlice(0, 100)...).Add(100).Delete(100)
+	assertEqualBool(t, true, s1.Equals(s2))
+	assertEqual(t, int(s1.Hash()), int(s2.Hash()))
+}
+
+func TestSetWithVectorElements(t *testing.T) {
+	paths := NewPaths(*NewIntVector(1, 2, 3), *NewIntVector(1, 2), *NewIntVector(inputSlice(0, 1000)...))
+	assertEqualBool(t, true, paths.Contains(*NewIntVector(1, 2).Append(3)))
+	assertEqualBool(t, true, paths.Contains(*NewIntVector(inputSlice(0, 1000)...)))
+	assertEqualBool(t, false, paths.Contains(*NewIntVector(1)))
+
+	// Adding an equal vector does not add an element
+	paths = paths.Add(*NewIntVector(1, 2))
+	assertEqual(t, 3, paths.Len())
+	paths = paths.Delete(*NewIntVector(1, 2, 3))
+	assertEqual(t, 2, paths.Len())
+}
+
+func TestMapWithVectorKeys(t *testing.T) {
+	counts := NewVectorCounts()
+	for i := 0; i < 100; i++ {
+		key := *NewIntVector(i%10, i%10+1)
+		count, _ := counts.Load(key)
+		counts = counts.Store(key, count+1)
+	}
+
+	assertEqual(t, 10, counts.Len())
+	count, ok := counts.Load(*NewIntVector(3, 4))
+	assertEqualBool(t, true, ok)
+	assertEqual(t, 10, count)
+}
+
+func TestNestedContainers(t *testing.T) {
+	p1 := *subpackage7.NewPath("a", "b")
+	p2 := *subpackage7.NewPath("a", "c")
+	index := subpackage7.NewPathSetIndex().Store(*subpackage7.NewPathSet(p1, p2), 1)
+	value, ok := index.Load(*subpackage7.NewPathSet(p2, *subpackage7.NewPath("a").Append("b")))
+	assertEqualBool(t, true, ok)
+	assertEqual(t, 1, value)
+
+	v1 := subpackage7.NewPathVector(p1, p2)
+	v2 := subpackage7.NewPathVector(*subpackage7.NewPath("a", "b"), *subpackage7.NewPath("a", "c"))
+	assertEqualBool(t, true, v1.Equals(v2))
+	assertEqual(t, int(v1.Hash()), int(v2.Hash()))
+	assertEqualBool(t, false, v1.Equals(subpackage7.NewPathVector(p2, p1)))
+
+	values := subpackage7.NewPathValues().Store("x", p1)
+	assertEqualBool(t, true, values.Equals(subpackage7.NewPathValues().Store("x", *subpackage7.NewPath("a", "b"))))
+	assertEqualBool(t, false, values.Equals(subpackage7.NewPathValues().Store("x", p2)))
+}
+
+func TestSetWithContainerFromOtherPackage(t *testing.T) {
+	s := subpackage7.NewOtherVectorSet(*subpackage2.NewOtherVector(subpackage.Baz(1), subpackage.Baz(2)))
+	assertEqualBool(t, true, s.Contains(*subpackage2.NewOtherVector(subpackage.Baz(1), subpackage.Baz(2))))
+	assertEqualBool(t, false, s.Contains(*subpackage2.NewOtherVector(subpackage.Baz(1))))
+}
+
+func TestMapWithVectorKeysJSON(t *testing.T) {
+	counts := NewVectorCounts().Store(*NewIntVector(1, 2), 3)
+	assertJSON(t, `[[[1,2],3]]`, counts)
+
+	decoded := &VectorCounts{}
+	unmarshalJSON(t, `[[[1,2],3]]`, decoded)
+	assertEqualBool(t, true, counts.Equals(decoded))
+}
+
+// hasHashMethods returns true if x has the Equals and Hash methods of a container.
+func hasHashMethods(x interface{}) bool {
+	v := reflect.ValueOf(x)
+	return v.MethodByName("Hash").IsValid() && v.MethodByName("Equals").IsValid()
+}
+
+func TestContainersOfUncomparableValuesHaveNoHashMethods(t *testing.T) {
+	bytes := subpackage5.NewByteSliceVector([]byte("a"), []byte("b"))
+	assertEqualBool(t, false, hasHashMethods(bytes))
+	assertEqualBool(t, false, hasHashMethods(bytes.Slice(0, 1)))
+	assertEqualString(t, "b", string(bytes.Get(1)))
+
+	maps := subpackage5.NewStringMapMap().Store("a", map[string]int{"b": 1})
+	assertEqualBool(t, false, hasHashMethods(maps))
+	value, _ := maps.Load("a")
+	assertEqual(t, 1, value["b"])
+
+	// Comparable composite types are still hashed and compared
+	arrays := subpackage5.NewFloat4Vector([4]float64{1, 2, 3, 4})
+	assertEqualBool(t, true, hasHashMethods(arrays))
+	assertEqualBool(t, true, arrays.Equals(subpackage5.NewFloat4Vector([4]float64{1, 2, 3, 4})))
+	assertEqualBool(t, false, arrays.Slice(0, 1).Equals(subpackage5.NewFloat4Vector([4]float64{1, 2, 3, 5}).Slice(0, 1)))
+	assertEqual(t, int(arrays.Hash()), int(subpackage5.NewFloat4Vector([4]float64{1, 2, 3, 4}).Hash()))
+}
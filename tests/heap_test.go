@@ -0,0 +1,156 @@
+package peds_testing
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/tobgu/peds/tests/subpackage6"
+)
+
+func assertHeapEqual(t *testing.T, expected []int, h *IntHeap) {
+	t.Helper()
+	sorted := append([]int{}, expected...)
+	sort.Ints(sorted)
+
+	assertEqual(t, len(sorted), h.Len())
+	actual := h.ToNativeSlice()
+	assertEqual(t, len(sorted), len(actual))
+	for i, x := range sorted {
+		assertEqual(t, x, actual[i])
+	}
+
+	ranged := make([]int, 0, h.Len())
+	h.Range(func(x int) bool {
+		ranged = append(ranged, x)
+		return true
+	})
+
+	sort.Ints(ranged)
+	assertEqual(t, len(sorted), len(ranged))
+	for i, x := range sorted {
+		assertEqual(t, x, ranged[i])
+	}
+
+	min, ok := h.Peek()
+	assertEqualBool(t, len(sorted) > 0, ok)
+	if len(sorted) > 0 {
+		assertEqual(t, sorted[0], min)
+	}
+}
+
+func TestPropertiesOfNewHeap(t *testing.T) {
+	for _, l := range testSizes {
+		t.Run(fmt.Sprintf("NewHeap %d", l), func(t *testing.T) {
+			items := rand.New(rand.NewSource(int64(l))).Perm(l)
+			assertHeapEqual(t, items, NewIntHeap(items...))
+		})
+	}
+}
+
+func TestHeapPushPop(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	items := r.Perm(1000)
+	h := NewIntHeap()
+	for _, x := range items {
+		h = h.Push(x)
+	}
+
+	assertHeapEqual(t, items, h)
+	for i := 0; i < 1000; i++ {
+		var x int
+		h, x = h.Pop()
+		assertEqual(t, i, x)
+	}
+
+	assertHeapEqual(t, []int{}, h)
+}
+
+func TestHeapDuplicates(t *testing.T) {
+	h := NewIntHeap(3, 1, 3, 1)
+	assertHeapEqual(t, []int{1, 1, 3, 3}, h)
+	h, _ = h.Pop()
+	assertHeapEqual(t, []int{1, 3, 3}, h)
+}
+
+func TestHeapMerge(t *testing.T) {
+	h1 := NewIntHeap(inputSlice(0, 500)...)
+	h2 := NewIntHeap(inputSlice(250, 500)...)
+	merged := h1.Merge(h2)
+	assertHeapEqual(t, append(inputSlice(0, 500), inputSlice(250, 500)...), merged)
+	assertHeapEqual(t, inputSlice(0, 500), NewIntHeap().Merge(h1).Merge(NewIntHeap()))
+
+	// Original heaps are unchanged
+	assertHeapEqual(t, inputSlice(0, 500), h1)
+	assertHeapEqual(t, inputSlice(250, 500), h2)
+}
+
+func TestHeapOldVersionsAreUnchanged(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	expected := make([]int, 0)
+	h := NewIntHeap()
+	versions := []*IntHeap{h}
+	expectedVersions := [][]int{expected}
+	for i := 0; i < 5000; i++ {
+		if r.Intn(3) == 0 && len(expected) > 0 {
+			var x int
+			h, x = h.Pop()
+			sort.Ints(expected)
+			assertEqual(t, expected[0], x)
+			expected = expected[1:]
+		} else {
+			x := r.Intn(1000)
+			h = h.Push(x)
+			expected = append(expected[:len(expected):len(expected)], x)
+		}
+
+		if i%100 == 0 {
+			versions = append(versions, h)
+			expectedVersions = append(expectedVersions, expected)
+		}
+	}
+
+	for i, v := range versions {
+		assertHeapEqual(t, expectedVersions[i], v)
+	}
+}
+
+func TestHeapWithCustomLess(t *testing.T) {
+	q := NewJobQueue(Job{Priority: 3, Tags: []string{"c"}}, Job{Priority: 1, Tags: []string{"a"}})
+	q = q.Push(Job{Priority: 2, Tags: []string{"b"}})
+
+	tags := ""
+	for q.Len() > 0 {
+		var j Job
+		q, j = q.Pop()
+		tags += j.Tags[0]
+	}
+
+	assertEqualString(t, "abc", tags)
+}
+
+func TestHeapPopEmpty(t *testing.T) {
+	defer assertPanic(t, "Pop on empty heap")
+	NewIntHeap().Pop()
+}
+
+func TestHeapCanceledIteration(t *testing.T) {
+	count := 0
+	NewIntHeap(inputSlice(0, 1000)...).Range(func(elem int) bool {
+		count++
+		return count < 5
+	})
+
+	assertEqual(t, 5, count)
+}
+
+func TestHeapWithSelectedMethods(t *testing.T) {
+	assertMethods(t, &subpackage6.IntHeap{},
+		[]string{"Push", "Pop"},
+		[]string{"Peek", "Merge", "Range", "ToNativeSlice"})
+
+	h := subpackage6.NewIntHeap(3, 1).Push(2)
+	_, x := h.Pop()
+	assertEqual(t, 1, x)
+}
@@ -0,0 +1,92 @@
+//go:build go1.23
+
+package peds_testing
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/tobgu/peds/tests/subpackage8"
+)
+
+func TestVectorIterators(t *testing.T) {
+	for _, l := range testSizes {
+		t.Run(fmt.Sprintf("Vector %d", l), func(t *testing.T) {
+			v := subpackage8.NewIntIterVector(inputSlice(0, l)...)
+			count := 0
+			for i, x := range v.All() {
+				assertEqual(t, count, i)
+				assertEqual(t, i, x)
+				count++
+			}
+
+			assertEqual(t, l, count)
+
+			expected := l - 1
+			for i, x := range v.Backward() {
+				assertEqual(t, expected, i)
+				assertEqual(t, i, x)
+				expected--
+			}
+
+			assertEqual(t, -1, expected)
+		})
+	}
+}
+
+func TestVectorIteratorBreak(t *testing.T) {
+	v := subpackage8.NewIntIterVector(inputSlice(0, 100)...)
+	count := 0
+	for i := range v.All() {
+		if i == 10 {
+			break
+		}
+
+		count++
+	}
+
+	assertEqual(t, 10, count)
+}
+
+func TestSliceIterators(t *testing.T) {
+	s := subpackage8.NewIntIterVector(inputSlice(0, 100)...).Slice(30, 70)
+	forward := make([]int, 0)
+	for i, x := range s.All() {
+		assertEqual(t, x-30, i)
+		forward = append(forward, x)
+	}
+
+	assertEqualBool(t, true, slices.Equal(inputSlice(30, 40), forward))
+
+	backward := make([]int, 0)
+	for i, x := range s.Backward() {
+		assertEqual(t, x-30, i)
+		backward = append(backward, x)
+	}
+
+	slices.Reverse(backward)
+	assertEqualBool(t, true, slices.Equal(forward, backward))
+}
+
+func TestMapIterators(t *testing.T) {
+	m := subpackage8.NewStringIntIterMap()
+	for i := 0; i < 100; i++ {
+		m = m.Store(fmt.Sprint(i), i)
+	}
+
+	count := 0
+	for k, v := range m.All() {
+		assertEqualString(t, fmt.Sprint(v), k)
+		count++
+	}
+
+	assertEqual(t, 100, count)
+	assertEqual(t, 100, len(slices.Collect(m.Keys())))
+	assertEqualBool(t, true, slices.Equal(inputSlice(0, 100), slices.Sorted(m.Values())))
+}
+
+func TestSetIterator(t *testing.T) {
+	s := subpackage8.NewIntIterSet(inputSlice(0, 100)...)
+	assertEqualBool(t, true, slices.Equal(inputSlice(0, 100), slices.Sorted(s.All())))
+}
@@ -0,0 +1,272 @@
+package peds_testing
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/tobgu/peds/tests/subpackage"
+	"github.com/tobgu/peds/tests/subpackage5"
+)
+
+func assertJSON(t *testing.T, expected string, v interface{}) {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	assertEqualString(t, expected, string(b))
+}
+
+func unmarshalJSON(t *testing.T, data string, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal([]byte(data), v); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestVectorJSON(t *testing.T) {
+	assertJSON(t, "[1,2,3]", NewIntVector(1, 2, 3))
+	assertJSON(t, "[]", NewIntVector())
+
+	v := NewIntVector(100)
+	unmarshalJSON(t, "[1,2,3]", v)
+	assertEqual(t, 3, v.Len())
+	assertEqual(t, 3, v.Get(2))
+
+	// Large enough to need more than one level in the trie
+	large := NewIntVector(inputSlice(0, 5000)...)
+	b, _ := json.Marshal(large)
+	decoded := &IntVector{}
+	unmarshalJSON(t, string(b), decoded)
+	assertEqual(t, 5000, decoded.Len())
+	for i := 0; i < 5000; i++ {
+		assertEqual(t, i, decoded.Get(i))
+	}
+
+	unmarshalJSON(t, "[]", decoded)
+	assertEqual(t, 0, decoded.Len())
+}
+
+func TestVectorJSONWithImportedType(t *testing.T) {
+	v := NewImportVector(subpackage.Baz(1), subpackage.Baz(2))
+	b, _ := json.Marshal(v)
+	decoded := &ImportVector{}
+	unmarshalJSON(t, string(b), decoded)
+	assertEqual(t, 2, decoded.Len())
+	assertEqual(t, 2, int(decoded.Get(1)))
+}
+
+func TestSliceJSON(t *testing.T) {
+	s := NewIntVector(inputSlice(0, 10)...).Slice(2, 5)
+	assertJSON(t, "[2,3,4]", s)
+
+	decoded := &IntVectorSlice{}
+	unmarshalJSON(t, "[7,8]", decoded)
+	assertEqual(t, 2, decoded.Len())
+	assertEqual(t, 8, decoded.Get(1))
+}
+
+func TestRRBVectorJSON(t *testing.T) {
+	v := NewIntRRBVector(1, 2).Concat(NewIntRRBVector(3))
+	assertJSON(t, "[1,2,3]", v)
+
+	decoded := &IntRRBVector{}
+	unmarshalJSON(t, "[4,5,6]", decoded)
+	assertEqual(t, 3, decoded.Len())
+	assertEqual(t, 6, decoded.Get(2))
+}
+
+func TestDequeJSON(t *testing.T) {
+	assertJSON(t, "[0,1,2]", NewIntDeque(1, 2).PushFront(0))
+
+	decoded := &IntDeque{}
+	unmarshalJSON(t, "[1,2,3]", decoded)
+	assertDequeEqual(t, []int{1, 2, 3}, decoded)
+}
+
+func TestHeapJSON(t *testing.T) {
+	assertJSON(t, "[1,2,3]", NewIntHeap(3, 1, 2))
+
+	decoded := &IntHeap{}
+	unmarshalJSON(t, "[3,1,2]", decoded)
+	assertHeapEqual(t, []int{1, 2, 3}, decoded)
+}
+
+func TestSetJSON(t *testing.T) {
+	decoded := &IntSet{}
+	unmarshalJSON(t, "[3,1,2,1]", decoded)
+	assertEqual(t, 3, decoded.Len())
+	for _, x := range []int{1, 2, 3} {
+		assertEqualBool(t, true, decoded.Contains(x))
+	}
+
+	b, _ := json.Marshal(decoded)
+	roundTripped := &IntSet{}
+	unmarshalJSON(t, string(b), roundTripped)
+	assertEqualBool(t, true, roundTripped.Equals(decoded))
+
+	assertJSON(t, "[]", NewIntSet())
+}
+
+func TestSortedSetJSON(t *testing.T) {
+	assertJSON(t, "[1,2,3]", NewIntSortedSet(3, 1, 2))
+
+	decoded := &IntSortedSet{}
+	unmarshalJSON(t, "[3,1,2]", decoded)
+	assertEqual(t, 3, decoded.Len())
+	assertJSON(t, "[1,2,3]", decoded)
+}
+
+func TestMapJSON(t *testing.T) {
+	m := NewStringIntMap().Store("a", 1).Store("b", 2)
+	b, _ := json.Marshal(m)
+	native := map[string]int{}
+	unmarshalJSON(t, string(b), &native)
+	assertEqual(t, 2, len(native))
+	assertEqual(t, 2, native["b"])
+
+	decoded := &StringIntMap{}
+	unmarshalJSON(t, `{"x":1,"y":2}`, decoded)
+	assertEqual(t, 2, decoded.Len())
+	value, ok := decoded.Load("y")
+	assertEqualBool(t, true, ok)
+	assertEqual(t, 2, value)
+
+	assertJSON(t, "{}", NewStringIntMap())
+}
+
+func TestMapJSONWithIntKeys(t *testing.T) {
+	assertJSON(t, `{"1":"a"}`, NewIntStringMap().Store(1, "a"))
+
+	decoded := &IntStringMap{}
+	unmarshalJSON(t, `{"1":"a","-2":"b"}`, decoded)
+	value, _ := decoded.Load(-2)
+	assertEqualString(t, "b", value)
+
+	err := json.Unmarshal([]byte(`{"x":"a"}`), decoded)
+	if err == nil {
+		t.Fatal("Expected error for non integer key")
+	}
+}
+
+func TestMapJSONWithNonObjectKeys(t *testing.T) {
+	m := subpackage5.NewArrayKeyMap().Store([2]int{1, 2}, "a")
+	assertJSON(t, `[[[1,2],"a"]]`, m)
+
+	decoded := &subpackage5.ArrayKeyMap{}
+	unmarshalJSON(t, `[[[1,2],"a"],[[3,4],"b"]]`, decoded)
+	assertEqual(t, 2, decoded.Len())
+	value, _ := decoded.Load([2]int{3, 4})
+	assertEqualString(t, "b", value)
+
+	// Maps with object keys can be decoded from arrays of pairs as well
+	intMap := &IntStringMap{}
+	unmarshalJSON(t, `[[1,"a"]]`, intMap)
+	value, _ = intMap.Load(1)
+	assertEqualString(t, "a", value)
+}
+
+func TestSortedMapJSON(t *testing.T) {
+	m := NewIntStringSortedMap().Store(2, "b").Store(1, "a")
+	assertJSON(t, `{"1":"a","2":"b"}`, m)
+
+	decoded := &IntStringSortedMap{}
+	unmarshalJSON(t, `{"3":"c","1":"a"}`, decoded)
+	assertJSON(t, `{"1":"a","3":"c"}`, decoded)
+}
+
+func TestOrderedMapUnmarshalJSONKeepsOrder(t *testing.T) {
+	decoded := &StringIntOrderedMap{}
+	unmarshalJSON(t, `{"z":1,"a":2,"m":3}`, decoded)
+	assertEqual(t, 3, decoded.Len())
+	assertJSON(t, `{"z":1,"a":2,"m":3}`, decoded)
+}
+
+func TestJSONNull(t *testing.T) {
+	v := NewIntVector(1)
+	unmarshalJSON(t, "null", v)
+	assertEqual(t, 0, v.Len())
+
+	m := NewStringIntMap().Store("a", 1)
+	unmarshalJSON(t, "null", m)
+	assertEqual(t, 0, m.Len())
+}
+
+func TestJSONInStruct(t *testing.T) {
+	type document struct {
+		Numbers *IntVector
+		Names   *StringIntMap
+		Missing *IntSet
+	}
+
+	decoded := document{}
+	unmarshalJSON(t, `{"Numbers":[1,2],"Names":{"a":1},"Missing":null}`, &decoded)
+	assertEqual(t, 2, decoded.Numbers.Len())
+	assertEqual(t, 1, decoded.Names.Len())
+	if decoded.Missing != nil {
+		t.Error("Expected nil set")
+	}
+
+	assertJSON(t, `{"Numbers":[1,2],"Names":{"a":1},"Missing":null}`, decoded)
+}
+
+func TestJSONInvalidInput(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data string
+		v    interface{}
+	}{
+		{name: "vector from object", data: `{"a":1}`, v: &IntVector{}},
+		{name: "vector wrong element type", data: `["a"]`, v: &IntVector{}},
+		{name: "map from number", data: `1`, v: &StringIntMap{}},
+		{name: "map pair too long", data: `[["a",1,2]]`, v: &StringIntMap{}},
+		{name: "set truncated", data: `[1,2`, v: &IntSet{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := json.Unmarshal([]byte(tc.data), tc.v); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+func TestSortedMapJSONBuildsValidTree(t *testing.T) {
+	for _, l := range []int{0, 1, 32, 1000, 40000} {
+		t.Run(fmt.Sprintf("UnmarshalJSON %d", l), func(t *testing.T) {
+			expected := make(map[int]string, l)
+			for _, k := range rand.Perm(l) {
+				expected[k] = fmt.Sprint(k)
+			}
+
+			b, _ := json.Marshal(expected)
+			decoded := &IntStringSortedMap{}
+			unmarshalJSON(t, string(b), decoded)
+			assertValidBTree(t, decoded)
+			assertSortedMapEqual(t, expected, decoded)
+		})
+	}
+}
+
+func TestSortedMapJSONDuplicateKeys(t *testing.T) {
+	decoded := &IntStringSortedMap{}
+	unmarshalJSON(t, `{"2":"b","1":"a","2":"c"}`, decoded)
+	assertJSON(t, `{"1":"a","2":"c"}`, decoded)
+
+	set := &IntSortedSet{}
+	unmarshalJSON(t, "[3,1,3,2,1]", set)
+	assertEqual(t, 3, set.Len())
+	assertJSON(t, "[1,2,3]", set)
+}
+
+func TestOrderedMapJSONDuplicateKeys(t *testing.T) {
+	decoded := &StringIntOrderedMap{}
+	unmarshalJSON(t, `{"z":1,"a":2,"z":3,"m":4}`, decoded)
+	assertEqual(t, 3, decoded.Len())
+	assertJSON(t, `{"z":3,"a":2,"m":4}`, decoded)
+
+	decoded = decoded.Store("b", 5).Delete("a")
+	assertJSON(t, `{"z":3,"m":4,"b":5}`, decoded)
+}
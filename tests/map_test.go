@@ -4,6 +4,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"hash/crc32"
+	"math/rand"
 	"testing"
 )
 
@@ -141,10 +142,96 @@ func TestFromToNativeMap(t *testing.T) {
 	}
 }
 
+func TestRandomStoreAndDelete(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	expected := make(map[int]string)
+	m := NewIntStringMap()
+	for i := 0; i < 20000; i++ {
+		key := r.Intn(5000)
+		if r.Intn(3) == 0 {
+			delete(expected, key)
+			m = m.Delete(key)
+		} else {
+			expected[key] = fmt.Sprintf("%d", i)
+			m = m.Store(key, fmt.Sprintf("%d", i))
+		}
+	}
+
+	assertEqual(t, len(expected), m.Len())
+	actual := m.ToNativeMap()
+	assertEqual(t, len(expected), len(actual))
+	for key, value := range expected {
+		assertEqualString(t, value, actual[key])
+		v, ok := m.Load(key)
+		assertEqualBool(t, true, ok)
+		assertEqualString(t, value, v)
+	}
+
+	// Delete everything and make sure the map is empty
+	for key := range expected {
+		m = m.Delete(key)
+	}
+
+	assertEqual(t, 0, m.Len())
+	assertEqual(t, 0, len(m.ToNativeMap()))
+}
+
 //////////////////
 /// Benchmarks ///
 //////////////////
 
+func TestCustomHashAndEqual(t *testing.T) {
+	m := NewNameIntMap().Store("Alice", 1).Store("BOB", 2)
+	m2 := m.Store("alice", 3)
+	assertEqual(t, 2, m2.Len())
+
+	v, ok := m2.Load("ALICE")
+	assertEqualBool(t, true, ok)
+	assertEqual(t, 3, v)
+
+	v, _ = m.Load("aLiCe")
+	assertEqual(t, 1, v)
+
+	m3 := m2.Delete("bob")
+	assertEqual(t, 1, m3.Len())
+	_, ok = m3.Load("Bob")
+	assertEqualBool(t, false, ok)
+}
+
+func TestHashCollisions(t *testing.T) {
+	size := 1000
+	m := NewCollidingMap()
+	for i := 0; i < size; i++ {
+		m = m.Store(CollidingKey(i), i)
+	}
+
+	assertEqual(t, size, m.Len())
+	for i := 0; i < size; i++ {
+		v, ok := m.Load(CollidingKey(i))
+		assertEqualBool(t, true, ok)
+		assertEqual(t, i, v)
+	}
+
+	for i := 0; i < size; i += 2 {
+		m = m.Delete(CollidingKey(i))
+	}
+
+	assertEqual(t, size/2, m.Len())
+	for i := 0; i < size; i++ {
+		_, ok := m.Load(CollidingKey(i))
+		assertEqualBool(t, i%2 == 1, ok)
+	}
+
+	count := 0
+	m.Range(func(k CollidingKey, v int) bool {
+		assertEqual(t, int(k), v)
+		count++
+		return true
+	})
+
+	assertEqual(t, size/2, count)
+}
+
 func BenchmarkInsertMap(b *testing.B) {
 	length := 0
 	for i := 0; i < b.N; i++ {
@@ -0,0 +1,87 @@
+package peds_testing
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tobgu/peds/tests/subpackage6"
+)
+
+func assertMethods(t *testing.T, value interface{}, present, absent []string) {
+	t.Helper()
+	typ := reflect.TypeOf(value)
+	for _, m := range present {
+		if _, ok := typ.MethodByName(m); !ok {
+			t.Errorf("Expected %s to have method %s", typ, m)
+		}
+	}
+
+	for _, m := range absent {
+		if _, ok := typ.MethodByName(m); ok {
+			t.Errorf("Expected %s not to have method %s", typ, m)
+		}
+	}
+}
+
+func TestSelectedMethods(t *testing.T) {
+	assertMethods(t, &subpackage6.IntStack{},
+		[]string{"Append", "Get", "Len"},
+		[]string{"Set", "Slice", "Range", "ToNativeSlice", "AsTransient"})
+
+	assertMethods(t, &subpackage6.IntRope{},
+		[]string{"Get", "Set", "Append", "Len", "Range", "Concat"},
+		[]string{"Slice", "InsertAt", "RemoveAt", "ToNativeSlice"})
+
+	assertMethods(t, &subpackage6.StringIntMap{},
+		[]string{"Load", "Store"},
+		[]string{"Delete", "Range", "ToNativeMap"})
+
+	assertMethods(t, &subpackage6.IntSet{},
+		[]string{"Add", "Delete", "Contains", "Len", "Range"},
+		[]string{"Union", "Intersection", "IsSubset", "ToNativeSlice"})
+
+	assertMethods(t, &subpackage6.IntStringSortedMap{},
+		[]string{"Load", "Store", "Min", "Floor", "RangeBetween", "ToNativeMap"},
+		[]string{"AsTransient"})
+
+	assertMethods(t, &subpackage6.IntSortedSet{},
+		[]string{"Add", "Min"},
+		[]string{"Contains", "Max", "Range", "ToNativeSlice"})
+
+	assertMethods(t, &subpackage6.StringIntOrderedMap{},
+		[]string{"Store", "Range"},
+		[]string{"Delete", "ToNativeSlice", "MarshalJSON"})
+}
+
+func TestContainersWithSelectedMethods(t *testing.T) {
+	v := subpackage6.NewIntStack(1, 2).Append(3)
+	assertEqual(t, 3, v.Len())
+	assertEqual(t, 3, v.Get(2))
+
+	r := subpackage6.NewIntRope(1, 2).Concat(subpackage6.NewIntRope(3))
+	assertEqual(t, 3, r.Len())
+	assertEqual(t, 3, r.Get(2))
+
+	m := subpackage6.NewStringIntMap().Store("a", 1)
+	value, ok := m.Load("a")
+	assertEqualBool(t, true, ok)
+	assertEqual(t, 1, value)
+
+	s := subpackage6.NewIntSet(1).Add(2).Delete(1)
+	assertEqualBool(t, false, s.Contains(1))
+	assertEqualBool(t, true, s.Contains(2))
+
+	ss := subpackage6.NewIntSortedSet(3, 1).Add(2)
+	min, ok := ss.Min()
+	assertEqualBool(t, true, ok)
+	assertEqual(t, 1, min)
+
+	om := subpackage6.NewStringIntOrderedMap().Store("b", 2).Store("a", 1)
+	keys := ""
+	om.Range(func(k string, v int) bool {
+		keys += k
+		return true
+	})
+
+	assertEqualString(t, "ba", keys)
+}
@@ -0,0 +1,79 @@
+package peds_testing
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func orderedMapKeys(m *StringIntOrderedMap) []string {
+	keys := make([]string, 0, m.Len())
+	for _, item := range m.ToNativeSlice() {
+		keys = append(keys, item.Key)
+	}
+
+	return keys
+}
+
+func assertKeysEqual(t *testing.T, expected, actual []string) {
+	t.Helper()
+	assertEqual(t, len(expected), len(actual))
+	for i, k := range expected {
+		assertEqualString(t, k, actual[i])
+	}
+}
+
+func TestOrderedMapKeepsInsertionOrder(t *testing.T) {
+	m := NewStringIntOrderedMap(StringIntOrderedMapItem{Key: "c", Value: 1}, StringIntOrderedMapItem{Key: "a", Value: 2})
+	m2 := m.Store("b", 3).Store("c", 4)
+	assertKeysEqual(t, []string{"c", "a", "b"}, orderedMapKeys(m2))
+	v, _ := m2.Load("c")
+	assertEqual(t, 4, v)
+
+	// Deleting and storing again moves the key last
+	m3 := m2.Delete("c").Store("c", 5)
+	assertKeysEqual(t, []string{"a", "b", "c"}, orderedMapKeys(m3))
+	assertEqual(t, 3, m3.Len())
+
+	// Earlier versions are unaffected
+	assertKeysEqual(t, []string{"c", "a"}, orderedMapKeys(m))
+	v, _ = m.Load("c")
+	assertEqual(t, 1, v)
+}
+
+func TestOrderedMapLargeInsertAndDelete(t *testing.T) {
+	size := 5000
+	m := NewStringIntOrderedMap()
+	for i := size - 1; i >= 0; i-- {
+		m = m.Store(fmt.Sprint(i), i)
+	}
+
+	for i := 0; i < size; i += 2 {
+		m = m.Delete(fmt.Sprint(i))
+	}
+
+	assertEqual(t, size/2, m.Len())
+	expected := size - 1
+	m.Range(func(k string, v int) bool {
+		assertEqual(t, expected, v)
+		assertEqualString(t, fmt.Sprint(expected), k)
+		expected -= 2
+		return true
+	})
+
+	assertEqual(t, -1, expected)
+	assertEqualBool(t, true, m == m.Delete("does not exist"))
+}
+
+func TestOrderedMapMarshalJSON(t *testing.T) {
+	m := NewStringIntOrderedMap().Store("z", 1).Store("a", 2).Store("m", 3)
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertEqualString(t, `{"z":1,"a":2,"m":3}`, string(b))
+
+	b, _ = json.Marshal(NewStringIntOrderedMap())
+	assertEqualString(t, `{}`, string(b))
+}
@@ -0,0 +1,5 @@
+// Package subpackage has the same name as tests/subpackage to test that
+// generated code imports one of them under another name.
+package subpackage
+
+type Qux string
@@ -0,0 +1,188 @@
+package peds_testing
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func assertRRBVectorEqual(t *testing.T, expected []int, v *IntRRBVector) {
+	t.Helper()
+	assertEqual(t, len(expected), v.Len())
+	for i, x := range expected {
+		if actual := v.Get(i); actual != x {
+			t.Fatalf("Unexpected value at %d. Expected: %d, actual: %d", i, x, actual)
+		}
+	}
+
+	actual := v.ToNativeSlice()
+	assertEqual(t, len(expected), len(actual))
+	for i, x := range expected {
+		assertEqual(t, x, actual[i])
+	}
+}
+
+func TestRRBPropertiesOfNewVector(t *testing.T) {
+	for _, l := range testSizes {
+		t.Run(fmt.Sprintf("NewRRBVector %d", l), func(t *testing.T) {
+			assertRRBVectorEqual(t, inputSlice(0, l), NewIntRRBVector(inputSlice(0, l)...))
+		})
+	}
+}
+
+func TestRRBSetItem(t *testing.T) {
+	for _, l := range testSizes {
+		t.Run(fmt.Sprintf("Set %d", l), func(t *testing.T) {
+			vec := NewIntRRBVector(inputSlice(0, l)...)
+			for i := 0; i < l; i++ {
+				newVec := vec.Set(i, -i)
+				assertEqual(t, -i, newVec.Get(i))
+				assertEqual(t, i, vec.Get(i))
+			}
+		})
+	}
+}
+
+func TestRRBAppend(t *testing.T) {
+	for _, l := range testSizes {
+		vec := NewIntRRBVector(inputSlice(0, l)...)
+		t.Run(fmt.Sprintf("Append %d", l), func(t *testing.T) {
+			for i := 0; i < 70; i++ {
+				newVec := vec.Append(inputSlice(l, i)...)
+				assertRRBVectorEqual(t, inputSlice(0, l+i), newVec)
+
+				// Original vector is unchanged
+				assertEqual(t, l, vec.Len())
+			}
+		})
+	}
+}
+
+func TestRRBAppendOneByOne(t *testing.T) {
+	vec := NewIntRRBVector()
+	for i := 0; i < 40000; i++ {
+		vec = vec.Append(i)
+	}
+
+	assertRRBVectorEqual(t, inputSlice(0, 40000), vec)
+}
+
+func TestRRBConcat(t *testing.T) {
+	for _, l1 := range testSizes {
+		for _, l2 := range testSizes {
+			t.Run(fmt.Sprintf("Concat %d %d", l1, l2), func(t *testing.T) {
+				v1 := NewIntRRBVector(inputSlice(0, l1)...)
+				v2 := NewIntRRBVector(inputSlice(l1, l2)...)
+				assertRRBVectorEqual(t, inputSlice(0, l1+l2), v1.Concat(v2))
+				assertEqual(t, l1, v1.Len())
+				assertEqual(t, l2, v2.Len())
+			})
+		}
+	}
+}
+
+func TestRRBRepeatedConcatOfSmallVectors(t *testing.T) {
+	expected := make([]int, 0)
+	vec := NewIntRRBVector()
+	for i := 0; i < 2000; i++ {
+		part := inputSlice(len(expected), i%37)
+		expected = append(expected, part...)
+		vec = vec.Concat(NewIntRRBVector(part...))
+	}
+
+	assertRRBVectorEqual(t, expected, vec)
+}
+
+func TestRRBSlice(t *testing.T) {
+	vec := NewIntRRBVector(inputSlice(0, 5000)...)
+	for _, bounds := range [][2]int{{0, 0}, {0, 5000}, {0, 1}, {4999, 5000}, {31, 33}, {100, 4000}, {1023, 1025}, {1500, 1500}} {
+		t.Run(fmt.Sprintf("Slice %d %d", bounds[0], bounds[1]), func(t *testing.T) {
+			assertRRBVectorEqual(t, inputSlice(bounds[0], bounds[1]-bounds[0]), vec.Slice(bounds[0], bounds[1]))
+		})
+	}
+}
+
+func TestRRBSliceOutOfBounds(t *testing.T) {
+	defer assertPanic(t, "Slice bounds out of range")
+	NewIntRRBVector(inputSlice(0, 10)...).Slice(0, 11)
+}
+
+func TestRRBInsertAt(t *testing.T) {
+	vec := NewIntRRBVector(inputSlice(0, 1000)...)
+	newVec := vec.InsertAt(500, -1, -2, -3)
+
+	expected := append(inputSlice(0, 500), -1, -2, -3)
+	expected = append(expected, inputSlice(500, 500)...)
+	assertRRBVectorEqual(t, expected, newVec)
+	assertRRBVectorEqual(t, inputSlice(0, 1000), vec)
+
+	assertRRBVectorEqual(t, append([]int{-1}, inputSlice(0, 1000)...), vec.InsertAt(0, -1))
+	assertRRBVectorEqual(t, append(inputSlice(0, 1000), -1), vec.InsertAt(1000, -1))
+}
+
+func TestRRBInsertAtOutOfBounds(t *testing.T) {
+	defer assertPanic(t, "Index out of bounds")
+	NewIntRRBVector(inputSlice(0, 10)...).InsertAt(11, 1)
+}
+
+func TestRRBRemoveAt(t *testing.T) {
+	vec := NewIntRRBVector(inputSlice(0, 1000)...)
+	assertRRBVectorEqual(t, append(inputSlice(0, 10), inputSlice(110, 890)...), vec.RemoveAt(10, 100))
+	assertRRBVectorEqual(t, inputSlice(1, 999), vec.RemoveAt(0, 1))
+	assertRRBVectorEqual(t, inputSlice(0, 999), vec.RemoveAt(999, 1))
+	assertRRBVectorEqual(t, []int{}, vec.RemoveAt(0, 1000))
+	assertRRBVectorEqual(t, inputSlice(0, 1000), vec)
+}
+
+func TestRRBRandomOperations(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	expected := make([]int, 0)
+	vec := NewIntRRBVector()
+	for i := 0; i < 1000; i++ {
+		switch r.Intn(4) {
+		case 0:
+			items := inputSlice(i*1000, r.Intn(100))
+			pos := r.Intn(len(expected) + 1)
+			vec = vec.InsertAt(pos, items...)
+			expected = append(expected[:pos], append(items, expected[pos:]...)...)
+		case 1:
+			if len(expected) > 0 {
+				pos := r.Intn(len(expected))
+				n := r.Intn(len(expected) - pos)
+				vec = vec.RemoveAt(pos, n)
+				expected = append(expected[:pos], expected[pos+n:]...)
+			}
+		case 2:
+			items := inputSlice(i*1000, r.Intn(2000))
+			vec = vec.Concat(NewIntRRBVector(items...))
+			expected = append(expected, items...)
+		case 3:
+			if len(expected) > 0 {
+				pos := r.Intn(len(expected))
+				vec = vec.Set(pos, -i)
+				expected[pos] = -i
+			}
+		}
+	}
+
+	assertRRBVectorEqual(t, expected, vec)
+}
+
+func TestRRBCanceledIteration(t *testing.T) {
+	count := 0
+	NewIntRRBVector(inputSlice(0, 10000)...).Range(func(elem int) bool {
+		count++
+		return count < 5
+	})
+
+	assertEqual(t, 5, count)
+}
+
+func BenchmarkRRBConcat(b *testing.B) {
+	v1 := NewIntRRBVector(inputSlice(0, 100000)...)
+	v2 := NewIntRRBVector(inputSlice(0, 100000)...)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		result += v1.Concat(v2).Len()
+	}
+}
@@ -1,6 +1,9 @@
 package peds_testing
 
-import "testing"
+import (
+	"sort"
+	"testing"
+)
 
 func TestSetAdd(t *testing.T) {
 	s := NewFooSet()
@@ -160,8 +163,16 @@ func TestSetIntersection(t *testing.T) {
 func TestSetToNativeSlice(t *testing.T) {
 	set := NewIntSet(1, 2, 3)
 	theSlice := set.ToNativeSlice()
+	sort.Ints(theSlice)
 	assertEqual(t, 3, len(theSlice))
 	assertEqual(t, 1, theSlice[0])
 	assertEqual(t, 2, theSlice[1])
 	assertEqual(t, 3, theSlice[2])
 }
+
+func TestSetCustomHashAndEqual(t *testing.T) {
+	s := NewNameSet("Alice", "alice", "Bob")
+	assertEqual(t, 2, s.Len())
+	assertEqualBool(t, true, s.Contains("ALICE"))
+	assertEqualBool(t, false, s.Delete("BOB").Contains("bob"))
+}
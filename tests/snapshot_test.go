@@ -0,0 +1,363 @@
+package peds_testing
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/tobgu/peds/tests/subpackage5"
+)
+
+func TestVectorSnapshotSharesNodes(t *testing.T) {
+	v := NewIntVector(inputSlice(0, 10000)...)
+	versions := []*IntVector{v}
+	for i := 0; i < 100; i++ {
+		v = v.Set(i*100, -i).Append(i)
+		versions = append(versions, v)
+	}
+
+	buf := &bytes.Buffer{}
+	enc := NewSnapshotEncoder(buf, nil)
+	for _, version := range versions {
+		if err := version.EncodeSnapshot(enc); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	// Each version after the first one adds a couple of nodes only
+	single := &bytes.Buffer{}
+	if err := v.EncodeSnapshot(NewSnapshotEncoder(single, nil)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if buf.Len() > 3*single.Len() {
+		t.Errorf("Expected a snapshot of all versions to be smaller than %d bytes, was %d", 3*single.Len(), buf.Len())
+	}
+
+	dec := NewSnapshotDecoder(buf, nil)
+	decoded := make([]*IntVector, 0, len(versions))
+	for _, version := range versions {
+		d := &IntVector{}
+		if err := d.DecodeSnapshot(dec); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		assertEqual(t, version.Len(), d.Len())
+		for i := 0; i < d.Len(); i++ {
+			assertEqual(t, version.Get(i), d.Get(i))
+		}
+
+		decoded = append(decoded, d)
+	}
+
+	if err := (&IntVector{}).DecodeSnapshot(dec); err != io.EOF {
+		t.Errorf("Expected EOF, got %v", err)
+	}
+
+	// The structure sharing is restored, leaves that were not touched are shared
+	first, last := decoded[0], decoded[len(decoded)-1]
+	if &first.sliceFor(9930)[0] != &last.sliceFor(9930)[0] {
+		t.Error("Expected leaf to be shared between decoded versions")
+	}
+
+	// Decoded vectors can be updated without affecting each other
+	updated := last.Set(9930, 1).Append(1, 2, 3)
+	assertEqual(t, 9930, first.Get(9930))
+	assertEqual(t, 9930, last.Get(9930))
+	assertEqual(t, 1, updated.Get(9930))
+	assertEqual(t, 3, updated.Get(updated.Len()-1))
+}
+
+func TestEmptyVectorSnapshot(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := NewIntVector().EncodeSnapshot(NewSnapshotEncoder(buf, nil)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	v := &IntVector{}
+	if err := v.DecodeSnapshot(NewSnapshotDecoder(buf, nil)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	assertEqual(t, 0, v.Len())
+	v = v.Append(inputSlice(0, 100)...)
+	assertEqual(t, 99, v.Get(99))
+}
+
+func TestMapAndSetSnapshot(t *testing.T) {
+	m := NewIntStringMap()
+	for i := 0; i < 5000; i++ {
+		m = m.Store(i, "a")
+	}
+
+	m2 := m.Store(5000, "b").Delete(0)
+	s := NewIntSet(inputSlice(0, 100)...)
+	c := NewCollidingMap().Store(1, 1).Store(5, 5).Store(9, 9)
+
+	buf := &bytes.Buffer{}
+	enc := NewSnapshotEncoder(buf, nil)
+	for _, container := range []interface {
+		EncodeSnapshot(*SnapshotEncoder) error
+	}{m, s, m2, NewIntVector(1, 2), c} {
+		if err := container.EncodeSnapshot(enc); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	dec := NewSnapshotDecoder(buf, nil)
+	dm, ds, dm2, dv, dc := &IntStringMap{}, &IntSet{}, &IntStringMap{}, &IntVector{}, &CollidingMap{}
+	for _, container := range []interface {
+		DecodeSnapshot(*SnapshotDecoder) error
+	}{dm, ds, dm2, dv, dc} {
+		if err := container.DecodeSnapshot(dec); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	assertEqual(t, 5000, dm.Len())
+	assertEqual(t, 5000, dm2.Len())
+	value, ok := dm.Load(0)
+	assertEqualBool(t, true, ok)
+	assertEqualString(t, "a", value)
+	_, ok = dm2.Load(0)
+	assertEqualBool(t, false, ok)
+	value, _ = dm2.Load(5000)
+	assertEqualString(t, "b", value)
+
+	assertEqualBool(t, true, ds.Equals(s))
+	assertEqual(t, 2, dv.Get(1))
+	value2, _ := dc.Load(9)
+	assertEqual(t, 9, value2)
+
+	// Decoded maps can be updated as usual
+	dm = dm.Store(0, "c").Delete(1)
+	value, _ = dm.Load(0)
+	assertEqualString(t, "c", value)
+	assertEqual(t, 4999, dm.Len())
+	value, _ = dm2.Load(1)
+	assertEqualString(t, "a", value)
+}
+
+func TestSnapshotWrongContainer(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := NewIntVector(1).EncodeSnapshot(NewSnapshotEncoder(buf, nil)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	err := (&IntSet{}).DecodeSnapshot(NewSnapshotDecoder(buf, nil))
+	if err == nil || !strings.Contains(err.Error(), "expected a set in snapshot, found a vector") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestSnapshotTruncated(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := NewIntVector(inputSlice(0, 100)...).EncodeSnapshot(NewSnapshotEncoder(buf, nil)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data := buf.Bytes()
+	for _, n := range []int{2, len(data) / 2, len(data) - 1} {
+		err := (&IntVector{}).DecodeSnapshot(NewSnapshotDecoder(bytes.NewReader(data[:n]), nil))
+		if err != io.ErrUnexpectedEOF {
+			t.Errorf("Expected unexpected EOF for %d bytes, got %v", n, err)
+		}
+	}
+}
+
+// vectorSnapshotWriter writes vector snapshots by hand, to create invalid ones.
+type vectorSnapshotWriter struct {
+	buf      bytes.Buffer
+	elements bytes.Buffer
+	enc      *gob.Encoder
+}
+
+func newVectorSnapshotWriter() *vectorSnapshotWriter {
+	w := &vectorSnapshotWriter{}
+	w.enc = gob.NewEncoder(&w.elements)
+	w.uvarint(snapshotVersion)
+	return w
+}
+
+func (w *vectorSnapshotWriter) uvarint(x uint64) *vectorSnapshotWriter {
+	w.buf.Write(binary.AppendUvarint(nil, x))
+	return w
+}
+
+func (w *vectorSnapshotWriter) vector(length, shift uint64) *vectorSnapshotWriter {
+	w.buf.WriteByte(snapshotVectorTag)
+	return w.uvarint(length).uvarint(shift)
+}
+
+// node starts a new node with count children, which must be written next.
+func (w *vectorSnapshotWriter) node(count int) *vectorSnapshotWriter {
+	return w.uvarint(0).uvarint(uint64(count))
+}
+
+func (w *vectorSnapshotWriter) leaf(length int) *vectorSnapshotWriter {
+	w.elements.Reset()
+	if err := w.enc.Encode(inputSlice(0, length)); err != nil {
+		panic(err)
+	}
+
+	w.uvarint(0).uvarint(uint64(w.elements.Len()))
+	w.buf.Write(w.elements.Bytes())
+	return w
+}
+
+func (w *vectorSnapshotWriter) leaves(count, length int) *vectorSnapshotWriter {
+	for i := 0; i < count; i++ {
+		w.leaf(length)
+	}
+
+	return w
+}
+
+// ref refers to the id:th node written, counting from one.
+func (w *vectorSnapshotWriter) ref(id uint64) *vectorSnapshotWriter {
+	return w.uvarint(id)
+}
+
+func TestCorruptVectorSnapshot(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		snapshot *vectorSnapshotWriter
+		expected string
+	}{
+		{
+			name:     "valid",
+			snapshot: newVectorSnapshotWriter().vector(1057, 10).node(2).node(32).leaves(32, 32).node(1).leaf(32).leaf(1),
+		},
+		{
+			name:     "short leaf",
+			snapshot: newVectorSnapshotWriter().vector(72, 5).node(2).leaf(31).leaf(32).leaf(8),
+			expected: "invalid snapshot, vector node of length 31 where 32 was expected",
+		},
+		{
+			name:     "long rightmost leaf",
+			snapshot: newVectorSnapshotWriter().vector(72, 5).node(2).leaf(32).leaf(33).leaf(8),
+			expected: "invalid snapshot, vector node of length 33 where 32 was expected",
+		},
+		{
+			name:     "short tail",
+			snapshot: newVectorSnapshotWriter().vector(72, 5).node(2).leaves(2, 32).leaf(7),
+			expected: "invalid snapshot, vector node of length 7 where 8 was expected",
+		},
+		{
+			name:     "short node",
+			snapshot: newVectorSnapshotWriter().vector(1057, 10).node(2).node(31).leaves(31, 32).node(1).leaf(32).leaf(1),
+			expected: "invalid snapshot, vector node of length 31 where 32 was expected",
+		},
+		{
+			name:     "too many children",
+			snapshot: newVectorSnapshotWriter().vector(72, 5).node(3).leaves(3, 32).leaf(8),
+			expected: "invalid snapshot, vector node of length 3 where 2 was expected",
+		},
+		{
+			name:     "too deep",
+			snapshot: newVectorSnapshotWriter().vector(40, 10).node(1).node(1).leaf(32).leaf(8),
+			expected: "invalid snapshot, vector shift 10 in vector of length 40",
+		},
+		{
+			name:     "too shallow",
+			snapshot: newVectorSnapshotWriter().vector(1057, 5),
+			expected: "invalid snapshot, vector shift 5 in vector of length 1057",
+		},
+		{
+			// The tail of the first vector is shared as a non-rightmost leaf of the second
+			name: "shared partial leaf",
+			snapshot: newVectorSnapshotWriter().vector(40, 5).node(1).leaf(32).leaf(8).
+				vector(104, 5).node(3).ref(3).leaf(32).leaf(32).leaf(8),
+			expected: "invalid snapshot, vector node of length 8 where 32 was expected",
+		},
+		{
+			// The root of the first vector is shared as a leaf of the second
+			name: "shared node at wrong level",
+			snapshot: newVectorSnapshotWriter().vector(40, 5).node(1).leaf(32).leaf(8).
+				vector(72, 5).node(2).ref(2).ref(1).leaf(8),
+			expected: "invalid snapshot, unexpected node of type []peds_testing.commonNode",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dec := NewSnapshotDecoder(&tc.snapshot.buf, nil)
+			var err error
+			for err == nil {
+				err = (&IntVector{}).DecodeSnapshot(dec)
+			}
+
+			if tc.expected == "" {
+				if err != io.EOF {
+					t.Errorf("Expected EOF, got %v", err)
+				}
+
+				return
+			}
+
+			if err == nil || err.Error() != tc.expected {
+				t.Errorf("Expected %q, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestSnapshotElementEncodingError(t *testing.T) {
+	v := subpackage5.NewErrorFuncVector(func(int) error { return nil })
+	enc := subpackage5.NewSnapshotEncoder(&bytes.Buffer{}, nil)
+	err := v.EncodeSnapshot(enc)
+	if err == nil {
+		t.Fatal("Expected error encoding functions")
+	}
+
+	// The encoder is unusable after an error
+	if err2 := subpackage5.NewFloat4Vector([4]float64{}).EncodeSnapshot(enc); err2 != err {
+		t.Errorf("Expected %v, got %v", err, err2)
+	}
+}
+
+func TestSnapshotCustomElementEncoding(t *testing.T) {
+	buf := &bytes.Buffer{}
+	enc := NewSnapshotEncoder(buf, func(w io.Writer) SnapshotElementEncoder { return json.NewEncoder(w) })
+	v := NewIntVector(inputSlice(0, 100)...)
+	for _, version := range []*IntVector{v, v.Append(100)} {
+		if err := version.EncodeSnapshot(enc); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if !bytes.Contains(buf.Bytes(), []byte("[0,1,2,3")) {
+		t.Error("Expected JSON encoded elements")
+	}
+
+	dec := NewSnapshotDecoder(buf, func(r io.Reader) SnapshotElementDecoder { return json.NewDecoder(r) })
+	for _, expectedLen := range []int{100, 101} {
+		d := &IntVector{}
+		if err := d.DecodeSnapshot(dec); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		assertEqual(t, expectedLen, d.Len())
+		assertEqual(t, expectedLen-1, d.Get(expectedLen-1))
+	}
+}
+
+func TestSnapshotOfMapWithCustomHash(t *testing.T) {
+	buf := &bytes.Buffer{}
+	enc := NewSnapshotEncoder(buf, func(w io.Writer) SnapshotElementEncoder { return gob.NewEncoder(w) })
+	if err := NewNameIntMap().Store("Anna", 1).EncodeSnapshot(enc); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	m := &NameIntMap{}
+	if err := m.DecodeSnapshot(NewSnapshotDecoder(buf, nil)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Custom hash and equality functions are used for the decoded map
+	value, ok := m.Load("ANNA")
+	assertEqualBool(t, true, ok)
+	assertEqual(t, 1, value)
+}
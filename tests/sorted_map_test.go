@@ -0,0 +1,294 @@
+package peds_testing
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func sortedMapKeys(m *IntStringSortedMap) []int {
+	keys := make([]int, 0, m.Len())
+	m.Range(func(k int, _ string) bool {
+		keys = append(keys, k)
+		return true
+	})
+
+	return keys
+}
+
+func assertSortedMapEqual(t *testing.T, expected map[int]string, m *IntStringSortedMap) {
+	t.Helper()
+	assertEqual(t, len(expected), m.Len())
+
+	expectedKeys := make([]int, 0, len(expected))
+	for k := range expected {
+		expectedKeys = append(expectedKeys, k)
+	}
+
+	sort.Ints(expectedKeys)
+	keys := sortedMapKeys(m)
+	assertEqual(t, len(expectedKeys), len(keys))
+	for i, k := range expectedKeys {
+		assertEqual(t, k, keys[i])
+		v, ok := m.Load(k)
+		assertEqualBool(t, true, ok)
+		assertEqualString(t, expected[k], v)
+
+		rankKey, rankValue := m.Get(i)
+		assertEqual(t, k, rankKey)
+		assertEqualString(t, expected[k], rankValue)
+	}
+}
+
+func TestSortedMapStoreAndDelete(t *testing.T) {
+	for _, l := range testSizes {
+		t.Run(fmt.Sprintf("StoreAndDelete %d", l), func(t *testing.T) {
+			m := NewIntStringSortedMap()
+			expected := make(map[int]string)
+			for _, k := range rand.Perm(l) {
+				m = m.Store(k, fmt.Sprint(k))
+				expected[k] = fmt.Sprint(k)
+			}
+
+			assertSortedMapEqual(t, expected, m)
+
+			m2 := m
+			for k := 0; k < l; k += 2 {
+				m2 = m2.Delete(k)
+				delete(expected, k)
+			}
+
+			assertSortedMapEqual(t, expected, m2)
+			assertEqual(t, l, m.Len())
+		})
+	}
+}
+
+func TestSortedMapRandomOperations(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	m := NewIntStringSortedMap()
+	expected := make(map[int]string)
+	for i := 0; i < 50000; i++ {
+		k := r.Intn(20000)
+		if r.Intn(3) == 0 {
+			m = m.Delete(k)
+			delete(expected, k)
+		} else {
+			m = m.Store(k, fmt.Sprint(i))
+			expected[k] = fmt.Sprint(i)
+		}
+	}
+
+	assertSortedMapEqual(t, expected, m)
+	for k := range expected {
+		m = m.Delete(k)
+	}
+
+	assertEqual(t, 0, m.Len())
+}
+
+func TestSortedMapDeleteNonExistingKey(t *testing.T) {
+	m := NewIntStringSortedMap(IntStringSortedMapItem{Key: 1, Value: "a"})
+	assertEqualBool(t, true, m == m.Delete(2))
+}
+
+func TestSortedMapMinMaxFloorCeiling(t *testing.T) {
+	m := NewIntStringSortedMap()
+	_, _, ok := m.Min()
+	assertEqualBool(t, false, ok)
+	_, _, ok = m.Max()
+	assertEqualBool(t, false, ok)
+
+	for i := 0; i < 1000; i++ {
+		m = m.Store(10*i, fmt.Sprint(i))
+	}
+
+	k, v, _ := m.Min()
+	assertEqual(t, 0, k)
+	assertEqualString(t, "0", v)
+	k, _, _ = m.Max()
+	assertEqual(t, 9990, k)
+
+	for i := -5; i < 10005; i++ {
+		k, _, ok := m.Floor(i)
+		assertEqualBool(t, i >= 0, ok)
+		if ok {
+			expected := i - i%10
+			if i > 9990 {
+				expected = 9990
+			}
+
+			assertEqual(t, expected, k)
+		}
+
+		k, _, ok = m.Ceiling(i)
+		assertEqualBool(t, i <= 9990, ok)
+		if ok {
+			expected := i
+			if i < 0 {
+				expected = 0
+			} else if i%10 != 0 {
+				expected = i - i%10 + 10
+			}
+
+			assertEqual(t, expected, k)
+		}
+	}
+}
+
+func TestSortedMapRangeFromAndBetween(t *testing.T) {
+	m := NewIntStringSortedMap()
+	for i := 0; i < 1000; i++ {
+		m = m.Store(2*i, fmt.Sprint(i))
+	}
+
+	keys := make([]int, 0)
+	m.RangeFrom(1501, func(k int, _ string) bool {
+		keys = append(keys, k)
+		return true
+	})
+
+	assertEqual(t, 249, len(keys))
+	assertEqual(t, 1502, keys[0])
+	assertEqual(t, 1998, keys[len(keys)-1])
+
+	keys = keys[:0]
+	m.RangeBetween(100, 200, func(k int, _ string) bool {
+		keys = append(keys, k)
+		return true
+	})
+
+	assertEqual(t, 50, len(keys))
+	for i, k := range keys {
+		assertEqual(t, 100+2*i, k)
+	}
+
+	count := 0
+	m.RangeFrom(0, func(k int, _ string) bool {
+		count++
+		return count < 5
+	})
+
+	assertEqual(t, 5, count)
+}
+
+func TestSortedMapGetOutOfBounds(t *testing.T) {
+	defer assertPanic(t, "Index out of bounds")
+	NewIntStringSortedMap(IntStringSortedMapItem{Key: 1, Value: "a"}).Get(1)
+}
+
+func TestSortedMapCustomLess(t *testing.T) {
+	m := NewNameIntSortedMap().Store("bob", 1).Store("Alice", 2).Store("carl", 3).Store("ALICE", 4)
+	assertEqual(t, 3, m.Len())
+	k, v := m.Get(0)
+	assertEqualString(t, "ALICE", string(k))
+	assertEqual(t, 4, v)
+	k, _ = m.Get(2)
+	assertEqualString(t, "carl", string(k))
+}
+
+func TestSortedSet(t *testing.T) {
+	s := NewIntSortedSet(5, 3, 9, 1, 3)
+	assertEqual(t, 4, s.Len())
+	assertEqualBool(t, true, s.Contains(9))
+	assertEqualBool(t, false, s.Delete(9).Contains(9))
+	assertEqual(t, 3, s.Get(1))
+
+	actual := s.ToNativeSlice()
+	for i, x := range []int{1, 3, 5, 9} {
+		assertEqual(t, x, actual[i])
+	}
+
+	x, _ := s.Floor(4)
+	assertEqual(t, 3, x)
+	x, _ = s.Ceiling(4)
+	assertEqual(t, 5, x)
+	x, _ = s.Min()
+	assertEqual(t, 1, x)
+	x, _ = s.Max()
+	assertEqual(t, 9, x)
+
+	between := make([]int, 0)
+	s.RangeBetween(3, 9, func(x int) bool {
+		between = append(between, x)
+		return true
+	})
+
+	assertEqual(t, 2, len(between))
+}
+
+func BenchmarkSortedMapStore(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		m := NewIntStringSortedMap()
+		for i := 0; i < 10000; i++ {
+			m = m.Store(i, "")
+		}
+
+		result += m.Len()
+	}
+}
+
+// assertValidBTree checks that all nodes below the root of m hold between btreeMinItems
+// and btreeMaxItems items, that all leaves are at the same depth and that the sizes and
+// key order of the nodes are consistent.
+func assertValidBTree(t *testing.T, m *IntStringSortedMap) {
+	t.Helper()
+	leafDepth := -1
+	var check func(n *privateIntStringSortedMapItemNode, depth int)
+	check = func(n *privateIntStringSortedMapItemNode, depth int) {
+		if n != m.root && (len(n.items) < btreeMinItems || len(n.items) > btreeMaxItems) {
+			t.Fatalf("Node at depth %d has %d items", depth, len(n.items))
+		}
+
+		size := len(n.items)
+		for i := 1; i < len(n.items); i++ {
+			if n.items[i-1].Key >= n.items[i].Key {
+				t.Fatalf("Items out of order at depth %d: %d, %d", depth, n.items[i-1].Key, n.items[i].Key)
+			}
+		}
+
+		if n.isLeaf() {
+			if leafDepth == -1 {
+				leafDepth = depth
+			} else if leafDepth != depth {
+				t.Fatalf("Leaves at depths %d and %d", leafDepth, depth)
+			}
+		} else {
+			assertEqual(t, len(n.items)+1, len(n.children))
+			for _, child := range n.children {
+				check(child, depth+1)
+				size += child.size
+			}
+		}
+
+		assertEqual(t, size, n.size)
+	}
+
+	check(m.root, 0)
+}
+
+func TestSortedMapFromNativeMapBuildsValidTree(t *testing.T) {
+	for _, l := range []int{0, 1, 31, 32, 100, 1000, 1023, 1024, 40000} {
+		t.Run(fmt.Sprintf("FromNativeMap %d", l), func(t *testing.T) {
+			expected := make(map[int]string, l)
+			for _, k := range rand.Perm(l) {
+				expected[k] = fmt.Sprint(k)
+			}
+
+			m := NewIntStringSortedMapFromNativeMap(expected)
+			assertValidBTree(t, m)
+			assertSortedMapEqual(t, expected, m)
+
+			for k := 0; k < l; k += 3 {
+				m = m.Delete(k)
+				delete(expected, k)
+			}
+
+			m = m.Store(l, "last").Store(-1, "first")
+			expected[l], expected[-1] = "last", "first"
+			assertValidBTree(t, m)
+			assertSortedMapEqual(t, expected, m)
+		})
+	}
+}
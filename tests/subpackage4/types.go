@@ -0,0 +1,37 @@
+// Package subpackage4 contains types annotated with peds directives. The containers
+// are generated into peds_gen.go in this package during testing.
+package subpackage4
+
+import (
+	"hash/crc32"
+	"strings"
+
+	"github.com/tobgu/peds/tests/subpackage"
+)
+
+// Person is the element type of the containers declared below.
+//
+//peds:vector PersonVector
+//peds:map PersonByName key=Name
+//peds:sortedmap PersonByAge key=int
+//peds:orderedmap PersonByBaz key=subpackage.Baz
+type Person struct {
+	Name Name
+	Age  int
+	Baz  subpackage.Baz
+}
+
+type (
+	// Name is compared case insensitively.
+	//
+	//peds:set NameSet hash=NameHash eq=NameEq
+	Name string
+)
+
+func NameHash(n Name) uint32 {
+	return crc32.ChecksumIEEE([]byte(strings.ToLower(string(n))))
+}
+
+func NameEq(n1, n2 Name) bool {
+	return strings.EqualFold(string(n1), string(n2))
+}
@@ -1,17 +1,80 @@
 package peds_testing
 
+import (
+	"hash/crc32"
+	"strings"
+
+	othersubpackage "github.com/tobgu/peds/tests/other/subpackage"
+	"github.com/tobgu/peds/tests/subpackage"
+)
+
 // Types for testing.
 // Defined in a separate file from the tests since they are not picked up
 // if defined in a files with a *_test.go name.
 type Foo uint
 type Bar float64
 
+// Name is compared case insensitively using custom hash and equality functions.
+type Name string
+
+func NameHash(n Name) uint32 {
+	return crc32.ChecksumIEEE([]byte(strings.ToLower(string(n))))
+}
+
+func NameEq(n1, n2 Name) bool {
+	return strings.EqualFold(string(n1), string(n2))
+}
+
+func NameLess(n1, n2 Name) bool {
+	return strings.ToLower(string(n1)) < strings.ToLower(string(n2))
+}
+
+// CollidingKey has a hash function with very few distinct values to force hash collisions.
+type CollidingKey int
+
+func CollidingKeyHash(k CollidingKey) uint32 {
+	return uint32(k % 4)
+}
+
+// Job is ordered by priority using a custom less function, it is not comparable.
+type Job struct {
+	Priority int
+	Tags     []string
+}
+
+func JobLess(j1, j2 Job) bool {
+	return j1.Priority < j2.Priority
+}
+
+// The packages of the types below are imported by this file, which is where peds
+// looks for the import paths of package qualifiers used in the type specifications.
+type Baz = subpackage.Baz
+type Qux = othersubpackage.Qux
+
 // NOTE: The awkward quoting below is just to test that white spaces in the type specifications are ignored.
 //       If you stay away from using white space the quoting should not be required.
-//go:generate peds "-vectors=\"FooVector<Foo>; IntVector<int >;ImportVector<subpackage.Baz>\"" "-maps=\"StringIntMap<string, int>;IntStringMap<int,string>\"" "-sets=\"FooSet<Foo>; IntSet< int>\"" -pkg=peds_testing -file=types_gen.go -imports github.com/tobgu/peds/tests/subpackage
+//go:generate peds "-vectors=\"FooVector<Foo>; IntVector<int >;ImportVector<subpackage.Baz>;QuxVector<othersubpackage.Qux>\"" -rrbvectors=IntRRBVector<int> "-maps=\"StringIntMap<string, int>;IntStringMap<int,string>;NameIntMap<Name,int;hash=NameHash;eq=NameEq>;CollidingMap<CollidingKey,int;hash=CollidingKeyHash>;VectorCounts<IntVector,int>\"" "-sets=\"FooSet<Foo>; IntSet< int>;NameSet<Name;hash=NameHash;eq=NameEq>;Paths<IntVector>\"" "-sortedmaps=\"IntStringSortedMap<int,string>;NameIntSortedMap<Name,int;less=NameLess>\"" -sortedsets=IntSortedSet<int> -orderedmaps=StringIntOrderedMap<string,int> -deques=IntDeque<int> "-heaps=IntHeap<int>;JobQueue<Job;less=JobLess>" -pkg=peds_testing -file=types_gen.go
 
 // Put vector in other package than type it contains
-//go:generate peds "-vectors=\"OtherVector<subpackage.Baz>\"" -pkg=subpackage2 -file=subpackage2/types_gen.go -imports github.com/tobgu/peds/tests/subpackage
+//go:generate peds "-vectors=\"OtherVector<subpackage.Baz>\"" -pkg=subpackage2 -file=subpackage2/types_gen.go
+
+// Generate containers into multiple files in the same package sharing one common file
+//go:generate peds -config=subpackage3/peds.yaml
+
+// Composite element and key types
+//go:generate peds "-vectors=ByteSliceVector<[]byte>;ErrorFuncVector<func(int) error>;IntChanVector<<-chan int>;Float4Vector<[4]float64>" "-maps=StringMapMap<string, map[string]int>;ArrayKeyMap<[2]int,string>;PointKeyMap<struct{X, Y int},string>" -sets=ArraySet<[2]string> -pkg=subpackage5 -file=subpackage5/types_gen.go
+
+// Generate containers declared by //peds: directives on the types in a package
+//go:generate peds -scan=subpackage4
+
+// Containers with a selection of methods
+//go:generate peds "-vectors=IntStack<int;methods=Append,Get,Len>" "-rrbvectors=IntRope<int;methods=minimal,Concat>" "-maps=StringIntMap<string,int;methods=Load,Store>" "-sets=IntSet<int;methods=minimal>" "-sortedmaps=IntStringSortedMap<int,string;methods=standard>" "-sortedsets=IntSortedSet<int;methods=Add,Min>" "-orderedmaps=StringIntOrderedMap<string,int;methods=Store,Range>" "-deques=IntQueue<int;methods=PushBack,PopFront>" "-heaps=IntHeap<int;methods=Push,Pop>" -pkg=subpackage6 -file=subpackage6/types_gen.go
+
+// Containers used as elements, keys and values of other containers
+//go:generate peds "-vectors=Path<string;methods=minimal>;PathVector<Path>" "-maps=PathSetIndex<PathSet,int;methods=minimal>;PathValues<string,Path>" "-sets=PathSet<Path>;OtherVectorSet<subpackage2.OtherVector>" -pkg=subpackage7 -file=subpackage7/types_gen.go
+
+// Containers with iterator methods, the tests module itself targets an older Go version
+//go:generate peds -vectors=IntIterVector<int> -maps=StringIntIterMap<string,int> -sets=IntIterSet<int> -goversion=1.23 -pkg=subpackage8 -file=subpackage8/types_gen.go
 
 //  go generate seems to require a function in the file that contains the generation expression...
 func f() {
@@ -3,6 +3,9 @@ package peds_testing
 import (
 	"fmt"
 	"github.com/tobgu/peds/tests/subpackage2"
+	"github.com/tobgu/peds/tests/subpackage3"
+	"github.com/tobgu/peds/tests/subpackage4"
+	"github.com/tobgu/peds/tests/subpackage5"
 	"runtime"
 	"strings"
 	"testing"
@@ -85,6 +88,19 @@ func TestSetItem(t *testing.T) {
 	}
 }
 
+func TestAppendAfterSet(t *testing.T) {
+	// Set on the last, partially filled, branch of the trie must not leave
+	// holes that appends descend into later
+	vec := NewIntVector(inputSlice(0, 1100)...).Set(1060, -1)
+	appended := vec.Append(inputSlice(1100, 2000)...)
+	assertEqual(t, -1, appended.Get(1060))
+	assertEqual(t, 3099, appended.Get(3099))
+
+	tr := vec.AsTransient()
+	tr.Append(inputSlice(1100, 2000)...)
+	assertEqual(t, 3099, tr.Persistent().Get(3099))
+}
+
 func TestAppend(t *testing.T) {
 	for _, l := range testSizes {
 		vec := NewIntVector(inputSlice(0, l)...)
@@ -103,6 +119,100 @@ func TestAppend(t *testing.T) {
 	}
 }
 
+func TestPop(t *testing.T) {
+	for _, l := range testSizes[1:] {
+		t.Run(fmt.Sprintf("Pop %d", l), func(t *testing.T) {
+			vec := NewIntVector(inputSlice(0, l)...)
+			for i := l - 1; i >= 0; i-- {
+				newVec, item := vec.Pop()
+				assertEqual(t, i, item)
+				assertEqual(t, i, newVec.Len())
+				if i > 0 {
+					assertEqual(t, i-1, newVec.Get(i-1))
+				}
+
+				// Original vector is unchanged
+				assertEqual(t, i+1, vec.Len())
+				assertEqual(t, i, vec.Get(i))
+				vec = newVec
+			}
+		})
+	}
+}
+
+func TestPopEmpty(t *testing.T) {
+	defer assertPanic(t, "Pop on empty vector")
+	NewIntVector().Pop()
+}
+
+func TestDropLast(t *testing.T) {
+	for _, l := range testSizes {
+		vec := NewIntVector(inputSlice(0, l)...)
+		t.Run(fmt.Sprintf("DropLast %d", l), func(t *testing.T) {
+			for _, n := range []int{0, 1, 31, 32, 33, l / 2, l - 33, l - 32, l - 1, l} {
+				if n < 0 || n > l {
+					continue
+				}
+
+				newVec := vec.DropLast(n)
+				assertEqual(t, l-n, newVec.Len())
+				for j := 0; j < l-n; j++ {
+					assertEqual(t, j, newVec.Get(j))
+				}
+
+				// The result can be grown again
+				newVec = newVec.Append(inputSlice(l-n, n+40)...)
+				assertEqual(t, l+40, newVec.Len())
+				for j := 0; j < l+40; j++ {
+					assertEqual(t, j, newVec.Get(j))
+				}
+
+				assertEqual(t, l, vec.Len())
+			}
+		})
+	}
+}
+
+func TestDropFirst(t *testing.T) {
+	for _, l := range testSizes {
+		vec := NewIntVector(inputSlice(0, l)...)
+		t.Run(fmt.Sprintf("DropFirst %d", l), func(t *testing.T) {
+			for _, n := range []int{0, 1, 31, 32, 33, l / 2, l - 1, l} {
+				if n < 0 || n > l {
+					continue
+				}
+
+				newVec := vec.DropFirst(n)
+				assertEqual(t, l-n, newVec.Len())
+				for j := 0; j < l-n; j++ {
+					assertEqual(t, n+j, newVec.Get(j))
+				}
+
+				assertEqual(t, l, vec.Len())
+			}
+		})
+	}
+}
+
+func TestVectorDropOutOfBounds(t *testing.T) {
+	tests := []struct {
+		name string
+		drop func(v *IntVector) *IntVector
+	}{
+		{"DropLast negative", func(v *IntVector) *IntVector { return v.DropLast(-1) }},
+		{"DropLast beyond end", func(v *IntVector) *IntVector { return v.DropLast(11) }},
+		{"DropFirst negative", func(v *IntVector) *IntVector { return v.DropFirst(-1) }},
+		{"DropFirst beyond end", func(v *IntVector) *IntVector { return v.DropFirst(11) }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer assertPanic(t, "Index out of bounds")
+			test.drop(NewIntVector(inputSlice(0, 10)...))
+		})
+	}
+}
+
 func TestVectorSetOutOfBoundsNegative(t *testing.T) {
 	defer assertPanic(t, "Index out of bounds")
 	NewIntVector(inputSlice(0, 10)...).Set(-1, 0)
@@ -336,6 +446,76 @@ func TestVectorInOtherPackage(t *testing.T) {
 	assertEqual(t, 3, v.Len())
 }
 
+func TestContainersFromMultipleGenerationsInOnePackage(t *testing.T) {
+	v := subpackage3.NewIntVector(1, 2, 3)
+	assertEqual(t, 3, v.Len())
+
+	m := subpackage3.NewStringIntMap().Store("a", 1)
+	assertEqual(t, 1, m.Len())
+
+	rrb := subpackage3.NewIntRRBVector(1, 2).Concat(subpackage3.NewIntRRBVector(3))
+	assertEqual(t, 3, rrb.Len())
+}
+
+func TestContainersFromDirectives(t *testing.T) {
+	alice := subpackage4.Person{Name: "Alice", Age: 30}
+	bob := subpackage4.Person{Name: "Bob", Age: 25}
+
+	v := subpackage4.NewPersonVector(alice, bob)
+	assertEqual(t, 2, v.Len())
+
+	m := subpackage4.NewPersonByName().Store(alice.Name, alice)
+	p, ok := m.Load("Alice")
+	assertEqualBool(t, true, ok)
+	assertEqual(t, 30, p.Age)
+
+	sm := subpackage4.NewPersonByAge().Store(alice.Age, alice).Store(bob.Age, bob)
+	_, p = sm.Get(0)
+	assertEqualString(t, "Bob", string(p.Name))
+
+	om := subpackage4.NewPersonByBaz().Store(2, bob).Store(1, alice)
+	assertEqualString(t, "Bob", string(om.ToNativeSlice()[0].Value.Name))
+
+	s := subpackage4.NewNameSet("alice", "ALICE", "bob")
+	assertEqual(t, 2, s.Len())
+}
+
+func TestVectorOfTypeFromPackagesSharingName(t *testing.T) {
+	v := NewQuxVector("a").Append("b")
+	assertEqualString(t, "b", string(v.Get(1)))
+	assertEqual(t, 3, int(NewImportVector(3).Get(0)))
+}
+
+func TestContainersOfCompositeTypes(t *testing.T) {
+	v := subpackage5.NewByteSliceVector([]byte("a"), []byte("bc"))
+	assertEqualString(t, "bc", string(v.Get(1)))
+
+	f := subpackage5.NewErrorFuncVector(func(i int) error { return fmt.Errorf("%d", i) })
+	assertEqualString(t, "5", f.Get(0)(5).Error())
+
+	c := make(chan int, 1)
+	c <- 7
+	assertEqual(t, 7, <-subpackage5.NewIntChanVector(c).Get(0))
+
+	a := subpackage5.NewFloat4Vector([4]float64{1, 2, 3, 4})
+	assertEqual(t, 4, int(a.Get(0)[3]))
+
+	m := subpackage5.NewStringMapMap().Store("a", map[string]int{"b": 1})
+	inner, _ := m.Load("a")
+	assertEqual(t, 1, inner["b"])
+
+	am := subpackage5.NewArrayKeyMap().Store([2]int{1, 2}, "a").Store([2]int{2, 1}, "b")
+	value, _ := am.Load([2]int{2, 1})
+	assertEqualString(t, "b", value)
+
+	pm := subpackage5.NewPointKeyMap().Store(struct{ X, Y int }{1, 2}, "p")
+	value, _ = pm.Load(struct{ X, Y int }{1, 2})
+	assertEqualString(t, "p", value)
+
+	s := subpackage5.NewArraySet([2]string{"a", "b"}, [2]string{"a", "b"}, [2]string{"b", "a"})
+	assertEqual(t, 2, s.Len())
+}
+
 func TestToNativeVector(t *testing.T) {
 	lengths := []int{0, 1, 7, 32, 512, 1000}
 	for _, length := range lengths {
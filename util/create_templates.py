@@ -1,41 +1,68 @@
 # Basic script to generate go text template strings from container code.
 # Compatible with Python 2 and 3. Only relies on stdlib.
 
+import glob
+
 if __name__ == "__main__":
     template_start_mark = '//template:'
-    input_file_name = 'internal/generic_types/containers.go'
+    input_file_names = sorted(glob.glob('internal/generic_types/*.go'))
     output_file_name = 'internal/templates/templates.go'
     template_package_name = 'templates'
-    generic_types = {'GenericVectorType': 'VectorTypeName',
+    generic_types = {'GenericRRBVectorType': 'VectorTypeName',
+                     'GenericVectorType': 'VectorTypeName',
+                     'GenericDequeType': 'DequeTypeName',
+                     'GenericHeapType': 'HeapTypeName',
                      'GenericType': 'TypeName',
                      'GenericMapType': 'MapTypeName',
                      'GenericMapItem': 'MapItemTypeName',
                      'GenericMapKeyType': 'MapKeyTypeName',
                      'GenericMapValueType': 'MapValueTypeName',
                      'genericHash': 'MapKeyHashFunc',
-                     'GenericSetType': 'SetTypeName'}
+                     'genericEqual': 'MapKeyEqFunc',
+                     'genericLess': 'MapKeyLessFunc',
+                     'genericHeapLess': 'HeapLessFunc',
+                     'GenericSetType': 'SetTypeName',
+                     'GenericSortedMapType': 'MapTypeName',
+                     'GenericSortedMapItem': 'MapItemTypeName',
+                     'GenericSortedSetType': 'SetTypeName',
+                     'GenericOrderedMapType': 'MapTypeName',
+                     'GenericOrderedMapItem': 'MapItemTypeName',
+                     'GenericComparableType': 'ValueTypeName',
+                     'GenericContainerType': 'ValueTypeName',
+                     'genericComparableHash': 'ValueHashFunc',
+                     'genericContainerHash': 'ValueHashFunc',
+                     'genericComparableEqual': 'ValueEqualFunc',
+                     'genericContainerEqual': 'ValueEqualFunc',
+                     'genericContainerKeyHash': 'ValueHashFunc',
+                     'genericContainerKeyEqual': 'ValueEqualFunc',
+                     'genericBasicHash': 'BasicHashFunc',
+                     'genericElementHash': 'ElementHashFunc',
+                     'genericElementEqual': 'ElementEqualFunc',
+                     'genericValueHash': 'MapValueHashFunc',
+                     'genericValueEqual': 'MapValueEqFunc'}
 
-    state = 'searching'
-    template_name = ''
-    template = ''
     templates = {}
 
     print("Generating templates")
-    with open(input_file_name, 'r') as input_file:
-        for line in input_file:
-            if line.startswith(template_start_mark):
-                if state == 'reading':
-                    templates[template_name] = template
-
-                template_name = line[len(template_start_mark):].strip()
-                template = ''
-                state = 'reading'
-            else:
-                if state == 'reading':
-                    template += line
-
-        if state == 'reading':
-            templates[template_name] = template
+    for input_file_name in input_file_names:
+        state = 'searching'
+        template_name = ''
+        template = ''
+        with open(input_file_name, 'r') as input_file:
+            for line in input_file:
+                if line.startswith(template_start_mark):
+                    if state == 'reading':
+                        templates[template_name] = template
+
+                    template_name = line[len(template_start_mark):].strip()
+                    template = ''
+                    state = 'reading'
+                else:
+                    if state == 'reading':
+                        template += line
+
+            if state == 'reading':
+                templates[template_name] = template
 
     with open(output_file_name, 'w') as output_file:
         output_file.write('package {}\n\n'.format(template_package_name))